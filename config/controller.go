@@ -36,11 +36,19 @@ func Exist(obj IConfiguration) bool {
 }
 
 func Load(obj IConfiguration) error {
+	// Values that are not in the file retain their previous value: make sure these are not expanded twice
+	_ = unexpanded(obj)
+
 	err := read(obj, obj.GetLocation()...)
 	if err != nil {
 		return err
 	}
 
+	err = expand(obj)
+	if err != nil {
+		return err
+	}
+
 	obj.OnIO()
 
 	return nil
@@ -49,6 +57,10 @@ func Load(obj IConfiguration) error {
 func Save(obj IConfiguration) error {
 	obj.OnIO()
 
+	// Persist expressions, not their expanded values
+	restore := unexpanded(obj)
+	defer restore()
+
 	return write(obj, obj.GetLocation()...)
 }
 
@@ -0,0 +1,9 @@
+package config
+
+import "errors"
+
+var (
+	ErrExpressionCycle   = errors.New("cycle detected in configuration expression")
+	ErrInvalidExpression = errors.New("invalid configuration expression")
+	ErrUnknownReference  = errors.New("unknown reference in configuration expression")
+)
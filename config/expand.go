@@ -0,0 +1,229 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Expressions may appear in any string value of a configuration object:
+// - ${env.NAME} is replaced by the environment variable NAME
+// - ${dirs.home}, ${dirs.data}, ${dirs.cache}, ${dirs.log} and ${dirs.config} are replaced by the app directories
+// - ${some.key} is replaced by the (expanded) value of another string key, designated by its json path
+// Use $${ to produce a literal ${.
+const (
+	exprOpen   = "${"
+	exprClose  = "}"
+	exprEscape = "$${"
+	exprEnv    = "env."
+	exprDirs   = "dirs."
+)
+
+// expressions remembers the original (unexpanded) values per configuration object, so that Save preserves them.
+var expressions sync.Map //nolint:gochecknoglobals
+
+type expander struct {
+	obj      IConfiguration
+	values   map[string]reflect.Value
+	raw      map[string]string
+	resolved map[string]string
+	stack    map[string]bool
+}
+
+// expand replaces expressions in all string values of obj.
+func expand(obj IConfiguration) error {
+	exp := &expander{
+		obj:      obj,
+		values:   map[string]reflect.Value{},
+		raw:      map[string]string{},
+		resolved: map[string]string{},
+		stack:    map[string]bool{},
+	}
+
+	walkStrings(reflect.ValueOf(obj), "", func(key string, value reflect.Value) {
+		exp.values[key] = value
+		exp.raw[key] = value.String()
+	})
+
+	originals := map[string]string{}
+
+	for key, value := range exp.values {
+		if !strings.Contains(exp.raw[key], exprOpen) {
+			continue
+		}
+
+		expanded, err := exp.resolve(key)
+		if err != nil {
+			return err
+		}
+
+		originals[key] = exp.raw[key]
+		value.SetString(expanded)
+	}
+
+	expressions.Store(obj, originals)
+
+	return nil
+}
+
+// unexpanded temporarily restores original expressions into obj, and returns a function to re-apply expanded values.
+func unexpanded(obj IConfiguration) func() {
+	stored, ok := expressions.Load(obj)
+	if !ok {
+		return func() {}
+	}
+
+	originals, _ := stored.(map[string]string)
+	expanded := map[string]string{}
+
+	walkStrings(reflect.ValueOf(obj), "", func(key string, value reflect.Value) {
+		if original, ok := originals[key]; ok {
+			expanded[key] = value.String()
+			value.SetString(original)
+		}
+	})
+
+	return func() {
+		walkStrings(reflect.ValueOf(obj), "", func(key string, value reflect.Value) {
+			if v, ok := expanded[key]; ok {
+				value.SetString(v)
+			}
+		})
+	}
+}
+
+func (exp *expander) resolve(key string) (string, error) {
+	if value, ok := exp.resolved[key]; ok {
+		return value, nil
+	}
+
+	if exp.stack[key] {
+		return "", fmt.Errorf("%w: %s", ErrExpressionCycle, key)
+	}
+
+	exp.stack[key] = true
+	defer delete(exp.stack, key)
+
+	value, err := exp.interpolate(key, exp.raw[key])
+	if err != nil {
+		return "", err
+	}
+
+	exp.resolved[key] = value
+
+	return value, nil
+}
+
+func (exp *expander) interpolate(key string, input string) (string, error) {
+	var out strings.Builder
+
+	for {
+		start := strings.Index(input, exprOpen)
+		if start == -1 {
+			out.WriteString(input)
+
+			return out.String(), nil
+		}
+
+		if start > 0 && strings.HasPrefix(input[start-1:], exprEscape) {
+			out.WriteString(input[:start-1] + exprOpen)
+			input = input[start+len(exprOpen):]
+
+			continue
+		}
+
+		end := strings.Index(input[start:], exprClose)
+		if end == -1 {
+			return "", fmt.Errorf("%w: unterminated expression in %s", ErrInvalidExpression, key)
+		}
+
+		ref := input[start+len(exprOpen) : start+end]
+
+		value, err := exp.lookup(key, ref)
+		if err != nil {
+			return "", err
+		}
+
+		out.WriteString(input[:start] + value)
+		input = input[start+end+len(exprClose):]
+	}
+}
+
+func (exp *expander) lookup(key string, ref string) (string, error) {
+	switch {
+	case strings.HasPrefix(ref, exprEnv):
+		return os.Getenv(strings.TrimPrefix(ref, exprEnv)), nil
+	case strings.HasPrefix(ref, exprDirs):
+		switch strings.TrimPrefix(ref, exprDirs) {
+		case "home":
+			return exp.obj.GetHome(), nil
+		case "data":
+			return exp.obj.GetDataRoot(), nil
+		case "cache":
+			return exp.obj.GetCacheRoot(), nil
+		case "log":
+			return exp.obj.GetLogRoot(), nil
+		case "config":
+			return exp.obj.Resolve(), nil
+		}
+	default:
+		if _, ok := exp.values[ref]; ok {
+			return exp.resolve(ref)
+		}
+	}
+
+	return "", fmt.Errorf("%w: %s (in %s)", ErrUnknownReference, ref, key)
+}
+
+// walkStrings visits every settable string (and string slice element) of v, recursively, keyed by json path.
+func walkStrings(v reflect.Value, prefix string, visit func(string, reflect.Value)) {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return
+		}
+
+		v = v.Elem()
+	}
+
+	switch v.Kind() { //nolint:exhaustive
+	case reflect.String:
+		if v.CanSet() {
+			visit(prefix, v)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			walkStrings(v.Index(i), prefix+"."+strconv.Itoa(i), visit)
+		}
+	case reflect.Struct:
+		typ := v.Type()
+
+		for i := 0; i < typ.NumField(); i++ {
+			field := typ.Field(i)
+
+			name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if name == "-" || (!field.IsExported() && !field.Anonymous) {
+				continue
+			}
+
+			if field.Anonymous && name == "" {
+				walkStrings(v.Field(i), prefix, visit)
+
+				continue
+			}
+
+			if name == "" {
+				name = field.Name
+			}
+
+			key := name
+			if prefix != "" {
+				key = prefix + "." + name
+			}
+
+			walkStrings(v.Field(i), key, visit)
+		}
+	}
+}
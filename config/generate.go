@@ -0,0 +1,297 @@
+package config
+
+import (
+	"encoding"
+	"encoding/json"
+	"math/rand"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	generateMaxItems = 3
+	generateMaxInt   = 1024
+	generateMaxDepth = 8
+	// One chance out of generateNilOdds for an optional (pointer, slice or map) value to be left empty
+	generateNilOdds = 4
+)
+
+// Strings used to populate string values. They are valid for expansion, but exercise unicode, escaping and
+// expressions.
+var generateStrings = []string{ //nolint:gochecknoglobals
+	"",
+	"value",
+	"with space",
+	"/tmp/some/path",
+	"C:\\Windows\\path",
+	"日本語 – ünïcödé",
+	"\"quoted\"",
+	"${env.HOME}",
+	"${dirs.cache}/sub",
+	"$${literal}",
+	"https://example.com:8443/path?q=1",
+}
+
+// Mutation is an invalid variant of a configuration, produced by Generator.Mutations.
+type Mutation struct {
+	// Key is the dotted path of the mutated key
+	Key string
+	// Kind describes the mutation (type, enum, expression)
+	Kind string
+	// Data is the complete json document
+	Data []byte
+}
+
+// Generator produces randomized but valid configuration instances from their structure (json keys, plus the
+// enum tag listing valid values), and targeted invalid mutations of them, for fuzz and property tests.
+type Generator struct {
+	rnd *rand.Rand
+}
+
+// NewGenerator returns a generator - the same seed always produces the same sequence.
+func NewGenerator(seed int64) *Generator {
+	return &Generator{
+		rnd: rand.New(rand.NewSource(seed)), //nolint:gosec
+	}
+}
+
+// Fill sets all json-serialized fields of obj (a pointer) to random valid values.
+func (gen *Generator) Fill(obj interface{}) {
+	gen.fill(reflect.ValueOf(obj), "", 0)
+}
+
+// Mutations returns, for every key of obj, documents where that key alone holds an invalid value: a value of the
+// wrong type, a value outside of its enum, or a broken expression. obj is marshalled first, so callers typically
+// Fill it beforehand.
+func (gen *Generator) Mutations(obj interface{}) ([]*Mutation, error) {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	enums := map[string]bool{}
+
+	walkKeys(reflect.TypeOf(obj), "", nil, func(key string, field reflect.StructField, _ bool) {
+		if _, ok := field.Tag.Lookup(tagEnum); ok {
+			enums[key] = true
+		}
+	})
+
+	var leaves []string
+
+	doc := map[string]interface{}{}
+	_ = json.Unmarshal(data, &doc)
+
+	collectLeaves(doc, "", &leaves)
+	sort.Strings(leaves)
+
+	mutations := []*Mutation{}
+
+	for _, key := range leaves {
+		original := lookupPath(doc, key)
+
+		variants := map[string]interface{}{}
+
+		switch original.(type) {
+		case string:
+			variants["type"] = gen.rnd.Intn(generateMaxInt)
+			variants["expression"] = "${unknown.reference"
+		case float64:
+			variants["type"] = "not a number"
+			variants["overflow"] = 1e300
+		case bool:
+			variants["type"] = "yes"
+		case []interface{}:
+			variants["type"] = map[string]interface{}{"not": "a list"}
+		}
+
+		if enums[key] {
+			variants["enum"] = "not-a-valid-value"
+		}
+
+		kinds := make([]string, 0, len(variants))
+		for kind := range variants {
+			kinds = append(kinds, kind)
+		}
+
+		sort.Strings(kinds)
+
+		for _, kind := range kinds {
+			mutated := map[string]interface{}{}
+			_ = json.Unmarshal(data, &mutated)
+
+			setPath(mutated, key, variants[kind])
+
+			out, err := json.Marshal(mutated)
+			if err != nil {
+				return nil, err
+			}
+
+			mutations = append(mutations, &Mutation{Key: key, Kind: kind, Data: out})
+		}
+	}
+
+	return mutations, nil
+}
+
+func (gen *Generator) fill(v reflect.Value, tag reflect.StructTag, depth int) {
+	if depth > generateMaxDepth || !v.IsValid() {
+		return
+	}
+
+	if enum, ok := tag.Lookup(tagEnum); ok && v.CanSet() {
+		gen.fillEnum(v, strings.Split(enum, ","))
+
+		return
+	}
+
+	switch v.Kind() { //nolint:exhaustive
+	case reflect.Pointer:
+		if v.IsNil() {
+			if !v.CanSet() || (depth > 0 && gen.rnd.Intn(generateNilOdds) == 0) {
+				return
+			}
+
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+
+		gen.fill(v.Elem(), tag, depth+1)
+	case reflect.Struct:
+		typ := v.Type()
+
+		for i := 0; i < typ.NumField(); i++ {
+			field := typ.Field(i)
+
+			name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if name == "-" || !field.IsExported() {
+				continue
+			}
+
+			gen.fill(v.Field(i), field.Tag, depth+1)
+		}
+	case reflect.Slice:
+		if gen.rnd.Intn(generateNilOdds) == 0 {
+			return
+		}
+
+		size := gen.rnd.Intn(generateMaxItems) + 1
+		slice := reflect.MakeSlice(v.Type(), size, size)
+
+		for i := 0; i < size; i++ {
+			gen.fill(slice.Index(i), "", depth+1)
+		}
+
+		v.Set(slice)
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String || gen.rnd.Intn(generateNilOdds) == 0 {
+			return
+		}
+
+		size := gen.rnd.Intn(generateMaxItems) + 1
+		m := reflect.MakeMapWithSize(v.Type(), size)
+
+		for i := 0; i < size; i++ {
+			elem := reflect.New(v.Type().Elem()).Elem()
+			gen.fill(elem, "", depth+1)
+			m.SetMapIndex(reflect.ValueOf("key"+strconv.Itoa(i)).Convert(v.Type().Key()), elem)
+		}
+
+		v.Set(m)
+	case reflect.String:
+		v.SetString(generateStrings[gen.rnd.Intn(len(generateStrings))])
+	case reflect.Bool:
+		v.SetBool(gen.rnd.Intn(2) == 1)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		max := int64(generateMaxInt)
+		if v.Type().Bits() < 16 {
+			max = 1 << (v.Type().Bits() - 1)
+		}
+
+		v.SetInt(gen.rnd.Int63n(max))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		max := int64(generateMaxInt)
+		if v.Type().Bits() < 16 {
+			max = 1 << v.Type().Bits()
+		}
+
+		v.SetUint(uint64(gen.rnd.Int63n(max)))
+	case reflect.Float32, reflect.Float64:
+		v.SetFloat(gen.rnd.Float64() * generateMaxInt)
+	}
+}
+
+// fillEnum sets v to one of the listed values, decoded as json (quoted for strings and text unmarshalers).
+func (gen *Generator) fillEnum(v reflect.Value, values []string) {
+	value := strings.TrimSpace(values[gen.rnd.Intn(len(values))])
+
+	target := v
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+
+		target = v.Elem()
+	}
+
+	literal := value
+
+	_, isText := target.Addr().Interface().(encoding.TextUnmarshaler)
+	if target.Kind() == reflect.String || isText {
+		literal = strconv.Quote(value)
+	}
+
+	_ = json.Unmarshal([]byte(literal), target.Addr().Interface())
+}
+
+func collectLeaves(node interface{}, prefix string, leaves *[]string) {
+	obj, ok := node.(map[string]interface{})
+	if !ok {
+		if prefix != "" {
+			*leaves = append(*leaves, prefix)
+		}
+
+		return
+	}
+
+	for key, value := range obj {
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+
+		collectLeaves(value, key, leaves)
+	}
+}
+
+func lookupPath(doc map[string]interface{}, key string) interface{} {
+	parts := strings.Split(key, ".")
+
+	var node interface{} = doc
+
+	for _, part := range parts {
+		obj, ok := node.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+
+		node = obj[part]
+	}
+
+	return node
+}
+
+func setPath(doc map[string]interface{}, key string, value interface{}) {
+	parts := strings.Split(key, ".")
+
+	for _, part := range parts[:len(parts)-1] {
+		next, ok := doc[part].(map[string]interface{})
+		if !ok {
+			return
+		}
+
+		doc = next
+	}
+
+	doc[parts[len(parts)-1]] = value
+}
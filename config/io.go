@@ -39,7 +39,14 @@ func read(cfg interface{}, location ...string) error {
 		return fmt.Errorf("failed reading config file %w", err)
 	}
 
-	return json.Unmarshal(data, &cfg)
+	err = json.Unmarshal(data, &cfg)
+	if err != nil {
+		return err
+	}
+
+	warnDeprecated(cfg, data)
+
+	return nil
 }
 
 func write(cfg interface{}, location ...string) error {
@@ -0,0 +1,167 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+
+	"go.codecomet.dev/core/log"
+)
+
+// Struct tags understood on configuration fields:
+// - env: the environment variable that can override that key
+// - deprecated: the key is deprecated - the value is a migration hint (typically the replacing key)
+// - removal: the version in which a deprecated key will be removed
+// - enum: comma separated list of the valid values for the key.
+const (
+	tagEnv        = "env"
+	tagDeprecated = "deprecated"
+	tagRemoval    = "removal"
+	tagEnum       = "enum"
+)
+
+var warned sync.Map //nolint:gochecknoglobals
+
+// KeyDoc documents a configuration key.
+type KeyDoc struct {
+	// Key is the dotted path of the key in the config file
+	Key string `json:"key"`
+	// Env is the environment variable overriding the key, if any
+	Env string `json:"env,omitempty"`
+	// Deprecated is true if the key should not be used anymore
+	Deprecated bool `json:"deprecated,omitempty"`
+	// Replacement is the migration hint for a deprecated key
+	Replacement string `json:"replacement,omitempty"`
+	// Removal is the version in which a deprecated key will be removed
+	Removal string `json:"removal,omitempty"`
+	// Enum lists the valid values for the key, if restricted
+	Enum []string `json:"enum,omitempty"`
+}
+
+// Describe returns documentation for all keys of a configuration object.
+func Describe(obj interface{}) []*KeyDoc {
+	docs := []*KeyDoc{}
+
+	walkKeys(reflect.TypeOf(obj), "", nil, func(key string, field reflect.StructField, _ bool) {
+		replacement, deprecated := field.Tag.Lookup(tagDeprecated)
+		doc := &KeyDoc{
+			Key:         key,
+			Env:         field.Tag.Get(tagEnv),
+			Deprecated:  deprecated,
+			Replacement: replacement,
+			Removal:     field.Tag.Get(tagRemoval),
+		}
+
+		if enum, ok := field.Tag.Lookup(tagEnum); ok {
+			doc.Enum = strings.Split(enum, ",")
+		}
+
+		docs = append(docs, doc)
+	})
+
+	return docs
+}
+
+// warnDeprecated logs a warning (once per key) for every deprecated key present in the raw config data,
+// or set through its environment variable.
+func warnDeprecated(obj interface{}, data []byte) {
+	raw := map[string]interface{}{}
+	// The data has already been successfully decoded into obj, so, ignore errors
+	_ = json.Unmarshal(data, &raw)
+
+	walkKeys(reflect.TypeOf(obj), "", raw, func(key string, field reflect.StructField, present bool) {
+		replacement, deprecated := field.Tag.Lookup(tagDeprecated)
+		if !deprecated {
+			return
+		}
+
+		env := field.Tag.Get(tagEnv)
+		if _, ok := os.LookupEnv(env); env != "" && ok {
+			warnOnce("env:"+env, key, env, replacement, field.Tag.Get(tagRemoval))
+		}
+
+		if present {
+			warnOnce("key:"+key, key, "", replacement, field.Tag.Get(tagRemoval))
+		}
+	})
+}
+
+func warnOnce(id string, key string, env string, replacement string, removal string) {
+	if _, loaded := warned.LoadOrStore(id, true); loaded {
+		return
+	}
+
+	evt := log.Warn().Str("key", key)
+	if env != "" {
+		evt = evt.Str("env", env)
+	}
+
+	if replacement != "" {
+		evt = evt.Str("replacement", replacement)
+	}
+
+	if removal != "" {
+		evt = evt.Str("removal", removal)
+	}
+
+	evt.Msg("Deprecated configuration in use. Please migrate.")
+}
+
+// walkKeys visits every json-serialized field of typ, recursively, alongside the matching raw data (if any).
+func walkKeys(typ reflect.Type, prefix string, raw map[string]interface{}, visit func(string, reflect.StructField, bool)) {
+	for typ != nil && typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+
+	if typ == nil || typ.Kind() != reflect.Struct {
+		return
+	}
+
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" || (!field.IsExported() && !field.Anonymous) {
+			continue
+		}
+
+		// Embedded structs without a name are flattened
+		if field.Anonymous && name == "" {
+			walkKeys(field.Type, prefix, raw, visit)
+
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+
+		key := name
+		if prefix != "" {
+			key = prefix + "." + name
+		}
+
+		value, present := lookupKey(raw, name)
+		visit(key, field, present)
+
+		sub, _ := value.(map[string]interface{})
+		walkKeys(field.Type, key, sub, visit)
+	}
+}
+
+// lookupKey mimics encoding/json matching, preferring an exact match, then a case-insensitive one.
+func lookupKey(raw map[string]interface{}, name string) (interface{}, bool) {
+	if value, ok := raw[name]; ok {
+		return value, true
+	}
+
+	for k, value := range raw {
+		if strings.EqualFold(k, name) {
+			return value, true
+		}
+	}
+
+	return nil, false
+}
@@ -0,0 +1,142 @@
+package exec
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"go.codecomet.dev/core/filesystem"
+)
+
+const artifactDigestAlgorithm = "sha256"
+
+// Artifacts declares the files a command is expected to produce. After a successful execution, each pattern must
+// match at least one regular file, or the execution fails with ErrArtifactMissing.
+type Artifacts struct {
+	// Patterns are globs (see filesystem.Glob), relative to the command directory
+	Patterns []string
+	// Store, if set, is a content addressed directory artifacts are copied into, as sha256/<hex digest>
+	Store string
+}
+
+// Artifact is a file produced by a command.
+type Artifact struct {
+	// Path is the path of the file, as matched
+	Path string `json:"path"`
+	// Size is the size of the file in bytes
+	Size int64 `json:"size"`
+	// Digest is the digest of the file content, as sha256:<hex>
+	Digest string `json:"digest"`
+	// Stored is the path of the copy in the Artifacts Store, if any
+	Stored string `json:"stored,omitempty"`
+}
+
+// collect verifies, hashes, and stores the artifacts produced in dir.
+func (arts *Artifacts) collect(dir string) ([]*Artifact, error) {
+	collected := []*Artifact{}
+	seen := map[string]bool{}
+
+	for _, pattern := range arts.Patterns {
+		glob := pattern
+		if dir != "" && !filepath.IsAbs(glob) {
+			glob = filepath.Join(dir, glob)
+		}
+
+		matches, err := filesystem.Glob(glob)
+		if err != nil {
+			return collected, fmt.Errorf("invalid artifact pattern %s: %w", pattern, err)
+		}
+
+		found := false
+
+		for _, name := range matches {
+			info, err := os.Stat(name)
+			if err != nil || !info.Mode().IsRegular() {
+				continue
+			}
+
+			found = true
+
+			if seen[name] {
+				continue
+			}
+
+			seen[name] = true
+
+			artifact, err := arts.artifact(name, info.Size())
+			if err != nil {
+				return collected, err
+			}
+
+			collected = append(collected, artifact)
+		}
+
+		if !found {
+			return collected, fmt.Errorf("%w: %s", ErrArtifactMissing, pattern)
+		}
+	}
+
+	return collected, nil
+}
+
+func (arts *Artifacts) artifact(name string, size int64) (*Artifact, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed reading artifact: %w", err)
+	}
+
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err = io.Copy(hash, file); err != nil {
+		return nil, fmt.Errorf("failed reading artifact: %w", err)
+	}
+
+	sum := hex.EncodeToString(hash.Sum(nil))
+	artifact := &Artifact{Path: name, Size: size, Digest: artifactDigestAlgorithm + ":" + sum}
+
+	if arts.Store == "" {
+		return artifact, nil
+	}
+
+	stored := filepath.Join(arts.Store, artifactDigestAlgorithm, sum)
+
+	// Content addressed: an existing copy is the same file
+	if _, err = os.Stat(stored); err != nil {
+		if err = os.MkdirAll(filepath.Dir(stored), filesystem.DirPermissionsDefault); err != nil {
+			return nil, fmt.Errorf("failed storing artifact: %w", err)
+		}
+
+		// Copy then rename, so that the store never holds partial content
+		if err = filesystem.CopyFile(name, stored+".partial"); err != nil {
+			return nil, fmt.Errorf("failed storing artifact: %w", err)
+		}
+
+		if err = os.Rename(stored+".partial", stored); err != nil {
+			return nil, fmt.Errorf("failed storing artifact: %w", err)
+		}
+	}
+
+	artifact.Stored = stored
+
+	return artifact, nil
+}
+
+// artifacts collects the artifacts of a successful execution into res, if the command declares some.
+func (cmd *Command) artifacts(res *Result, err error) error {
+	if err != nil || cmd.conf.Artifacts == nil {
+		return err
+	}
+
+	dir := cmd.cmd.Dir
+	if cmd.conf.Container != nil {
+		dir = cmd.conf.Dir
+	}
+
+	res.Artifacts, err = cmd.conf.Artifacts.collect(dir)
+
+	return err
+}
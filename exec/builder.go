@@ -0,0 +1,74 @@
+package exec
+
+import (
+	"io"
+	"os"
+	"syscall"
+)
+
+// The methods below adjust a prepared Command before it is started, and return it, so that they chain:
+//
+//	cmd := com.Command(ctx, "build").Dir(dir).Env("GOOS", "linux").Stdout(out)
+//	_, _, err := cmd.Start()
+//
+// They apply to the process started locally: for containers, that is the runtime, and Dir and Env do not reach
+// the containerized child. Calling them once the command is started has no effect on the running child.
+
+// Dir sets the working directory of the child.
+func (cmd *Command) Dir(dir string) *Command {
+	cmd.cmd.Dir = dir
+
+	return cmd
+}
+
+// Env sets an environment variable of the child, overriding inherited and configured values.
+func (cmd *Command) Env(key string, value string) *Command {
+	cmd.cmd.Env = mergeEnv(cmd.cmd.Env, key+"="+value)
+
+	return cmd
+}
+
+// Stdin sets the reader the child reads from, instead of Commander.Stdin.
+func (cmd *Command) Stdin(reader io.Reader) *Command {
+	cmd.cmd.Stdin = reader
+
+	return cmd
+}
+
+// Stdout sends the output of the child to writer as well. Output and Run still capture it, while Start returns no
+// pipe for it: writer gets it all, and line handlers are not called.
+func (cmd *Command) Stdout(writer io.Writer) *Command {
+	cmd.cmd.Stdout = writer
+
+	return cmd
+}
+
+// Stderr is Stdout, for the error output.
+func (cmd *Command) Stderr(writer io.Writer) *Command {
+	cmd.cmd.Stderr = writer
+
+	return cmd
+}
+
+// SysProcAttr sets OS specific attributes of the child. The process group is still set up as usual on top of them.
+func (cmd *Command) SysProcAttr(attr *syscall.SysProcAttr) *Command {
+	cmd.cmd.SysProcAttr = attr
+
+	return cmd
+}
+
+// ExtraFiles hands open files over to the child, as file descriptors 3 and up (not supported on Windows).
+func (cmd *Command) ExtraFiles(files ...*os.File) *Command {
+	cmd.cmd.ExtraFiles = append(cmd.cmd.ExtraFiles, files...)
+
+	return cmd
+}
+
+// alsoTo tees into the writer set with Stdout or Stderr, if any.
+func alsoTo(writer io.Writer, redirected io.Writer) io.Writer {
+	if redirected == nil {
+		return writer
+	}
+
+	return io.MultiWriter(writer, redirected)
+}
@@ -0,0 +1,178 @@
+package exec
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go.codecomet.dev/core/filesystem"
+	"go.codecomet.dev/core/log"
+)
+
+// ResultCache stores the results of successful Run and RunContext executions on disk, and serves them instead of
+// running the command again, within TTL and across invocations of the program. It is meant for expensive,
+// idempotent queries (version probes, inspections): results are keyed on the binary content, and on the arguments,
+// working directory and Commander.Env variables once rewritten by policies. The environment settings of the
+// Commander (CleanEnv, InheritEnv, UnsetEnv) are part of the key too, but inherited variables only count through
+// the values of the Env ones - any other input is ignored. Executions reading Stdin, or rejected by policies, are
+// never cached.
+type ResultCache struct {
+	// Dir holds the cached results
+	Dir string
+	// TTL is how long results are served for
+	TTL time.Duration
+	// Env lists the environment variables whose values are part of the key
+	Env []string
+}
+
+// cacheEntry is a cached result, with the output Result does not serialize.
+type cacheEntry struct {
+	Stored time.Time `json:"stored"`
+	Result *Result   `json:"result"`
+	Stdout []byte    `json:"stdout"`
+	Stderr []byte    `json:"stderr"`
+}
+
+// cacheKey is what identifies an execution.
+type cacheKey struct {
+	Binary     string            `json:"binary"`
+	Args       []string          `json:"args"`
+	Dir        string            `json:"dir"`
+	Env        map[string]string `json:"env"`
+	Inherited  map[string]string `json:"inherited"`
+	CleanEnv   bool              `json:"cleanEnv"`
+	InheritEnv []string          `json:"inheritEnv"`
+	UnsetEnv   []string          `json:"unsetEnv"`
+}
+
+// lookup returns the key of the execution of com with args, and its cached result, if any. The key is empty if the
+// execution cannot be cached.
+func (cache *ResultCache) lookup(com *Commander, args []string) (string, *Result) {
+	if cache == nil {
+		return "", nil
+	}
+
+	key, err := cache.key(com, args)
+	if err != nil {
+		log.Debug().Err(err).Str("binary", com.bin).Msg("Execution cannot be cached")
+
+		return "", nil
+	}
+
+	data, err := os.ReadFile(filepath.Join(cache.Dir, key+".json"))
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			log.Debug().Err(err).Msg("Failed reading cached result")
+		}
+
+		return key, nil
+	}
+
+	entry := &cacheEntry{}
+	if err = json.Unmarshal(data, entry); err != nil || entry.Result == nil || time.Since(entry.Stored) > cache.TTL {
+		return key, nil
+	}
+
+	res := entry.Result
+	res.Stdout = entry.Stdout
+	res.Stderr = entry.Stderr
+	res.Cached = true
+
+	log.Trace().Str("binary", com.bin).Strs("arguments", res.Args).Msg("Serving cached result")
+
+	return key, res
+}
+
+func (cache *ResultCache) store(key string, res *Result) {
+	if cache == nil || key == "" {
+		return
+	}
+
+	data, err := json.Marshal(&cacheEntry{Stored: time.Now(), Result: res, Stdout: res.Stdout, Stderr: res.Stderr})
+	if err == nil {
+		if err = os.MkdirAll(cache.Dir, filesystem.DirPermissionsPrivate); err == nil {
+			err = filesystem.WriteFile(filepath.Join(cache.Dir, key+".json"), data, filesystem.FilePermissionsPrivate)
+		}
+	}
+
+	if err != nil {
+		log.Warn().Err(err).Str("dir", cache.Dir).Msg("Failed caching result")
+	}
+}
+
+func (cache *ResultCache) key(com *Commander, args []string) (string, error) {
+	if com.Stdin != nil {
+		return "", errUncacheable
+	}
+
+	// Policies rewrite executions the same way prepare does
+	inv := &Invocation{
+		Binary: com.bin,
+		Name:   com.name,
+		Args:   append(append([]string{}, com.PreArgs...), args...),
+		Env:    map[string]string{},
+		Dir:    com.Dir,
+	}
+
+	for k, v := range com.Env {
+		inv.Env[k] = v
+	}
+
+	if err := applyPolicies(inv); err != nil {
+		return "", err
+	}
+
+	key := &cacheKey{
+		Binary:     com.bin,
+		Args:       inv.Args,
+		Dir:        inv.Dir,
+		Env:        inv.Env,
+		Inherited:  map[string]string{},
+		CleanEnv:   com.CleanEnv,
+		InheritEnv: com.InheritEnv,
+		UnsetEnv:   com.UnsetEnv,
+	}
+
+	// Binaries in containers are not ours to hash
+	if com.Container == nil {
+		digest, err := digestBinary(com.bin)
+		if err != nil {
+			return "", err
+		}
+
+		key.Binary = digest
+	}
+
+	if key.Dir == "" {
+		dir, err := os.Getwd()
+		if err != nil {
+			return "", err
+		}
+
+		key.Dir = dir
+	}
+
+	// Values as the child sees them: unset or filtered out variables are empty
+	environ := map[string]string{}
+	for _, kv := range com.environ(inv.Env) {
+		environ[envName(kv)] = strings.TrimPrefix(kv[len(envName(kv)):], "=")
+	}
+
+	for _, name := range cache.Env {
+		key.Inherited[name] = environ[name]
+	}
+
+	data, err := json.Marshal(key)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(data)
+
+	return hex.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,117 @@
+package exec
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"go.codecomet.dev/core/log"
+)
+
+// Checksum pins the binary of a Commander to known content: every execution verifies it first, and fails with
+// ErrChecksumMismatch if the binary was tampered with. Digests are cached as long as the file (inode, size and
+// modification time) does not change.
+type Checksum struct {
+	// SHA256 is the expected hex digest of the binary
+	SHA256 string `json:"sha256,omitempty"`
+	// Versions are accepted hex digests, per version of the binary - any of them, or SHA256, matches
+	Versions map[string]string `json:"versions,omitempty"`
+}
+
+type checksumEntry struct {
+	info   os.FileInfo
+	digest string
+}
+
+var (
+	checksums   = map[string]*checksumEntry{} //nolint:gochecknoglobals
+	checksumsMu sync.Mutex                    //nolint:gochecknoglobals
+)
+
+// verify checks the digest of bin, and returns the matching version, if any.
+func (sum *Checksum) verify(bin string) (string, error) {
+	digest, err := digestBinary(bin)
+	if err != nil {
+		return "", fmt.Errorf("failed verifying checksum of %s: %w", bin, err)
+	}
+
+	if sum.SHA256 != "" && strings.EqualFold(sum.SHA256, digest) {
+		return "", nil
+	}
+
+	versions := make([]string, 0, len(sum.Versions))
+
+	for version, expected := range sum.Versions {
+		if strings.EqualFold(expected, digest) {
+			return version, nil
+		}
+
+		versions = append(versions, version)
+	}
+
+	sort.Strings(versions)
+
+	if len(versions) > 0 {
+		return "", fmt.Errorf("%w: %s has sha256 %s, which matches none of the known versions (%s)",
+			ErrChecksumMismatch, bin, digest, strings.Join(versions, ", "))
+	}
+
+	return "", fmt.Errorf("%w: %s has sha256 %s, expected %s", ErrChecksumMismatch, bin, digest, sum.SHA256)
+}
+
+// digestBinary returns the hex sha256 of bin, from the cache if the file did not change.
+func digestBinary(bin string) (string, error) {
+	info, err := os.Stat(bin)
+	if err != nil {
+		return "", err
+	}
+
+	checksumsMu.Lock()
+	cached := checksums[bin]
+	checksumsMu.Unlock()
+
+	if cached != nil && os.SameFile(cached.info, info) && cached.info.Size() == info.Size() &&
+		cached.info.ModTime().Equal(info.ModTime()) {
+		return cached.digest, nil
+	}
+
+	file, err := os.Open(bin)
+	if err != nil {
+		return "", err
+	}
+
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err = io.Copy(hash, file); err != nil {
+		return "", err
+	}
+
+	digest := hex.EncodeToString(hash.Sum(nil))
+
+	checksumsMu.Lock()
+	checksums[bin] = &checksumEntry{info: info, digest: digest}
+	checksumsMu.Unlock()
+
+	return digest, nil
+}
+
+// verify checks the binary against the configured checksum. Containers and replayed executions have no binary to
+// verify.
+func (com *Commander) verify() error {
+	if com.Checksum == nil || com.Container != nil || (com.Fixtures != nil && com.Fixtures.Mode == FixtureReplay) {
+		return nil
+	}
+
+	version, err := com.Checksum.verify(com.bin)
+	if err == nil && version != "" {
+		log.Trace().Str("binary", com.bin).Str("version", version).Msg("Binary checksum verified")
+	}
+
+	return err
+}
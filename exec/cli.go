@@ -2,39 +2,73 @@ package exec
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
-	"os/exec"
 	"path/filepath"
-	"strings"
 	"sync"
 
+	"github.com/mattn/go-isatty"
 	"go.codecomet.dev/core/log"
 	"go.codecomet.dev/core/reporter"
 )
 
+// Commander holds the configuration of executions of a binary. It is safe for concurrent use: every execution gets
+// its own Command, snapshotting the configuration, which should hence not be modified while executions are starting.
+// The methods operating on the "active" command (PreExec, ExecAndWait, Wait, Signal, Stop...) are kept for
+// compatibility, and refer to the last command prepared - prefer Command in new code.
 type Commander struct {
-	Stdin         io.Reader
-	mu            *sync.Mutex
-	activeCommand *exec.Cmd
-	Env           map[string]string
-	bin           string
-	Dir           string
-	PreArgs       []string
-	NoReport      bool
-}
-
-func Resolve(bin string) (string, error) {
-	o, err := exec.Command("which", bin).Output()
-	if err != nil {
-		return "", fmt.Errorf("resolve errored with: %w", err)
-	}
-
-	out := string(o)
-	out = strings.Trim(out, "\n")
-
-	return out, nil
+	Stdin    io.Reader
+	mu       *sync.Mutex
+	active   *Command
+	Env      map[string]string
+	bin      string
+	name     string
+	Dir      string
+	PreArgs  []string
+	NoReport bool
+	// Container, if set, runs commands inside that container instead of on the host
+	Container *Container
+	// Umask, if set, is the umask of the child process (unix only)
+	Umask *int
+	// Credential, if set, runs the child process as another user (requires privileges, see LookupCredential)
+	Credential *Credential
+	// Retry, if set, retries failed ExecAndComplete and ExecContext calls
+	Retry *RetryPolicy
+	// CleanEnv starts children with an empty environment (plus InheritEnv variables) instead of ours
+	CleanEnv bool
+	// InheritEnv lists variables passed to children in CleanEnv mode - entries ending with * match prefixes
+	InheritEnv []string
+	// UnsetEnv lists inherited variables removed from the environment of children - entries ending with * match
+	// prefixes. Env variables are still passed
+	UnsetEnv []string
+	// PTY runs attached commands on a pseudo terminal, when our stdin is a terminal (linux and macOS)
+	PTY bool
+	// OutputLimit, if set, caps the output buffered in memory for each stream
+	OutputLimit *OutputLimit
+	// Transcribe records a Transcript of buffered executions, interleaving stdout and stderr (see Result.Transcript)
+	Transcribe bool
+	// Watchdog, if set, terminates commands that stop producing output (see Watchdog)
+	Watchdog *Watchdog
+	// Progress, if set, extracts progress from the output of children (see Progress)
+	Progress *Progress
+	// LogOutput, if set, also emits output lines through the log package
+	LogOutput *LogOutput
+	// ExitCodes maps exit codes with a meaning for the binary (eg: 2 for a usage error) to errors: failed
+	// executions with these codes return an *ExitCodeError matching the mapped error
+	ExitCodes map[int]error
+	// Artifacts, if set, declares the files Run is expected to produce (see Result.Artifacts)
+	Artifacts *Artifacts
+	// Checksum, if set, pins the binary: executions fail with ErrChecksumMismatch if its content differs
+	Checksum *Checksum
+	// Cache, if set, serves results of Run and RunContext from a disk cache (see ResultCache)
+	Cache *ResultCache
+	// Fixtures, if set, records or replays the outcome of buffered executions (see Fixtures)
+	Fixtures *Fixtures
+	onStdout func(string)
+	onStderr func(string)
 }
 
 func New(defaultBin string, envBin string) *Commander {
@@ -70,44 +104,72 @@ func New(defaultBin string, envBin string) *Commander {
 	}
 
 	return &Commander{
-		mu:  &sync.Mutex{},
-		bin: execut,
+		mu:   &sync.Mutex{},
+		bin:  execut,
+		name: bin,
+	}
+}
+
+// NewInContainer returns a Commander running bin inside the provided container.
+// Contrary to New, bin is not resolved on the host.
+func NewInContainer(bin string, container *Container) *Commander {
+	return &Commander{
+		mu:        &sync.Mutex{},
+		bin:       bin,
+		name:      bin,
+		Container: container,
 	}
 }
 
+// PreExec prepares the command ExecAndWait starts, reading stdin. Prefer Command, which returns the execution itself,
+// and lets it be adjusted (Dir, Env, Stdout, SysProcAttr, ExtraFiles...) before it starts.
 func (com *Commander) PreExec(stdin io.Reader, args ...string) {
-	args = append(com.PreArgs, args...)
+	com.PreExecContext(context.Background(), stdin, args...)
+}
 
-	envs := []string{}
-	for k, v := range com.Env {
-		envs = append(envs, fmt.Sprintf("%s=%s", k, v))
-	}
+// PreExecContext prepares the command like PreExec. The child is killed if ctx is done before it completes.
+func (com *Commander) PreExecContext(ctx context.Context, stdin io.Reader, args ...string) {
+	com.activate(com.prepare(ctx, stdin, args))
+}
 
-	log.Trace().Str("binary", com.bin).Strs("arguments", args).Strs("env", envs).Str("ctx", "exec/PreExec").Msg("Preparing Command")
+func (com *Commander) Attach(args ...string) error {
+	return com.AttachContext(context.Background(), args...)
+}
 
-	command := exec.Command(com.bin, args...) //nolint:gosec
+// AttachContext is Attach, terminating the child if ctx is done.
+func (com *Commander) AttachContext(ctx context.Context, args ...string) error {
+	err := errPTYUnsupported
 
-	if com.Dir != "" {
-		command.Dir = com.Dir
-	}
+	if com.PTY && com.Stdin == nil && isatty.IsTerminal(os.Stdin.Fd()) {
+		cmd := com.activate(com.prepare(ctx, nil, args))
 
-	command.Env = append(os.Environ(), envs...)
-	command.Stdin = stdin
+		if err = cmd.runTerminal(); errors.Is(err, errPTYUnsupported) {
+			log.Debug().Msg("PTY is not supported on this platform. Falling back to pipes.")
+		} else if err != nil {
+			err = fmt.Errorf("attached execution errored: %w", cmd.canceled(err))
+		}
+	}
 
-	com.activeCommand = command
-}
+	if errors.Is(err, errPTYUnsupported) {
+		stdin := com.Stdin
+		if stdin == nil {
+			stdin = os.Stdin
+		}
 
-func (com *Commander) Attach(args ...string) error {
-	var err error
+		cmd := com.activate(com.prepare(ctx, stdin, args))
+		// Attached commands stay in our process group, so that they keep the terminal and receive interrupts
+		cmd.attached = true
 
-	if com.Stdin != nil {
-		com.PreExec(com.Stdin, args...)
-	} else {
-		com.PreExec(os.Stdin, args...)
+		if com.Stdin == nil && isatty.IsTerminal(os.Stdin.Fd()) && isatty.IsTerminal(os.Stdout.Fd()) {
+			if err = cmd.runInteractive(); err != nil {
+				err = fmt.Errorf("attached execution errored: %w", cmd.canceled(err))
+			}
+		} else {
+			_, _, err = cmd.Output()
+		}
 	}
-	_, _, err = com.ExecAndComplete() // TODO: Probably should be ExecAndWait
 
-	if err != nil && !com.NoReport {
+	if err != nil && !com.NoReport && !errors.Is(err, ErrCanceled) {
 		reporter.CaptureException(fmt.Errorf("failed attached execution: %w", err))
 		log.Error().Err(err).Msg("Attached execution failed")
 	}
@@ -116,33 +178,38 @@ func (com *Commander) Attach(args ...string) error {
 }
 
 func (com *Commander) ExecAndComplete(args ...string) (bytes.Buffer, bytes.Buffer, error) {
-	// prepare the command
-	com.PreExec(com.Stdin, args...)
-
-	command := com.activeCommand
+	return com.ExecContext(context.Background(), args...)
+}
 
-	var stdout, stderr bytes.Buffer
-	command.Stdout = &stdout
-	command.Stderr = &stderr
+// ExecContext is ExecAndComplete, terminating the child if ctx is done.
+// In that case, the returned error matches ErrCanceled, as well as the context error.
+func (com *Commander) ExecContext(ctx context.Context, args ...string) (bytes.Buffer, bytes.Buffer, error) {
+	_, stdout, stderr, err := com.exec(ctx, args)
 
-	com.mu.Lock()
-	err := command.Run()
-	com.mu.Unlock()
+	return stdout, stderr, err
+}
 
-	if err != nil {
-		err = fmt.Errorf("ExecAndComplete errored: %w", err)
+// exec runs the command to completion, with retries if configured, and returns the last command.
+func (com *Commander) exec(ctx context.Context, args []string) (*Command, bytes.Buffer, bytes.Buffer, error) {
+	if com.Retry != nil {
+		return com.retry(ctx, args)
 	}
 
-	return stdout, stderr, err
+	cmd := com.activate(com.prepare(ctx, com.Stdin, args))
+	stdout, stderr, err := cmd.Output()
+
+	return cmd, stdout, stderr, err
 }
 
 func (com *Commander) ExecWithBuffer(args ...string) (io.ReadCloser, io.ReadCloser, error) {
-	// prepare the command
-	com.PreExec(com.Stdin, args...)
+	return com.ExecWithBufferContext(context.Background(), args...)
+}
 
-	sout, serr, err := com.ExecAndWait()
+// ExecWithBufferContext is ExecWithBuffer, terminating the child if ctx is done.
+func (com *Commander) ExecWithBufferContext(ctx context.Context, args ...string) (io.ReadCloser, io.ReadCloser, error) {
+	sout, serr, err := com.activate(com.prepare(ctx, com.Stdin, args)).Start()
 
-	if !com.NoReport && err != nil {
+	if !com.NoReport && err != nil && !errors.Is(err, ErrCanceled) {
 		reporter.CaptureException(fmt.Errorf("failed sub execution: %w - out: %s - err: %s", err, sout, serr))
 		log.Error().Err(err).Msg("Execution failed")
 	}
@@ -150,27 +217,40 @@ func (com *Commander) ExecWithBuffer(args ...string) (io.ReadCloser, io.ReadClos
 	return sout, serr, err
 }
 
+// ExecAndWait starts the prepared command. Use PreExecContext to bind it to a context.
+// Line handlers, if any, are called as the returned pipes are read.
 func (com *Commander) ExecAndWait() (io.ReadCloser, io.ReadCloser, error) {
-	command := com.activeCommand
+	cmd := com.current()
+	if cmd == nil {
+		return nil, nil, ErrNotStarted
+	}
 
-	outpipe, _ := command.StdoutPipe()
-	errpipe, _ := command.StderrPipe()
+	return cmd.Start()
+}
 
-	err := command.Start()
-	if err != nil {
-		err = fmt.Errorf("ExecAndWait errored: %w", err)
+func (com *Commander) Wait() error {
+	cmd := com.current()
+	if cmd == nil {
+		return ErrNotStarted
 	}
 
-	return outpipe, errpipe, err
+	return cmd.Wait()
 }
 
-func (com *Commander) Wait() error {
-	command := com.activeCommand
+// activate makes cmd the active command, the one the compatibility methods operate on.
+func (com *Commander) activate(cmd *Command) *Command {
+	com.mu.Lock()
+	defer com.mu.Unlock()
 
-	err := command.Wait()
-	if err != nil {
-		err = fmt.Errorf("Wait errored: %w", err)
-	}
+	com.active = cmd
 
-	return err
+	return cmd
+}
+
+// current returns the active command, if any.
+func (com *Commander) current() *Command {
+	com.mu.Lock()
+	defer com.mu.Unlock()
+
+	return com.active
 }
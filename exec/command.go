@@ -0,0 +1,353 @@
+package exec
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+
+	"go.codecomet.dev/core/log"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Command is a single execution, prepared by Commander.Command. Its configuration is a snapshot of the Commander
+// taken at that time, so that any number of commands can run in parallel from the same Commander, and later
+// Commander changes do not affect them.
+// A Command runs once: use one of Run, Output or Start (followed by Wait).
+type Command struct {
+	// Accessed atomically: first, so that they are 64-bit aligned on 32-bit platforms
+	activity   int64
+	stalled    int64
+	conf       *Commander
+	cmd        *exec.Cmd
+	ctx        context.Context //nolint:containedctx
+	attached   bool
+	exited     chan struct{}
+	rejected   error
+	started    time.Time
+	captured   [2]*capture
+	transcript *Transcript
+	span       trace.Span
+	replayed   *fixture
+	// env is what containerized children receive (the runtime itself gets our environment)
+	env []string
+	// phase guards starting and waiting, as the Commander shims (ExecAndWait, Wait, Signal...) let goroutines share
+	// the active command
+	phase    sync.Mutex
+	launched bool
+	waited   bool
+	// waitErr is the result of the first wait, for the others, once exited is closed
+	waitErr error
+}
+
+// Command prepares an execution of the binary with args, reading com.Stdin. The child is killed if ctx is done
+// before it completes.
+func (com *Commander) Command(ctx context.Context, args ...string) *Command {
+	return com.prepare(ctx, com.Stdin, args)
+}
+
+// prepare snapshots the configuration, applies policies, and builds the underlying command.
+func (com *Commander) prepare(ctx context.Context, stdin io.Reader, args []string) *Command {
+	com.mu.Lock()
+	conf := *com
+	com.mu.Unlock()
+
+	conf.mu = nil
+	conf.active = nil
+
+	inv := &Invocation{
+		Binary: conf.bin,
+		Name:   conf.name,
+		Args:   append(append([]string{}, conf.PreArgs...), args...),
+		Env:    map[string]string{},
+		Dir:    conf.Dir,
+	}
+
+	for k, v := range conf.Env {
+		inv.Env[k] = v
+	}
+
+	cmd := &Command{
+		conf:     &conf,
+		ctx:      ctx,
+		exited:   make(chan struct{}),
+		rejected: applyPolicies(inv),
+	}
+
+	if cmd.rejected != nil {
+		log.Warn().Err(cmd.rejected).Str("binary", conf.bin).Msg("Execution rejected by policy")
+	} else if cmd.rejected = conf.verify(); cmd.rejected != nil {
+		log.Error().Err(cmd.rejected).Str("binary", conf.bin).Msg("Binary failed checksum verification")
+	}
+
+	args = inv.Args
+
+	envs := sortedEnv(inv.Env)
+
+	log.Trace().Str("binary", conf.bin).Strs("arguments", args).Strs("env", envs).Str("ctx", "exec/PreExec").Msg("Preparing Command")
+
+	if conf.Container != nil {
+		// Env and directory are translated for the container - the runtime itself runs with our environment, plus
+		// the values it forwards
+		bin, cArgs := conf.Container.command(conf.name, inv.Dir, envs, stdin != nil, args)
+
+		cmd.cmd = exec.CommandContext(ctx, bin, cArgs...) //nolint:gosec
+		cmd.cmd.Env = mergeEnv(os.Environ(), envs...)
+		cmd.cmd.Stdin = stdin
+		cmd.env = envs
+
+		return cmd
+	}
+
+	cmd.cmd = exec.CommandContext(ctx, conf.bin, args...) //nolint:gosec
+
+	if inv.Dir != "" {
+		cmd.cmd.Dir = inv.Dir
+	}
+
+	cmd.cmd.Env = conf.environ(inv.Env)
+	cmd.cmd.Stdin = stdin
+
+	return cmd
+}
+
+// EffectiveEnv returns the environment of the child, as KEY=VALUE entries sorted by name: inherited variables
+// (see CleanEnv, InheritEnv, UnsetEnv) overridden by Env and policies, plus the trace context once started.
+// For containers, it is the environment passed to the container.
+func (cmd *Command) EffectiveEnv() []string {
+	if cmd.conf.Container != nil {
+		return append([]string{}, cmd.env...)
+	}
+
+	return append([]string{}, cmd.cmd.Env...)
+}
+
+// Run executes the command to completion, and returns its Result. The error is non-nil if the command did not
+// succeed, in which case the result still carries everything known about the execution.
+// Contrary to Commander.Run, a single attempt is made, whatever the retry policy.
+func (cmd *Command) Run() (*Result, error) {
+	started := time.Now()
+
+	stdout, stderr, err := cmd.Output()
+
+	res := cmd.result(started, stdout.Bytes(), stderr.Bytes(), err)
+
+	return res, cmd.artifacts(res, err)
+}
+
+// Output executes the command to completion, and returns its buffered output.
+func (cmd *Command) Output() (bytes.Buffer, bytes.Buffer, error) {
+	command := cmd.cmd
+
+	stdout := newCapture(cmd.conf.OutputLimit, StreamStdout)
+	stderr := newCapture(cmd.conf.OutputLimit, StreamStderr)
+	cmd.captured = [2]*capture{stdout, stderr}
+	cmd.transcript = cmd.conf.newTranscript()
+
+	var outLines, errLines *lineWriter
+
+	command.Stdout, outLines = teeLines(alsoTo(stdout, command.Stdout), cmd.conf.lineWriter(StreamStdout, cmd.transcript))
+	command.Stderr, errLines = teeLines(alsoTo(stderr, command.Stderr), cmd.conf.lineWriter(StreamStderr, cmd.transcript))
+	cmd.watchWriters()
+
+	var err error
+
+	if fix := cmd.conf.Fixtures; fix != nil && fix.Mode == FixtureReplay {
+		err = cmd.replay()
+	} else if err = cmd.start(!cmd.attached); err == nil {
+		cmd.watch()
+		err = cmd.wait()
+	}
+
+	outLines.Flush()
+	errLines.Flush()
+	stdout.close()
+	stderr.close()
+
+	if fix := cmd.conf.Fixtures; fix != nil && fix.Mode == FixtureRecord {
+		outBuf, errBuf := stdout.buffer(), stderr.buffer()
+		if recErr := cmd.record(outBuf.Bytes(), errBuf.Bytes(), err); recErr != nil {
+			log.Warn().Err(recErr).Str("binary", cmd.conf.bin).Msg("Failed recording fixture")
+		}
+	}
+
+	if err != nil {
+		err = fmt.Errorf("ExecAndComplete errored: %w", cmd.canceled(err))
+	}
+
+	return stdout.buffer(), stderr.buffer(), err
+}
+
+// Start starts the command, and returns pipes to its output, which must be read before calling Wait.
+// Line handlers, if any, are called as the pipes are read. Streams redirected with Stdout or Stderr have no pipe.
+func (cmd *Command) Start() (io.ReadCloser, io.ReadCloser, error) {
+	cmd.phase.Lock()
+	defer cmd.phase.Unlock()
+
+	if cmd.launched {
+		return nil, nil, fmt.Errorf("ExecAndWait errored: %w", ErrAlreadyStarted)
+	}
+
+	outpipe, errpipe := cmd.watchPipes(cmd.pipe(StreamStdout), cmd.pipe(StreamStderr))
+
+	err := cmd.startLocked(!cmd.attached)
+	if err != nil {
+		err = fmt.Errorf("ExecAndWait errored: %w", cmd.canceled(err))
+	} else {
+		cmd.watch()
+	}
+
+	return outpipe, errpipe, err
+}
+
+// pipe returns a pipe to an output stream of the child, feeding line handlers as it is read, or nil if the stream
+// was redirected to a writer.
+func (cmd *Command) pipe(stream string) io.ReadCloser {
+	command := cmd.cmd
+
+	target, open := &command.Stdout, command.StdoutPipe
+	if stream == StreamStderr {
+		target, open = &command.Stderr, command.StderrPipe
+	}
+
+	if *target != nil {
+		if cmd.watched() {
+			*target = &activityWriter{Writer: *target, last: &cmd.activity}
+		}
+
+		return nil
+	}
+
+	pipe, _ := open()
+
+	if lines := cmd.conf.lineWriter(stream, nil); lines != nil {
+		pipe = &lineReader{ReadCloser: pipe, lines: lines}
+	}
+
+	return pipe
+}
+
+// Wait waits for a command started with Start to exit.
+func (cmd *Command) Wait() error {
+	err := cmd.wait()
+	if err != nil {
+		err = fmt.Errorf("Wait errored: %w", cmd.canceled(err))
+	}
+
+	return err
+}
+
+// start starts the prepared command, unless it was rejected by a policy.
+// Unless group is false, the child is started in its own process group.
+func (cmd *Command) start(group bool) error {
+	cmd.phase.Lock()
+	defer cmd.phase.Unlock()
+
+	if cmd.launched {
+		return ErrAlreadyStarted
+	}
+
+	return cmd.startLocked(group)
+}
+
+// startLocked is start, with phase held.
+func (cmd *Command) startLocked(group bool) error {
+	if cmd.rejected != nil {
+		return cmd.rejected
+	}
+
+	cmd.started = time.Now()
+	cmd.startSpan()
+	cmd.guardStreams()
+
+	err := startCommand(cmd.cmd, cmd.conf.Umask, cmd.conf.Credential, group)
+	if err != nil {
+		cmd.endSpan(err)
+
+		return err
+	}
+
+	cmd.launched = true
+
+	// The context only kills the child itself: grandchildren holding its output open would delay Wait until they exit
+	if group && cmd.ctx != nil {
+		go func() {
+			select {
+			case <-cmd.ctx.Done():
+				_ = signalCommand(cmd.cmd, os.Kill)
+			case <-cmd.exited:
+			}
+		}()
+	}
+
+	return nil
+}
+
+// wait waits for the command, maps its exit code to an error if configured, and signals its exit to Stop.
+// Concurrent callers all get the result of the first one.
+func (cmd *Command) wait() error {
+	cmd.phase.Lock()
+
+	if !cmd.launched {
+		cmd.phase.Unlock()
+
+		return ErrNotStarted
+	}
+
+	if cmd.waited {
+		cmd.phase.Unlock()
+		<-cmd.exited
+
+		return cmd.waitErr
+	}
+
+	cmd.waited = true
+	cmd.phase.Unlock()
+
+	err := cmd.cmd.Wait()
+
+	cmd.endSpan(err)
+
+	cmd.waitErr = cmd.inactive(cmd.exitError(err))
+	close(cmd.exited)
+
+	return cmd.waitErr
+}
+
+// process returns the process of the child, or nil if it has not started.
+func (cmd *Command) process() *os.Process {
+	cmd.phase.Lock()
+	defer cmd.phase.Unlock()
+
+	if !cmd.launched {
+		return nil
+	}
+
+	return cmd.cmd.Process
+}
+
+// exitError maps the exit code of a failed command to an error, if configured in ExitCodes.
+func (cmd *Command) exitError(err error) error {
+	var coder exitCoder
+	if errors.As(err, &coder) {
+		if mapped := cmd.conf.ExitCodes[coder.ExitCode()]; mapped != nil {
+			return &ExitCodeError{Code: coder.ExitCode(), Mapped: mapped, Err: err}
+		}
+	}
+
+	return err
+}
+
+// canceled wraps err distinctly if the command context is done.
+func (cmd *Command) canceled(err error) error {
+	if cmd.ctx == nil || cmd.ctx.Err() == nil {
+		return err
+	}
+
+	return &CanceledError{Cause: cmd.ctx.Err(), Err: err}
+}
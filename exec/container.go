@@ -0,0 +1,100 @@
+package exec
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Container describes a container inside of which commands are run, instead of on the host.
+// It is meant to be marshalled from config, so that callers can switch between host and containerized execution.
+type Container struct {
+	// Runtime is the container cli to use (eg: docker or podman) - defaults to the first one found in the path
+	Runtime string `json:"runtime,omitempty"`
+	// Image is the image to run
+	Image string `json:"image"`
+	// Mounts maps host paths to container paths
+	Mounts map[string]string `json:"mounts,omitempty"`
+	// Workdir is the working directory inside the container - defaults to the Commander Dir mapped through Mounts
+	Workdir string `json:"workdir,omitempty"`
+	// Args are extra arguments passed to the runtime "run" command
+	Args []string `json:"args,omitempty"`
+}
+
+// runtime returns the container cli to use.
+func (ctr *Container) runtime() string {
+	if ctr.Runtime != "" {
+		return ctr.Runtime
+	}
+
+	if resolved, err := ResolveFirst("docker", "podman"); err == nil {
+		return resolved
+	}
+
+	// Let the execution fail with a meaningful error
+	return "docker"
+}
+
+// workdir returns the container working directory for a host directory.
+func (ctr *Container) workdir(dir string) string {
+	if ctr.Workdir != "" || dir == "" {
+		return ctr.Workdir
+	}
+
+	// Longest host prefix wins
+	best := ""
+
+	for host := range ctr.Mounts {
+		if (dir == host || strings.HasPrefix(dir, strings.TrimSuffix(host, "/")+"/")) && len(host) > len(best) {
+			best = host
+		}
+	}
+
+	if best == "" {
+		return ""
+	}
+
+	rel, err := filepath.Rel(best, dir)
+	if err != nil {
+		return ""
+	}
+
+	return path.Join(ctr.Mounts[best], filepath.ToSlash(rel))
+}
+
+// command translates a host invocation into a container runtime invocation.
+// Environment variables are passed by name only, so that values do not show in the process list: the runtime reads
+// them from its own environment, which must hold envs.
+func (ctr *Container) command(bin string, dir string, envs []string, interactive bool, args []string) (string, []string) {
+	cArgs := []string{"run", "--rm"}
+
+	if interactive {
+		cArgs = append(cArgs, "--interactive")
+	}
+
+	hosts := make([]string, 0, len(ctr.Mounts))
+	for host := range ctr.Mounts {
+		hosts = append(hosts, host)
+	}
+
+	sort.Strings(hosts)
+
+	for _, host := range hosts {
+		cArgs = append(cArgs, "--volume", fmt.Sprintf("%s:%s", host, ctr.Mounts[host]))
+	}
+
+	for _, env := range envs {
+		cArgs = append(cArgs, "--env", envName(env))
+	}
+
+	if workdir := ctr.workdir(dir); workdir != "" {
+		cArgs = append(cArgs, "--workdir", workdir)
+	}
+
+	cArgs = append(cArgs, ctr.Args...)
+	cArgs = append(cArgs, ctr.Image, bin)
+
+	return ctr.runtime(), append(cArgs, args...)
+}
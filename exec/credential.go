@@ -0,0 +1,53 @@
+package exec
+
+import (
+	"fmt"
+	"os/user"
+	"strconv"
+)
+
+// Credential identifies the user and group a child process runs as.
+type Credential struct {
+	UID uint32 `json:"uid"`
+	GID uint32 `json:"gid"`
+	// Groups are the supplementary groups of the child (unix only). If empty, the child keeps ours when running as
+	// ourselves, and has none otherwise
+	Groups []uint32 `json:"groups,omitempty"`
+	// Token is a primary token handle (windows only, eg: from LogonUser), which UID, GID and Groups are ignored for.
+	// Starting a process with a token requires SeAssignPrimaryTokenPrivilege
+	Token uintptr `json:"-"`
+}
+
+// LookupCredential returns the credential of username (unix only), with its primary and supplementary groups, so
+// that a privileged process can drop to it for its children.
+func LookupCredential(username string) (*Credential, error) {
+	usr, err := user.Lookup(username)
+	if err != nil {
+		return nil, fmt.Errorf("failed looking up user %s: %w", username, err)
+	}
+
+	uid, err := strconv.ParseUint(usr.Uid, 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("%w: user id %s", ErrUnsupportedPlatform, usr.Uid)
+	}
+
+	gid, err := strconv.ParseUint(usr.Gid, 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("%w: group id %s", ErrUnsupportedPlatform, usr.Gid)
+	}
+
+	cred := &Credential{UID: uint32(uid), GID: uint32(gid)}
+
+	groups, err := usr.GroupIds()
+	if err != nil {
+		return nil, fmt.Errorf("failed looking up groups of %s: %w", username, err)
+	}
+
+	for _, group := range groups {
+		if id, err := strconv.ParseUint(group, 10, 32); err == nil && uint32(id) != cred.GID {
+			cred.Groups = append(cred.Groups, uint32(id))
+		}
+	}
+
+	return cred, nil
+}
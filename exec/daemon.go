@@ -0,0 +1,140 @@
+package exec
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.codecomet.dev/core/filesystem"
+	"go.codecomet.dev/core/log"
+)
+
+const daemonStopPoll = 50 * time.Millisecond
+
+// Daemon manages a long running helper process, detached from ours so that it outlives it, and tracked through a
+// pidfile. Its environment, directory, and other settings come from its Commander.
+// Process IDs can be reused once a process exited: a stale pidfile could point at an unrelated process, hence
+// the pidfile should live in a directory private to the application (eg: its runtime or cache directory).
+type Daemon struct {
+	Commander *Commander
+	// PidFile is the file holding the daemon process ID
+	PidFile string
+	// LogFile receives the daemon stdout and stderr, appended - discarded if empty
+	LogFile string
+}
+
+// NewDaemon returns a daemon running com, tracked with pidFile, and logging to logFile.
+func NewDaemon(com *Commander, pidFile string, logFile string) *Daemon {
+	return &Daemon{Commander: com, PidFile: pidFile, LogFile: logFile}
+}
+
+// Start starts the daemon with args, and returns its process ID. It fails with ErrDaemonRunning if it is already
+// running.
+func (daemon *Daemon) Start(args ...string) (int, error) {
+	if pid, err := daemon.Status(); err == nil {
+		return pid, fmt.Errorf("%w: pid %d", ErrDaemonRunning, pid)
+	}
+
+	cmd := daemon.Commander.prepare(context.Background(), nil, args)
+	if cmd.rejected != nil {
+		return 0, cmd.rejected
+	}
+
+	if daemon.LogFile != "" {
+		output, err := os.OpenFile(daemon.LogFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, filesystem.FilePermissionsPrivate)
+		if err != nil {
+			return 0, fmt.Errorf("failed opening daemon log: %w", err)
+		}
+
+		// The child has its own copy
+		defer output.Close()
+
+		cmd.cmd.Stdout = output
+		cmd.cmd.Stderr = output
+	}
+
+	detachCommand(cmd.cmd)
+
+	if err := startCommand(cmd.cmd, cmd.conf.Umask, cmd.conf.Credential, false); err != nil {
+		return 0, fmt.Errorf("failed starting daemon: %w", err)
+	}
+
+	pid := cmd.cmd.Process.Pid
+
+	// Reap the daemon if it exits while we are still running
+	go func() {
+		_ = cmd.cmd.Wait()
+	}()
+
+	if err := filesystem.WriteFile(daemon.PidFile, []byte(strconv.Itoa(pid)+"\n"), filesystem.FilePermissionsPrivate); err != nil {
+		_ = killPID(pid)
+
+		return 0, fmt.Errorf("failed writing pidfile: %w", err)
+	}
+
+	log.Debug().Str("binary", cmd.conf.bin).Int("pid", pid).Str("pidfile", daemon.PidFile).Msg("Daemon started")
+
+	return pid, nil
+}
+
+// Status returns the process ID of the daemon, or ErrDaemonNotRunning.
+func (daemon *Daemon) Status() (int, error) {
+	data, err := os.ReadFile(daemon.PidFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, ErrDaemonNotRunning
+	}
+
+	if err != nil {
+		return 0, fmt.Errorf("failed reading pidfile: %w", err)
+	}
+
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || pid <= 0 {
+		return 0, fmt.Errorf("invalid pidfile %s: %q", daemon.PidFile, data)
+	}
+
+	if !processAlive(pid) {
+		return pid, fmt.Errorf("%w: stale pid %d", ErrDaemonNotRunning, pid)
+	}
+
+	return pid, nil
+}
+
+// Stop asks the daemon to terminate (see Command.Stop), waits up to timeout for it to exit, then kills it, and
+// removes the pidfile. It returns ErrDaemonNotRunning if the daemon was not running.
+func (daemon *Daemon) Stop(timeout time.Duration) error {
+	pid, err := daemon.Status()
+	if err != nil {
+		if errors.Is(err, ErrDaemonNotRunning) {
+			_ = os.Remove(daemon.PidFile)
+		}
+
+		return err
+	}
+
+	if err = terminatePID(pid); err != nil {
+		log.Debug().Err(err).Int("pid", pid).Msg("Failed requesting daemon termination. Killing.")
+	}
+
+	for deadline := time.Now().Add(timeout); processAlive(pid) && time.Now().Before(deadline); {
+		time.Sleep(daemonStopPoll)
+	}
+
+	if processAlive(pid) {
+		log.Warn().Int("pid", pid).Dur("timeout", timeout).Msg("Daemon did not stop in time. Killing.")
+
+		if err = killPID(pid); err != nil {
+			return fmt.Errorf("failed killing daemon: %w", err)
+		}
+	}
+
+	if err = os.Remove(daemon.PidFile); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed removing pidfile: %w", err)
+	}
+
+	return nil
+}
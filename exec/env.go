@@ -0,0 +1,111 @@
+package exec
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// environ returns the child environment: the parent one (entirely, or only allow-listed variables in clean mode),
+// minus UnsetEnv variables, overridden by extra variables. Entries are unique and sorted by name, so that the result
+// is deterministic.
+func (com *Commander) environ(extra map[string]string) []string {
+	envs := []string{}
+
+	for _, kv := range os.Environ() {
+		name := envName(kv)
+		if com.CleanEnv && !allowed(com.InheritEnv, name) || allowed(com.UnsetEnv, name) {
+			continue
+		}
+
+		envs = append(envs, kv)
+	}
+
+	return mergeEnv(envs, sortedEnv(extra)...)
+}
+
+// mergeEnv returns envs overridden by the KEY=VALUE entries of overrides, without duplicates, sorted by name.
+// Names are case-insensitive on Windows.
+func mergeEnv(envs []string, overrides ...string) []string {
+	merged := map[string]string{}
+	keys := []string{}
+
+	for _, kv := range append(append([]string{}, envs...), overrides...) {
+		key := envName(kv)
+		if runtime.GOOS == "windows" {
+			key = strings.ToUpper(key)
+		}
+
+		if _, ok := merged[key]; !ok {
+			keys = append(keys, key)
+		}
+
+		merged[key] = kv
+	}
+
+	sort.Strings(keys)
+
+	result := make([]string, 0, len(keys))
+	for _, key := range keys {
+		result = append(result, merged[key])
+	}
+
+	return result
+}
+
+// envName returns the name of a KEY=VALUE entry. Windows has entries starting with = (eg: "=C:=C:\\dir"), whose
+// name includes it.
+func envName(kv string) string {
+	if kv == "" {
+		return kv
+	}
+
+	if i := strings.Index(kv[1:], "="); i >= 0 {
+		return kv[:i+1]
+	}
+
+	return kv
+}
+
+// sortedEnv formats env as KEY=VALUE entries, sorted by key.
+func sortedEnv(env map[string]string) []string {
+	keys := make([]string, 0, len(env))
+	for k := range env {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	envs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		envs = append(envs, fmt.Sprintf("%s=%s", k, env[k]))
+	}
+
+	return envs
+}
+
+// allowed matches name against the allow-list, where entries ending with * are prefixes.
+// Names are case-insensitive on Windows.
+func allowed(allowList []string, name string) bool {
+	for _, entry := range allowList {
+		prefix := strings.HasSuffix(entry, "*")
+		pattern := strings.TrimSuffix(entry, "*")
+
+		candidate := name
+		if prefix && len(candidate) > len(pattern) {
+			candidate = candidate[:len(pattern)]
+		}
+
+		if runtime.GOOS == "windows" {
+			if strings.EqualFold(candidate, pattern) {
+				return true
+			}
+		} else if candidate == pattern {
+			return true
+		}
+	}
+
+	return false
+}
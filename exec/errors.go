@@ -0,0 +1,97 @@
+package exec
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrUnsupportedPlatform    = errors.New("not supported on this platform")
+	ErrInsufficientPrivileges = errors.New("insufficient privileges")
+	ErrCanceled               = errors.New("command canceled")
+	ErrNotStarted             = errors.New("command not started")
+	ErrAlreadyStarted         = errors.New("command already started")
+	ErrPolicyRejected         = errors.New("execution rejected by policy")
+	ErrArtifactMissing        = errors.New("expected artifact not produced")
+	ErrDaemonRunning          = errors.New("daemon already running")
+	ErrDaemonNotRunning       = errors.New("daemon not running")
+	ErrFixtureMissing         = errors.New("no fixture recorded for execution")
+	ErrChecksumMismatch       = errors.New("binary checksum mismatch")
+	ErrInactive               = errors.New("no output")
+	ErrNotReady               = errors.New("not ready")
+	ErrShellDisabled          = errors.New("shell execution is not enabled (see EnableShell)")
+	ErrStreamPanic            = errors.New("panic while pumping command streams")
+	// Deprecated: commanders can now appear several times in a pipeline, this is not returned anymore.
+	ErrPipelineReuse = errors.New("a commander cannot appear twice in a pipeline")
+
+	errPTYUnsupported = fmt.Errorf("%w: pty", ErrUnsupportedPlatform)
+	errUncacheable    = errors.New("standard input is set")
+)
+
+// CanceledError is returned when a command is terminated because its context is done.
+// It matches both ErrCanceled and the context error (context.Canceled or context.DeadlineExceeded).
+type CanceledError struct {
+	// Cause is the context error
+	Cause error
+	// Err is the underlying execution error
+	Err error
+}
+
+func (e *CanceledError) Error() string {
+	return fmt.Sprintf("%s: %s (%s)", ErrCanceled, e.Cause, e.Err)
+}
+
+func (e *CanceledError) Unwrap() error {
+	return e.Cause
+}
+
+func (e *CanceledError) Is(target error) bool {
+	return target == ErrCanceled //nolint:errorlint
+}
+
+// ExitCodeError is returned when a command exits with a code mapped in Commander.ExitCodes. It matches the mapped
+// error with errors.Is, and still wraps the *exec.ExitError.
+type ExitCodeError struct {
+	// Code is the exit code
+	Code int
+	// Mapped is the error the code is mapped to
+	Mapped error
+	// Err is the underlying *exec.ExitError
+	Err error
+}
+
+func (e *ExitCodeError) Error() string {
+	return fmt.Sprintf("%s (exit code %d)", e.Mapped, e.Code)
+}
+
+func (e *ExitCodeError) Unwrap() error {
+	return e.Err
+}
+
+func (e *ExitCodeError) Is(target error) bool {
+	return errors.Is(e.Mapped, target)
+}
+
+// ExitCode returns the exit code.
+func (e *ExitCodeError) ExitCode() int {
+	return e.Code
+}
+
+// StderrError is returned by RunJSON and StreamJSON when the command fails, folding its standard error into the
+// message. It wraps the execution error.
+type StderrError struct {
+	Err    error
+	Stderr string
+}
+
+func (e *StderrError) Error() string {
+	if e.Stderr == "" {
+		return e.Err.Error()
+	}
+
+	return fmt.Sprintf("%s: %s", e.Err, e.Stderr)
+}
+
+func (e *StderrError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,163 @@
+package exec
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"go.codecomet.dev/core/filesystem"
+)
+
+// FixtureMode selects whether fixtures are recorded or replayed.
+type FixtureMode string
+
+const (
+	// FixtureRecord runs commands, and records their outcome
+	FixtureRecord FixtureMode = "record"
+	// FixtureReplay serves recorded outcomes, without running anything
+	FixtureReplay FixtureMode = "replay"
+)
+
+// Fixtures record the outcome (output and exit code) of buffered executions (Run, ExecAndComplete, etc) into
+// files, one per binary name and arguments, and replay them, so that tests of code built on Commander run
+// hermetically. Streamed, attached and piped executions are not covered.
+type Fixtures struct {
+	// Dir holds the fixture files
+	Dir  string
+	Mode FixtureMode
+}
+
+// fixture is the recorded outcome of an execution.
+type fixture struct {
+	Name     string   `json:"name"`
+	Args     []string `json:"args"`
+	Stdout   string   `json:"stdout"`
+	Stderr   string   `json:"stderr"`
+	ExitCode int      `json:"exitCode"`
+}
+
+// ReplayedExitError is returned by replayed executions that exited with a non-zero code.
+type ReplayedExitError struct {
+	Code int
+}
+
+func (e *ReplayedExitError) Error() string {
+	return fmt.Sprintf("exit status %d (replayed)", e.Code)
+}
+
+// ExitCode returns the recorded exit code.
+func (e *ReplayedExitError) ExitCode() int {
+	return e.Code
+}
+
+// NewReplay returns a Commander replaying the fixtures of bin from dir. Contrary to New, bin is not resolved, and
+// does not need to exist.
+func NewReplay(bin string, dir string) *Commander {
+	return &Commander{
+		mu:       &sync.Mutex{},
+		bin:      bin,
+		name:     bin,
+		Fixtures: &Fixtures{Dir: dir, Mode: FixtureReplay},
+	}
+}
+
+// file returns the fixture file of an execution.
+func (fix *Fixtures) file(name string, args []string) string {
+	sum := sha256.Sum256([]byte(strings.Join(append([]string{name}, args...), "\x00")))
+
+	return filepath.Join(fix.Dir, filepath.Base(name)+"-"+hex.EncodeToString(sum[:8])+".json")
+}
+
+func (fix *Fixtures) load(name string, args []string) (*fixture, error) {
+	file := fix.file(name, args)
+
+	data, err := os.ReadFile(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s %s (%s)", ErrFixtureMissing, name, strings.Join(args, " "), file)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed reading fixture: %w", err)
+	}
+
+	recorded := &fixture{}
+	if err = json.Unmarshal(data, recorded); err != nil {
+		return nil, fmt.Errorf("invalid fixture %s: %w", file, err)
+	}
+
+	return recorded, nil
+}
+
+func (fix *Fixtures) save(recorded *fixture) error {
+	var data bytes.Buffer
+
+	// Fixtures are meant to be read and reviewed: keep shell operators as is
+	encoder := json.NewEncoder(&data)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+
+	if err := encoder.Encode(recorded); err != nil {
+		return fmt.Errorf("failed marshalling fixture: %w", err)
+	}
+
+	if err := os.MkdirAll(fix.Dir, filesystem.DirPermissionsDefault); err != nil {
+		return fmt.Errorf("failed creating fixtures directory: %w", err)
+	}
+
+	return filesystem.WriteFile(fix.file(recorded.Name, recorded.Args), data.Bytes(), filesystem.FilePermissionsDefault)
+}
+
+// replay writes the recorded output of the command to its stdout and stderr, and returns its recorded outcome.
+func (cmd *Command) replay() error {
+	if cmd.rejected != nil {
+		return cmd.rejected
+	}
+
+	recorded, err := cmd.conf.Fixtures.load(cmd.conf.name, cmd.cmd.Args[1:])
+	if err != nil {
+		return err
+	}
+
+	cmd.replayed = recorded
+
+	if _, err = cmd.cmd.Stdout.Write([]byte(recorded.Stdout)); err != nil {
+		return err
+	}
+
+	if _, err = cmd.cmd.Stderr.Write([]byte(recorded.Stderr)); err != nil {
+		return err
+	}
+
+	if recorded.ExitCode != 0 {
+		return cmd.exitError(&ReplayedExitError{Code: recorded.ExitCode})
+	}
+
+	return nil
+}
+
+// record saves the outcome of the command, if it ran to completion.
+func (cmd *Command) record(stdout []byte, stderr []byte, err error) error {
+	code := ExitCode(err)
+	if err == nil {
+		code = 0
+	}
+
+	if code < 0 {
+		return nil
+	}
+
+	return cmd.conf.Fixtures.save(&fixture{
+		Name:     cmd.conf.name,
+		Args:     cmd.cmd.Args[1:],
+		Stdout:   string(stdout),
+		Stderr:   string(stderr),
+		ExitCode: code,
+	})
+}
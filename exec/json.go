@@ -0,0 +1,133 @@
+package exec
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// RunJSON executes the command to completion (with retries, if configured), and decodes its standard output into v.
+// If v points to a slice, the output may be either a JSON array, or a stream of values (NDJSON, eg: docker ps
+// --format '{{json .}}'), each appended to the slice.
+// On failure, the error is a *StderrError, carrying the standard error of the command.
+func (com *Commander) RunJSON(ctx context.Context, v interface{}, args ...string) error {
+	_, stdout, stderr, err := com.exec(ctx, args)
+	if err != nil {
+		return stderrError(err, stderr.Bytes())
+	}
+
+	if err = decodeJSON(stdout.Bytes(), v); err != nil {
+		return fmt.Errorf("failed decoding output of %s: %w", com.name, err)
+	}
+
+	return nil
+}
+
+// StreamJSON starts the command, and calls handle with each JSON value on its standard output, as soon as it is
+// written (eg: kubectl get --watch -o json). If handle returns an error, the command is terminated, and StreamJSON
+// returns that error. On failure, the error is a *StderrError, carrying the standard error of the command.
+func (com *Commander) StreamJSON(ctx context.Context, handle func(json.RawMessage) error, args ...string) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	cmd := com.activate(com.prepare(ctx, com.Stdin, args))
+
+	stdout, errpipe, err := cmd.Start()
+	if err != nil {
+		return err
+	}
+
+	stderr := newCapture(cmd.conf.OutputLimit, StreamStderr)
+	copied := make(chan struct{})
+
+	go func() {
+		_, _ = io.Copy(stderr, errpipe)
+		close(copied)
+	}()
+
+	decoder := json.NewDecoder(stdout)
+
+	var handleErr error
+
+	for handleErr == nil {
+		var raw json.RawMessage
+
+		if err = decoder.Decode(&raw); err != nil {
+			if !errors.Is(err, io.EOF) {
+				handleErr = fmt.Errorf("failed decoding output of %s: %w", com.name, err)
+			}
+
+			break
+		}
+
+		handleErr = handle(raw)
+	}
+
+	if handleErr != nil {
+		cancel()
+
+		// Pipes must be drained before waiting
+		_, _ = io.Copy(io.Discard, stdout)
+	}
+
+	<-copied
+	stderr.close()
+
+	err = cmd.Wait()
+
+	if handleErr != nil {
+		return handleErr
+	}
+
+	if err != nil {
+		buf := stderr.buffer()
+
+		return stderrError(err, buf.Bytes())
+	}
+
+	return nil
+}
+
+func stderrError(err error, stderr []byte) error {
+	return &StderrError{Err: err, Stderr: strings.TrimSpace(string(stderr))}
+}
+
+// decodeJSON decodes data into v, accepting a stream of values for slices.
+func decodeJSON(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+
+	target := reflect.ValueOf(v)
+	trimmed := bytes.TrimSpace(data)
+
+	if target.Kind() != reflect.Pointer || target.Elem().Kind() != reflect.Slice || bytes.HasPrefix(trimmed, []byte("[")) {
+		if err := decoder.Decode(v); err != nil {
+			return err
+		}
+
+		// More misses closing delimiters, which Token rejects
+		if _, err := decoder.Token(); !errors.Is(err, io.EOF) {
+			return fmt.Errorf("unexpected data after the JSON value at offset %d", decoder.InputOffset())
+		}
+
+		return nil
+	}
+
+	slice := target.Elem()
+	slice.Set(reflect.MakeSlice(slice.Type(), 0, 0))
+
+	for decoder.More() {
+		item := reflect.New(slice.Type().Elem())
+		if err := decoder.Decode(item.Interface()); err != nil {
+			return err
+		}
+
+		slice.Set(reflect.Append(slice, item.Elem()))
+	}
+
+	return nil
+}
@@ -0,0 +1,140 @@
+package exec
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"sync"
+)
+
+// OnStdoutLine registers a handler called with every line written by the child to stdout, as it is produced.
+// Pass nil to remove it.
+func (com *Commander) OnStdoutLine(handler func(string)) {
+	com.onStdout = handler
+}
+
+// OnStderrLine registers a handler called with every line written by the child to stderr, as it is produced.
+// Pass nil to remove it.
+func (com *Commander) OnStderrLine(handler func(string)) {
+	com.onStderr = handler
+}
+
+// lineHandler composes the line handlers of stream: the transcript (if any), the logger (if LogOutput is set), and
+// the registered handler. It returns nil if there is nothing to do with lines.
+func (com *Commander) lineHandler(stream string, tr *Transcript) func(string) {
+	handler := com.onStdout
+	if stream == StreamStderr {
+		handler = com.onStderr
+	}
+
+	return tr.lines(stream, com.logLines(stream, handler))
+}
+
+// lineWriter returns a writer calling the line and progress handlers of stream, or nil if there are none.
+func (com *Commander) lineWriter(stream string, tr *Transcript) *lineWriter {
+	return newLineWriter(com.lineHandler(stream, tr), com.progressHandler())
+}
+
+// lineWriter splits written data into lines, calling handler for each, without trailing line terminators.
+// Updates, if set, is called with every segment terminated by \r or \n: progress bars redraw with \r alone.
+type lineWriter struct {
+	mu      sync.Mutex
+	handler func(string)
+	partial bytes.Buffer
+	updates func(string)
+	segment bytes.Buffer
+}
+
+func newLineWriter(handler func(string), updates func(string)) *lineWriter {
+	if handler == nil && updates == nil {
+		return nil
+	}
+
+	return &lineWriter{handler: handler, updates: updates}
+}
+
+func (w *lineWriter) Write(data []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.updates != nil {
+		w.split(data)
+	}
+
+	if w.handler == nil {
+		return len(data), nil
+	}
+
+	w.partial.Write(data)
+
+	for {
+		idx := bytes.IndexByte(w.partial.Bytes(), '\n')
+		if idx == -1 {
+			break
+		}
+
+		line := string(w.partial.Next(idx + 1))
+		w.handler(strings.TrimRight(line, "\r\n"))
+	}
+
+	return len(data), nil
+}
+
+// split calls updates with the segments of data, keeping the incomplete last one.
+func (w *lineWriter) split(data []byte) {
+	for {
+		idx := bytes.IndexAny(data, "\r\n")
+		if idx == -1 {
+			w.segment.Write(data)
+
+			return
+		}
+
+		w.segment.Write(data[:idx])
+		data = data[idx+1:]
+
+		if w.segment.Len() > 0 {
+			w.updates(w.segment.String())
+			w.segment.Reset()
+		}
+	}
+}
+
+// Flush sends any pending incomplete line.
+func (w *lineWriter) Flush() {
+	if w == nil {
+		return
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.segment.Len() > 0 {
+		w.updates(w.segment.String())
+		w.segment.Reset()
+	}
+
+	if w.partial.Len() > 0 {
+		w.handler(strings.TrimRight(w.partial.String(), "\r"))
+		w.partial.Reset()
+	}
+}
+
+// lineReader feeds everything read from a pipe to a lineWriter.
+type lineReader struct {
+	io.ReadCloser
+	lines *lineWriter
+}
+
+func (r *lineReader) Read(data []byte) (int, error) {
+	n, err := r.ReadCloser.Read(data)
+	if n > 0 {
+		_, _ = r.lines.Write(data[:n])
+	}
+
+	if err != nil {
+		r.lines.Flush()
+	}
+
+	return n, err
+}
@@ -0,0 +1,38 @@
+package exec
+
+import "go.codecomet.dev/core/log"
+
+// LogOutput emits the output lines of children through the log package, while they are still captured for the
+// caller. Lines are tagged with ctx=exec, the binary name and the stream.
+type LogOutput struct {
+	// Stdout is the level of stdout lines - log.Disabled to not log them
+	Stdout log.Level
+	// Stderr is the level of stderr lines - log.Disabled to not log them
+	Stderr log.Level
+}
+
+// logLines returns a line handler logging stream lines if com has a LogOutput, before calling handler.
+func (com *Commander) logLines(stream string, handler func(string)) func(string) {
+	if com.LogOutput == nil {
+		return handler
+	}
+
+	level := com.LogOutput.Stdout
+	if stream == StreamStderr {
+		level = com.LogOutput.Stderr
+	}
+
+	if level == log.Disabled {
+		return handler
+	}
+
+	name := com.name
+
+	return func(line string) {
+		log.WithLevel(level).Str(log.ContextFieldName, "exec").Str("binary", name).Str("stream", stream).Msg(line)
+
+		if handler != nil {
+			handler(line)
+		}
+	}
+}
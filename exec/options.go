@@ -0,0 +1,86 @@
+package exec
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// Option customizes a single execution (see RunWith).
+type Option func(*runOptions)
+
+type runOptions struct {
+	com     *Commander
+	timeout time.Duration
+}
+
+// WithDir runs the command in dir.
+func WithDir(dir string) Option {
+	return func(opts *runOptions) {
+		opts.com.Dir = dir
+	}
+}
+
+// WithEnv adds env to the Commander environment, overriding variables set in both.
+func WithEnv(env map[string]string) Option {
+	return func(opts *runOptions) {
+		for k, v := range env {
+			opts.com.Env[k] = v
+		}
+	}
+}
+
+// WithStdin feeds stdin to the command.
+func WithStdin(stdin io.Reader) Option {
+	return func(opts *runOptions) {
+		opts.com.Stdin = stdin
+	}
+}
+
+// WithTimeout terminates the command if it does not complete within timeout, retries included.
+func WithTimeout(timeout time.Duration) Option {
+	return func(opts *runOptions) {
+		opts.timeout = timeout
+	}
+}
+
+// WithNoReport does not report failures of the command.
+func WithNoReport() Option {
+	return func(opts *runOptions) {
+		opts.com.NoReport = true
+	}
+}
+
+// RunWith is RunContext with options applying to this execution only, leaving the Commander untouched, so that
+// a Commander shared between goroutines needs not be modified between calls.
+// The execution does not become the active command of the Commander (see Signal, Stop).
+func (com *Commander) RunWith(ctx context.Context, args []string, opts ...Option) (*Result, error) {
+	com.mu.Lock()
+	conf := *com
+	com.mu.Unlock()
+
+	env := conf.Env
+
+	conf.mu = &sync.Mutex{}
+	conf.active = nil
+	conf.Env = make(map[string]string, len(env))
+
+	for k, v := range env {
+		conf.Env[k] = v
+	}
+
+	options := &runOptions{com: &conf}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	if options.timeout > 0 {
+		var cancel context.CancelFunc
+
+		ctx, cancel = context.WithTimeout(ctx, options.timeout)
+		defer cancel()
+	}
+
+	return conf.RunContext(ctx, args...)
+}
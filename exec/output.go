@@ -0,0 +1,126 @@
+package exec
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+)
+
+// OutputLimit caps the output of each stream buffered in memory by ExecAndComplete, ExecContext, Run and pipelines,
+// so that a runaway child cannot exhaust our memory. Line handlers still see the complete output.
+type OutputLimit struct {
+	// Bytes is the maximum size kept in memory per stream - output beyond is dropped, and a marker appended
+	Bytes int
+	// Spill writes streams exceeding Bytes in their entirety to temporary files, left for the caller to remove
+	// (see Result.StdoutFile and Result.StderrFile)
+	Spill bool
+	// SpillDir is where spill files are created - defaults to the system temporary directory
+	SpillDir string
+}
+
+// capture is a stream buffer honoring an OutputLimit.
+type capture struct {
+	buf      bytes.Buffer
+	limit    *OutputLimit
+	name     string
+	total    int64
+	spill    *os.File
+	spillErr error
+}
+
+func newCapture(limit *OutputLimit, name string) *capture {
+	return &capture{limit: limit, name: name}
+}
+
+// Write always succeeds, so that the child is never blocked or failed by the cap.
+func (stream *capture) Write(data []byte) (int, error) {
+	stream.total += int64(len(data))
+
+	if stream.limit == nil || stream.limit.Bytes <= 0 {
+		return stream.buf.Write(data)
+	}
+
+	if stream.limit.Spill && stream.spillErr == nil {
+		if stream.spill == nil && stream.total > int64(stream.limit.Bytes) {
+			stream.startSpill()
+		}
+
+		if stream.spill != nil {
+			_, stream.spillErr = stream.spill.Write(data)
+		}
+	}
+
+	if room := stream.limit.Bytes - stream.buf.Len(); room > 0 {
+		if room > len(data) {
+			room = len(data)
+		}
+
+		stream.buf.Write(data[:room])
+	}
+
+	return len(data), nil
+}
+
+// startSpill creates the spill file, with the output buffered so far.
+func (stream *capture) startSpill() {
+	stream.spill, stream.spillErr = os.CreateTemp(stream.limit.SpillDir, "exec-"+stream.name+"-*")
+	if stream.spillErr == nil {
+		_, stream.spillErr = stream.spill.Write(stream.buf.Bytes())
+	}
+}
+
+// truncated returns true if output was dropped from the buffer.
+func (stream *capture) truncated() bool {
+	return stream.total > int64(stream.buf.Len())
+}
+
+// file returns the spill file name, if the complete output was spilled.
+func (stream *capture) file() string {
+	if stream.spill == nil || stream.spillErr != nil {
+		return ""
+	}
+
+	return stream.spill.Name()
+}
+
+// close closes the spill file, discarding it if it could not be written entirely.
+func (stream *capture) close() {
+	if stream.spill == nil {
+		return
+	}
+
+	if err := stream.spill.Close(); err != nil && stream.spillErr == nil {
+		stream.spillErr = err
+	}
+
+	if stream.spillErr != nil {
+		_ = os.Remove(stream.spill.Name())
+	}
+}
+
+// discard removes the spill file, if any.
+func (stream *capture) discard() {
+	if name := stream.file(); name != "" {
+		_ = os.Remove(name)
+	}
+}
+
+// buffer returns the buffered output, with a truncation marker if output was dropped.
+func (stream *capture) buffer() bytes.Buffer {
+	var out bytes.Buffer
+
+	out.Write(stream.buf.Bytes())
+
+	if !stream.truncated() {
+		return out
+	}
+
+	if file := stream.file(); file != "" {
+		fmt.Fprintf(&out, "\n[%s truncated: %d of %d bytes shown, complete output in %s]\n",
+			stream.name, stream.buf.Len(), stream.total, file)
+	} else {
+		fmt.Fprintf(&out, "\n[%s truncated: %d of %d bytes shown]\n", stream.name, stream.buf.Len(), stream.total)
+	}
+
+	return out
+}
@@ -0,0 +1,160 @@
+package exec
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"syscall"
+	"time"
+)
+
+// Pipeline chains commanders, the standard output of each stage feeding the standard input of the next, like a
+// shell pipe. Stages run with their PreArgs, Env, Dir, and the other Commander settings.
+type Pipeline struct {
+	stages []*Commander
+}
+
+// PipelineError reports the first failed stage of a pipeline.
+type PipelineError struct {
+	// Stage is the index of the failed stage
+	Stage int
+	// Name is the binary name of the failed stage
+	Name string
+	Err  error
+}
+
+func (e *PipelineError) Error() string {
+	return fmt.Sprintf("pipeline stage %d (%s) failed: %s", e.Stage, e.Name, e.Err)
+}
+
+func (e *PipelineError) Unwrap() error {
+	return e.Err
+}
+
+// Pipe returns a pipeline feeding the output of com to next.
+func (com *Commander) Pipe(next *Commander) *Pipeline {
+	return &Pipeline{stages: []*Commander{com, next}}
+}
+
+// Pipe appends a stage to the pipeline.
+func (pipe *Pipeline) Pipe(next *Commander) *Pipeline {
+	pipe.stages = append(pipe.stages, next)
+
+	return pipe
+}
+
+// Run runs all stages concurrently, the first one reading its Commander Stdin, and returns the results of every
+// stage - the pipeline output is the Stdout of the last result. The same Commander may be used in several stages.
+// Like with the shell pipefail option, the error reports the first stage that failed, ignoring upstream stages
+// terminated by SIGPIPE because a downstream stage exited early.
+func (pipe *Pipeline) Run(ctx context.Context) ([]*Result, error) {
+	count := len(pipe.stages)
+	cmds := make([]*Command, count)
+
+	var pipes []io.Closer
+
+	var lines []*lineWriter
+
+	defer func() {
+		for _, closer := range pipes {
+			_ = closer.Close()
+		}
+	}()
+
+	stdin := pipe.stages[0].Stdin
+
+	for i, stage := range pipe.stages {
+		cmd := stage.activate(stage.prepare(ctx, stdin, nil))
+		cmds[i] = cmd
+
+		var errLines, outLines *lineWriter
+
+		cmd.captured = [2]*capture{nil, newCapture(cmd.conf.OutputLimit, StreamStderr)}
+		cmd.transcript = cmd.conf.newTranscript()
+		cmd.cmd.Stderr, errLines = teeLines(cmd.captured[1], cmd.conf.lineWriter(StreamStderr, cmd.transcript))
+		lines = append(lines, errLines)
+
+		if i == count-1 {
+			cmd.captured[0] = newCapture(cmd.conf.OutputLimit, StreamStdout)
+			cmd.cmd.Stdout, outLines = teeLines(cmd.captured[0], cmd.conf.lineWriter(StreamStdout, cmd.transcript))
+			lines = append(lines, outLines)
+
+			break
+		}
+
+		reader, writer, err := os.Pipe()
+		if err != nil {
+			return nil, fmt.Errorf("failed creating pipe: %w", err)
+		}
+
+		pipes = append(pipes, reader, writer)
+		cmd.cmd.Stdout = writer
+		stdin = reader
+	}
+
+	started := time.Now()
+
+	for i, cmd := range cmds {
+		if err := cmd.start(true); err != nil {
+			for _, previous := range cmds[:i] {
+				_ = previous.Kill()
+				_ = previous.wait()
+			}
+
+			return nil, &PipelineError{Stage: i, Name: cmd.conf.name, Err: err}
+		}
+	}
+
+	// Children have their own copies: close ours, so that stages see EOF when their upstream exits
+	for _, closer := range pipes {
+		_ = closer.Close()
+	}
+
+	pipes = nil
+
+	errs := make([]error, count)
+	for i, cmd := range cmds {
+		if err := cmd.wait(); err != nil {
+			errs[i] = cmd.canceled(err)
+		}
+	}
+
+	for _, writer := range lines {
+		writer.Flush()
+	}
+
+	results := make([]*Result, count)
+
+	var failed error
+
+	for i, cmd := range cmds {
+		var output []byte
+
+		if cmd.captured[0] != nil {
+			cmd.captured[0].close()
+			stdout := cmd.captured[0].buffer()
+			output = stdout.Bytes()
+		}
+
+		cmd.captured[1].close()
+		stderr := cmd.captured[1].buffer()
+
+		results[i] = cmd.result(started, output, stderr.Bytes(), errs[i])
+
+		if failed == nil && errs[i] != nil && !(i < count-1 && results[i].Signal == syscall.SIGPIPE.String()) {
+			failed = &PipelineError{Stage: i, Name: cmd.conf.name, Err: errs[i]}
+		}
+	}
+
+	return results, failed
+}
+
+// teeLines adds a line writer to a buffer, if any. The returned line writer (possibly nil) must be flushed.
+func teeLines(buf io.Writer, lines *lineWriter) (io.Writer, *lineWriter) {
+	if lines == nil {
+		return buf, nil
+	}
+
+	return io.MultiWriter(buf, lines), lines
+}
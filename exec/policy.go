@@ -0,0 +1,66 @@
+package exec
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Invocation describes an execution about to happen. Policies may rewrite it.
+type Invocation struct {
+	// Binary is the resolved binary path (or name, for containers)
+	Binary string
+	// Name is the binary name, as passed to New
+	Name string
+	// Args are the arguments, including PreArgs
+	Args []string
+	// Env are the extra environment variables
+	Env map[string]string
+	// Dir is the working directory
+	Dir string
+}
+
+// Policy inspects an invocation before every execution, and may rewrite its arguments, environment and directory,
+// or reject it by returning an error (typically a *PolicyError).
+type Policy func(inv *Invocation) error
+
+// PolicyError is returned when a policy rejects an execution. It matches ErrPolicyRejected.
+type PolicyError struct {
+	Binary string
+	Reason string
+}
+
+func (e *PolicyError) Error() string {
+	return fmt.Sprintf("%s: %s: %s", ErrPolicyRejected, e.Binary, e.Reason)
+}
+
+func (e *PolicyError) Is(target error) bool {
+	return target == ErrPolicyRejected //nolint:errorlint
+}
+
+var (
+	policies   []Policy     //nolint:gochecknoglobals
+	policiesMu sync.RWMutex //nolint:gochecknoglobals
+)
+
+// AddPolicy registers a policy, applied to all subsequent executions in registration order.
+func AddPolicy(policy Policy) {
+	policiesMu.Lock()
+	defer policiesMu.Unlock()
+
+	policies = append(policies, policy)
+}
+
+// applyPolicies runs all registered policies on inv.
+func applyPolicies(inv *Invocation) error {
+	policiesMu.RLock()
+	registered := policies
+	policiesMu.RUnlock()
+
+	for _, policy := range registered {
+		if err := policy(inv); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,147 @@
+package exec
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Pool runs many executions concurrently, at most Limit at a time, in the order they were added.
+// The same Commander may be added any number of times.
+type Pool struct {
+	// Limit is the maximum number of concurrent executions - all of them if zero
+	Limit int
+	// FailFast stops the pool on the first failure: executions not started yet are skipped, and running ones
+	// are terminated. Otherwise, all executions run, and all errors are collected.
+	FailFast bool
+	jobs     []poolJob
+}
+
+type poolJob struct {
+	com  *Commander
+	args []string
+}
+
+// PoolError reports the failed executions of a pool, and matches the first of them with errors.Is and errors.As.
+type PoolError struct {
+	// Errors has the error of each execution, in the order they were added - nil for those that succeeded, or
+	// that were terminated or skipped because another one failed in FailFast mode
+	Errors []error
+}
+
+func (e *PoolError) Error() string {
+	failed := 0
+	first := -1
+
+	for i, err := range e.Errors {
+		if err != nil {
+			failed++
+
+			if first < 0 {
+				first = i
+			}
+		}
+	}
+
+	if failed == 1 {
+		return fmt.Sprintf("pool execution %d failed: %s", first, e.Errors[first])
+	}
+
+	return fmt.Sprintf("%d pool executions failed, first (%d): %s", failed, first, e.Errors[first])
+}
+
+func (e *PoolError) Unwrap() error {
+	for _, err := range e.Errors {
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// NewPool returns a pool running at most limit executions at a time.
+func NewPool(limit int) *Pool {
+	return &Pool{Limit: limit}
+}
+
+// Add queues an execution of com with args.
+func (pool *Pool) Add(com *Commander, args ...string) *Pool {
+	pool.jobs = append(pool.jobs, poolJob{com: com, args: args})
+
+	return pool
+}
+
+// Run runs the queued executions, and returns their results, in the order they were added. Executions use the
+// settings of their Commander, retry policy included. Results of executions that were never started (because ctx
+// is done, or another one failed in FailFast mode) are nil.
+// The error, if any, is a *PoolError. If ctx is done, running executions are terminated, and those not started
+// yet fail with a CanceledError.
+func (pool *Pool) Run(ctx context.Context) ([]*Result, error) {
+	count := len(pool.jobs)
+	results := make([]*Result, count)
+	errs := make([]error, count)
+
+	limit := pool.Limit
+	if limit <= 0 || limit > count {
+		limit = count
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Once runCtx is done, the remaining executions are all skipped: a slot taken by one of them is never needed
+	// again, and needs no release
+	slots := make(chan struct{}, limit)
+
+	var group sync.WaitGroup
+
+	for i, job := range pool.jobs {
+		select {
+		case slots <- struct{}{}:
+		case <-runCtx.Done():
+		}
+
+		if runCtx.Err() != nil {
+			if err := ctx.Err(); err != nil {
+				errs[i] = &CanceledError{Cause: err, Err: ErrNotStarted}
+			}
+
+			continue
+		}
+
+		group.Add(1)
+
+		go func(i int, job poolJob) {
+			defer func() {
+				<-slots
+				group.Done()
+			}()
+
+			res, err := job.com.RunContext(runCtx, job.args...)
+			results[i] = res
+
+			// Executions terminated because another one failed are not failures of their own
+			if err == nil || (ctx.Err() == nil && errors.Is(err, ErrCanceled)) {
+				return
+			}
+
+			errs[i] = err
+
+			if pool.FailFast {
+				cancel()
+			}
+		}(i, job)
+	}
+
+	group.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return results, &PoolError{Errors: errs}
+		}
+	}
+
+	return results, nil
+}
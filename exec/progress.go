@@ -0,0 +1,91 @@
+package exec
+
+import (
+	"regexp"
+	"strconv"
+)
+
+// ProgressReporter receives the progress of children - *log.Status is one.
+type ProgressReporter interface {
+	Progress(msg string, current int64, total int64)
+}
+
+// Progress extracts the progress of children from their output, as it is produced, and feeds it to a reporter, so
+// that UIs show the live progress of wrapped tools. Output segments terminated by \n or \r (progress bars redraw
+// with \r alone) of both streams are matched against extractors in order, the first match winning.
+type Progress struct {
+	Extractors []*ProgressExtractor
+	Reporter   ProgressReporter
+	// Message is the message passed to the reporter - defaults to the binary name
+	Message string
+}
+
+// ProgressExtractor recognizes progress in a segment of output.
+type ProgressExtractor struct {
+	Pattern *regexp.Regexp
+	// Extract computes progress from the submatches of Pattern. If nil, a single submatch is a percentage, and two
+	// submatches are the current and total counts (eg: `(\d+)/(\d+) files`)
+	Extract func(submatches []string) (current int64, total int64, ok bool)
+}
+
+// ProgressPattern returns an extractor interpreting submatches of pattern as a percentage, or current and total
+// counts (see ProgressExtractor). It panics if pattern does not compile.
+func ProgressPattern(pattern string) *ProgressExtractor {
+	return &ProgressExtractor{Pattern: regexp.MustCompile(pattern)}
+}
+
+// ProgressPercent is an extractor for percentages, as most tools print them (eg: "Receiving objects:  45% (9/20)").
+var ProgressPercent = ProgressPattern(`(\d+(?:\.\d+)?)%`) //nolint:gochecknoglobals
+
+func (ext *ProgressExtractor) extract(segment string) (int64, int64, bool) {
+	submatches := ext.Pattern.FindStringSubmatch(segment)
+	if submatches == nil {
+		return 0, 0, false
+	}
+
+	if ext.Extract != nil {
+		return ext.Extract(submatches[1:])
+	}
+
+	switch len(submatches) {
+	case 2:
+		percent, err := strconv.ParseFloat(submatches[1], 64)
+
+		//nolint:gomnd
+		return int64(percent * 10), 1000, err == nil && percent >= 0
+	case 3:
+		current, err := strconv.ParseInt(submatches[1], 10, 64)
+		if err != nil {
+			return 0, 0, false
+		}
+
+		total, err := strconv.ParseInt(submatches[2], 10, 64)
+
+		return current, total, err == nil
+	default:
+		return 0, 0, false
+	}
+}
+
+// progressHandler returns a handler extracting progress from output segments, or nil if there is no Progress.
+func (com *Commander) progressHandler() func(string) {
+	progress := com.Progress
+	if progress == nil || progress.Reporter == nil || len(progress.Extractors) == 0 {
+		return nil
+	}
+
+	msg := progress.Message
+	if msg == "" {
+		msg = com.name
+	}
+
+	return func(segment string) {
+		for _, ext := range progress.Extractors {
+			if current, total, ok := ext.extract(segment); ok {
+				progress.Reporter.Progress(msg, current, total)
+
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,63 @@
+//go:build darwin
+
+package exec
+
+import (
+	"bytes"
+	"os"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	ioctlGetTermios = unix.TIOCGETA
+	ioctlSetTermios = unix.TIOCSETA
+	ptsNameSize     = 128
+)
+
+// openPTY allocates a pseudo terminal, returning its master and slave ends.
+func openPTY() (*os.File, *os.File, error) {
+	master, err := os.OpenFile("/dev/ptmx", os.O_RDWR|syscall.O_NOCTTY|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	fd := int(master.Fd())
+
+	// grantpt and unlockpt
+	if err = unix.IoctlSetInt(fd, unix.TIOCPTYGRANT, 0); err == nil {
+		err = unix.IoctlSetInt(fd, unix.TIOCPTYUNLK, 0)
+	}
+
+	if err != nil {
+		master.Close()
+
+		return nil, nil, err
+	}
+
+	// ptsname
+	name := make([]byte, ptsNameSize)
+
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), uintptr(unix.TIOCPTYGNAME),
+		uintptr(unsafe.Pointer(&name[0])))
+	if errno != 0 {
+		master.Close()
+
+		return nil, nil, errno
+	}
+
+	if i := bytes.IndexByte(name, 0); i != -1 {
+		name = name[:i]
+	}
+
+	slave, err := os.OpenFile(string(name), os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		master.Close()
+
+		return nil, nil, err
+	}
+
+	return master, slave, nil
+}
@@ -0,0 +1,13 @@
+//go:build !linux && !darwin
+
+package exec
+
+// runTerminal is not supported on this platform - Attach falls back to pipes.
+func (cmd *Command) runTerminal() error {
+	return errPTYUnsupported
+}
+
+// saveTerminal is not supported on this platform.
+func saveTerminal(_ int) (func(), error) {
+	return nil, errPTYUnsupported
+}
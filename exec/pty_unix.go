@@ -0,0 +1,193 @@
+//go:build linux || darwin
+
+package exec
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// ptyDrainTimeout bounds how long we keep copying terminal output after the child exited.
+const ptyDrainTimeout = 100 * time.Millisecond
+
+// runTerminal runs the prepared command attached to a pseudo terminal, wired to our own terminal.
+func (cmd *Command) runTerminal() error {
+	master, slave, err := openPTY()
+	if err != nil {
+		return fmt.Errorf("failed allocating a pty: %w", err)
+	}
+	defer master.Close()
+
+	command := cmd.cmd
+	command.Stdin, command.Stdout, command.Stderr = slave, slave, slave
+
+	if command.SysProcAttr == nil {
+		command.SysProcAttr = &syscall.SysProcAttr{}
+	}
+
+	// New session, with the pty (stdin in the child) as controlling terminal
+	command.SysProcAttr.Setsid = true
+	command.SysProcAttr.Setctty = true
+	command.SysProcAttr.Ctty = 0
+
+	// Propagate window size changes
+	resizePTY(master)
+
+	winch := make(chan os.Signal, 1)
+	signal.Notify(winch, syscall.SIGWINCH)
+
+	defer func() {
+		signal.Stop(winch)
+		close(winch)
+	}()
+
+	go func() {
+		for range winch {
+			resizePTY(master)
+		}
+	}()
+
+	if restore, err := makeRaw(int(os.Stdin.Fd())); err == nil {
+		defer guardTerminal(restore)()
+	}
+
+	err = cmd.start(false)
+	// The child has its own copy now
+	slave.Close()
+
+	if err != nil {
+		return err
+	}
+
+	// Reading stdin must be interruptible: a copier left blocked on it would swallow the next keystroke
+	wakeRead, wakeWrite, err := os.Pipe()
+	if err != nil {
+		return fmt.Errorf("failed allocating a pty: %w", err)
+	}
+
+	copied := make(chan struct{})
+
+	go func() {
+		defer close(copied)
+
+		copyInput(master, int(os.Stdin.Fd()), int(wakeRead.Fd()))
+	}()
+
+	defer func() {
+		wakeWrite.Close()
+		<-copied
+		wakeRead.Close()
+	}()
+
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		_, _ = io.Copy(os.Stdout, master)
+	}()
+
+	err = cmd.wait()
+
+	select {
+	case <-done:
+	case <-time.After(ptyDrainTimeout):
+	}
+
+	return err
+}
+
+// copyInput copies what is read from the fd input to dst, until input is exhausted or fails, or until wake is
+// readable (or closed). Input is only read when it has data, so that no read is pending once it returns.
+func copyInput(dst io.Writer, input int, wake int) {
+	buf := make([]byte, 32*1024) //nolint:gomnd
+
+	for {
+		fds := []unix.PollFd{{Fd: int32(input), Events: unix.POLLIN}, {Fd: int32(wake), Events: unix.POLLIN}}
+
+		if _, err := unix.Poll(fds, -1); err != nil {
+			if errors.Is(err, unix.EINTR) {
+				continue
+			}
+
+			return
+		}
+
+		if fds[1].Revents != 0 {
+			return
+		}
+
+		if fds[0].Revents == 0 {
+			continue
+		}
+
+		read, err := unix.Read(input, buf)
+		if errors.Is(err, unix.EINTR) {
+			continue
+		}
+
+		if read <= 0 {
+			return
+		}
+
+		if _, err = dst.Write(buf[:read]); err != nil {
+			return
+		}
+	}
+}
+
+// resizePTY copies our terminal size to the pty.
+func resizePTY(master *os.File) {
+	size, err := unix.IoctlGetWinsize(int(os.Stdin.Fd()), unix.TIOCGWINSZ)
+	if err != nil {
+		return
+	}
+
+	_ = unix.IoctlSetWinsize(int(master.Fd()), unix.TIOCSWINSZ, size)
+}
+
+// saveTerminal returns a function restoring the current state of the terminal fd.
+func saveTerminal(fd int) (func(), error) {
+	termios, err := unix.IoctlGetTermios(fd, ioctlGetTermios)
+	if err != nil {
+		return nil, err
+	}
+
+	return func() {
+		_ = unix.IoctlSetTermios(fd, ioctlSetTermios, termios)
+	}, nil
+}
+
+// makeRaw puts the terminal fd in raw mode, and returns a function restoring its previous state.
+func makeRaw(fd int) (func(), error) {
+	termios, err := unix.IoctlGetTermios(fd, ioctlGetTermios)
+	if err != nil {
+		return nil, err
+	}
+
+	previous := *termios
+
+	termios.Iflag &^= unix.IGNBRK | unix.BRKINT | unix.PARMRK | unix.ISTRIP | unix.INLCR | unix.IGNCR | unix.ICRNL |
+		unix.IXON
+	termios.Oflag &^= unix.OPOST
+	termios.Lflag &^= unix.ECHO | unix.ECHONL | unix.ICANON | unix.ISIG | unix.IEXTEN
+	termios.Cflag &^= unix.CSIZE | unix.PARENB
+	termios.Cflag |= unix.CS8
+	termios.Cc[unix.VMIN] = 1
+	termios.Cc[unix.VTIME] = 0
+
+	if err = unix.IoctlSetTermios(fd, ioctlSetTermios, termios); err != nil {
+		return nil, err
+	}
+
+	return func() {
+		_ = unix.IoctlSetTermios(fd, ioctlSetTermios, &previous)
+	}, nil
+}
@@ -0,0 +1,108 @@
+package exec
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"go.codecomet.dev/core/log"
+	"go.codecomet.dev/core/reporter"
+)
+
+// recoverWriter recovers panics of the writer it wraps. Writers fed with the output of children (captures, line
+// handlers, user writers) run on goroutines spawned by os/exec, where a panic would crash the process.
+// The panicking write fails instead, which stops the copy, and makes Wait return the error.
+type recoverWriter struct {
+	io.Writer
+	cmd    *Command
+	stream string
+}
+
+func (writer *recoverWriter) Write(data []byte) (written int, err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			written, err = 0, writer.cmd.recovered(recovered, writer.stream)
+		}
+	}()
+
+	return writer.Writer.Write(data)
+}
+
+// recoverReader is recoverWriter for the reader feeding the stdin of children.
+type recoverReader struct {
+	io.Reader
+	cmd *Command
+}
+
+func (reader *recoverReader) Read(data []byte) (read int, err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			read, err = 0, reader.cmd.recovered(recovered, "stdin")
+		}
+	}()
+
+	return reader.Reader.Read(data)
+}
+
+// guardStreams wraps the streams os/exec pumps in goroutines - files are handed over to the child as is.
+func (cmd *Command) guardStreams() {
+	command := cmd.cmd
+
+	if command.Stdin != nil {
+		if _, ok := command.Stdin.(*os.File); !ok {
+			command.Stdin = &recoverReader{Reader: command.Stdin, cmd: cmd}
+		}
+	}
+
+	// A single writer for both streams gets a single goroutine: keep it that way
+	shared := command.Stdout != nil && command.Stdout == command.Stderr
+
+	if command.Stdout != nil {
+		if _, ok := command.Stdout.(*os.File); !ok {
+			stream := StreamStdout
+			if shared {
+				stream = "output"
+			}
+
+			command.Stdout = &recoverWriter{Writer: command.Stdout, cmd: cmd, stream: stream}
+		}
+	}
+
+	if shared {
+		command.Stderr = command.Stdout
+	} else if command.Stderr != nil {
+		if _, ok := command.Stderr.(*os.File); !ok {
+			command.Stderr = &recoverWriter{Writer: command.Stderr, cmd: cmd, stream: StreamStderr}
+		}
+	}
+}
+
+// recovered logs and reports a panic that happened while pumping stream, and returns the error failing the copy.
+func (cmd *Command) recovered(recovered interface{}, stream string) error {
+	err := fmt.Errorf("%w: %s of %s: %v", ErrStreamPanic, stream, cmd.conf.name, recovered)
+
+	log.Error().Err(err).Str("binary", cmd.conf.bin).Strs("arguments", cmd.cmd.Args[1:]).
+		Msg("Recovered a panic while pumping command streams")
+
+	if !cmd.conf.NoReport {
+		reporter.CapturePanic(recovered, map[string]reporter.Context{
+			"command": {
+				"binary":    cmd.conf.bin,
+				"arguments": cmd.cmd.Args[1:],
+				"dir":       cmd.cmd.Dir,
+				"stream":    stream,
+				"pid":       cmd.pid(),
+			},
+		})
+	}
+
+	return err
+}
+
+func (cmd *Command) pid() int {
+	if process := cmd.process(); process != nil {
+		return process.Pid
+	}
+
+	return 0
+}
@@ -0,0 +1,44 @@
+package exec
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Resolve returns the absolute path of bin, looked up in the PATH (honoring PATHEXT on Windows, so that "docker"
+// finds "docker.exe"). Paths containing a separator are checked directly, without PATH lookup.
+// Executables found relative to the current directory through PATH are rejected.
+func Resolve(bin string) (string, error) {
+	resolved, err := exec.LookPath(bin)
+	if err != nil {
+		return "", fmt.Errorf("resolve errored with: %w", err)
+	}
+
+	if !filepath.IsAbs(resolved) {
+		if resolved, err = filepath.Abs(resolved); err != nil {
+			return "", fmt.Errorf("resolve errored with: %w", err)
+		}
+	}
+
+	return resolved, nil
+}
+
+// ResolveFirst returns the resolved path of the first candidate found (eg: ResolveFirst("docker", "podman")).
+func ResolveFirst(candidates ...string) (string, error) {
+	for _, candidate := range candidates {
+		resolved, err := Resolve(candidate)
+		if err == nil {
+			return resolved, nil
+		}
+
+		// Anything else than not found (eg: permission issue) is worth telling
+		if !errors.Is(err, exec.ErrNotFound) {
+			return "", err
+		}
+	}
+
+	return "", fmt.Errorf("resolve errored with: none of %s found: %w", strings.Join(candidates, ", "), exec.ErrNotFound)
+}
@@ -0,0 +1,147 @@
+package exec
+
+import (
+	"context"
+	"errors"
+	"syscall"
+	"time"
+
+	"go.codecomet.dev/core/log"
+)
+
+// defaultResultOutput is the maximum amount of each output stream retained in a Result.
+const defaultResultOutput = 1024 * 1024
+
+// Result describes a completed execution.
+type Result struct {
+	// Binary is the resolved binary that was executed
+	Binary string `json:"binary"`
+	// Args are the arguments, including PreArgs
+	Args []string `json:"args"`
+	// Started is when the (last attempt of the) command started
+	Started time.Time `json:"started"`
+	// Duration is the wall time of the execution, including retries
+	Duration time.Duration `json:"duration"`
+	// ExitCode is the exit code, or -1 if the command did not run to completion or was terminated by a signal
+	ExitCode int `json:"exitCode"`
+	// Signal is the name of the signal that terminated the command, if any
+	Signal string `json:"signal,omitempty"`
+	// Stdout is the standard output, truncated to its first MiB or to the Commander OutputLimit
+	Stdout []byte `json:"-"`
+	// StdoutTruncated is true if Stdout has been truncated
+	StdoutTruncated bool `json:"stdoutTruncated,omitempty"`
+	// StdoutFile is the file holding the complete standard output, if it was truncated and spilled
+	StdoutFile string `json:"stdoutFile,omitempty"`
+	// Stderr is the standard error, truncated to its first MiB or to the Commander OutputLimit
+	Stderr []byte `json:"-"`
+	// StderrTruncated is true if Stderr has been truncated
+	StderrTruncated bool `json:"stderrTruncated,omitempty"`
+	// StderrFile is the file holding the complete standard error, if it was truncated and spilled
+	StderrFile string `json:"stderrFile,omitempty"`
+	// Transcript interleaves stdout and stderr lines, if the Commander Transcribe option is set
+	Transcript *Transcript `json:"transcript,omitempty"`
+	// Artifacts are the files produced by the command, if the Commander declares Artifacts
+	Artifacts []*Artifact `json:"artifacts,omitempty"`
+	// Cached is true if the result was served from the Commander Cache, without running the command
+	Cached bool `json:"cached,omitempty"`
+}
+
+// Success returns true if the command exited with code 0.
+func (res *Result) Success() bool {
+	return res.ExitCode == 0
+}
+
+// MarshalZerologObject makes a Result loggable with log.Event.Object.
+func (res *Result) MarshalZerologObject(evt *log.Event) {
+	evt.Str("binary", res.Binary).
+		Strs("args", res.Args).
+		Time("started", res.Started).
+		Dur("duration", res.Duration).
+		Int("exitCode", res.ExitCode)
+
+	if res.Signal != "" {
+		evt.Str("signal", res.Signal)
+	}
+}
+
+// Run executes the command to completion, and returns its Result. The error is non-nil if the command did not
+// succeed, in which case the result still carries everything known about the execution.
+func (com *Commander) Run(args ...string) (*Result, error) {
+	return com.RunContext(context.Background(), args...)
+}
+
+// RunContext is Run, terminating the child if ctx is done.
+func (com *Commander) RunContext(ctx context.Context, args ...string) (*Result, error) {
+	key, res := com.Cache.lookup(com, args)
+	if res != nil {
+		return res, nil
+	}
+
+	started := time.Now()
+
+	cmd, stdout, stderr, err := com.exec(ctx, args)
+
+	res = cmd.result(started, stdout.Bytes(), stderr.Bytes(), err)
+
+	if err = cmd.artifacts(res, err); err == nil {
+		com.Cache.store(key, res)
+	}
+
+	return res, err
+}
+
+// result builds the Result of the execution.
+func (cmd *Command) result(started time.Time, stdout []byte, stderr []byte, err error) *Result {
+	res := &Result{
+		Binary:     cmd.cmd.Path,
+		Args:       cmd.cmd.Args[1:],
+		Started:    cmd.started,
+		Duration:   time.Since(started),
+		ExitCode:   -1,
+		Transcript: cmd.transcript,
+	}
+
+	if res.Started.IsZero() {
+		res.Started = started
+	}
+
+	res.Stdout, res.StdoutTruncated = truncate(stdout, defaultResultOutput)
+	res.Stderr, res.StderrTruncated = truncate(stderr, defaultResultOutput)
+
+	if stream := cmd.captured[0]; stream != nil && stream.truncated() {
+		res.StdoutTruncated = true
+		res.StdoutFile = stream.file()
+	}
+
+	if stream := cmd.captured[1]; stream != nil && stream.truncated() {
+		res.StderrTruncated = true
+		res.StderrFile = stream.file()
+	}
+
+	if cmd.replayed != nil {
+		res.ExitCode = cmd.replayed.ExitCode
+	}
+
+	if state := cmd.cmd.ProcessState; state != nil {
+		res.ExitCode = state.ExitCode()
+
+		if status, ok := state.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+			res.Signal = status.Signal().String()
+		}
+	}
+
+	var coder exitCoder
+	if err != nil && !errors.As(err, &coder) {
+		log.Debug().Err(err).Object("result", res).Msg("Command did not run to completion")
+	}
+
+	return res
+}
+
+func truncate(data []byte, limit int) ([]byte, bool) {
+	if len(data) <= limit {
+		return data, false
+	}
+
+	return data[:limit], true
+}
@@ -0,0 +1,131 @@
+package exec
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.codecomet.dev/core/log"
+	"go.codecomet.dev/core/reporter"
+)
+
+const (
+	defaultRetryBackoff    = time.Second
+	defaultRetryMultiplier = 2
+)
+
+// RetryPolicy controls how ExecAndComplete and ExecContext retry failed commands.
+// Note that a Stdin reader is consumed by the first attempt - use retries for commands that do not read stdin.
+type RetryPolicy struct {
+	// Attempts is the maximum number of attempts, including the first one
+	Attempts int
+	// Backoff is the delay before the first retry - defaults to one second
+	Backoff time.Duration
+	// Multiplier is applied to the delay after every retry - defaults to 2
+	Multiplier float64
+	// MaxBackoff caps the delay between attempts - zero means no cap
+	MaxBackoff time.Duration
+	// RetryOn decides if a failed attempt should be retried, from its exit code (-1 if the command did not run)
+	// and stderr - defaults to retrying any failure
+	RetryOn func(exitCode int, stderr string) bool
+}
+
+// RetryError aggregates the errors of all attempts, and wraps the last one.
+type RetryError struct {
+	Errors []error
+}
+
+func (e *RetryError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		msgs[i] = fmt.Sprintf("attempt %d: %s", i+1, err)
+	}
+
+	return fmt.Sprintf("failed after %d attempts: %s", len(e.Errors), strings.Join(msgs, "; "))
+}
+
+func (e *RetryError) Unwrap() error {
+	if len(e.Errors) == 0 {
+		return nil
+	}
+
+	return e.Errors[len(e.Errors)-1]
+}
+
+// exitCoder is implemented by errors of commands that ran to completion (*exec.ExitError, ReplayedExitError).
+type exitCoder interface {
+	ExitCode() int
+}
+
+// ExitCode returns the exit code of a failed command, or -1 if it did not run to completion.
+func ExitCode(err error) int {
+	var coder exitCoder
+	if errors.As(err, &coder) {
+		return coder.ExitCode()
+	}
+
+	return -1
+}
+
+// retry runs the command according to the retry policy, and returns the last attempt.
+func (com *Commander) retry(ctx context.Context, args []string) (*Command, bytes.Buffer, bytes.Buffer, error) {
+	policy := com.Retry
+
+	delay := policy.Backoff
+	if delay <= 0 {
+		delay = defaultRetryBackoff
+	}
+
+	multiplier := policy.Multiplier
+	if multiplier <= 0 {
+		multiplier = defaultRetryMultiplier
+	}
+
+	var errs []error
+
+	for attempt := 1; ; attempt++ {
+		cmd := com.activate(com.prepare(ctx, com.Stdin, args))
+
+		stdout, stderr, err := cmd.Output()
+		if err == nil || errors.Is(err, ErrCanceled) {
+			return cmd, stdout, stderr, err
+		}
+
+		errs = append(errs, err)
+		code := ExitCode(err)
+
+		log.Warn().Err(err).Str("binary", com.bin).Int("attempt", attempt).Int("exitCode", code).
+			Msg("Command attempt failed")
+
+		if attempt >= policy.Attempts || (policy.RetryOn != nil && !policy.RetryOn(code, stderr.String())) {
+			err = &RetryError{Errors: errs}
+			if !com.NoReport {
+				reporter.CaptureException(fmt.Errorf("failed retried execution: %w", err))
+			}
+
+			return cmd, stdout, stderr, err
+		}
+
+		// The output of retried attempts is not returned: do not leak their spill files
+		for _, stream := range cmd.captured {
+			stream.discard()
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+
+			return cmd, stdout, stderr, &CanceledError{Cause: ctx.Err(), Err: &RetryError{Errors: errs}}
+		case <-timer.C:
+		}
+
+		delay = time.Duration(float64(delay) * multiplier)
+		if policy.MaxBackoff > 0 && delay > policy.MaxBackoff {
+			delay = policy.MaxBackoff
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package exec
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+var shellEnabled int32 //nolint:gochecknoglobals
+
+// EnableShell opts the program into Shell. Running scripts through a shell is an injection risk, which applications
+// should accept explicitly, once, rather than each call site.
+func EnableShell() {
+	atomic.StoreInt32(&shellEnabled, 1)
+}
+
+// Shell runs script through the platform shell (/bin/sh on unix, PowerShell on Windows), with options applying to
+// this execution. Values interpolated into the script must be quoted with Quote.
+// It fails with ErrShellDisabled unless EnableShell was called.
+func Shell(script string, opts ...Option) (*Result, error) {
+	return ShellContext(context.Background(), script, opts...)
+}
+
+// ShellContext is Shell, terminating the shell if ctx is done.
+func ShellContext(ctx context.Context, script string, opts ...Option) (*Result, error) {
+	if atomic.LoadInt32(&shellEnabled) == 0 {
+		return nil, ErrShellDisabled
+	}
+
+	name, args := shellCommand(script)
+
+	bin, err := Resolve(name)
+	if err != nil {
+		return nil, err
+	}
+
+	com := &Commander{
+		mu:   &sync.Mutex{},
+		bin:  bin,
+		name: name,
+	}
+
+	return com.RunWith(ctx, args, opts...)
+}
+
+// Quote quotes args for the platform shell, so that each one reaches the script as a single, literal word.
+func Quote(args ...string) string {
+	quoted := make([]string, len(args))
+
+	for i, arg := range args {
+		quoted[i] = quoteArg(arg)
+	}
+
+	return strings.Join(quoted, " ")
+}
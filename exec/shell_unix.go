@@ -0,0 +1,18 @@
+//go:build !windows
+
+package exec
+
+import "strings"
+
+func shellCommand(script string) (string, []string) {
+	return "/bin/sh", []string{"-c", script}
+}
+
+// quoteArg single quotes arg, unless it only holds characters the shell never interprets.
+func quoteArg(arg string) string {
+	if arg != "" && strings.Trim(arg, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_./:,+@%") == "" {
+		return arg
+	}
+
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
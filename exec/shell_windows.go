@@ -0,0 +1,20 @@
+//go:build windows
+
+package exec
+
+import "strings"
+
+// PowerShell parses its command line like any other program, contrary to cmd.exe, whose quoting cannot prevent
+// variable expansion.
+func shellCommand(script string) (string, []string) {
+	return "powershell.exe", []string{"-NoProfile", "-NonInteractive", "-Command", script}
+}
+
+// quoteArg single quotes arg: PowerShell does not expand anything within single quotes, which are doubled.
+// Typographic single quotes are quotes as well to PowerShell.
+func quoteArg(arg string) string {
+	replacer := strings.NewReplacer("'", "''", "‘", "‘‘", "’", "’’",
+		"‚", "‚‚", "‛", "‛‛")
+
+	return "'" + replacer.Replace(arg) + "'"
+}
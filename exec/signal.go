@@ -0,0 +1,86 @@
+package exec
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"go.codecomet.dev/core/log"
+)
+
+// Signal sends sig to the command. Commands (except attached ones) run in their own process group, and on unix, the
+// signal is delivered to the whole group, reaching grandchildren as well.
+// On Windows, only os.Kill is supported.
+func (cmd *Command) Signal(sig os.Signal) error {
+	if cmd.process() == nil {
+		return ErrNotStarted
+	}
+
+	if err := signalCommand(cmd.cmd, sig); err != nil {
+		return fmt.Errorf("failed signaling %s: %w", cmd.conf.name, err)
+	}
+
+	return nil
+}
+
+// Kill terminates the command and its process group.
+func (cmd *Command) Kill() error {
+	return cmd.Signal(os.Kill)
+}
+
+// Stop asks the command to terminate (SIGTERM to its process group on unix, CTRL_BREAK on Windows), waits up to
+// timeout for it to exit, then kills it. It returns true if the command exited gracefully.
+// The command must be waited on by its caller (Run, Output, or Wait after Start).
+func (cmd *Command) Stop(timeout time.Duration) (bool, error) {
+	if cmd.process() == nil {
+		return false, ErrNotStarted
+	}
+
+	if err := terminateCommand(cmd.cmd); err != nil {
+		log.Debug().Err(err).Str("binary", cmd.conf.bin).Msg("Failed requesting termination. Killing.")
+	} else {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+
+		select {
+		case <-cmd.exited:
+			return true, nil
+		case <-timer.C:
+			log.Warn().Str("binary", cmd.conf.bin).Dur("timeout", timeout).Msg("Command did not stop in time. Killing.")
+		}
+	}
+
+	if err := cmd.Kill(); err != nil {
+		return false, err
+	}
+
+	<-cmd.exited
+
+	return false, nil
+}
+
+// Signal sends sig to the active command (see Command.Signal).
+func (com *Commander) Signal(sig os.Signal) error {
+	cmd := com.current()
+	if cmd == nil {
+		return ErrNotStarted
+	}
+
+	return cmd.Signal(sig)
+}
+
+// Kill terminates the active command and its process group.
+func (com *Commander) Kill() error {
+	return com.Signal(os.Kill)
+}
+
+// Stop stops the active command (see Command.Stop).
+// The command must be waited on by its caller (ExecAndComplete, Attach, or Wait after ExecAndWait).
+func (com *Commander) Stop(timeout time.Duration) (bool, error) {
+	cmd := com.current()
+	if cmd == nil {
+		return false, ErrNotStarted
+	}
+
+	return cmd.Stop(timeout)
+}
@@ -0,0 +1,136 @@
+package exec
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go.codecomet.dev/core/telemetry"
+	"go.codecomet.dev/core/version"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	redacted = "[redacted]"
+
+	spanAttributeExitCode = attribute.Key("process.exit.code")
+	spanAttributeDuration = attribute.Key("process.duration_ms")
+)
+
+// Argument names (flags, or the key part of key=value arguments) containing one of these are considered secret.
+var sensitiveArguments = []string{"password", "passwd", "secret", "token", "apikey", "api-key", "api_key", //nolint:gochecknoglobals
+	"credential", "authorization", "private-key", "private_key"}
+
+// startSpan starts the span of the command, as a child of the span of the command context.
+func (cmd *Command) startSpan() {
+	ctx := cmd.ctx
+	if ctx == nil {
+		return
+	}
+
+	command := cmd.cmd
+
+	ctx, cmd.span = telemetry.Tracer("exec", version.Module("go.codecomet.dev/core")).Start(ctx,
+		filepath.Base(cmd.conf.name),
+		trace.WithSpanKind(trace.SpanKindInternal),
+		trace.WithAttributes(
+			semconv.ProcessExecutablePathKey.String(command.Path),
+			semconv.ProcessCommandArgsKey.StringSlice(redactArgs(command.Args[1:])),
+		),
+	)
+
+	cmd.propagate(ctx)
+}
+
+// propagate passes the trace context to the child, as TRACEPARENT and TRACESTATE environment variables, so that
+// instrumented children continue the trace. Containers are not covered, their environment being already set.
+func (cmd *Command) propagate(ctx context.Context) {
+	if cmd.conf.Container != nil || !trace.SpanContextFromContext(ctx).IsValid() {
+		return
+	}
+
+	carrier := propagation.MapCarrier{}
+	propagation.TraceContext{}.Inject(ctx, carrier)
+
+	envs := []string{}
+	for _, key := range carrier.Keys() {
+		envs = append(envs, strings.ToUpper(key)+"="+carrier.Get(key))
+	}
+
+	// Override the variables we may have inherited ourselves
+	cmd.cmd.Env = mergeEnv(cmd.cmd.Env, envs...)
+}
+
+// endSpan ends the span of the command, recording its outcome.
+func (cmd *Command) endSpan(err error) {
+	span := cmd.span
+	if span == nil {
+		return
+	}
+
+	cmd.span = nil
+
+	span.SetAttributes(spanAttributeDuration.Int64(time.Since(cmd.started).Milliseconds()))
+
+	if state := cmd.cmd.ProcessState; state != nil {
+		span.SetAttributes(spanAttributeExitCode.Int(state.ExitCode()))
+	}
+
+	if err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			span.RecordError(err)
+		}
+
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	span.End()
+}
+
+// redactArgs returns a copy of args with the values of sensitive flags, and passwords in URLs, redacted.
+func redactArgs(args []string) []string {
+	result := make([]string, len(args))
+
+	for i, arg := range args {
+		result[i] = arg
+
+		if key, _, ok := strings.Cut(arg, "="); ok && sensitive(key) {
+			result[i] = key + "=" + redacted
+
+			continue
+		}
+
+		if i > 0 && strings.HasPrefix(args[i-1], "-") && !strings.Contains(args[i-1], "=") && sensitive(args[i-1]) {
+			result[i] = redacted
+
+			continue
+		}
+
+		if u, err := url.Parse(arg); err == nil && u.User != nil {
+			result[i] = u.Redacted()
+		}
+	}
+
+	return result
+}
+
+func sensitive(name string) bool {
+	name = strings.ToLower(strings.TrimLeft(name, "-"))
+
+	for _, word := range sensitiveArguments {
+		if strings.Contains(name, word) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,119 @@
+//go:build !windows
+
+package exec
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"sync"
+	"syscall"
+)
+
+// umaskMu serializes process umask changes around child start.
+var umaskMu sync.Mutex //nolint:gochecknoglobals
+
+// startCommand starts the command, applying umask and credential options.
+// If group is true, the child is started in its own process group, so that signals reach its descendants.
+// The umask is process wide: it is set for the duration of the fork only, but other goroutines creating files
+// during that window are affected.
+func startCommand(command *exec.Cmd, umask *int, cred *Credential, group bool) error {
+	if group {
+		if command.SysProcAttr == nil {
+			command.SysProcAttr = &syscall.SysProcAttr{}
+		}
+
+		command.SysProcAttr.Setpgid = true
+	}
+
+	if cred != nil {
+		euid := os.Geteuid()
+		self := uint32(euid) == cred.UID && uint32(os.Getegid()) == cred.GID
+
+		if euid != 0 && !self {
+			return fmt.Errorf("%w: running as uid %d gid %d requires root", ErrInsufficientPrivileges, cred.UID, cred.GID)
+		}
+
+		if euid != 0 && len(cred.Groups) > 0 {
+			return fmt.Errorf("%w: setting supplementary groups requires root", ErrInsufficientPrivileges)
+		}
+
+		if command.SysProcAttr == nil {
+			command.SysProcAttr = &syscall.SysProcAttr{}
+		}
+
+		command.SysProcAttr.Credential = &syscall.Credential{
+			Uid:    cred.UID,
+			Gid:    cred.GID,
+			Groups: cred.Groups,
+			// Clearing groups requires privileges too: running as ourselves, keep ours
+			NoSetGroups: self && len(cred.Groups) == 0,
+		}
+	}
+
+	err := startUmask(command, umask)
+
+	// Privileges may be restricted beyond root (eg: capabilities dropped in a container)
+	if cred != nil && errors.Is(err, syscall.EPERM) {
+		err = fmt.Errorf("%w: running as uid %d gid %d: %s", ErrInsufficientPrivileges, cred.UID, cred.GID, err)
+	}
+
+	return err
+}
+
+func startUmask(command *exec.Cmd, umask *int) error {
+	if umask == nil {
+		return command.Start()
+	}
+
+	umaskMu.Lock()
+	defer umaskMu.Unlock()
+
+	previous := syscall.Umask(*umask)
+	defer syscall.Umask(previous)
+
+	return command.Start()
+}
+
+// signalCommand sends sig to the command, and to its whole process group if it has its own.
+func signalCommand(command *exec.Cmd, sig os.Signal) error {
+	unixSig, ok := sig.(syscall.Signal)
+	if !ok || command.SysProcAttr == nil || !command.SysProcAttr.Setpgid {
+		return command.Process.Signal(sig)
+	}
+
+	return syscall.Kill(-command.Process.Pid, unixSig)
+}
+
+// terminateCommand asks the command (and its process group) to terminate.
+func terminateCommand(command *exec.Cmd) error {
+	return signalCommand(command, syscall.SIGTERM)
+}
+
+// detachCommand starts the command in its own session, detached from our terminal, and as the leader of its own
+// process group.
+func detachCommand(command *exec.Cmd) {
+	if command.SysProcAttr == nil {
+		command.SysProcAttr = &syscall.SysProcAttr{}
+	}
+
+	command.SysProcAttr.Setsid = true
+}
+
+// processAlive tells whether a process exists (zombies included).
+func processAlive(pid int) bool {
+	err := syscall.Kill(pid, 0)
+
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
+
+// terminatePID asks a detached process, and its process group, to terminate.
+func terminatePID(pid int) error {
+	return syscall.Kill(-pid, syscall.SIGTERM)
+}
+
+// killPID kills a detached process, and its process group.
+func killPID(pid int) error {
+	return syscall.Kill(-pid, syscall.SIGKILL)
+}
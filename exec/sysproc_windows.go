@@ -0,0 +1,110 @@
+//go:build windows
+
+package exec
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"syscall"
+
+	"golang.org/x/sys/windows"
+)
+
+// stillActive is the exit code of running processes (STILL_ACTIVE).
+const stillActive = 259
+
+// startCommand starts the command. Umask is not supported on Windows, and credentials only through a token.
+// If group is true, the child is started in a new process group.
+func startCommand(command *exec.Cmd, umask *int, cred *Credential, group bool) error {
+	if umask != nil {
+		return fmt.Errorf("%w: umask", ErrUnsupportedPlatform)
+	}
+
+	if cred != nil && cred.Token == 0 {
+		return fmt.Errorf("%w: credential without a token", ErrUnsupportedPlatform)
+	}
+
+	if group || cred != nil {
+		if command.SysProcAttr == nil {
+			command.SysProcAttr = &syscall.SysProcAttr{}
+		}
+	}
+
+	if group {
+		command.SysProcAttr.CreationFlags |= syscall.CREATE_NEW_PROCESS_GROUP
+	}
+
+	if cred != nil {
+		command.SysProcAttr.Token = syscall.Token(cred.Token)
+	}
+
+	err := command.Start()
+	if cred != nil && (errors.Is(err, windows.ERROR_PRIVILEGE_NOT_HELD) || errors.Is(err, windows.ERROR_ACCESS_DENIED)) {
+		err = fmt.Errorf("%w: running with a token: %s", ErrInsufficientPrivileges, err)
+	}
+
+	return err
+}
+
+// signalCommand only supports os.Kill on Windows, which terminates the whole process tree.
+func signalCommand(command *exec.Cmd, sig os.Signal) error {
+	if sig != os.Kill {
+		return fmt.Errorf("%w: signal %s", ErrUnsupportedPlatform, sig)
+	}
+
+	pid := strconv.Itoa(command.Process.Pid)
+	if err := exec.Command("taskkill", "/T", "/F", "/PID", pid).Run(); err != nil {
+		return command.Process.Kill()
+	}
+
+	return nil
+}
+
+// terminateCommand sends CTRL_BREAK to the command process group. This requires the command to have been
+// started in its own process group, and to share our console.
+func terminateCommand(command *exec.Cmd) error {
+	if command.SysProcAttr == nil || command.SysProcAttr.CreationFlags&syscall.CREATE_NEW_PROCESS_GROUP == 0 {
+		return fmt.Errorf("%w: terminating a command outside of its own process group", ErrUnsupportedPlatform)
+	}
+
+	return windows.GenerateConsoleCtrlEvent(windows.CTRL_BREAK_EVENT, uint32(command.Process.Pid))
+}
+
+// detachCommand starts the command without a console, in a new process group.
+func detachCommand(command *exec.Cmd) {
+	if command.SysProcAttr == nil {
+		command.SysProcAttr = &syscall.SysProcAttr{}
+	}
+
+	command.SysProcAttr.CreationFlags |= windows.DETACHED_PROCESS | syscall.CREATE_NEW_PROCESS_GROUP
+}
+
+// processAlive tells whether a process is still running.
+func processAlive(pid int) bool {
+	handle, err := windows.OpenProcess(windows.PROCESS_QUERY_LIMITED_INFORMATION, false, uint32(pid))
+	if err != nil {
+		return false
+	}
+
+	defer windows.CloseHandle(handle) //nolint:errcheck
+
+	var code uint32
+	if err = windows.GetExitCodeProcess(handle, &code); err != nil {
+		return false
+	}
+
+	return code == stillActive
+}
+
+// terminatePID is not supported on Windows, as detached processes have no console to receive CTRL_BREAK.
+func terminatePID(_ int) error {
+	return fmt.Errorf("%w: terminating a detached process", ErrUnsupportedPlatform)
+}
+
+// killPID kills a detached process tree.
+func killPID(pid int) error {
+	return exec.Command("taskkill", "/T", "/F", "/PID", strconv.Itoa(pid)).Run()
+}
@@ -0,0 +1,66 @@
+package exec
+
+import (
+	"os"
+	"sync"
+
+	"go.codecomet.dev/core/log"
+)
+
+var (
+	terminalMu      sync.Mutex //nolint:gochecknoglobals
+	terminalRestore func()     //nolint:gochecknoglobals
+	terminalHook    sync.Once  //nolint:gochecknoglobals
+)
+
+// guardTerminal registers restore to be run if the process exits on a fatal log while a child uses the terminal,
+// and returns the function to call once the child is done, restoring the terminal. Panics in the attaching
+// goroutine run it as well, as long as it is deferred.
+func guardTerminal(restore func()) func() {
+	terminalHook.Do(func() {
+		log.AddExitHook(log.ExitHookFunc(func() error {
+			terminalMu.Lock()
+			pending := terminalRestore
+			terminalRestore = nil
+			terminalMu.Unlock()
+
+			if pending != nil {
+				pending()
+			}
+
+			return nil
+		}))
+	})
+
+	terminalMu.Lock()
+	terminalRestore = restore
+	terminalMu.Unlock()
+
+	return func() {
+		terminalMu.Lock()
+		terminalRestore = nil
+		terminalMu.Unlock()
+
+		restore()
+	}
+}
+
+// runInteractive runs the command directly on our terminal, when a pty is not available or not requested.
+// The child shares our terminal and process group: it sets the terminal mode it needs itself, and receives window
+// size changes (SIGWINCH) from the kernel like we do. Whatever state it leaves the terminal in is reverted when it
+// exits, or if we panic or die on a fatal log meanwhile.
+// Output is not captured, nor passed to line handlers.
+func (cmd *Command) runInteractive() error {
+	if restore, err := saveTerminal(int(os.Stdin.Fd())); err == nil {
+		defer guardTerminal(restore)()
+	}
+
+	cmd.cmd.Stdout, cmd.cmd.Stderr = os.Stdout, os.Stderr
+
+	err := cmd.start(false)
+	if err == nil {
+		err = cmd.wait()
+	}
+
+	return err
+}
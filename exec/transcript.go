@@ -0,0 +1,96 @@
+package exec
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// Stream names used in transcripts.
+const (
+	StreamStdout = "stdout"
+	StreamStderr = "stderr"
+)
+
+// TranscriptLine is a line of output, timestamped when it was completed.
+type TranscriptLine struct {
+	Time   time.Time `json:"time"`
+	Stream string    `json:"stream"`
+	Text   string    `json:"text"`
+}
+
+// Transcript is the combined output of a command: stdout and stderr lines, interleaved in the order they were
+// produced. It is meant for humans (eg: attached to error reports), and may hence be truncated.
+type Transcript struct {
+	mu    sync.Mutex
+	limit int
+	size  int
+	// Lines are the transcript lines, in chronological order
+	Lines []*TranscriptLine `json:"lines"`
+	// Truncated is true if lines were dropped to honor the output limit
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// newTranscript returns a new transcript if com transcribes, holding up to OutputLimit bytes of text, or nil.
+func (com *Commander) newTranscript() *Transcript {
+	if !com.Transcribe {
+		return nil
+	}
+
+	tr := &Transcript{}
+	if com.OutputLimit != nil {
+		tr.limit = com.OutputLimit.Bytes
+	}
+
+	return tr
+}
+
+// String renders the transcript, one line per output line, prefixed by its time (relative to the first line) and
+// stream.
+func (tr *Transcript) String() string {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+
+	var out strings.Builder
+
+	for _, line := range tr.Lines {
+		out.WriteString("+" + line.Time.Sub(tr.Lines[0].Time).Truncate(time.Millisecond).String() + " ")
+		out.WriteString(line.Stream + " | " + line.Text + "\n")
+	}
+
+	if tr.Truncated {
+		out.WriteString("[transcript truncated]\n")
+	}
+
+	return out.String()
+}
+
+func (tr *Transcript) add(stream string, text string) {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+
+	if tr.limit > 0 && tr.size+len(text) > tr.limit {
+		tr.Truncated = true
+
+		return
+	}
+
+	tr.size += len(text)
+	tr.Lines = append(tr.Lines, &TranscriptLine{Time: time.Now(), Stream: stream, Text: text})
+}
+
+// lines returns a line handler recording stream lines into the transcript, before calling handler.
+// It returns handler itself if tr is nil.
+func (tr *Transcript) lines(stream string, handler func(string)) func(string) {
+	if tr == nil {
+		return handler
+	}
+
+	return func(line string) {
+		tr.add(stream, line)
+
+		if handler != nil {
+			handler(line)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package exec
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.codecomet.dev/core/log"
+)
+
+const (
+	defaultProbeMaxBackoff = 5 * time.Second
+	// minProbeInterval keeps probes (eg: forking commands) from running in a tight loop
+	minProbeInterval = 10 * time.Millisecond
+)
+
+// Probe checks whether something (typically a daemon) is ready, returning nil if it is.
+type Probe func(ctx context.Context) error
+
+// WaitError is returned by WaitFor when the probe did not succeed in time. It matches ErrNotReady, and wraps the
+// last probe error.
+type WaitError struct {
+	Timeout  time.Duration
+	Attempts int
+	Err      error
+}
+
+func (e *WaitError) Error() string {
+	return fmt.Sprintf("%s after %s (%d attempts): %s", ErrNotReady, e.Timeout, e.Attempts, e.Err)
+}
+
+func (e *WaitError) Unwrap() error {
+	return e.Err
+}
+
+func (e *WaitError) Is(target error) bool {
+	return target == ErrNotReady //nolint:errorlint
+}
+
+// WaitFor calls probe until it succeeds, or timeout elapses. Attempts start interval apart, the delay doubling after
+// every failure, up to 5 seconds (or interval, if longer). Intervals are at least 10ms.
+// If ctx is done first, the error matches ErrCanceled.
+func WaitFor(ctx context.Context, probe Probe, interval time.Duration, timeout time.Duration) error {
+	if interval < minProbeInterval {
+		interval = minProbeInterval
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	maxBackoff := defaultProbeMaxBackoff
+	if interval > maxBackoff {
+		maxBackoff = interval
+	}
+
+	delay := interval
+
+	for attempt := 1; ; attempt++ {
+		err := probe(waitCtx)
+		if err == nil {
+			return nil
+		}
+
+		log.Trace().Err(err).Int("attempt", attempt).Msg("Not ready yet")
+
+		timer := time.NewTimer(delay)
+
+		select {
+		case <-waitCtx.Done():
+			timer.Stop()
+
+			if ctx.Err() != nil {
+				return &CanceledError{Cause: ctx.Err(), Err: err}
+			}
+
+			return &WaitError{Timeout: timeout, Attempts: attempt, Err: err}
+		case <-timer.C:
+		}
+
+		delay *= defaultRetryMultiplier
+		if delay > maxBackoff {
+			delay = maxBackoff
+		}
+	}
+}
+
+// Probe returns a probe running the command with args, successful if it exits with 0. Retry policies do not apply,
+// and the probe does not become the active command of the Commander.
+func (com *Commander) Probe(args ...string) Probe {
+	return func(ctx context.Context) error {
+		_, stderr, err := com.prepare(ctx, nil, args).Output()
+		if err != nil {
+			return stderrError(err, stderr.Bytes())
+		}
+
+		return nil
+	}
+}
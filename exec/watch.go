@@ -0,0 +1,110 @@
+package exec
+
+import (
+	"context"
+	"time"
+
+	"go.codecomet.dev/core/filesystem"
+	"go.codecomet.dev/core/log"
+)
+
+const (
+	defaultWatchDebounce = 200 * time.Millisecond
+	watchPollInterval    = 250 * time.Millisecond
+)
+
+// WatchRun is the outcome of one run of a watched command.
+type WatchRun struct {
+	// Changes are the files that triggered the run - empty for the initial run
+	Changes []string
+	// Result is the result of the run
+	Result *Result
+	// Err is the error returned by Run - it matches ErrCanceled if the run was superseded by a newer one
+	Err error
+}
+
+// Watch runs com with args, then runs it again every time files matching globs (see filesystem.Glob) change,
+// canceling the previous run if it is still going. Changes are debounced: a run starts once no change has been seen
+// for debounce (defaults to 200ms).
+// Output is streamed through the Commander line handlers (see OnStdoutLine), and the outcome of every run, superseded
+// ones included, is sent on the returned channel, which must be drained. It is closed once ctx is done and the
+// last run has been canceled - that last outcome is not sent.
+func Watch(ctx context.Context, com *Commander, args []string, globs []string, debounce time.Duration,
+) (<-chan *WatchRun, error) {
+	watcher, err := filesystem.NewWatcher(watchPollInterval, globs...)
+	if err != nil {
+		return nil, err
+	}
+
+	if debounce <= 0 {
+		debounce = defaultWatchDebounce
+	}
+
+	changes := watcher.Watch(ctx)
+	runs := make(chan *WatchRun)
+
+	go func() {
+		defer close(runs)
+
+		var cancel context.CancelFunc
+
+		var finished chan struct{}
+
+		start := func(changed []string) {
+			var runCtx context.Context
+
+			runCtx, cancel = context.WithCancel(ctx)
+			finished = make(chan struct{})
+
+			go func(done chan struct{}) {
+				defer close(done)
+
+				res, err := com.RunContext(runCtx, args...)
+
+				select {
+				case runs <- &WatchRun{Changes: changed, Result: res, Err: err}:
+				case <-ctx.Done():
+				}
+			}(finished)
+		}
+
+		stop := func() {
+			cancel()
+			<-finished
+		}
+
+		start(nil)
+
+		var pending []string
+
+		var debounced <-chan time.Time
+
+		for {
+			select {
+			case <-ctx.Done():
+				stop()
+
+				return
+			case changed, ok := <-changes:
+				if !ok {
+					stop()
+
+					return
+				}
+
+				pending = append(pending, changed...)
+				debounced = time.After(debounce)
+			case <-debounced:
+				log.Debug().Strs("changes", pending).Str("binary", com.bin).Msg("Files changed, running again")
+
+				stop()
+				start(pending)
+
+				pending = nil
+				debounced = nil
+			}
+		}
+	}()
+
+	return runs, nil
+}
@@ -0,0 +1,146 @@
+package exec
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"go.codecomet.dev/core/log"
+)
+
+// Watchdog terminates commands that produce no output (on either stream) for a while, which catches hung tools
+// long before a hard timeout would. It applies to Run, Output and Start (as pipes are read) executions.
+type Watchdog struct {
+	// Inactivity is how long a command may go without output
+	Inactivity time.Duration
+	// OnIdle, if set, is called every Inactivity period a command stays silent, with the time since its last
+	// output. Returning true gives it another period, false (or a nil OnIdle) terminates it
+	OnIdle func(idle time.Duration) bool
+}
+
+// InactivityError is returned by executions terminated by a Watchdog. It matches ErrInactive, and wraps the
+// execution error.
+type InactivityError struct {
+	Idle time.Duration
+	Err  error
+}
+
+func (e *InactivityError) Error() string {
+	return fmt.Sprintf("%s for %s: %s", ErrInactive, e.Idle.Round(time.Millisecond), e.Err)
+}
+
+func (e *InactivityError) Unwrap() error {
+	return e.Err
+}
+
+func (e *InactivityError) Is(target error) bool {
+	return target == ErrInactive //nolint:errorlint
+}
+
+// activityWriter records output activity.
+type activityWriter struct {
+	io.Writer
+	last *int64
+}
+
+func (w *activityWriter) Write(data []byte) (int, error) {
+	atomic.StoreInt64(w.last, time.Now().UnixNano())
+
+	return w.Writer.Write(data)
+}
+
+// activityReader records output activity, as a pipe is read.
+type activityReader struct {
+	io.ReadCloser
+	last *int64
+}
+
+func (r *activityReader) Read(data []byte) (int, error) {
+	n, err := r.ReadCloser.Read(data)
+	if n > 0 {
+		atomic.StoreInt64(r.last, time.Now().UnixNano())
+	}
+
+	return n, err
+}
+
+// watchWriters wraps the output writers of the command, if it has a watchdog.
+func (cmd *Command) watchWriters() {
+	if !cmd.watched() {
+		return
+	}
+
+	cmd.cmd.Stdout = &activityWriter{Writer: cmd.cmd.Stdout, last: &cmd.activity}
+	cmd.cmd.Stderr = &activityWriter{Writer: cmd.cmd.Stderr, last: &cmd.activity}
+}
+
+// watchPipes wraps the output pipes of the command, if it has a watchdog.
+func (cmd *Command) watchPipes(stdout io.ReadCloser, stderr io.ReadCloser) (io.ReadCloser, io.ReadCloser) {
+	if !cmd.watched() {
+		return stdout, stderr
+	}
+
+	return cmd.watchPipe(stdout), cmd.watchPipe(stderr)
+}
+
+func (cmd *Command) watchPipe(pipe io.ReadCloser) io.ReadCloser {
+	if pipe == nil {
+		return nil
+	}
+
+	return &activityReader{ReadCloser: pipe, last: &cmd.activity}
+}
+
+func (cmd *Command) watched() bool {
+	return cmd.conf.Watchdog != nil && cmd.conf.Watchdog.Inactivity > 0
+}
+
+// watch starts the watchdog of the started command, if it has one, until it exits.
+func (cmd *Command) watch() {
+	if !cmd.watched() {
+		return
+	}
+
+	dog := cmd.conf.Watchdog
+
+	atomic.StoreInt64(&cmd.activity, time.Now().UnixNano())
+
+	go func() {
+		timer := time.NewTimer(dog.Inactivity)
+		defer timer.Stop()
+
+		for {
+			select {
+			case <-cmd.exited:
+				return
+			case <-timer.C:
+			}
+
+			idle := time.Since(time.Unix(0, atomic.LoadInt64(&cmd.activity)))
+
+			switch {
+			case idle < dog.Inactivity:
+				timer.Reset(dog.Inactivity - idle)
+			case dog.OnIdle != nil && dog.OnIdle(idle):
+				timer.Reset(dog.Inactivity)
+			default:
+				log.Warn().Str("binary", cmd.conf.bin).Dur("idle", idle).Msg("Command produced no output for too long. Terminating it.")
+				atomic.StoreInt64(&cmd.stalled, int64(idle))
+				_ = signalCommand(cmd.cmd, os.Kill)
+
+				return
+			}
+		}
+	}()
+}
+
+// inactive wraps err if the watchdog terminated the command.
+func (cmd *Command) inactive(err error) error {
+	if idle := atomic.LoadInt64(&cmd.stalled); idle > 0 && err != nil {
+		return &InactivityError{Idle: time.Duration(idle), Err: err}
+	}
+
+	return err
+}
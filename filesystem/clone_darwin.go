@@ -0,0 +1,12 @@
+//go:build darwin
+
+package filesystem
+
+import (
+	"golang.org/x/sys/unix"
+)
+
+// cloneFile attempts a copy-on-write clone of src into dst, which must not exist.
+func cloneFile(src string, dst string) error {
+	return unix.Clonefile(src, dst, unix.CLONE_NOFOLLOW)
+}
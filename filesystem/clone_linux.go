@@ -0,0 +1,35 @@
+//go:build linux
+
+package filesystem
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// cloneFile attempts a copy-on-write clone (reflink) of src into dst, which must not exist.
+func cloneFile(src string, dst string) error {
+	source, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+
+	destination, err := os.OpenFile(dst, os.O_CREATE|os.O_EXCL|os.O_WRONLY, FilePermissionsPrivate)
+	if err != nil {
+		return err
+	}
+
+	err = unix.IoctlFileClone(int(destination.Fd()), int(source.Fd()))
+
+	if cerr := destination.Close(); err == nil {
+		err = cerr
+	}
+
+	if err != nil {
+		_ = os.Remove(dst)
+	}
+
+	return err
+}
@@ -0,0 +1,8 @@
+//go:build !linux && !darwin
+
+package filesystem
+
+// cloneFile is not supported on this platform.
+func cloneFile(_ string, _ string) error {
+	return ErrCloneUnsupported
+}
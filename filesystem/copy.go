@@ -0,0 +1,46 @@
+package filesystem
+
+import (
+	"io"
+	"os"
+)
+
+// CopyFile copies src to dst, using a copy-on-write clone when the platform and filesystem support it.
+// dst is overwritten if it exists, and gets the permissions of src.
+func CopyFile(src string, dst string) error {
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+
+	_ = os.Remove(dst)
+
+	if err = cloneFile(src, dst); err != nil {
+		if err = copyContent(src, dst); err != nil {
+			return err
+		}
+	}
+
+	return os.Chmod(dst, info.Mode().Perm())
+}
+
+func copyContent(src string, dst string) error {
+	source, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+
+	destination, err := os.OpenFile(dst, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, FilePermissionsPrivate)
+	if err != nil {
+		return err
+	}
+
+	if _, err = io.Copy(destination, source); err != nil {
+		destination.Close()
+
+		return err
+	}
+
+	return destination.Close()
+}
@@ -0,0 +1,188 @@
+package filesystem
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SniffLength is the number of leading bytes content detection looks at.
+const SniffLength = 512
+
+const (
+	mimeOctetStream = "application/octet-stream"
+	mimeTextPlain   = "text/plain; charset=utf-8"
+)
+
+// FileType is the detected type of a file.
+type FileType struct {
+	// MIME is the media type, with parameters if any (eg: text/plain; charset=utf-8)
+	MIME string
+	// Text is true for content meant to be read by humans (text, JSON, XML, YAML, scripts...)
+	Text bool
+	// Compressed is true for content that would not shrink further if compressed (archives, most media)
+	Compressed bool
+}
+
+// signature is a magic number, at an offset, that http.DetectContentType does not know about.
+type signature struct {
+	offset int
+	magic  []byte
+	mime   string
+}
+
+var signatures = []signature{ //nolint:gochecknoglobals
+	{0, []byte{0x28, 0xB5, 0x2F, 0xFD}, "application/zstd"},
+	{0, []byte{0xFD, '7', 'z', 'X', 'Z', 0x00}, "application/x-xz"},
+	// The block magic, after the BZh<level> header
+	{4, []byte("1AY&SY"), "application/x-bzip2"},
+	{0, []byte{'7', 'z', 0xBC, 0xAF, 0x27, 0x1C}, "application/x-7z-compressed"},
+	{257, []byte("ustar"), "application/x-tar"},
+	{0, []byte{0x7F, 'E', 'L', 'F'}, "application/x-elf"},
+	{0, []byte{0xCF, 0xFA, 0xED, 0xFE}, "application/x-mach-binary"},
+	{0, []byte{0xFE, 0xED, 0xFA, 0xCF}, "application/x-mach-binary"},
+	{0, []byte{0xCA, 0xFE, 0xBA, 0xBE}, "application/x-mach-binary"},
+	{0, []byte("SQLite format 3\x00"), "application/vnd.sqlite3"},
+}
+
+// extensions complements the mime package, whose table depends on the system, for the files we commonly handle.
+var extensions = map[string]string{ //nolint:gochecknoglobals
+	".json": "application/json",
+	".yaml": "application/yaml",
+	".yml":  "application/yaml",
+	".toml": "application/toml",
+	".md":   "text/markdown; charset=utf-8",
+	".csv":  "text/csv; charset=utf-8",
+	".log":  mimeTextPlain,
+	".go":   "text/x-go; charset=utf-8",
+	".sh":   "application/x-sh",
+	".js":   "text/javascript; charset=utf-8",
+	".ts":   "application/typescript",
+	".zst":  "application/zstd",
+	".xz":   "application/x-xz",
+	".bz2":  "application/x-bzip2",
+	".tar":  "application/x-tar",
+}
+
+var compressedTypes = []string{ //nolint:gochecknoglobals
+	"application/zstd", "application/x-xz", "application/x-bzip2", "application/x-7z-compressed", "application/zip",
+	"application/x-gzip", "application/gzip", "application/x-rar-compressed", "application/pdf", "application/wasm",
+	"image/png", "image/jpeg", "image/gif", "image/webp", "video/", "audio/", "font/woff", "font/woff2",
+}
+
+var textTypes = []string{ //nolint:gochecknoglobals
+	"text/", "application/json", "application/xml", "application/yaml", "application/toml", "application/javascript",
+	"application/typescript", "application/x-sh", "image/svg+xml",
+}
+
+// DetectType returns the type of the file at path, from its leading bytes (see Sniff), or from its extension if
+// they are not conclusive. Only the first SniffLength bytes are read, whatever the size of the file.
+func DetectType(path string) (*FileType, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+
+	head := make([]byte, SniffLength)
+
+	read, err := io.ReadFull(file, head)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		var pathErr *os.PathError
+		if errors.As(err, &pathErr) && isDirectory(path) {
+			return nil, &os.PathError{Op: "detect", Path: path, Err: ErrIsDirectory}
+		}
+
+		return nil, err
+	}
+
+	return Detect(filepath.Base(path), head[:read]), nil
+}
+
+// DetectReader detects the type of streamed content, named name (for the extension fallback - may be empty).
+// It returns a reader yielding the whole content, the leading bytes included, so that it can be consumed as if
+// it had not been sniffed.
+func DetectReader(name string, reader io.Reader) (*FileType, io.Reader, error) {
+	head := make([]byte, SniffLength)
+
+	read, err := io.ReadFull(reader, head)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, nil, err
+	}
+
+	head = head[:read]
+
+	return Detect(name, head), io.MultiReader(bytes.NewReader(head), reader), nil
+}
+
+// Detect returns the type of content starting with head, falling back to the extension of name if the content is
+// not conclusive.
+func Detect(name string, head []byte) *FileType {
+	detected := Sniff(head)
+
+	// Generic answers from the content: the extension knows better (eg: JSON is plain text)
+	if detected == mimeOctetStream || detected == mimeTextPlain {
+		if byExtension := extensionType(name); byExtension != "" {
+			detected = byExtension
+		}
+	}
+
+	return &FileType{
+		MIME:       detected,
+		Text:       hasTypePrefix(detected, textTypes),
+		Compressed: hasTypePrefix(detected, compressedTypes),
+	}
+}
+
+// Sniff returns the media type of content starting with head, from magic numbers only. It considers at most
+// SniffLength bytes, and returns application/octet-stream if nothing matches.
+func Sniff(head []byte) string {
+	if len(head) > SniffLength {
+		head = head[:SniffLength]
+	}
+
+	for _, sig := range signatures {
+		if len(head) >= sig.offset+len(sig.magic) && bytes.Equal(head[sig.offset:sig.offset+len(sig.magic)], sig.magic) {
+			return sig.mime
+		}
+	}
+
+	return http.DetectContentType(head)
+}
+
+func extensionType(name string) string {
+	ext := strings.ToLower(filepath.Ext(name))
+	if ext == "" {
+		return ""
+	}
+
+	if known, ok := extensions[ext]; ok {
+		return known
+	}
+
+	return mime.TypeByExtension(ext)
+}
+
+func hasTypePrefix(mediaType string, prefixes []string) bool {
+	mediaType, _, _ = strings.Cut(mediaType, ";")
+
+	for _, prefix := range prefixes {
+		if strings.HasSuffix(prefix, "/") && strings.HasPrefix(mediaType, prefix) || mediaType == prefix {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isDirectory(path string) bool {
+	info, err := os.Stat(path)
+
+	return err == nil && info.IsDir()
+}
@@ -0,0 +1,13 @@
+package filesystem
+
+import "errors"
+
+var (
+	ErrCloneUnsupported = errors.New("file cloning is not supported on this platform")
+	ErrNotDirectory     = errors.New("not a directory")
+	ErrIsDirectory      = errors.New("is a directory")
+	ErrReadOnlyOpen     = errors.New("OpenFile is for writing - use Open to read")
+	ErrTrashUnsupported = errors.New("moving to the trash is not supported on this platform")
+	ErrOutsideRoot      = errors.New("path is outside of the managed root")
+	ErrJournalMismatch  = errors.New("content does not match the journal")
+)
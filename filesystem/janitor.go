@@ -0,0 +1,284 @@
+package filesystem
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Retention is a cleanup policy applied to a directory by a Janitor.
+type Retention struct {
+	// MaxAge removes files not modified for that long - zero disables
+	MaxAge time.Duration `json:"maxAge,omitempty"`
+	// MaxSize removes the oldest files until the directory total is under that many bytes - zero disables
+	MaxSize int64 `json:"maxSize,omitempty"`
+	// Exclude lists patterns (see filepath.Match) of files that are never removed, matched against the base name
+	// and the path relative to the directory
+	Exclude []string `json:"exclude,omitempty"`
+}
+
+// JanitorEntry is a file removed (or that would be removed in dry-run mode) by a Janitor.
+type JanitorEntry struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	Reason string `json:"reason"`
+}
+
+// JanitorReport describes the outcome of a janitor run.
+type JanitorReport struct {
+	DryRun    bool            `json:"dryRun"`
+	Entries   []*JanitorEntry `json:"entries"`
+	Reclaimed int64           `json:"reclaimed"`
+}
+
+// JanitorStats are cumulative metrics over all non dry-run runs.
+type JanitorStats struct {
+	Runs      uint64 `json:"runs"`
+	Removed   uint64 `json:"removed"`
+	Reclaimed uint64 `json:"reclaimed"`
+	Errors    uint64 `json:"errors"`
+}
+
+// Janitor applies retention policies to registered directories (typically cache and state), on demand or
+// periodically.
+type Janitor struct {
+	// OnReport, if set, is called after every scheduled run
+	OnReport func(*JanitorReport, error)
+
+	mu       sync.Mutex
+	policies map[string]*Retention
+	stop     chan struct{}
+	done     chan struct{}
+
+	runs      uint64
+	removed   uint64
+	reclaimed uint64
+	errors    uint64
+}
+
+// NewJanitor returns a Janitor with no registered directory.
+func NewJanitor() *Janitor {
+	return &Janitor{
+		policies: map[string]*Retention{},
+	}
+}
+
+// Register sets the retention policy for dir, replacing any previous one.
+func (jan *Janitor) Register(dir string, policy *Retention) {
+	jan.mu.Lock()
+	defer jan.mu.Unlock()
+
+	jan.policies[dir] = policy
+}
+
+// Unregister stops cleaning dir.
+func (jan *Janitor) Unregister(dir string) {
+	jan.mu.Lock()
+	defer jan.mu.Unlock()
+
+	delete(jan.policies, dir)
+}
+
+// Run applies all policies once. In dry-run mode, nothing is removed, and the report lists what would be.
+// Directories that do not exist are ignored. Errors do not stop the run - the first one is returned.
+func (jan *Janitor) Run(dryRun bool) (*JanitorReport, error) {
+	jan.mu.Lock()
+	policies := make(map[string]*Retention, len(jan.policies))
+
+	for dir, policy := range jan.policies {
+		policies[dir] = policy
+	}
+	jan.mu.Unlock()
+
+	dirs := make([]string, 0, len(policies))
+	for dir := range policies {
+		dirs = append(dirs, dir)
+	}
+
+	sort.Strings(dirs)
+
+	report := &JanitorReport{
+		DryRun:  dryRun,
+		Entries: []*JanitorEntry{},
+	}
+
+	var firstErr error
+
+	for _, dir := range dirs {
+		err := clean(dir, policies[dir], report)
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	if !dryRun {
+		atomic.AddUint64(&jan.runs, 1)
+		atomic.AddUint64(&jan.removed, uint64(len(report.Entries)))
+		atomic.AddUint64(&jan.reclaimed, uint64(report.Reclaimed))
+
+		if firstErr != nil {
+			atomic.AddUint64(&jan.errors, 1)
+		}
+	}
+
+	return report, firstErr
+}
+
+// Start runs the janitor every interval, until Stop is called. Calling Start on a started janitor is a no-op.
+func (jan *Janitor) Start(interval time.Duration) {
+	jan.mu.Lock()
+	defer jan.mu.Unlock()
+
+	if jan.stop != nil {
+		return
+	}
+
+	jan.stop = make(chan struct{})
+	jan.done = make(chan struct{})
+
+	go func(stop chan struct{}, done chan struct{}) {
+		defer close(done)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				report, err := jan.Run(false)
+				if jan.OnReport != nil {
+					jan.OnReport(report, err)
+				}
+			}
+		}
+	}(jan.stop, jan.done)
+}
+
+// Stop ends periodic runs, waiting for an ongoing run to complete.
+func (jan *Janitor) Stop() {
+	jan.mu.Lock()
+	stop, done := jan.stop, jan.done
+	jan.stop, jan.done = nil, nil
+	jan.mu.Unlock()
+
+	if stop == nil {
+		return
+	}
+
+	close(stop)
+	<-done
+}
+
+// Stats returns cumulative metrics.
+func (jan *Janitor) Stats() JanitorStats {
+	return JanitorStats{
+		Runs:      atomic.LoadUint64(&jan.runs),
+		Removed:   atomic.LoadUint64(&jan.removed),
+		Reclaimed: atomic.LoadUint64(&jan.reclaimed),
+		Errors:    atomic.LoadUint64(&jan.errors),
+	}
+}
+
+type janitorFile struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+// clean applies policy to dir, appending to report.
+func clean(dir string, policy *Retention, report *JanitorReport) error {
+	var files []*janitorFile
+
+	err := filepath.WalkDir(dir, func(pth string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && pth == dir {
+				return filepath.SkipDir
+			}
+
+			return err
+		}
+
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+
+		rel, _ := filepath.Rel(dir, pth)
+		if excluded(policy.Exclude, rel) {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+
+		files = append(files, &janitorFile{path: pth, size: info.Size(), modTime: info.ModTime()})
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed inspecting %s: %w", dir, err)
+	}
+
+	// Oldest first
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].modTime.Before(files[j].modTime)
+	})
+
+	var total int64
+	for _, file := range files {
+		total += file.size
+	}
+
+	var firstErr error
+
+	for _, file := range files {
+		reason := ""
+
+		switch {
+		case policy.MaxAge > 0 && time.Since(file.modTime) > policy.MaxAge:
+			reason = "maxAge"
+		case policy.MaxSize > 0 && total > policy.MaxSize:
+			reason = "maxSize"
+		default:
+			continue
+		}
+
+		if !report.DryRun {
+			if err = os.Remove(file.path); err != nil {
+				if firstErr == nil {
+					firstErr = fmt.Errorf("failed removing %s: %w", file.path, err)
+				}
+
+				continue
+			}
+		}
+
+		total -= file.size
+		report.Reclaimed += file.size
+		report.Entries = append(report.Entries, &JanitorEntry{Path: file.path, Size: file.size, Reason: reason})
+	}
+
+	return firstErr
+}
+
+func excluded(patterns []string, rel string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := filepath.Match(pattern, filepath.Base(rel)); ok {
+			return true
+		}
+
+		if ok, _ := filepath.Match(pattern, rel); ok {
+			return true
+		}
+	}
+
+	return false
+}
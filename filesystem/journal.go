@@ -0,0 +1,266 @@
+package filesystem
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// JournalOp is the kind of operation recorded in a journal.
+type JournalOp string
+
+const (
+	JournalCreate JournalOp = "create"
+	JournalModify JournalOp = "modify"
+	JournalDelete JournalOp = "delete"
+)
+
+// JournalEntry is an operation recorded in a journal.
+type JournalEntry struct {
+	Time time.Time `json:"time"`
+	Op   JournalOp `json:"op"`
+	// Path is relative to the root, slash separated
+	Path string `json:"path"`
+	// Size and Digest (as sha256:<hex>) describe the content after the operation - unset for deletions
+	Size   int64  `json:"size,omitempty"`
+	Digest string `json:"digest,omitempty"`
+}
+
+// Journal manages a directory: operations performed through its methods are appended to a journal file, one JSON
+// entry per line, so that changes can be audited, and replayed elsewhere (see ReplayJournal).
+// Changes made to the directory by other means are not recorded.
+type Journal struct {
+	root string
+	mu   sync.Mutex
+	file *os.File
+}
+
+// OpenJournal manages root, appending to the journal file at journalPath (created if needed). The journal file
+// should live outside of root.
+func OpenJournal(root string, journalPath string) (*Journal, error) {
+	file, err := os.OpenFile(journalPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, FilePermissionsPrivate)
+	if err != nil {
+		return nil, fmt.Errorf("failed opening journal: %w", err)
+	}
+
+	return &Journal{root: root, file: file}, nil
+}
+
+// Close closes the journal file.
+func (journal *Journal) Close() error {
+	return journal.file.Close()
+}
+
+// WriteFile atomically writes data to name, relative to the root (see WriteFile).
+func (journal *Journal) WriteFile(name string, data []byte, perm os.FileMode) error {
+	target, err := journal.resolve(name)
+	if err != nil {
+		return err
+	}
+
+	op := journal.op(target)
+
+	if err = WriteFile(target, data, perm); err != nil {
+		return err
+	}
+
+	sum := sha256.Sum256(data)
+
+	return journal.append(&JournalEntry{
+		Op:     op,
+		Path:   journal.rel(name),
+		Size:   int64(len(data)),
+		Digest: manifestDigestAlgorithm + ":" + hex.EncodeToString(sum[:]),
+	})
+}
+
+// CopyFile copies src to name, relative to the root (see CopyFile).
+func (journal *Journal) CopyFile(src string, name string) error {
+	target, err := journal.resolve(name)
+	if err != nil {
+		return err
+	}
+
+	op := journal.op(target)
+
+	if err = CopyFile(src, target); err != nil {
+		return err
+	}
+
+	return journal.appendFile(op, name, target)
+}
+
+// Remove removes name, relative to the root (see Remove). Trashed paths are recorded as deleted.
+func (journal *Journal) Remove(name string, opts *RemoveOptions) error {
+	target, err := journal.resolve(name)
+	if err != nil {
+		return err
+	}
+
+	if err = Remove(target, opts); err != nil {
+		return err
+	}
+
+	return journal.append(&JournalEntry{Op: JournalDelete, Path: journal.rel(name)})
+}
+
+// ReadJournal returns the entries of a journal file, in order.
+func ReadJournal(journalPath string) ([]*JournalEntry, error) {
+	file, err := os.Open(journalPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed opening journal: %w", err)
+	}
+
+	defer file.Close()
+
+	entries := []*JournalEntry{}
+	scanner := bufio.NewScanner(file)
+
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		entry := &JournalEntry{}
+		if err = json.Unmarshal(scanner.Bytes(), entry); err != nil {
+			return entries, fmt.Errorf("invalid journal entry at line %d: %w", line, err)
+		}
+
+		entries = append(entries, entry)
+	}
+
+	if err = scanner.Err(); err != nil {
+		return entries, fmt.Errorf("failed reading journal: %w", err)
+	}
+
+	return entries, nil
+}
+
+// ReplayJournal brings dst up to date with the operations of entries performed in src: for every path, the last
+// operation applies. Deleted paths are removed from dst, and others are copied over from src, provided their
+// content still matches the recorded digest - otherwise, src changed since, and ErrJournalMismatch is returned.
+func ReplayJournal(entries []*JournalEntry, src string, dst string) error {
+	last := map[string]*JournalEntry{}
+	order := []string{}
+
+	for _, entry := range entries {
+		if _, ok := last[entry.Path]; !ok {
+			order = append(order, entry.Path)
+		}
+
+		last[entry.Path] = entry
+	}
+
+	for _, rel := range order {
+		entry := last[rel]
+
+		// Journals are data: do not let them touch anything outside of dst
+		target, err := rooted(dst, rel)
+		if err != nil {
+			return err
+		}
+
+		if entry.Op == JournalDelete {
+			if err := os.RemoveAll(target); err != nil {
+				return fmt.Errorf("failed replaying deletion of %s: %w", rel, err)
+			}
+
+			continue
+		}
+
+		source := filepath.Join(src, filepath.FromSlash(rel))
+
+		digest, err := digestFile(source)
+		if err != nil {
+			return fmt.Errorf("failed replaying %s: %w", rel, err)
+		}
+
+		if digest != entry.Digest {
+			return fmt.Errorf("%w: %s", ErrJournalMismatch, rel)
+		}
+
+		if err = os.MkdirAll(filepath.Dir(target), DirPermissionsDefault); err != nil {
+			return fmt.Errorf("failed replaying %s: %w", rel, err)
+		}
+
+		if err = CopyFile(source, target); err != nil {
+			return fmt.Errorf("failed replaying %s: %w", rel, err)
+		}
+	}
+
+	return nil
+}
+
+// resolve returns the path of name under the root, refusing names escaping it.
+func (journal *Journal) resolve(name string) (string, error) {
+	return rooted(journal.root, name)
+}
+
+// rooted returns the path of name under root, refusing names escaping it, and root itself.
+func rooted(root string, name string) (string, error) {
+	rel := path.Clean(filepath.ToSlash(name))
+	if rel == "." || rel == ".." || strings.HasPrefix(rel, "../") || path.IsAbs(rel) || filepath.VolumeName(name) != "" {
+		return "", fmt.Errorf("%w: %s", ErrOutsideRoot, name)
+	}
+
+	return filepath.Join(root, filepath.FromSlash(rel)), nil
+}
+
+func (journal *Journal) rel(name string) string {
+	return path.Clean(filepath.ToSlash(name))
+}
+
+// op tells whether writing target creates or modifies it.
+func (journal *Journal) op(target string) JournalOp {
+	if _, err := os.Lstat(target); err == nil {
+		return JournalModify
+	}
+
+	return JournalCreate
+}
+
+func (journal *Journal) appendFile(op JournalOp, name string, target string) error {
+	info, err := os.Stat(target)
+	if err != nil {
+		return err
+	}
+
+	entry := &JournalEntry{Op: op, Path: journal.rel(name)}
+
+	if info.Mode().IsRegular() {
+		entry.Size = info.Size()
+
+		if entry.Digest, err = digestFile(target); err != nil {
+			return err
+		}
+	}
+
+	return journal.append(entry)
+}
+
+// append writes entry as a single line, so that concurrent appends do not interleave.
+func (journal *Journal) append(entry *JournalEntry) error {
+	entry.Time = time.Now().UTC()
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed marshalling journal entry: %w", err)
+	}
+
+	journal.mu.Lock()
+	defer journal.mu.Unlock()
+
+	if _, err = journal.file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed writing journal entry for %s: %w", entry.Path, err)
+	}
+
+	return nil
+}
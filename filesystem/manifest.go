@@ -0,0 +1,188 @@
+package filesystem
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const manifestDigestAlgorithm = "sha256"
+
+// TreeManifest describes the content of a directory tree. It is canonical: two trees with the same content have
+// the same manifest, whatever their location, walk order, or modification times.
+type TreeManifest struct {
+	// Entries are sorted by path
+	Entries []*ManifestEntry `json:"entries"`
+}
+
+// ManifestEntry describes a file, directory or symbolic link.
+type ManifestEntry struct {
+	// Path is relative to the root, slash separated
+	Path string      `json:"path"`
+	Mode fs.FileMode `json:"mode"`
+	// Size is zero for directories and links
+	Size int64 `json:"size"`
+	// Digest is the content digest of regular files, as sha256:<hex>
+	Digest string `json:"digest,omitempty"`
+	// Link is the target of symbolic links
+	Link string `json:"link,omitempty"`
+}
+
+// ManifestChange is an entry present in both manifests, with a different mode, content, or target.
+type ManifestChange struct {
+	Before *ManifestEntry `json:"before"`
+	After  *ManifestEntry `json:"after"`
+}
+
+// ManifestDiff lists the differences between two manifests, sorted by path.
+type ManifestDiff struct {
+	Added   []*ManifestEntry  `json:"added,omitempty"`
+	Removed []*ManifestEntry  `json:"removed,omitempty"`
+	Changed []*ManifestChange `json:"changed,omitempty"`
+}
+
+// Manifest walks dir, and returns its manifest. The root itself is not part of it.
+func Manifest(dir string) (*TreeManifest, error) {
+	manifest := &TreeManifest{Entries: []*ManifestEntry{}}
+
+	err := filepath.WalkDir(dir, func(pth string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, _ := filepath.Rel(dir, pth)
+		if rel == "." {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+
+		item := &ManifestEntry{
+			Path: filepath.ToSlash(rel),
+			Mode: info.Mode(),
+		}
+
+		switch {
+		case info.Mode()&fs.ModeSymlink != 0:
+			if item.Link, err = os.Readlink(pth); err != nil {
+				return err
+			}
+		case info.Mode().IsRegular():
+			item.Size = info.Size()
+
+			if item.Digest, err = digestFile(pth); err != nil {
+				return err
+			}
+		}
+
+		manifest.Entries = append(manifest.Entries, item)
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed building manifest of %s: %w", dir, err)
+	}
+
+	// WalkDir order sorts "a/b" before "a.txt": sort on full paths instead
+	sort.Slice(manifest.Entries, func(i, j int) bool {
+		return manifest.Entries[i].Path < manifest.Entries[j].Path
+	})
+
+	return manifest, nil
+}
+
+// String renders the manifest canonically, one entry per line.
+func (manifest *TreeManifest) String() string {
+	var out strings.Builder
+
+	for _, entry := range manifest.Entries {
+		out.WriteString(entry.String())
+		out.WriteByte('\n')
+	}
+
+	return out.String()
+}
+
+// Digest returns the digest of the canonical form of the manifest, as sha256:<hex>: it changes whenever any entry
+// does, which makes it a cache key for the whole tree.
+func (manifest *TreeManifest) Digest() string {
+	sum := sha256.Sum256([]byte(manifest.String()))
+
+	return manifestDigestAlgorithm + ":" + hex.EncodeToString(sum[:])
+}
+
+// String renders the entry as "mode size digest path", with " -> target" for links.
+func (entry *ManifestEntry) String() string {
+	digest := entry.Digest
+	if digest == "" {
+		digest = "-"
+	}
+
+	line := fmt.Sprintf("%s %d %s %s", entry.Mode, entry.Size, digest, entry.Path)
+	if entry.Link != "" {
+		line += " -> " + entry.Link
+	}
+
+	return line
+}
+
+// DiffManifests returns what changed from a to b.
+func DiffManifests(a *TreeManifest, b *TreeManifest) *ManifestDiff {
+	diff := &ManifestDiff{}
+	before := make(map[string]*ManifestEntry, len(a.Entries))
+
+	for _, entry := range a.Entries {
+		before[entry.Path] = entry
+	}
+
+	for _, entry := range b.Entries {
+		previous, ok := before[entry.Path]
+
+		switch {
+		case !ok:
+			diff.Added = append(diff.Added, entry)
+		case previous.String() != entry.String():
+			diff.Changed = append(diff.Changed, &ManifestChange{Before: previous, After: entry})
+		}
+
+		delete(before, entry.Path)
+	}
+
+	for _, entry := range a.Entries {
+		if _, ok := before[entry.Path]; ok {
+			diff.Removed = append(diff.Removed, entry)
+		}
+	}
+
+	return diff
+}
+
+// Empty is true if the manifests are identical.
+func (diff *ManifestDiff) Empty() bool {
+	return len(diff.Added) == 0 && len(diff.Removed) == 0 && len(diff.Changed) == 0
+}
+
+func digestFile(name string) (string, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return "", err
+	}
+
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err = io.Copy(hash, file); err != nil {
+		return "", err
+	}
+
+	return manifestDigestAlgorithm + ":" + hex.EncodeToString(hash.Sum(nil)), nil
+}
@@ -0,0 +1,399 @@
+package filesystem
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// Overlay composes read-only layers with a writable upper directory, in userspace: reads see the upper
+// directory first, then each layer in order, and writes copy files up to the upper directory before modifying them.
+// Removals of lower files are recorded as (in memory) whiteouts. Names are slash separated, as in io/fs.
+// Overlay implements fs.FS, fs.StatFS and fs.ReadDirFS.
+type Overlay struct {
+	upper     string
+	layers    []fs.FS
+	mu        sync.RWMutex
+	whiteouts map[string]bool
+	opaque    map[string]bool
+}
+
+// NewOverlay returns an overlay writing to upper (created if needed), over layers, topmost first.
+func NewOverlay(upper string, layers ...fs.FS) (*Overlay, error) {
+	if err := os.MkdirAll(upper, DirPermissionsDefault); err != nil {
+		return nil, err
+	}
+
+	return &Overlay{
+		upper:     upper,
+		layers:    layers,
+		whiteouts: map[string]bool{},
+		opaque:    map[string]bool{},
+	}, nil
+}
+
+// Open opens the named file for reading. Directories list the merged content of all layers.
+func (ov *Overlay) Open(name string) (fs.File, error) {
+	info, layer, err := ov.lookup("open", name)
+	if err != nil {
+		return nil, err
+	}
+
+	if info.IsDir() {
+		entries, err := ov.ReadDir(name)
+		if err != nil {
+			return nil, err
+		}
+
+		return &overlayDir{info: info, entries: entries}, nil
+	}
+
+	if layer == nil {
+		return os.Open(ov.upperPath(name))
+	}
+
+	return layer.Open(name)
+}
+
+// Stat returns the file info of the topmost visible version of name.
+func (ov *Overlay) Stat(name string) (fs.FileInfo, error) {
+	info, _, err := ov.lookup("stat", name)
+
+	return info, err
+}
+
+// ReadDir returns the merged, sorted entries of the named directory.
+func (ov *Overlay) ReadDir(name string) ([]fs.DirEntry, error) {
+	info, _, err := ov.lookup("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+
+	if !info.IsDir() {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: ErrNotDirectory}
+	}
+
+	ov.mu.RLock()
+	defer ov.mu.RUnlock()
+
+	merged := map[string]fs.DirEntry{}
+
+	if entries, err := os.ReadDir(ov.upperPath(name)); err == nil {
+		for _, entry := range entries {
+			merged[entry.Name()] = entry
+		}
+	}
+
+	if !ov.opaqueLocked(name) {
+		for _, layer := range ov.layers {
+			entries, err := fs.ReadDir(layer, name)
+			if err != nil {
+				continue
+			}
+
+			for _, entry := range entries {
+				if _, ok := merged[entry.Name()]; !ok {
+					merged[entry.Name()] = entry
+				}
+			}
+		}
+	}
+
+	result := make([]fs.DirEntry, 0, len(merged))
+
+	for entryName, entry := range merged {
+		if !ov.hiddenLocked(path.Join(name, entryName)) {
+			result = append(result, entry)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name() < result[j].Name()
+	})
+
+	return result, nil
+}
+
+// OpenFile opens the named file in the upper directory, with the given flags (see os.OpenFile). When opening for
+// writing a file that only exists in a lower layer, it is copied up first (unless truncated).
+func (ov *Overlay) OpenFile(name string, flag int, perm fs.FileMode) (*os.File, error) {
+	if flag&(os.O_WRONLY|os.O_RDWR|os.O_CREATE|os.O_APPEND|os.O_TRUNC) == 0 {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: ErrReadOnlyOpen}
+	}
+
+	if err := ov.copyUp("open", name, flag&os.O_TRUNC == 0); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	if err := ov.ensureParent(name); err != nil {
+		return nil, err
+	}
+
+	ov.reveal(name)
+
+	return os.OpenFile(ov.upperPath(name), flag, perm)
+}
+
+// WriteFile writes data to the named file in the upper directory, creating it if necessary.
+func (ov *Overlay) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	file, err := ov.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+
+	_, err = file.Write(data)
+	if cerr := file.Close(); err == nil {
+		err = cerr
+	}
+
+	return err
+}
+
+// MkdirAll creates the named directory, and any missing parent, in the upper directory.
+func (ov *Overlay) MkdirAll(name string, perm fs.FileMode) error {
+	if !fs.ValidPath(name) {
+		return &fs.PathError{Op: "mkdir", Path: name, Err: fs.ErrInvalid}
+	}
+
+	ov.reveal(name)
+
+	return os.MkdirAll(ov.upperPath(name), perm)
+}
+
+// Remove removes the named file or directory (recursively) from the merged view.
+func (ov *Overlay) Remove(name string) error {
+	if _, _, err := ov.lookup("remove", name); err != nil {
+		return err
+	}
+
+	if name == "." {
+		return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrInvalid}
+	}
+
+	if err := os.RemoveAll(ov.upperPath(name)); err != nil {
+		return err
+	}
+
+	ov.mu.Lock()
+	defer ov.mu.Unlock()
+
+	ov.whiteouts[name] = true
+
+	return nil
+}
+
+// Materialize writes the merged view to dst, which is created if needed.
+func (ov *Overlay) Materialize(dst string) error {
+	return fs.WalkDir(ov, ".", func(name string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		target := filepath.Join(dst, filepath.FromSlash(name))
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+
+		if entry.IsDir() {
+			return os.MkdirAll(target, info.Mode().Perm()|0o700)
+		}
+
+		source, err := ov.Open(name)
+		if err != nil {
+			return err
+		}
+		defer source.Close()
+
+		destination, err := os.OpenFile(target, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, info.Mode().Perm())
+		if err != nil {
+			return err
+		}
+
+		if _, err = io.Copy(destination, source); err != nil {
+			destination.Close()
+
+			return err
+		}
+
+		return destination.Close()
+	})
+}
+
+// lookup finds the topmost visible version of name, returning a nil layer if it is in the upper directory.
+func (ov *Overlay) lookup(operation string, name string) (fs.FileInfo, fs.FS, error) {
+	if !fs.ValidPath(name) {
+		return nil, nil, &fs.PathError{Op: operation, Path: name, Err: fs.ErrInvalid}
+	}
+
+	ov.mu.RLock()
+	defer ov.mu.RUnlock()
+
+	if ov.hiddenLocked(name) {
+		return nil, nil, &fs.PathError{Op: operation, Path: name, Err: fs.ErrNotExist}
+	}
+
+	if info, err := os.Stat(ov.upperPath(name)); err == nil {
+		return info, nil, nil
+	}
+
+	if !ov.opaqueLocked(name) {
+		for _, layer := range ov.layers {
+			if info, err := fs.Stat(layer, name); err == nil {
+				return info, layer, nil
+			}
+		}
+	}
+
+	return nil, nil, &fs.PathError{Op: operation, Path: name, Err: fs.ErrNotExist}
+}
+
+// copyUp copies a lower layer file to the upper directory, if it is not already there.
+func (ov *Overlay) copyUp(operation string, name string, content bool) error {
+	info, layer, err := ov.lookup(operation, name)
+	if err != nil || layer == nil {
+		return err
+	}
+
+	if info.IsDir() {
+		return &fs.PathError{Op: operation, Path: name, Err: ErrIsDirectory}
+	}
+
+	if err = ov.ensureParent(name); err != nil {
+		return err
+	}
+
+	destination, err := os.OpenFile(ov.upperPath(name), os.O_CREATE|os.O_TRUNC|os.O_WRONLY, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+
+	if content {
+		source, err := layer.Open(name)
+		if err != nil {
+			destination.Close()
+
+			return err
+		}
+		defer source.Close()
+
+		if _, err = io.Copy(destination, source); err != nil {
+			destination.Close()
+
+			return err
+		}
+	}
+
+	return destination.Close()
+}
+
+// ensureParent creates the parent of name in the upper directory, with the modes of the visible directories.
+func (ov *Overlay) ensureParent(name string) error {
+	parent := path.Dir(name)
+	if parent == "." {
+		return nil
+	}
+
+	perm := fs.FileMode(DirPermissionsDefault)
+	if info, _, err := ov.lookup("mkdir", parent); err == nil {
+		if !info.IsDir() {
+			return &fs.PathError{Op: "mkdir", Path: parent, Err: ErrNotDirectory}
+		}
+
+		perm = info.Mode().Perm()
+	}
+
+	ov.reveal(parent)
+
+	return os.MkdirAll(ov.upperPath(parent), perm|0o700)
+}
+
+// reveal clears whiteouts on name and its parents. Directories that were removed become opaque, so that their
+// former lower content does not reappear.
+func (ov *Overlay) reveal(name string) {
+	ov.mu.Lock()
+	defer ov.mu.Unlock()
+
+	for current := name; current != "."; current = path.Dir(current) {
+		if ov.whiteouts[current] {
+			delete(ov.whiteouts, current)
+			ov.opaque[current] = true
+		}
+	}
+}
+
+func (ov *Overlay) hiddenLocked(name string) bool {
+	for current := name; current != "."; current = path.Dir(current) {
+		if ov.whiteouts[current] {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (ov *Overlay) opaqueLocked(name string) bool {
+	for current := name; current != "."; current = path.Dir(current) {
+		if ov.opaque[current] {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (ov *Overlay) upperPath(name string) string {
+	return filepath.Join(ov.upper, filepath.FromSlash(name))
+}
+
+// overlayDir is an opened merged directory.
+type overlayDir struct {
+	info    fs.FileInfo
+	entries []fs.DirEntry
+	offset  int
+}
+
+func (dir *overlayDir) Stat() (fs.FileInfo, error) {
+	return dir.info, nil
+}
+
+func (dir *overlayDir) Read(_ []byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: dir.info.Name(), Err: ErrIsDirectory}
+}
+
+func (dir *overlayDir) Close() error {
+	return nil
+}
+
+func (dir *overlayDir) ReadDir(count int) ([]fs.DirEntry, error) {
+	remaining := dir.entries[dir.offset:]
+
+	if count <= 0 {
+		dir.offset = len(dir.entries)
+
+		return remaining, nil
+	}
+
+	if len(remaining) == 0 {
+		return nil, io.EOF
+	}
+
+	if count > len(remaining) {
+		count = len(remaining)
+	}
+
+	dir.offset += count
+
+	return remaining[:count], nil
+}
+
+var (
+	_ fs.ReadDirFS = (*Overlay)(nil)
+	_ fs.StatFS    = (*Overlay)(nil)
+)
@@ -0,0 +1,31 @@
+package filesystem
+
+import (
+	"fmt"
+	"os"
+)
+
+// RemoveOptions control Remove.
+type RemoveOptions struct {
+	// Trash moves the path to the platform trash (FreeDesktop trash, macOS Trash, Windows Recycle Bin) instead of
+	// deleting it, so that users can recover it
+	Trash bool
+}
+
+// Remove deletes path and any children it contains, or moves it to the trash. Contrary to os.RemoveAll, removing
+// a path that does not exist is an error, since the user asked for something that did not happen.
+func Remove(path string, opts *RemoveOptions) error {
+	if _, err := os.Lstat(path); err != nil {
+		return err
+	}
+
+	if opts == nil || !opts.Trash {
+		return os.RemoveAll(path)
+	}
+
+	if err := trash(path); err != nil {
+		return fmt.Errorf("failed moving %s to the trash: %w", path, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,195 @@
+package filesystem
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// TreeSnapshot is a point in time copy of a directory tree, that can be restored to undo changes.
+type TreeSnapshot struct {
+	// Root is the directory that was captured
+	Root string
+	// Store is where file copies are kept
+	Store   string
+	entries map[string]*snapshotEntry
+}
+
+type snapshotEntry struct {
+	mode    fs.FileMode
+	size    int64
+	modTime time.Time
+	link    string
+	stored  string
+}
+
+// Snapshot captures dir (manifest plus file copies, cloned when supported) into a temporary store.
+// Call Restore to roll back, and Discard once the snapshot is not needed anymore.
+func Snapshot(dir string) (*TreeSnapshot, error) {
+	root, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	store, err := os.MkdirTemp("", "snapshot-")
+	if err != nil {
+		return nil, fmt.Errorf("failed creating snapshot store: %w", err)
+	}
+
+	snap := &TreeSnapshot{
+		Root:    root,
+		Store:   store,
+		entries: map[string]*snapshotEntry{},
+	}
+
+	count := 0
+
+	err = filepath.WalkDir(root, func(pth string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, _ := filepath.Rel(root, pth)
+		if rel == "." {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+
+		item := &snapshotEntry{
+			mode:    info.Mode(),
+			size:    info.Size(),
+			modTime: info.ModTime(),
+		}
+
+		switch {
+		case info.Mode()&fs.ModeSymlink != 0:
+			if item.link, err = os.Readlink(pth); err != nil {
+				return err
+			}
+		case info.Mode().IsRegular():
+			count++
+			item.stored = filepath.Join(store, strconv.Itoa(count))
+
+			if err = CopyFile(pth, item.stored); err != nil {
+				return err
+			}
+		}
+
+		snap.entries[rel] = item
+
+		return nil
+	})
+	if err != nil {
+		_ = os.RemoveAll(store)
+
+		return nil, fmt.Errorf("failed snapshotting %s: %w", root, err)
+	}
+
+	return snap, nil
+}
+
+// Restore rolls the directory back to its snapshotted state: extraneous entries are removed, and modified or
+// removed ones are restored. Unchanged files are left alone.
+func Restore(snap *TreeSnapshot) error {
+	var extraneous []string
+
+	// Find what has been added or changed type
+	err := filepath.WalkDir(snap.Root, func(pth string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, _ := filepath.Rel(snap.Root, pth)
+		if rel == "." {
+			return nil
+		}
+
+		item, ok := snap.entries[rel]
+		if !ok || item.mode.Type() != entry.Type() {
+			extraneous = append(extraneous, pth)
+
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed inspecting %s: %w", snap.Root, err)
+	}
+
+	for _, pth := range extraneous {
+		if err = os.RemoveAll(pth); err != nil {
+			return fmt.Errorf("failed removing %s: %w", pth, err)
+		}
+	}
+
+	// Parents sort before their children
+	paths := make([]string, 0, len(snap.entries))
+	for rel := range snap.entries {
+		paths = append(paths, rel)
+	}
+
+	sort.Strings(paths)
+
+	for _, rel := range paths {
+		if err = snap.restoreEntry(rel, snap.entries[rel]); err != nil {
+			return fmt.Errorf("failed restoring %s: %w", rel, err)
+		}
+	}
+
+	return nil
+}
+
+func (snap *TreeSnapshot) restoreEntry(rel string, item *snapshotEntry) error {
+	pth := filepath.Join(snap.Root, rel)
+
+	switch {
+	case item.mode.IsDir():
+		if err := os.MkdirAll(pth, item.mode.Perm()); err != nil {
+			return err
+		}
+
+		return os.Chmod(pth, item.mode.Perm())
+	case item.mode&fs.ModeSymlink != 0:
+		if current, err := os.Readlink(pth); err == nil && current == item.link {
+			return nil
+		}
+
+		_ = os.Remove(pth)
+
+		return os.Symlink(item.link, pth)
+	case item.mode.IsRegular():
+		info, err := os.Lstat(pth)
+		if err == nil && info.Size() == item.size && info.ModTime().Equal(item.modTime) && info.Mode() == item.mode {
+			return nil
+		}
+
+		if err = CopyFile(item.stored, pth); err != nil {
+			return err
+		}
+
+		if err = os.Chmod(pth, item.mode.Perm()); err != nil {
+			return err
+		}
+
+		return os.Chtimes(pth, item.modTime, item.modTime)
+	}
+
+	// Other types (devices, sockets, pipes) are not restored
+	return nil
+}
+
+// Discard removes the snapshot store.
+func (snap *TreeSnapshot) Discard() error {
+	return os.RemoveAll(snap.Store)
+}
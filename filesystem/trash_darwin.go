@@ -0,0 +1,50 @@
+//go:build darwin
+
+package filesystem
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// trash moves path to the user Trash if on the same device, or to the .Trashes of its volume otherwise. The Finder
+// "Put Back" is not available, as it relies on private metadata.
+func trash(path string) error {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+
+	dev, err := device(abs)
+	if err != nil {
+		return err
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Join(home, ".Trash")
+
+	if homeDev, err := device(dir); err != nil || homeDev != dev {
+		top, err := mountPoint(abs)
+		if err != nil {
+			return err
+		}
+
+		dir = filepath.Join(top, ".Trashes", uid())
+	}
+
+	if err = os.MkdirAll(dir, DirPermissionsPrivate); err != nil {
+		return err
+	}
+
+	return os.Rename(abs, filepath.Join(dir, trashName(dir, filepath.Base(abs), trashTaken)))
+}
+
+func trashTaken(dir string, name string) bool {
+	_, err := os.Lstat(filepath.Join(dir, name))
+
+	return err == nil
+}
@@ -0,0 +1,135 @@
+//go:build unix && !darwin
+
+package filesystem
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const trashInfoTimeFormat = "2006-01-02T15:04:05"
+
+// trash implements the FreeDesktop trash specification: files go to the home trash if on the same device, or to the
+// trash at the top of their mount otherwise, along with a trashinfo file so that they can be restored.
+func trash(path string) error {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+
+	dev, err := device(abs)
+	if err != nil {
+		return err
+	}
+
+	dir, err := homeTrash()
+	if err != nil {
+		return err
+	}
+
+	original := abs
+
+	if homeDev, err := device(dir); err != nil || homeDev != dev {
+		top, err := mountPoint(abs)
+		if err != nil {
+			return err
+		}
+
+		if dir, err = topTrash(top); err != nil {
+			return err
+		}
+
+		// Top directory trashes record paths relative to the mount
+		if original, err = filepath.Rel(top, abs); err != nil {
+			return err
+		}
+	}
+
+	for _, sub := range []string{"files", "info"} {
+		if err = os.MkdirAll(filepath.Join(dir, sub), DirPermissionsPrivate); err != nil {
+			return err
+		}
+	}
+
+	info := fmt.Sprintf("[Trash Info]\nPath=%s\nDeletionDate=%s\n",
+		(&url.URL{Path: original}).EscapedPath(), time.Now().Format(trashInfoTimeFormat))
+
+	// The info file is created exclusively first, reserving the name
+	for {
+		name := trashName(dir, filepath.Base(abs), trashTaken)
+		infoFile := filepath.Join(dir, "info", name+".trashinfo")
+
+		file, err := os.OpenFile(infoFile, os.O_WRONLY|os.O_CREATE|os.O_EXCL, FilePermissionsPrivate)
+		if errors.Is(err, fs.ErrExist) {
+			continue
+		}
+
+		if err != nil {
+			return err
+		}
+
+		_, err = file.WriteString(info)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+
+		if err == nil {
+			err = os.Rename(abs, filepath.Join(dir, "files", name))
+		}
+
+		if err != nil {
+			_ = os.Remove(infoFile)
+		}
+
+		return err
+	}
+}
+
+func trashTaken(dir string, name string) bool {
+	for _, candidate := range []string{filepath.Join(dir, "files", name), filepath.Join(dir, "info", name+".trashinfo")} {
+		if _, err := os.Lstat(candidate); err == nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+// homeTrash returns the home trash, creating it if needed.
+func homeTrash() (string, error) {
+	data := os.Getenv("XDG_DATA_HOME")
+	if data == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+
+		data = filepath.Join(home, ".local", "share")
+	}
+
+	dir := filepath.Join(data, "Trash")
+
+	return dir, os.MkdirAll(dir, DirPermissionsPrivate)
+}
+
+// topTrash returns the trash of a mount: $top/.Trash/$uid if the administrator set up $top/.Trash (a sticky
+// directory, not a link), $top/.Trash-$uid otherwise.
+func topTrash(top string) (string, error) {
+	shared := filepath.Join(top, ".Trash")
+
+	if info, err := os.Lstat(shared); err == nil && info.IsDir() && info.Mode()&fs.ModeSticky != 0 {
+		dir := filepath.Join(shared, uid())
+		if err = os.MkdirAll(dir, DirPermissionsPrivate); err == nil {
+			return dir, nil
+		}
+	}
+
+	dir := filepath.Join(top, ".Trash-"+uid())
+
+	return dir, os.MkdirAll(dir, DirPermissionsPrivate)
+}
@@ -0,0 +1,8 @@
+//go:build !unix && !windows
+
+package filesystem
+
+// trash is not supported on this platform.
+func trash(_ string) error {
+	return ErrTrashUnsupported
+}
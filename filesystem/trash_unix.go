@@ -0,0 +1,61 @@
+//go:build unix
+
+package filesystem
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+)
+
+// device returns the device holding path.
+func device(path string) (uint64, error) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return 0, err
+	}
+
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, ErrTrashUnsupported
+	}
+
+	return uint64(stat.Dev), nil //nolint:unconvert
+}
+
+// mountPoint returns the top directory of the mount holding path, which must be absolute.
+func mountPoint(path string) (string, error) {
+	dev, err := device(path)
+	if err != nil {
+		return "", err
+	}
+
+	for {
+		parent := filepath.Dir(path)
+		if parent == path {
+			return path, nil
+		}
+
+		if parentDev, err := device(parent); err != nil || parentDev != dev {
+			return path, nil //nolint:nilerr
+		}
+
+		path = parent
+	}
+}
+
+// trashName returns a name for base, not used yet in dir: base, then base 2, base 3...
+func trashName(dir string, base string, taken func(dir string, name string) bool) string {
+	name := base
+
+	for i := 2; taken(dir, name); i++ {
+		name = base + " " + strconv.Itoa(i)
+	}
+
+	return name
+}
+
+func uid() string {
+	return strconv.Itoa(os.Getuid())
+}
@@ -0,0 +1,61 @@
+//go:build windows
+
+package filesystem
+
+import (
+	"fmt"
+	"path/filepath"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+const (
+	foDelete          = 0x3
+	fofSilent         = 0x4
+	fofNoConfirmation = 0x10
+	fofAllowUndo      = 0x40
+	fofNoErrorUI      = 0x400
+)
+
+var procSHFileOperation = windows.NewLazySystemDLL("shell32.dll").NewProc("SHFileOperationW") //nolint:gochecknoglobals
+
+// shFileOpStruct is SHFILEOPSTRUCTW. Fields after Flags are only ever zero, which keeps the layout valid on 386,
+// where the structure is packed.
+type shFileOpStruct struct {
+	hwnd                  uintptr
+	wFunc                 uint32
+	pFrom                 *uint16
+	pTo                   *uint16
+	fFlags                uint16
+	fAnyOperationsAborted int32
+	hNameMappings         uintptr
+	lpszProgressTitle     *uint16
+}
+
+// trash moves path to the Recycle Bin. Beware that Windows deletes paths on drives without one (eg: network
+// shares) for good.
+func trash(path string) error {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+
+	// pFrom is a list of paths, terminated by an empty one
+	from, err := windows.UTF16FromString(abs)
+	if err != nil {
+		return err
+	}
+
+	op := &shFileOpStruct{
+		wFunc:  foDelete,
+		pFrom:  &append(from, 0)[0],
+		fFlags: fofAllowUndo | fofNoConfirmation | fofSilent | fofNoErrorUI,
+	}
+
+	if code, _, _ := procSHFileOperation.Call(uintptr(unsafe.Pointer(op))); code != 0 {
+		return fmt.Errorf("SHFileOperation failed with code 0x%x", code) //nolint:goerr113
+	}
+
+	return nil
+}
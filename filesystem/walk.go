@@ -0,0 +1,195 @@
+package filesystem
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+)
+
+// WalkEntry is a file or directory found by WalkParallel.
+type WalkEntry struct {
+	// Path is the entry path, root joined with its relative path
+	Path  string
+	Entry fs.DirEntry
+}
+
+// WalkError aggregates the errors met while walking: unreadable directories do not stop the walk.
+type WalkError struct {
+	Errors []error
+}
+
+func (e *WalkError) Error() string {
+	if len(e.Errors) == 1 {
+		return e.Errors[0].Error()
+	}
+
+	return fmt.Sprintf("%d errors while walking, first: %s", len(e.Errors), e.Errors[0])
+}
+
+func (e *WalkError) Unwrap() error {
+	return e.Errors[0]
+}
+
+// WalkParallel walks the tree under root with a bounded number of workers (defaults to the number of CPUs, times
+// 4, as directory reads mostly wait on IO), streaming entries as they are found, in no particular order - root
+// itself is not sent. Symbolic links are not followed.
+// The returned function waits for the walk to complete - the channel must be drained until then - and returns
+// nil, a *WalkError, or the context error if ctx is done first.
+// Contrary to filepath.WalkDir, which reads directories one at a time and sorts their entries, reads overlap: on
+// large trees with several CPUs, cold caches or network filesystems, this is significantly faster. On small, cached
+// trees, the channel overhead dominates (see the benchmarks in tests).
+func WalkParallel(ctx context.Context, root string, workers int) (<-chan WalkEntry, func() error) {
+	if workers <= 0 {
+		workers = runtime.NumCPU() * 4 //nolint:gomnd
+	}
+
+	walk := &parallelWalk{
+		ctx:     ctx,
+		out:     make(chan WalkEntry, workers),
+		queue:   []string{root},
+		pending: 1,
+	}
+	walk.cond = sync.NewCond(&walk.mu)
+
+	var group sync.WaitGroup
+
+	for i := 0; i < workers; i++ {
+		group.Add(1)
+
+		go func() {
+			defer group.Done()
+			walk.work()
+		}()
+	}
+
+	done := make(chan struct{})
+
+	go func() {
+		group.Wait()
+		close(walk.out)
+		close(done)
+	}()
+
+	// Wake up idle workers if the context is done, so that they can exit
+	go func() {
+		select {
+		case <-ctx.Done():
+			walk.mu.Lock()
+			walk.cond.Broadcast()
+			walk.mu.Unlock()
+		case <-done:
+		}
+	}()
+
+	return walk.out, func() error {
+		<-done
+
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if len(walk.errs) > 0 {
+			return &WalkError{Errors: walk.errs}
+		}
+
+		return nil
+	}
+}
+
+// parallelWalk is an unbounded queue of directories to read, consumed by workers.
+type parallelWalk struct {
+	ctx     context.Context //nolint:containedctx
+	out     chan WalkEntry
+	mu      sync.Mutex
+	cond    *sync.Cond
+	queue   []string
+	pending int
+	errs    []error
+}
+
+func (walk *parallelWalk) work() {
+	for {
+		dir, ok := walk.next()
+		if !ok {
+			return
+		}
+
+		subdirs, err := walk.read(dir)
+
+		walk.mu.Lock()
+
+		if err != nil {
+			walk.errs = append(walk.errs, err)
+		}
+
+		walk.queue = append(walk.queue, subdirs...)
+		walk.pending += len(subdirs) - 1
+		walk.cond.Broadcast()
+		walk.mu.Unlock()
+	}
+}
+
+// next returns the next directory to read, waiting for one if needed, or false once the walk is over.
+func (walk *parallelWalk) next() (string, bool) {
+	walk.mu.Lock()
+	defer walk.mu.Unlock()
+
+	for len(walk.queue) == 0 && walk.pending > 0 && walk.ctx.Err() == nil {
+		walk.cond.Wait()
+	}
+
+	if len(walk.queue) == 0 || walk.ctx.Err() != nil {
+		return "", false
+	}
+
+	// Depth first keeps the queue short
+	dir := walk.queue[len(walk.queue)-1]
+	walk.queue = walk.queue[:len(walk.queue)-1]
+
+	return dir, true
+}
+
+// read sends the entries of dir, and returns its subdirectories.
+func (walk *parallelWalk) read(dir string) ([]string, error) {
+	file, err := os.Open(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+
+	var subdirs []string
+
+	for {
+		// Reading in batches (unsorted) keeps memory bounded on huge directories
+		entries, err := file.ReadDir(256) //nolint:gomnd
+
+		for _, entry := range entries {
+			name := filepath.Join(dir, entry.Name())
+
+			select {
+			case walk.out <- WalkEntry{Path: name, Entry: entry}:
+			case <-walk.ctx.Done():
+				return subdirs, nil
+			}
+
+			if entry.IsDir() {
+				subdirs = append(subdirs, name)
+			}
+		}
+
+		if errors.Is(err, io.EOF) {
+			return subdirs, nil
+		}
+
+		if err != nil {
+			return subdirs, err
+		}
+	}
+}
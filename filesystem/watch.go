@@ -0,0 +1,201 @@
+package filesystem
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const defaultWatchInterval = 500 * time.Millisecond
+
+// Watcher polls the files matching a set of glob patterns, and reports the ones that were created, modified or
+// removed. Polling is portable and works on network filesystems, at the cost of some latency.
+type Watcher struct {
+	patterns []string
+	interval time.Duration
+}
+
+type watchedFile struct {
+	modTime time.Time
+	size    int64
+	mode    fs.FileMode
+}
+
+// NewWatcher returns a watcher polling every interval (defaults to 500ms) the files matching patterns (see Glob).
+func NewWatcher(interval time.Duration, patterns ...string) (*Watcher, error) {
+	for _, pattern := range patterns {
+		for _, segment := range strings.Split(filepath.ToSlash(pattern), "/") {
+			if _, err := filepath.Match(segment, ""); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if interval <= 0 {
+		interval = defaultWatchInterval
+	}
+
+	return &Watcher{patterns: patterns, interval: interval}, nil
+}
+
+// Watch polls until ctx is done, sending the sorted list of changed files after each poll that found some.
+// The channel is closed when ctx is done.
+func (w *Watcher) Watch(ctx context.Context) <-chan []string {
+	changes := make(chan []string)
+
+	go func() {
+		defer close(changes)
+
+		previous := w.snapshot()
+		ticker := time.NewTicker(w.interval)
+
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			current := w.snapshot()
+			changed := diffSnapshots(previous, current)
+			previous = current
+
+			if len(changed) == 0 {
+				continue
+			}
+
+			select {
+			case changes <- changed:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return changes
+}
+
+func (w *Watcher) snapshot() map[string]*watchedFile {
+	files := map[string]*watchedFile{}
+
+	for _, pattern := range w.patterns {
+		matches, _ := Glob(pattern)
+
+		for _, match := range matches {
+			if info, err := os.Stat(match); err == nil && !info.IsDir() {
+				files[match] = &watchedFile{modTime: info.ModTime(), size: info.Size(), mode: info.Mode()}
+			}
+		}
+	}
+
+	return files
+}
+
+func diffSnapshots(previous map[string]*watchedFile, current map[string]*watchedFile) []string {
+	changed := []string{}
+
+	for name, file := range current {
+		if old, ok := previous[name]; !ok || *old != *file {
+			changed = append(changed, name)
+		}
+	}
+
+	for name := range previous {
+		if _, ok := current[name]; !ok {
+			changed = append(changed, name)
+		}
+	}
+
+	sort.Strings(changed)
+
+	return changed
+}
+
+// Glob is filepath.Glob, with support for "**" segments matching any number of directories (eg: "src/**/*.go").
+func Glob(pattern string) ([]string, error) {
+	if !strings.Contains(pattern, "**") {
+		return filepath.Glob(pattern)
+	}
+
+	segments := strings.Split(filepath.ToSlash(pattern), "/")
+
+	static := 0
+	for static < len(segments) && !strings.ContainsAny(segments[static], `*?[\`) {
+		static++
+	}
+
+	root := strings.Join(segments[:static], "/")
+	if root == "" {
+		root = "."
+		if static > 0 {
+			root = "/"
+		}
+	}
+
+	root = filepath.FromSlash(root)
+	segments = segments[static:]
+
+	for _, segment := range segments {
+		if _, err := filepath.Match(segment, ""); err != nil {
+			return nil, err
+		}
+	}
+
+	var matches []string
+
+	err := filepath.WalkDir(root, func(name string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			// Unreadable directories are skipped, like filepath.Glob does
+			return nil //nolint:nilerr
+		}
+
+		rel, err := filepath.Rel(root, name)
+		if err != nil {
+			return nil //nolint:nilerr
+		}
+
+		if matchSegments(segments, strings.Split(filepath.ToSlash(rel), "/")) {
+			matches = append(matches, name)
+		}
+
+		return nil
+	})
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	return matches, nil
+}
+
+func matchSegments(pattern []string, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+
+			return false
+		}
+
+		if len(name) == 0 {
+			return false
+		}
+
+		if ok, _ := filepath.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+
+		pattern, name = pattern[1:], name[1:]
+	}
+
+	return len(name) == 0
+}
@@ -5,18 +5,20 @@ go 1.19
 require (
 	github.com/getsentry/sentry-go v0.21.0
 	github.com/getsentry/sentry-go/otel v0.21.0
+	github.com/go-logr/logr v1.2.4
 	github.com/mattn/go-colorable v0.1.13
+	github.com/mattn/go-isatty v0.0.18
+	github.com/pkg/errors v0.9.1
 	github.com/rs/zerolog v1.29.1
 	go.opentelemetry.io/otel v1.15.1
 	go.opentelemetry.io/otel/exporters/jaeger v1.15.1
 	go.opentelemetry.io/otel/sdk v1.15.1
 	go.opentelemetry.io/otel/trace v1.15.1
+	golang.org/x/net v0.10.0
+	golang.org/x/sys v0.8.0
 )
 
 require (
-	github.com/go-logr/logr v1.2.4 // indirect
 	github.com/go-logr/stdr v1.2.2 // indirect
-	github.com/mattn/go-isatty v0.0.18 // indirect
-	golang.org/x/sys v0.8.0 // indirect
 	golang.org/x/text v0.9.0 // indirect
 )
@@ -0,0 +1,35 @@
+// Package id provides random identifiers.
+package id
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"encoding/hex"
+	"strings"
+)
+
+const (
+	shortLength = 5
+	longLength  = 16
+)
+
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding) //nolint:gochecknoglobals
+
+// Short returns a short (8 characters), human friendly random identifier, suitable for referencing log lines.
+// It is not meant to be globally unique.
+func Short() string {
+	return strings.ToLower(encoding.EncodeToString(random(shortLength)))
+}
+
+// New returns a 128 bits random identifier, hex encoded.
+func New() string {
+	return hex.EncodeToString(random(longLength))
+}
+
+func random(size int) []byte {
+	buf := make([]byte, size)
+	// crypto/rand Read does not fail on supported platforms
+	_, _ = rand.Read(buf)
+
+	return buf
+}
@@ -0,0 +1,96 @@
+package log
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/rs/zerolog"
+)
+
+const clock24Layout = "15:04"
+
+// accessibleLevels are the level prefixes of accessible console output: words, that do not rely on color to be
+// told apart, and that screen readers speak.
+var accessibleLevels = map[string]string{ //nolint:gochecknoglobals
+	zerolog.LevelTraceValue: "TRACE",
+	zerolog.LevelDebugValue: "DEBUG",
+	zerolog.LevelInfoValue:  "INFO",
+	zerolog.LevelWarnValue:  "WARNING",
+	zerolog.LevelErrorValue: "ERROR",
+	zerolog.LevelFatalValue: "FATAL",
+	zerolog.LevelPanicValue: "PANIC",
+}
+
+// twelveHourLocales are the locales (language, or language_TERRITORY) whose users expect 12-hour clocks.
+var twelveHourLocales = []string{ //nolint:gochecknoglobals
+	"en_US", "en_CA", "en_AU", "en_NZ", "en_IN", "en_PH", "es_US", "es_MX", "hi_IN", "ko_KR", "zh_TW", "ar",
+}
+
+// DetectAccessible tells whether the environment calls for accessible console output: CODECOMET_LOG_ACCESSIBLE if
+// set to a boolean, or else a dumb terminal (eg: the Emacs shell, or a terminal driven by a screen reader), which
+// cannot render colors.
+func DetectAccessible() bool {
+	if accessible, err := strconv.ParseBool(os.Getenv("CODECOMET_LOG_ACCESSIBLE")); err == nil {
+		return accessible
+	}
+
+	return os.Getenv("TERM") == "dumb"
+}
+
+// DetectNoColor honors the NO_COLOR convention (https://no-color.org).
+func DetectNoColor() bool {
+	return os.Getenv("NO_COLOR") != ""
+}
+
+// localeTimeFormat returns a 12 or 24-hour clock layout, according to the locale of the environment (LC_ALL,
+// LC_TIME, then LANG). Unknown locales get a 24-hour clock.
+func localeTimeFormat() string {
+	locale := ""
+
+	for _, name := range []string{"LC_ALL", "LC_TIME", "LANG"} {
+		if locale = os.Getenv(name); locale != "" {
+			break
+		}
+	}
+
+	// Drop the encoding and modifier (eg: en_US.UTF-8@euro)
+	locale, _, _ = strings.Cut(locale, ".")
+	locale, _, _ = strings.Cut(locale, "@")
+	language, _, _ := strings.Cut(locale, "_")
+
+	for _, candidate := range twelveHourLocales {
+		if candidate == locale || candidate == language {
+			return consoleDefaultTimeFormat
+		}
+	}
+
+	return clock24Layout
+}
+
+// consoleAccessibleFormatLevel renders levels as words. Colors, if any, are kept as a complement.
+func consoleAccessibleFormatLevel(noColor bool) Formatter {
+	return func(i interface{}) string {
+		level, _ := i.(string)
+
+		word, ok := accessibleLevels[level]
+		color := colorBold
+
+		switch {
+		case ok:
+			if lvl, err := zerolog.ParseLevel(level); err == nil && lvl >= zerolog.WarnLevel {
+				color = colorRed
+			}
+		case level != "":
+			word = strings.ToUpper(level)
+			if _, custom := lookupLevelName(level); custom != nil {
+				color = custom.Color
+			}
+		default:
+			word = "UNKNOWN"
+		}
+
+		return colorize(fmt.Sprintf("%-8s", word+":"), color, noColor)
+	}
+}
@@ -0,0 +1,205 @@
+// Package audit provides a tamper-evident, append-only log for security relevant events.
+// Each record carries the hash of the previous one, so that edits, removals and reordering can be detected by Verify.
+// Note that the chain alone cannot detect removal of the most recent records: callers who care should store
+// the Head returned by Verify or Logger.Head out of band, and compare.
+package audit
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"go.codecomet.dev/core/filesystem"
+)
+
+// Record is a single audit entry.
+type Record struct {
+	Seq      uint64    `json:"seq"`
+	Time     time.Time `json:"time"`
+	Actor    string    `json:"actor"`
+	Action   string    `json:"action"`
+	Target   string    `json:"target"`
+	Result   string    `json:"result"`
+	Previous string    `json:"prev"`
+	Hash     string    `json:"hash,omitempty"`
+}
+
+// Head identifies the last record of a log.
+type Head struct {
+	Seq  uint64 `json:"seq"`
+	Hash string `json:"hash"`
+}
+
+func (rec *Record) validate() error {
+	if rec.Actor == "" || rec.Action == "" || rec.Target == "" || rec.Result == "" {
+		return fmt.Errorf("%w: actor, action, target and result are all required", ErrInvalidRecord)
+	}
+
+	return nil
+}
+
+// digest computes the record hash, over its canonical json form without the hash itself.
+func (rec *Record) digest() (string, error) {
+	clone := *rec
+	clone.Hash = ""
+
+	data, err := json.Marshal(&clone)
+	if err != nil {
+		return "", fmt.Errorf("failed marshalling audit record: %w", err)
+	}
+
+	sum := sha256.Sum256(data)
+
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Logger appends records to an audit file.
+type Logger struct {
+	mu   sync.Mutex
+	file *os.File
+	head *Head
+}
+
+// Open opens (or creates) the audit log at location. Existing content is verified first.
+func Open(location string) (*Logger, error) {
+	head, err := Verify(location)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(location, os.O_APPEND|os.O_CREATE|os.O_WRONLY, filesystem.FilePermissionsPrivate)
+	if err != nil {
+		return nil, fmt.Errorf("failed opening audit log: %w", err)
+	}
+
+	return &Logger{
+		file: file,
+		head: head,
+	}, nil
+}
+
+// Record appends a new record to the log.
+func (l *Logger) Record(actor string, action string, target string, result string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	rec := &Record{
+		Time:   time.Now().UTC(),
+		Actor:  actor,
+		Action: action,
+		Target: target,
+		Result: result,
+	}
+
+	if l.head != nil {
+		rec.Seq = l.head.Seq + 1
+		rec.Previous = l.head.Hash
+	}
+
+	if err := rec.validate(); err != nil {
+		return err
+	}
+
+	hash, err := rec.digest()
+	if err != nil {
+		return err
+	}
+
+	rec.Hash = hash
+
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("failed marshalling audit record: %w", err)
+	}
+
+	if _, err = l.file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed writing audit record: %w", err)
+	}
+
+	// Audit records must hit the disk
+	if err = l.file.Sync(); err != nil {
+		return fmt.Errorf("failed syncing audit log: %w", err)
+	}
+
+	l.head = &Head{Seq: rec.Seq, Hash: rec.Hash}
+
+	return nil
+}
+
+// Head returns the last record identity, or nil if the log is empty.
+func (l *Logger) Head() *Head {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.head == nil {
+		return nil
+	}
+
+	head := *l.head
+
+	return &head
+}
+
+func (l *Logger) Close() error {
+	return l.file.Close()
+}
+
+// Verify walks the entire log and checks the hash chain. It returns the head of the log (nil if empty).
+func Verify(location string) (*Head, error) {
+	file, err := os.Open(location)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var head *Head
+
+	reader := bufio.NewReader(file)
+
+	for line := 1; ; line++ {
+		data, err := reader.ReadBytes('\n')
+		if len(data) == 0 && err != nil {
+			break
+		}
+
+		if err != nil {
+			return head, fmt.Errorf("%w: line %d is truncated", ErrTampered, line)
+		}
+
+		rec := &Record{}
+		if err = json.Unmarshal(data, rec); err != nil {
+			return head, fmt.Errorf("%w: line %d is not a valid record: %s", ErrTampered, line, err)
+		}
+
+		if err = rec.validate(); err != nil {
+			return head, fmt.Errorf("%w: line %d: %s", ErrTampered, line, err)
+		}
+
+		expectedSeq, expectedPrev := uint64(0), ""
+		if head != nil {
+			expectedSeq, expectedPrev = head.Seq+1, head.Hash
+		}
+
+		if rec.Seq != expectedSeq || rec.Previous != expectedPrev {
+			return head, fmt.Errorf("%w: line %d breaks the chain", ErrTampered, line)
+		}
+
+		hash, err := rec.digest()
+		if err != nil {
+			return head, err
+		}
+
+		if hash != rec.Hash {
+			return head, fmt.Errorf("%w: line %d content does not match its hash", ErrTampered, line)
+		}
+
+		head = &Head{Seq: rec.Seq, Hash: rec.Hash}
+	}
+
+	return head, nil
+}
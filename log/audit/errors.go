@@ -0,0 +1,8 @@
+package audit
+
+import "errors"
+
+var (
+	ErrTampered      = errors.New("audit log has been tampered with")
+	ErrInvalidRecord = errors.New("invalid audit record")
+)
@@ -0,0 +1,124 @@
+package log
+
+import (
+	"bytes"
+	"fmt"
+	stdlog "log"
+
+	"github.com/go-logr/logr"
+	"github.com/rs/zerolog"
+)
+
+// Logr returns a logr.Logger writing through this package, tagged with ctx, for dependencies built on logr
+// (controller-runtime, client-go, otel...). Verbosity 0 logs at info level, 1 at debug, and beyond at trace.
+// Names are appended to ctx (eg: ctx/manager/controller).
+func Logr(ctx string) logr.Logger {
+	return logr.New(&logrSink{ctx: ctx})
+}
+
+// StdLogger returns a standard library logger writing every line through this package, with level, tagged
+// with ctx.
+func StdLogger(ctx string, level Level) *stdlog.Logger {
+	return stdlog.New(&stdWriter{ctx: ctx, level: level}, "", 0)
+}
+
+// RedirectStdLog routes the output of the standard library default logger (log.Print, etc) through this package,
+// with level, tagged with ctx.
+func RedirectStdLog(ctx string, level Level) {
+	stdlog.SetFlags(0)
+	stdlog.SetPrefix("")
+	stdlog.SetOutput(&stdWriter{ctx: ctx, level: level})
+}
+
+// stdWriter logs every line written to it.
+type stdWriter struct {
+	ctx   string
+	level Level
+}
+
+func (w *stdWriter) Write(data []byte) (int, error) {
+	for _, line := range bytes.Split(bytes.TrimRight(data, "\n"), []byte("\n")) {
+		if evt := WithLevel(w.level); evt != nil {
+			evt.Str(ContextFieldName, w.ctx).Msg(string(line))
+		}
+	}
+
+	return len(data), nil
+}
+
+// logrSink implements logr.LogSink.
+type logrSink struct {
+	ctx    string
+	values []interface{}
+}
+
+func (sink *logrSink) Init(logr.RuntimeInfo) {}
+
+func (sink *logrSink) level(verbosity int) Level {
+	switch {
+	case verbosity <= 0:
+		return zerolog.InfoLevel
+	case verbosity == 1:
+		return zerolog.DebugLevel
+	default:
+		return zerolog.TraceLevel
+	}
+}
+
+func (sink *logrSink) Enabled(verbosity int) bool {
+	return sink.level(verbosity) >= GetLevel()
+}
+
+func (sink *logrSink) Info(verbosity int, msg string, keysAndValues ...interface{}) {
+	sink.write(WithLevel(sink.level(verbosity)), msg, keysAndValues)
+}
+
+func (sink *logrSink) Error(err error, msg string, keysAndValues ...interface{}) {
+	sink.write(Error().Err(err), msg, keysAndValues)
+}
+
+func (sink *logrSink) write(evt *Event, msg string, keysAndValues []interface{}) {
+	if evt == nil {
+		return
+	}
+
+	evt.Str(ContextFieldName, sink.ctx)
+	fields(evt, sink.values)
+	fields(evt, keysAndValues)
+	evt.Msg(msg)
+}
+
+func (sink *logrSink) WithValues(keysAndValues ...interface{}) logr.LogSink {
+	child := *sink
+	child.values = append(append([]interface{}{}, sink.values...), keysAndValues...)
+
+	return &child
+}
+
+func (sink *logrSink) WithName(name string) logr.LogSink {
+	child := *sink
+	child.ctx = sink.ctx + "/" + name
+
+	return &child
+}
+
+// fields adds logr key value pairs to evt. A dangling key gets a nil value, like other logr sinks do.
+func fields(evt *Event, keysAndValues []interface{}) {
+	for i := 0; i < len(keysAndValues); i += 2 {
+		key, ok := keysAndValues[i].(string)
+		if !ok {
+			key = fmt.Sprint(keysAndValues[i])
+		}
+
+		var value interface{}
+		if i+1 < len(keysAndValues) {
+			value = keysAndValues[i+1]
+		}
+
+		if err, ok := value.(error); ok {
+			evt.AnErr(key, err)
+		} else {
+			evt.Interface(key, value)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package log
+
+import (
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// BudgetFieldName is the field holding the warning key of budgeted events.
+var BudgetFieldName = "budget" //nolint:gochecknoglobals
+
+// Budget is the number of warnings allowed for a key within a window, before escalating to an error.
+type Budget struct {
+	Count  int           `json:"count"`
+	Window time.Duration `json:"window"`
+}
+
+// EscalationFunc is called when a warning key exceeds its budget.
+type EscalationFunc func(key string, count int, window time.Duration)
+
+type budgetState struct {
+	budget *Budget
+	seen   []time.Time
+}
+
+var (
+	defaultBudget *Budget                     //nolint:gochecknoglobals
+	budgets       = map[string]*Budget{}      //nolint:gochecknoglobals
+	budgetStates  = map[string]*budgetState{} //nolint:gochecknoglobals
+	escalations   []EscalationFunc            //nolint:gochecknoglobals
+	budgetMu      sync.Mutex                  //nolint:gochecknoglobals
+)
+
+// SetBudget sets the warning budget for key, overriding the default one. A nil budget restores the default.
+func SetBudget(key string, budget *Budget) {
+	budgetMu.Lock()
+	defer budgetMu.Unlock()
+
+	if budget == nil {
+		delete(budgets, key)
+	} else {
+		budgets[key] = budget
+	}
+
+	delete(budgetStates, key)
+}
+
+// SetDefaultBudget sets the budget applying to keys without a specific one. Nil disables escalation for them.
+func SetDefaultBudget(budget *Budget) {
+	budgetMu.Lock()
+	defer budgetMu.Unlock()
+
+	defaultBudget = budget
+	budgetStates = map[string]*budgetState{}
+}
+
+// OnEscalation registers a function called every time a warning key exceeds its budget (eg: to page someone).
+func OnEscalation(handler EscalationFunc) {
+	budgetMu.Lock()
+	defer budgetMu.Unlock()
+
+	escalations = append(escalations, handler)
+}
+
+// WarnBudget starts a warning event for key. Once more than Count warnings for that key happened within
+// Window, the event is escalated to an error instead, escalation handlers are called, and counting starts over.
+func WarnBudget(key string) *zerolog.Event {
+	count, budget, handlers := spend(key)
+	if budget == nil || count <= budget.Count {
+		return log.Warn().Str(BudgetFieldName, key)
+	}
+
+	for _, handler := range handlers {
+		handler(key, count, budget.Window)
+	}
+
+	return log.Error().
+		Str(BudgetFieldName, key).
+		Bool("escalated", true).
+		Int("occurrences", count).
+		Dur("window", budget.Window)
+}
+
+// spend records a warning for key, and returns the number of warnings within the window.
+func spend(key string) (int, *Budget, []EscalationFunc) {
+	budgetMu.Lock()
+	defer budgetMu.Unlock()
+
+	budget, ok := budgets[key]
+	if !ok {
+		budget = defaultBudget
+	}
+
+	if budget == nil || budget.Count <= 0 {
+		return 0, nil, nil
+	}
+
+	state, ok := budgetStates[key]
+	if !ok || state.budget != budget {
+		state = &budgetState{budget: budget}
+		budgetStates[key] = state
+	}
+
+	current := now()
+	kept := state.seen[:0]
+
+	for _, seen := range state.seen {
+		if current.Sub(seen) < budget.Window {
+			kept = append(kept, seen)
+		}
+	}
+
+	state.seen = append(kept, current)
+	count := len(state.seen)
+
+	if count <= budget.Count {
+		return count, budget, nil
+	}
+
+	state.seen = nil
+
+	return count, budget, append([]EscalationFunc{}, escalations...)
+}
@@ -0,0 +1,92 @@
+package log
+
+import (
+	"bufio"
+	"io"
+	"sync"
+	"time"
+)
+
+// BufferedWriter coalesces writes into fewer syscalls. Data is flushed when the buffer is full,
+// every flush interval, or explicitly through Flush.
+type BufferedWriter struct {
+	mu     sync.Mutex
+	out    io.Writer
+	buf    *bufio.Writer
+	ticker *time.Ticker
+	done   chan struct{}
+	// stopped is closed once the flush loop has exited
+	stopped chan struct{}
+	close   sync.Once
+}
+
+// NewBufferedWriter returns a BufferedWriter on top of out.
+// If interval is zero or negative, data is only flushed when the buffer is full, or explicitly.
+func NewBufferedWriter(out io.Writer, size int, interval time.Duration) *BufferedWriter {
+	w := &BufferedWriter{
+		out:  out,
+		buf:  bufio.NewWriterSize(out, size),
+		done: make(chan struct{}),
+	}
+
+	if interval > 0 {
+		w.ticker = time.NewTicker(interval)
+		w.stopped = make(chan struct{})
+
+		go w.loop(w.ticker.C, w.done, w.stopped)
+	}
+
+	return w
+}
+
+func (w *BufferedWriter) loop(tick <-chan time.Time, done <-chan struct{}, stopped chan<- struct{}) {
+	defer close(stopped)
+
+	for {
+		select {
+		case <-tick:
+			_ = w.Flush()
+		case <-done:
+			return
+		}
+	}
+}
+
+func (w *BufferedWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.buf.Write(p)
+}
+
+// Flush writes any buffered data to the underlying writer.
+func (w *BufferedWriter) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.buf.Flush()
+}
+
+// Close stops the flush loop, flushes, and closes the underlying writer if it is a Closer.
+func (w *BufferedWriter) Close() error {
+	var err error
+
+	w.close.Do(func() {
+		close(w.done)
+
+		if w.ticker != nil {
+			w.ticker.Stop()
+			<-w.stopped
+		}
+
+		err = w.Flush()
+
+		if closer, ok := w.out.(io.Closer); ok {
+			if cerr := closer.Close(); err == nil {
+				err = cerr
+			}
+		}
+	})
+
+	return err
+}
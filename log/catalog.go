@@ -0,0 +1,74 @@
+package log
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/rs/zerolog"
+)
+
+var (
+	catalog   Catalog      //nolint:gochecknoglobals
+	catalogMu sync.RWMutex //nolint:gochecknoglobals
+)
+
+// Catalog renders a message ID into user-facing text, given the event fields.
+// It returns false if it has no entry for that message, in which case the message is displayed as-is.
+// Only console output is affected: json output retains the original message, for aggregation.
+type Catalog func(msgID string, evt map[string]interface{}) (string, bool)
+
+// SetCatalog sets the global catalog. Pass nil to disable translation.
+func SetCatalog(c Catalog) {
+	catalogMu.Lock()
+	defer catalogMu.Unlock()
+
+	catalog = c
+}
+
+func getCatalog() Catalog {
+	catalogMu.RLock()
+	defer catalogMu.RUnlock()
+
+	return catalog
+}
+
+// MapCatalog returns a Catalog backed by a map of message IDs to templates.
+// Templates may reference event fields with {field}.
+func MapCatalog(templates map[string]string) Catalog {
+	return func(msgID string, evt map[string]interface{}) (string, bool) {
+		template, ok := templates[msgID]
+		if !ok {
+			return "", false
+		}
+
+		replacements := make([]string, 0, len(evt)*2) //nolint:gomnd
+		for field, value := range evt {
+			replacements = append(replacements, "{"+field+"}", fmt.Sprintf("%v", value))
+		}
+
+		return strings.NewReplacer(replacements...).Replace(template), true
+	}
+}
+
+// translate applies the catalog c (or the global one if nil) to the event message.
+func translate(c Catalog, evt map[string]interface{}) interface{} {
+	msg, ok := evt[zerolog.MessageFieldName].(string)
+	if !ok {
+		return evt[zerolog.MessageFieldName]
+	}
+
+	if c == nil {
+		c = getCatalog()
+	}
+
+	if c == nil {
+		return msg
+	}
+
+	if text, ok := c(msg, evt); ok {
+		return text
+	}
+
+	return msg
+}
@@ -0,0 +1,433 @@
+package log
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// CBOR major types and additional information, as written by zerolog when built with the binary_log tag.
+const (
+	cborUnsignedInt = 0
+	cborNegativeInt = 1
+	cborByteString  = 2
+	cborTextString  = 3
+	cborArray       = 4
+	cborMap         = 5
+	cborTag         = 6
+
+	cborUint8      = 24
+	cborUint16     = 25
+	cborUint32     = 26
+	cborUint64     = 27
+	cborIndefinite = 31
+	cborBreak      = 0xff
+
+	cborFalse = 20
+	cborTrue  = 21
+	cborNull  = 22
+
+	cborTagTimestamp     = 1
+	cborTagNetworkAddr   = 260
+	cborTagNetworkPrefix = 261
+	cborTagEmbeddedJSON  = 262
+	cborTagHexString     = 263
+
+	cborMaxDepth = 64
+)
+
+var (
+	errCBORTruncated = errors.New("truncated binary event")
+	errCBORDepth     = errors.New("binary event nested too deeply")
+)
+
+// isBinaryEvent tells whether p is a binary (CBOR) event rather than JSON: zerolog starts binary events with a map
+// header, which is never printable.
+func isBinaryEvent(p []byte) bool {
+	return len(p) > 0 && p[0] > 0x7F
+}
+
+// decodeEvent decodes a JSON or binary event, with numbers as json.Number.
+func decodeEvent(p []byte) (map[string]interface{}, error) {
+	// Services built with the binary_log tag write CBOR events
+	if isBinaryEvent(p) {
+		return decodeBinaryEvent(p)
+	}
+
+	var evt map[string]interface{}
+
+	d := json.NewDecoder(bytes.NewReader(p))
+	d.UseNumber()
+
+	err := d.Decode(&evt)
+
+	return evt, err
+}
+
+// decodeBinaryEvent decodes a zerolog binary event into the same form as a JSON event decoded with UseNumber:
+// numbers are json.Number, timestamps are rendered in zerolog.TimeFieldFormat, and network addresses as strings.
+func decodeBinaryEvent(p []byte) (map[string]interface{}, error) {
+	dec := &cborDecoder{data: p}
+
+	value, err := dec.value(0)
+	if err != nil {
+		return nil, err
+	}
+
+	evt, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("binary event is not a map: %T", value)
+	}
+
+	return evt, nil
+}
+
+type cborDecoder struct {
+	data []byte
+	pos  int
+}
+
+func (dec *cborDecoder) byte() (byte, error) {
+	if dec.pos >= len(dec.data) {
+		return 0, errCBORTruncated
+	}
+
+	b := dec.data[dec.pos]
+	dec.pos++
+
+	return b, nil
+}
+
+func (dec *cborDecoder) bytes(n uint64) ([]byte, error) {
+	if n > uint64(len(dec.data)-dec.pos) {
+		return nil, errCBORTruncated
+	}
+
+	b := dec.data[dec.pos : dec.pos+int(n)]
+	dec.pos += int(n)
+
+	return b, nil
+}
+
+// header reads an item header, returning its major type, additional information and argument.
+func (dec *cborDecoder) header() (byte, byte, uint64, error) {
+	b, err := dec.byte()
+	if err != nil {
+		return 0, 0, 0, err
+	}
+
+	major, info := b>>5, b&0x1f
+
+	var size uint64
+
+	switch info {
+	case cborUint8:
+		size = 1
+	case cborUint16:
+		size = 2
+	case cborUint32:
+		size = 4
+	case cborUint64:
+		size = 8
+	default:
+		return major, info, uint64(info), nil
+	}
+
+	raw, err := dec.bytes(size)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+
+	var arg uint64
+	for _, c := range raw {
+		arg = arg<<8 | uint64(c)
+	}
+
+	return major, info, arg, nil
+}
+
+// breaks consumes the break marker ending an indefinite length item, if it is next.
+func (dec *cborDecoder) breaks() bool {
+	if dec.pos < len(dec.data) && dec.data[dec.pos] == cborBreak {
+		dec.pos++
+
+		return true
+	}
+
+	return false
+}
+
+//nolint:gocyclo,cyclop
+func (dec *cborDecoder) value(depth int) (interface{}, error) {
+	if depth > cborMaxDepth {
+		return nil, errCBORDepth
+	}
+
+	major, info, arg, err := dec.header()
+	if err != nil {
+		return nil, err
+	}
+
+	switch major {
+	case cborUnsignedInt:
+		return json.Number(strconv.FormatUint(arg, 10)), nil
+	case cborNegativeInt:
+		if arg > math.MaxInt64 {
+			n := new(big.Int).SetUint64(arg)
+
+			return json.Number(n.Neg(n.Add(n, big.NewInt(1))).String()), nil
+		}
+
+		return json.Number(strconv.FormatInt(-1-int64(arg), 10)), nil
+	case cborByteString, cborTextString:
+		b, err := dec.string(major, info, arg)
+
+		return string(b), err
+	case cborArray:
+		list := []interface{}{}
+
+		for i := uint64(0); info == cborIndefinite || i < arg; i++ {
+			if info == cborIndefinite && dec.breaks() {
+				break
+			}
+
+			item, err := dec.value(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+
+			list = append(list, item)
+		}
+
+		return list, nil
+	case cborMap:
+		obj := map[string]interface{}{}
+
+		for i := uint64(0); info == cborIndefinite || i < arg; i++ {
+			if info == cborIndefinite && dec.breaks() {
+				break
+			}
+
+			key, err := dec.value(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+
+			item, err := dec.value(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+
+			obj[fmt.Sprint(key)] = item
+		}
+
+		return obj, nil
+	case cborTag:
+		return dec.tag(arg, depth)
+	default:
+		return dec.simple(info, arg)
+	}
+}
+
+// string reads the content of a byte or text string, concatenating the chunks of indefinite length strings.
+func (dec *cborDecoder) string(major byte, info byte, arg uint64) ([]byte, error) {
+	if info != cborIndefinite {
+		return dec.bytes(arg)
+	}
+
+	var out []byte
+
+	for !dec.breaks() {
+		chunkMajor, chunkInfo, chunkArg, err := dec.header()
+		if err != nil {
+			return nil, err
+		}
+
+		if chunkMajor != major || chunkInfo == cborIndefinite {
+			return nil, fmt.Errorf("invalid chunk of type %d in indefinite string", chunkMajor)
+		}
+
+		chunk, err := dec.bytes(chunkArg)
+		if err != nil {
+			return nil, err
+		}
+
+		out = append(out, chunk...)
+	}
+
+	return out, nil
+}
+
+func (dec *cborDecoder) simple(info byte, arg uint64) (interface{}, error) {
+	switch info {
+	case cborFalse:
+		return false, nil
+	case cborTrue:
+		return true, nil
+	case cborNull:
+		return nil, nil
+	case cborUint16:
+		return float(float64(halfToFloat(uint16(arg))), 32), nil
+	case cborUint32:
+		return float(float64(math.Float32frombits(uint32(arg))), 32), nil
+	case cborUint64:
+		return float(math.Float64frombits(arg), 64), nil
+	default:
+		return nil, fmt.Errorf("unsupported simple value %d in binary event", info)
+	}
+}
+
+// tag decodes the tagged items zerolog writes: timestamps, embedded JSON, network addresses and hex strings.
+//
+//nolint:gocyclo,cyclop
+func (dec *cborDecoder) tag(tag uint64, depth int) (interface{}, error) {
+	switch tag {
+	case cborTagTimestamp:
+		value, err := dec.value(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+
+		return binaryTime(value)
+	case cborTagEmbeddedJSON:
+		raw, err := dec.value(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+
+		var embedded interface{}
+
+		d := json.NewDecoder(bytes.NewReader([]byte(fmt.Sprint(raw))))
+		d.UseNumber()
+
+		if err = d.Decode(&embedded); err != nil {
+			return nil, fmt.Errorf("cannot decode embedded JSON: %w", err)
+		}
+
+		return embedded, nil
+	case cborTagNetworkAddr:
+		raw, err := dec.value(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+
+		octets := []byte(fmt.Sprint(raw))
+
+		switch len(octets) {
+		case 6: //nolint:gomnd
+			return net.HardwareAddr(octets).String(), nil
+		case net.IPv4len, net.IPv6len:
+			return net.IP(octets).String(), nil
+		default:
+			return nil, fmt.Errorf("unexpected network address length %d in binary event", len(octets))
+		}
+	case cborTagNetworkPrefix:
+		raw, err := dec.value(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+
+		prefix, ok := raw.(map[string]interface{})
+		if !ok || len(prefix) != 1 {
+			return nil, errors.New("network prefix in binary event is not a map of one element")
+		}
+
+		var network net.IPNet
+
+		for ip, length := range prefix {
+			bits, err := strconv.Atoi(fmt.Sprint(length))
+			if err != nil {
+				return nil, fmt.Errorf("invalid network prefix length in binary event: %w", err)
+			}
+
+			network = net.IPNet{IP: net.IP(ip), Mask: net.CIDRMask(bits, len(ip)*8)} //nolint:gomnd
+		}
+
+		return network.String(), nil
+	case cborTagHexString:
+		raw, err := dec.value(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+
+		return hex.EncodeToString([]byte(fmt.Sprint(raw))), nil
+	}
+
+	// Unknown tags do not change the meaning of what they tag for display purposes
+	return dec.value(depth + 1)
+}
+
+// binaryTime renders a binary timestamp (seconds since the epoch) the way zerolog renders it in JSON, so that the
+// console timestamp formatter handles both alike.
+func binaryTime(value interface{}) (interface{}, error) {
+	number, ok := value.(json.Number)
+	if !ok {
+		return nil, fmt.Errorf("unexpected timestamp type %T in binary event", value)
+	}
+
+	seconds, err := number.Float64()
+	if err != nil {
+		return nil, fmt.Errorf("invalid timestamp in binary event: %w", err)
+	}
+
+	whole := math.Floor(seconds)
+	t := time.Unix(int64(whole), int64((seconds-whole)*float64(time.Second))).UTC()
+
+	switch zerolog.TimeFieldFormat {
+	case zerolog.TimeFormatUnix:
+		return json.Number(strconv.FormatInt(t.Unix(), 10)), nil
+	case zerolog.TimeFormatUnixMs:
+		return json.Number(strconv.FormatInt(t.UnixMilli(), 10)), nil
+	case zerolog.TimeFormatUnixMicro:
+		return json.Number(strconv.FormatInt(t.UnixMicro(), 10)), nil
+	case zerolog.TimeFormatUnixNano:
+		return json.Number(strconv.FormatInt(t.UnixNano(), 10)), nil
+	default:
+		return t.Format(zerolog.TimeFieldFormat), nil
+	}
+}
+
+// float renders a float as zerolog does in JSON, where non finite values are strings.
+func float(f float64, bits int) interface{} {
+	switch {
+	case math.IsNaN(f):
+		return "NaN"
+	case math.IsInf(f, 1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	}
+
+	return json.Number(strconv.FormatFloat(f, 'f', -1, bits))
+}
+
+// halfToFloat converts an IEEE 754 half precision float.
+func halfToFloat(half uint16) float32 {
+	sign := uint32(half>>15) << 31 //nolint:gomnd
+	exp := uint32(half>>10) & 0x1f //nolint:gomnd
+	mant := uint32(half) & 0x3ff   //nolint:gomnd
+
+	switch exp {
+	case 0:
+		// Subnormal
+		f := float32(mant) / (1 << 24) //nolint:gomnd
+		if sign != 0 {
+			f = -f
+		}
+
+		return f
+	case 0x1f: //nolint:gomnd
+		return math.Float32frombits(sign | 0x7f800000 | mant<<13) //nolint:gomnd
+	}
+
+	return math.Float32frombits(sign | (exp+112)<<23 | mant<<13) //nolint:gomnd
+}
@@ -0,0 +1,130 @@
+package log
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// CIProvider selects the grouping and annotation syntax used on the console, for CI providers that fold or
+// highlight log sections in their UI.
+type CIProvider string
+
+const (
+	// CIAuto detects the provider from the environment
+	CIAuto CIProvider = "auto"
+	// CINone disables CI formatting
+	CINone      CIProvider = "none"
+	CIGitHub    CIProvider = "github"
+	CIGitLab    CIProvider = "gitlab"
+	CIBuildkite CIProvider = "buildkite"
+)
+
+var (
+	ciCurrent     = CINone                              //nolint:gochecknoglobals
+	ciMu          sync.Mutex                            //nolint:gochecknoglobals
+	ciSectionName = regexp.MustCompile(`[^a-z0-9_.-]+`) //nolint:gochecknoglobals
+	ciSections    int                                   //nolint:gochecknoglobals
+)
+
+// DetectCI returns the CI provider we are running under, from its well-known environment variables, or CINone.
+func DetectCI() CIProvider {
+	switch {
+	case os.Getenv("GITHUB_ACTIONS") == "true":
+		return CIGitHub
+	case os.Getenv("GITLAB_CI") == "true":
+		return CIGitLab
+	case os.Getenv("BUILDKITE") == "true":
+		return CIBuildkite
+	}
+
+	return CINone
+}
+
+// Group starts a foldable section titled title in the console output, and returns the function closing it.
+// Groups do not nest: starting a group while another one is open implicitly closes it on most providers.
+// Outside of CI, Group is a no-op.
+func Group(title string) func() {
+	ciMu.Lock()
+	provider := ciCurrent
+	ciSections++
+	name := fmt.Sprintf("%s_%d", strings.Trim(ciSectionName.ReplaceAllString(strings.ToLower(title), "_"), "_"),
+		ciSections)
+	ciMu.Unlock()
+
+	title = strings.ReplaceAll(title, "\n", " ")
+
+	switch provider { //nolint:exhaustive
+	case CIGitHub:
+		_, _ = io.WriteString(statusOut, "::group::"+title+"\n")
+
+		return func() {
+			_, _ = io.WriteString(statusOut, "::endgroup::\n")
+		}
+	case CIGitLab:
+		_, _ = fmt.Fprintf(statusOut, "\x1b[0Ksection_start:%d:%s[collapsed=true]\r\x1b[0K%s\n",
+			time.Now().Unix(), name, title)
+
+		return func() {
+			_, _ = fmt.Fprintf(statusOut, "\x1b[0Ksection_end:%d:%s\r\x1b[0K\n", time.Now().Unix(), name)
+		}
+	case CIBuildkite:
+		_, _ = io.WriteString(statusOut, "--- "+title+"\n")
+	}
+
+	return func() {}
+}
+
+// setCI sets the provider used by Group, resolving CIAuto.
+func setCI(provider CIProvider) CIProvider {
+	if provider == "" || provider == CIAuto {
+		provider = DetectCI()
+	}
+
+	ciMu.Lock()
+	ciCurrent = provider
+	ciMu.Unlock()
+
+	return provider
+}
+
+// ciAnnotate wraps a formatted console line with the annotation syntax of the provider, for warning and error
+// levels: GitHub gets workflow commands (highlighted in the run summary), Buildkite expands the current group.
+func ciAnnotate(provider CIProvider, level string, line []byte) []byte {
+	severity, err := zerolog.ParseLevel(level)
+	if err != nil {
+		if custom, info := lookupLevelName(level); info != nil {
+			severity = Severity(custom)
+		}
+	}
+
+	if severity < WarnLevel || severity > PanicLevel {
+		return line
+	}
+
+	switch provider { //nolint:exhaustive
+	case CIGitHub:
+		command := "::error::"
+		if severity == WarnLevel {
+			command = "::warning::"
+		}
+
+		// Workflow command values are single line: escape as the runner expects
+		escaped := strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").
+			Replace(strings.TrimSuffix(string(line), "\n"))
+
+		return []byte(command + escaped + "\n")
+	case CIBuildkite:
+		if severity >= ErrorLevel {
+			return append(line, "^^^ +++\n"...)
+		}
+	}
+
+	return line
+}
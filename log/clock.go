@@ -0,0 +1,79 @@
+package log
+
+import (
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+var (
+	clock    Clock      //nolint:gochecknoglobals
+	clockUTC bool       //nolint:gochecknoglobals
+	clockMu  sync.Mutex //nolint:gochecknoglobals
+)
+
+// Clock tells the time of log events and warning budgets. Tests inject one (see SetClock) so that timestamps are
+// deterministic.
+type Clock interface {
+	Now() time.Time
+}
+
+// ClockFunc adapts a function to a Clock.
+type ClockFunc func() time.Time
+
+// Now calls fn.
+func (fn ClockFunc) Now() time.Time {
+	return fn()
+}
+
+// FixedClock always tells the same time.
+type FixedClock time.Time
+
+// Now returns the fixed time.
+func (c FixedClock) Now() time.Time {
+	return time.Time(c)
+}
+
+// SetClock makes the logger take its time from c, until it is called again - nil restores the system clock.
+// Config.TimeUTC still applies.
+func SetClock(c Clock) {
+	clockMu.Lock()
+	defer clockMu.Unlock()
+
+	clock = c
+	zerolog.TimestampFunc = timestamp
+}
+
+// now returns the time of the current clock.
+func now() time.Time {
+	clockMu.Lock()
+	c := clock
+	clockMu.Unlock()
+
+	if c == nil {
+		return time.Now()
+	}
+
+	return c.Now()
+}
+
+// timestamp is the zerolog timestamp function: the time of the current clock, in UTC if configured so.
+func timestamp() time.Time {
+	clockMu.Lock()
+	utc := clockUTC
+	clockMu.Unlock()
+
+	if utc {
+		return now().UTC()
+	}
+
+	return now()
+}
+
+func setClockUTC(utc bool) {
+	clockMu.Lock()
+	defer clockMu.Unlock()
+
+	clockUTC = utc
+}
@@ -11,9 +11,12 @@ import (
 	"strings"
 	"sync"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/mattn/go-colorable"
 	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/pkgerrors"
 )
 
 const (
@@ -42,6 +45,12 @@ var ContextFieldName = "ctx"
 
 var ContextFieldDefault = "core"
 
+var EventIDFieldName = "eid"
+
+// SequenceFieldName holds the sequence number of events (see OrderedWriter). It is not displayed on the console.
+// The underscore keeps it apart from fields of the application (eg: a "seq" of its own).
+var SequenceFieldName = "_seq"
+
 const (
 	consoleDefaultTimeFormat = time.Kitchen
 )
@@ -61,6 +70,9 @@ type CodecometWriter struct {
 	// TimeFormat specifies the format for timestamp in output.
 	TimeFormat string
 
+	// UTC displays timestamps in UTC instead of local time.
+	UTC bool
+
 	// PartsOrder defines the order of parts in output.
 	PartsOrder []string
 
@@ -75,12 +87,29 @@ type CodecometWriter struct {
 	FormatMessage       Formatter
 	FormatContext       Formatter
 	FormatMode          Formatter
+	FormatEventID       Formatter
 	FormatFieldName     Formatter
 	FormatFieldValue    Formatter
 	FormatErrFieldName  Formatter
 	FormatErrFieldValue Formatter
+	FormatErrStack      Formatter
 
 	FormatExtra func(map[string]interface{}, *bytes.Buffer) error
+
+	// Middlewares are run on the decoded event before anything is written (see Middleware)
+	Middlewares []Middleware
+
+	// Catalog translates messages - defaults to the global catalog (see SetCatalog)
+	Catalog Catalog
+
+	// Quoting decides which string values get quoted - defaults to QuoteASCII
+	Quoting QuotePolicy
+
+	// CI wraps warnings and errors with the annotation syntax of that provider - empty or CINone disables it
+	CI CIProvider
+
+	// Accessible renders levels as words instead of relying on color and three letters tags (see DetectAccessible)
+	Accessible bool
 }
 
 // NewCodecometWriter creates and initializes a new CodecometWriter.
@@ -120,20 +149,21 @@ func (w CodecometWriter) Write(p []byte) (n int, err error) {
 		consoleBufPool.Put(buf)
 	}()
 
-	var evt map[string]interface{}
-	// p = decodeIfBinaryToBytes(p)
-	d := json.NewDecoder(bytes.NewReader(p))
-	d.UseNumber()
-	err = d.Decode(&evt)
+	evt, err := decodeEvent(p)
 	if err != nil {
 		return n, fmt.Errorf("cannot decode event: %s", err)
 	}
 
+	if !runMiddlewares(w.Middlewares, evt) {
+		return len(p), nil
+	}
+
 	for _, p := range w.PartsOrder {
 		w.writePart(buf, evt, p)
 	}
 
 	w.writeFields(evt, buf)
+	w.writeStack(evt, buf)
 
 	if w.FormatExtra != nil {
 		err = w.FormatExtra(evt, buf)
@@ -147,6 +177,13 @@ func (w CodecometWriter) Write(p []byte) (n int, err error) {
 		return n, err
 	}
 
+	if w.CI != "" && w.CI != CINone {
+		level, _ := evt[zerolog.LevelFieldName].(string)
+		_, err = w.Out.Write(ciAnnotate(w.CI, level, buf.Bytes()))
+
+		return len(p), err
+	}
+
 	_, err = buf.WriteTo(w.Out)
 	return len(p), err
 }
@@ -171,7 +208,8 @@ func (w CodecometWriter) writeFields(evt map[string]interface{}, buf *bytes.Buff
 		}
 
 		switch field {
-		case zerolog.LevelFieldName, zerolog.TimestampFieldName, zerolog.MessageFieldName, zerolog.CallerFieldName, ContextFieldName, ModeFieldName:
+		case zerolog.LevelFieldName, zerolog.TimestampFieldName, zerolog.MessageFieldName, zerolog.CallerFieldName,
+			zerolog.ErrorStackFieldName, ContextFieldName, ModeFieldName, EventIDFieldName, SequenceFieldName:
 			continue
 		}
 
@@ -233,9 +271,19 @@ func (w CodecometWriter) writeFields(evt map[string]interface{}, buf *bytes.Buff
 
 		buf.WriteString(fn(field))
 
+		if chain, ok := evt[field].([]interface{}); ok && field == zerolog.ErrorFieldName {
+			buf.WriteString(fv(formatErrorChain(chain)))
+
+			if i < len(fields)-1 {
+				buf.WriteByte(' ')
+			}
+
+			continue
+		}
+
 		switch fValue := evt[field].(type) {
 		case string:
-			if needsQuote(fValue) {
+			if w.Quoting.needsQuote(fValue) {
 				buf.WriteString(fv(strconv.Quote(fValue)))
 			} else {
 				buf.WriteString(fv(fValue))
@@ -257,6 +305,21 @@ func (w CodecometWriter) writeFields(evt map[string]interface{}, buf *bytes.Buff
 	}
 }
 
+// writeStack appends the error stack, if any, to buf.
+func (w CodecometWriter) writeStack(evt map[string]interface{}, buf *bytes.Buffer) {
+	stack, ok := evt[zerolog.ErrorStackFieldName]
+	if !ok || stack == nil {
+		return
+	}
+
+	f := w.FormatErrStack
+	if f == nil {
+		f = consoleDefaultFormatErrStack(w.NoColor)
+	}
+
+	buf.WriteString(f(stack))
+}
+
 // writePart appends a formatted part to buf.
 func (w CodecometWriter) writePart(buf *bytes.Buffer, evt map[string]interface{}, p string) {
 	var f Formatter
@@ -271,14 +334,17 @@ func (w CodecometWriter) writePart(buf *bytes.Buffer, evt map[string]interface{}
 
 	switch p {
 	case zerolog.LevelFieldName:
-		if w.FormatLevel == nil {
-			f = consoleDefaultFormatLevel(w.NoColor)
-		} else {
+		switch {
+		case w.FormatLevel != nil:
 			f = w.FormatLevel
+		case w.Accessible:
+			f = consoleAccessibleFormatLevel(w.NoColor)
+		default:
+			f = consoleDefaultFormatLevel(w.NoColor)
 		}
 	case zerolog.TimestampFieldName:
 		if w.FormatTimestamp == nil {
-			f = consoleDefaultFormatTimestamp(w.TimeFormat, w.NoColor)
+			f = consoleDefaultFormatTimestamp(w.TimeFormat, w.UTC, w.NoColor)
 		} else {
 			f = w.FormatTimestamp
 		}
@@ -290,16 +356,22 @@ func (w CodecometWriter) writePart(buf *bytes.Buffer, evt map[string]interface{}
 		}
 	case ContextFieldName:
 		if w.FormatContext == nil {
-			f = consoleDefaultFormatContext
+			f = consoleDefaultFormatContext(w.NoColor)
 		} else {
 			f = w.FormatContext
 		}
 	case ModeFieldName:
 		if w.FormatMode == nil {
-			f = consoleDefaultFormatMode
+			f = consoleDefaultFormatMode(w.NoColor)
 		} else {
 			f = w.FormatMode
 		}
+	case EventIDFieldName:
+		if w.FormatEventID == nil {
+			f = consoleDefaultFormatEventID(w.NoColor)
+		} else {
+			f = w.FormatEventID
+		}
 	// case zerolog.CallerFieldName:
 	// 	if w.FormatCaller == nil {
 	// 		f = consoleDefaultFormatCaller(w.NoColor)
@@ -314,7 +386,12 @@ func (w CodecometWriter) writePart(buf *bytes.Buffer, evt map[string]interface{}
 		}
 	}
 
-	s := f(evt[p])
+	value := evt[p]
+	if p == zerolog.MessageFieldName {
+		value = translate(w.Catalog, evt)
+	}
+
+	s := f(value)
 
 	if len(s) > 0 {
 		if buf.Len() > 0 {
@@ -324,6 +401,38 @@ func (w CodecometWriter) writePart(buf *bytes.Buffer, evt map[string]interface{}
 	}
 }
 
+// QuotePolicy controls which string values are quoted in console output.
+type QuotePolicy string
+
+const (
+	// QuoteASCII quotes any value containing bytes outside of printable ASCII
+	QuoteASCII QuotePolicy = "ascii"
+	// QuoteUnicode leaves printable unicode (paths, names) intact, and only quotes values with control or
+	// non-printable characters, invalid UTF-8, spaces, backslashes or double quotes
+	QuoteUnicode QuotePolicy = "unicode"
+)
+
+// needsQuote returns true when the string s should be quoted in output.
+func (p QuotePolicy) needsQuote(s string) bool {
+	if p != QuoteUnicode {
+		return needsQuote(s)
+	}
+
+	for i, r := range s {
+		if r == utf8.RuneError {
+			if _, size := utf8.DecodeRuneInString(s[i:]); size == 1 {
+				return true
+			}
+		}
+
+		if !unicode.IsPrint(r) || r == ' ' || r == '\\' || r == '"' {
+			return true
+		}
+	}
+
+	return false
+}
+
 // needsQuote returns true when the string s should be quoted in output.
 func needsQuote(s string) bool {
 	for i := range s {
@@ -348,25 +457,32 @@ func consoleDefaultPartsOrder() []string {
 	return []string{
 		zerolog.TimestampFieldName,
 		zerolog.LevelFieldName,
+		EventIDFieldName,
 		ContextFieldName,
 		ModeFieldName,
 		zerolog.MessageFieldName,
 	}
 }
 
-func consoleDefaultFormatTimestamp(timeFormat string, noColor bool) Formatter {
+func consoleDefaultFormatTimestamp(timeFormat string, utc bool, noColor bool) Formatter {
 	if timeFormat == "" {
 		timeFormat = consoleDefaultTimeFormat
 	}
+
+	location := time.Local
+	if utc {
+		location = time.UTC
+	}
+
 	return func(i interface{}) string {
 		t := "<nil>"
 		switch tt := i.(type) {
 		case string:
-			ts, err := time.ParseInLocation(zerolog.TimeFieldFormat, tt, time.Local)
+			ts, err := time.ParseInLocation(zerolog.TimeFieldFormat, tt, location)
 			if err != nil {
 				t = tt
 			} else {
-				t = ts.Local().Format(timeFormat)
+				t = ts.In(location).Format(timeFormat)
 			}
 		case json.Number:
 			i, err := tt.Int64()
@@ -387,7 +503,7 @@ func consoleDefaultFormatTimestamp(timeFormat string, noColor bool) Formatter {
 				}
 
 				ts := time.Unix(sec, nsec)
-				t = ts.Format(timeFormat)
+				t = ts.In(location).Format(timeFormat)
 			}
 		}
 		return colorize(t, colorDarkGray, noColor)
@@ -414,7 +530,11 @@ func consoleDefaultFormatLevel(noColor bool) Formatter {
 			case zerolog.LevelPanicValue:
 				l = colorize(colorize("PNC", colorRed, noColor), colorBold, noColor)
 			default:
-				l = colorize(ll, colorBold, noColor)
+				if _, custom := lookupLevelName(ll); custom != nil {
+					l = colorize(colorize(custom.Tag, custom.Color, noColor), colorBold, noColor)
+				} else {
+					l = colorize(ll, colorBold, noColor)
+				}
 			}
 		} else {
 			if i == nil {
@@ -427,18 +547,32 @@ func consoleDefaultFormatLevel(noColor bool) Formatter {
 	}
 }
 
-func consoleDefaultFormatContext(i interface{}) string {
-	if i == nil {
-		i = "core"
+func consoleDefaultFormatContext(noColor bool) Formatter {
+	return func(i interface{}) string {
+		if i == nil {
+			i = "core"
+		}
+		return colorize(fmt.Sprintf("%-15s", i), colorBold, noColor)
 	}
-	return colorize(fmt.Sprintf("%-15s", i), colorBold, false)
 }
 
-func consoleDefaultFormatMode(i interface{}) string {
-	if i == nil {
-		return colorize("appint:", colorYellow, false)
+func consoleDefaultFormatMode(noColor bool) Formatter {
+	return func(i interface{}) string {
+		if i == nil {
+			return colorize("appint:", colorYellow, noColor)
+		}
+		return colorize(fmt.Sprintf("%6s: ", i), colorRed, noColor)
+	}
+}
+
+func consoleDefaultFormatEventID(noColor bool) Formatter {
+	return func(i interface{}) string {
+		if i == nil {
+			return ""
+		}
+
+		return colorize(fmt.Sprintf("[%s]", i), colorDarkGray, noColor)
 	}
-	return colorize(fmt.Sprintf("%6s: ", i), colorRed, false)
 }
 
 func consoleDefaultFormatMessage(i interface{}) string {
@@ -464,6 +598,36 @@ func consoleDefaultFormatErrFieldName(noColor bool) Formatter {
 	}
 }
 
+func consoleDefaultFormatErrStack(noColor bool) Formatter {
+	return func(i interface{}) string {
+		frames, ok := i.([]interface{})
+		if !ok {
+			b, err := zerolog.InterfaceMarshalFunc(i)
+			if err != nil {
+				return colorize(fmt.Sprintf("\n\t\t\t[error: %v]", err), colorRed, noColor)
+			}
+
+			return colorize(fmt.Sprintf("\n\t\t\t%s", b), colorDarkGray, noColor)
+		}
+
+		var out strings.Builder
+
+		for _, frame := range frames {
+			fr, ok := frame.(map[string]interface{})
+			if !ok {
+				fmt.Fprintf(&out, "\n\t\t\tat %v", frame)
+
+				continue
+			}
+
+			fmt.Fprintf(&out, "\n\t\t\tat %v (%v:%v)",
+				fr[pkgerrors.StackSourceFunctionName], fr[pkgerrors.StackSourceFileName], fr[pkgerrors.StackSourceLineName])
+		}
+
+		return colorize(out.String(), colorDarkGray, noColor)
+	}
+}
+
 func consoleDefaultFormatErrFieldValue(noColor bool) Formatter {
 	return func(i interface{}) string {
 		return colorize(fmt.Sprintf("%s", i), colorRed, noColor)
@@ -1,5 +1,45 @@
 package log
 
+import "time"
+
 type Config struct {
-	Level Level `json:"level,omitempty"`
+	Level Level `json:"level,omitempty" env:"CODECOMET_LOG_LEVEL" enum:"trace,debug,info,warn,error"`
+	// ErrorStack enables stack capture on Error() and Fatal() events, for errors that carry one (eg: pkg/errors)
+	ErrorStack bool `json:"errorStack,omitempty"`
+	// File, if set, additionally writes JSON events to that file
+	File string `json:"file,omitempty"`
+	// FlushInterval controls how often buffered writes to File are flushed
+	FlushInterval time.Duration `json:"flushInterval,omitempty"`
+	// EventLogSource, if set, sends warn and above events to the Windows Event Log under that source name
+	EventLogSource string `json:"eventLogSource,omitempty"`
+	// TimeFieldFormat is the timestamp format in json output: unix, unixms, unixmicro, unixnano, rfc3339 (default),
+	// rfc3339nano, or any Go layout
+	TimeFieldFormat string `json:"timeFieldFormat,omitempty"`
+	// TimeFormat is the timestamp format in console output: kitchen (default), 24h, locale (12 or 24-hour clock
+	// according to LC_ALL, LC_TIME or LANG), millis, rfc3339, rfc3339nano, or any Go layout
+	TimeFormat string `json:"timeFormat,omitempty"`
+	// TimeUTC uses UTC instead of local time for timestamps
+	TimeUTC bool `json:"timeUtc,omitempty"`
+	// NonBlocking makes console and file output asynchronous, dropping events instead of blocking (see Dropped)
+	NonBlocking bool `json:"nonBlocking,omitempty"`
+	// BufferEvents is the number of events buffered in non-blocking mode
+	BufferEvents int `json:"bufferEvents,omitempty"`
+	// Quoting is the console quoting policy for field values: ascii (default) or unicode, which leaves printable
+	// unicode unquoted
+	Quoting QuotePolicy `json:"quoting,omitempty" enum:"ascii,unicode"`
+	// WarningBudget is the default budget for WarnBudget warnings - nil disables escalation
+	WarningBudget *Budget `json:"warningBudget,omitempty"`
+	// CI is the CI provider whose grouping and annotation syntax is used on the console: auto (default, detected
+	// from the environment), none, github, gitlab or buildkite. Console output is colorless under CI
+	CI CIProvider `json:"ci,omitempty" enum:"auto,none,github,gitlab,buildkite"`
+	// Accessible renders console levels as words, for colorblind users and screen readers - also enabled on dumb
+	// terminals, or through the environment (see DetectAccessible). Console output is colorless if NO_COLOR is set
+	Accessible bool `json:"accessible,omitempty" env:"CODECOMET_LOG_ACCESSIBLE"`
+	// Ordered makes non-blocking output write events in the order they were logged, as far as they reach it within
+	// OrderWindow (see OrderedWriter)
+	Ordered bool `json:"ordered,omitempty"`
+	// OrderWindow is how long events are held waiting for earlier ones - defaults to 100ms
+	OrderWindow time.Duration `json:"orderWindow,omitempty"`
+	// PollInterval is how often the non-blocking buffer is polled - zero means on every write
+	PollInterval time.Duration `json:"pollInterval,omitempty"`
 }
@@ -1,5 +1,17 @@
 package log
 
+import "time"
+
+const (
+	exitHooksTimeout     = 5 * time.Second
+	defaultFlushInterval = time.Second
+	defaultBufferSize    = 64 * 1024
+	defaultBufferEvents  = 1000
+	eventLogID           = 1
+	statusRefresh        = 100 * time.Millisecond
+	statusPlainInterval  = 5 * time.Second
+)
+
 const (
 	// DebugLevel defines debug log level.
 	DebugLevel Level = iota
@@ -20,4 +32,9 @@ const (
 
 	// TraceLevel defines trace log level.
 	TraceLevel Level = -1
+
+	// NoticeLevel defines the custom notice level (severity info).
+	NoticeLevel Level = 16
+	// CriticalLevel defines the custom critical level (severity error).
+	CriticalLevel Level = 17
 )
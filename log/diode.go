@@ -0,0 +1,136 @@
+package log
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+
+	"github.com/rs/zerolog/diode"
+)
+
+var (
+	dropped  atomic.Uint64  //nolint:gochecknoglobals
+	diodes   []*asyncWriter //nolint:gochecknoglobals
+	diodesMu sync.Mutex     //nolint:gochecknoglobals
+)
+
+// Dropped returns the number of events dropped by non-blocking writers since the process started.
+func Dropped() uint64 {
+	return dropped.Load()
+}
+
+// asyncWriter is a many-writers/one-reader ring buffer in front of out, that can be drained without being closed.
+// It never closes out, which belongs to whoever opened it (eg: the file sink).
+type asyncWriter struct {
+	mu     sync.RWMutex
+	out    io.Writer
+	conf   *Config
+	diode  diode.Writer
+	closed bool
+}
+
+func (w *asyncWriter) Write(p []byte) (int, error) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	// Once closed, events are written synchronously
+	if w.closed {
+		return w.out.Write(p)
+	}
+
+	return w.diode.Write(p)
+}
+
+// Flush waits for buffered events to be written out, and starts over with a new buffer.
+func (w *asyncWriter) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return nil
+	}
+
+	w.drain()
+	w.diode = w.newDiode()
+
+	return nil
+}
+
+// Close waits for buffered events to be written out, and stops the buffer.
+func (w *asyncWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return nil
+	}
+
+	w.drain()
+	w.closed = true
+
+	return nil
+}
+
+// drain stops the current buffer once written out, then flushes out if it holds events of its own.
+func (w *asyncWriter) drain() {
+	_ = w.diode.Close()
+
+	if flusher, ok := w.out.(interface{ Flush() error }); ok {
+		_ = flusher.Flush()
+	}
+}
+
+func (w *asyncWriter) newDiode() diode.Writer {
+	size := w.conf.BufferEvents
+	if size <= 0 {
+		size = defaultBufferEvents
+	}
+
+	// Hide Close from the diode, which would close out along with itself
+	return diode.NewWriter(struct{ io.Writer }{w.out}, size, w.conf.PollInterval, func(missed int) {
+		dropped.Add(uint64(missed))
+	})
+}
+
+// nonBlocking wraps out into a many-writers/one-reader ring buffer, so that logging never blocks.
+// When the buffer is full, older events are dropped and counted.
+// If Ordered, events go through an OrderedWriter after the buffer, undoing the interleaving of concurrent writes.
+func nonBlocking(out io.Writer, conf *Config) io.Writer {
+	if conf.Ordered {
+		out = NewOrderedWriter(out, conf.OrderWindow)
+	}
+
+	writer := &asyncWriter{out: out, conf: conf}
+	writer.diode = writer.newDiode()
+
+	diodesMu.Lock()
+	defer diodesMu.Unlock()
+
+	diodes = append(diodes, writer)
+
+	return writer
+}
+
+// flushDiodes waits for pending events of all non-blocking writers to be written out. They keep working.
+func flushDiodes() {
+	diodesMu.Lock()
+	current := append([]*asyncWriter{}, diodes...)
+	diodesMu.Unlock()
+
+	for _, writer := range current {
+		_ = writer.Flush()
+	}
+}
+
+// drainDiodes flushes pending events and stops all non-blocking writers. Events still sent to them are written
+// synchronously.
+func drainDiodes() {
+	diodesMu.Lock()
+	current := diodes
+	diodes = nil
+	diodesMu.Unlock()
+
+	for _, writer := range current {
+		_ = writer.Close()
+	}
+}
@@ -0,0 +1,60 @@
+package log
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var ErrEventLogUnsupported = errors.New("event log is only supported on windows")
+
+// marshalErrorChain marshals wrapped errors as an array of messages, outermost first.
+// Each message is stripped of the trailing cause it wraps, so that the chain does not repeat itself. Links that only
+// decorate their cause (eg: pkg/errors stacks) are skipped.
+// Errors that do not wrap anything are marshalled as a plain string.
+func marshalErrorChain(err error) interface{} {
+	if err == nil {
+		return nil
+	}
+
+	chain := []string{}
+
+	for err != nil {
+		next := errors.Unwrap(err)
+		msg := err.Error()
+
+		if next != nil {
+			if msg == next.Error() {
+				err = next
+
+				continue
+			}
+
+			msg = strings.TrimSuffix(msg, ": "+next.Error())
+		}
+
+		chain = append(chain, msg)
+		err = next
+	}
+
+	if len(chain) == 1 {
+		return chain[0]
+	}
+
+	return chain
+}
+
+// formatErrorChain renders an error chain with each cause on its own indented line.
+func formatErrorChain(chain []interface{}) string {
+	var out strings.Builder
+
+	for i, cause := range chain {
+		if i > 0 {
+			out.WriteString("\n\t\t\tcaused by: ")
+		}
+
+		fmt.Fprintf(&out, "%s", cause)
+	}
+
+	return out.String()
+}
@@ -0,0 +1,23 @@
+//go:build !windows
+
+package log
+
+// EventLogWriter is only functional on Windows.
+type EventLogWriter struct{}
+
+// NewEventLogWriter always returns ErrEventLogUnsupported outside of Windows.
+func NewEventLogWriter(_ string) (*EventLogWriter, error) {
+	return nil, ErrEventLogUnsupported
+}
+
+func (w *EventLogWriter) Write(p []byte) (int, error) {
+	return len(p), nil
+}
+
+func (w *EventLogWriter) WriteLevel(_ Level, p []byte) (int, error) {
+	return len(p), nil
+}
+
+func (w *EventLogWriter) Close() error {
+	return nil
+}
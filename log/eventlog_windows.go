@@ -0,0 +1,52 @@
+//go:build windows
+
+package log
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/sys/windows/svc/eventlog"
+)
+
+// EventLogWriter sends warn, error, fatal and panic events to the Windows Event Log.
+type EventLogWriter struct {
+	elog *eventlog.Log
+}
+
+// NewEventLogWriter opens the Windows Event Log for the provided source name.
+func NewEventLogWriter(source string) (*EventLogWriter, error) {
+	// Registering the source requires elevated privileges, and fails if it is already registered: ignore
+	_ = eventlog.InstallAsEventCreate(source, eventlog.Error|eventlog.Warning|eventlog.Info)
+
+	elog, err := eventlog.Open(source)
+	if err != nil {
+		return nil, fmt.Errorf("failed opening event log for source %s: %w", source, err)
+	}
+
+	return &EventLogWriter{elog: elog}, nil
+}
+
+// Write drops events without a level.
+func (w *EventLogWriter) Write(p []byte) (int, error) {
+	return len(p), nil
+}
+
+func (w *EventLogWriter) WriteLevel(level Level, p []byte) (int, error) {
+	var err error
+
+	msg := strings.TrimSpace(string(p))
+
+	switch Severity(level) { //nolint:exhaustive
+	case WarnLevel:
+		err = w.elog.Warning(eventLogID, msg)
+	case ErrorLevel, FatalLevel, PanicLevel:
+		err = w.elog.Error(eventLogID, msg)
+	}
+
+	return len(p), err
+}
+
+func (w *EventLogWriter) Close() error {
+	return w.elog.Close()
+}
@@ -0,0 +1,119 @@
+package log
+
+import (
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+var (
+	exitHooks   []io.Closer //nolint:gochecknoglobals
+	exitHooksMu sync.Mutex  //nolint:gochecknoglobals
+	crashed     atomic.Bool //nolint:gochecknoglobals
+)
+
+// ExitHookFunc adapts an ordinary function to be used as an exit hook.
+type ExitHookFunc func() error
+
+func (f ExitHookFunc) Close() error {
+	return f()
+}
+
+// AddExitHook registers a closer to be run before the process terminates on a fatal or panic event.
+// Buffered log output is always flushed first.
+// Hooks are run in reverse registration order, and collectively bounded by a deadline.
+func AddExitHook(closer io.Closer) {
+	exitHooksMu.Lock()
+	defer exitHooksMu.Unlock()
+
+	exitHooks = append(exitHooks, closer)
+}
+
+// Crashed reports whether a fatal or panic event has been logged, typically from within an exit hook.
+func Crashed() bool {
+	return crashed.Load()
+}
+
+// RunExitHooks runs (and unregisters) all exit hooks, giving up after the exit deadline.
+// Log output is flushed before and after, and keeps working: hooks may log, and the process may go on after a
+// recovered panic event.
+func RunExitHooks() {
+	flushDiodes()
+
+	_ = Flush()
+
+	defer func() {
+		flushDiodes()
+
+		_ = Flush()
+	}()
+
+	exitHooksMu.Lock()
+	hooks := exitHooks
+	exitHooks = nil
+	exitHooksMu.Unlock()
+
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		for i := len(hooks) - 1; i >= 0; i-- {
+			if err := hooks[i].Close(); err != nil {
+				// Do not go through the logger here, as we are likely already inside a write
+				_, _ = os.Stderr.WriteString("exit hook failed: " + err.Error() + "\n")
+			}
+		}
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(exitHooksTimeout):
+		_, _ = os.Stderr.WriteString("exit hooks did not complete in time\n")
+	}
+}
+
+// Exit runs exit hooks, then terminates the program with the provided code.
+func Exit(code int) {
+	RunExitHooks()
+
+	// Nothing is buffered past this point
+	drainDiodes()
+
+	_ = Flush()
+
+	os.Exit(code)
+}
+
+// exitWriter runs exit hooks after a fatal or panic event has been written.
+// For fatal events, it also exits, as zerolog would otherwise call os.Exit right away.
+type exitWriter struct {
+	io.Writer
+}
+
+func (w exitWriter) WriteLevel(level Level, p []byte) (int, error) {
+	var n int
+
+	var err error
+
+	if lw, ok := w.Writer.(zerolog.LevelWriter); ok {
+		n, err = lw.WriteLevel(level, p)
+	} else {
+		n, err = w.Writer.Write(p)
+	}
+
+	switch level { //nolint:exhaustive
+	case FatalLevel:
+		crashed.Store(true)
+		Exit(1)
+	case PanicLevel:
+		crashed.Store(true)
+		RunExitHooks()
+	}
+
+	return n, err
+}
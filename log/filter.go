@@ -0,0 +1,93 @@
+package log
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"sync"
+
+	"github.com/rs/zerolog"
+)
+
+var filter = &FilterWriter{} //nolint:gochecknoglobals
+
+// Predicate returns true if the event should be dropped.
+// The event is provided in its decoded form, whether it was written as JSON or, with the binary_log tag, as CBOR.
+type Predicate func(level Level, evt map[string]interface{}) bool
+
+// FilterWriter drops events matching any of its registered predicates, and passes the others to Out.
+type FilterWriter struct {
+	Out io.Writer
+
+	mu         sync.RWMutex
+	predicates []Predicate
+}
+
+// NewFilterWriter creates a new FilterWriter writing to out.
+func NewFilterWriter(out io.Writer, predicates ...Predicate) *FilterWriter {
+	return &FilterWriter{
+		Out:        out,
+		predicates: predicates,
+	}
+}
+
+// AddFilter registers a predicate on the global logger output.
+func AddFilter(predicate Predicate) {
+	filter.Add(predicate)
+}
+
+// Add registers a new predicate.
+func (w *FilterWriter) Add(predicate Predicate) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.predicates = append(w.predicates, predicate)
+}
+
+func (w *FilterWriter) Write(p []byte) (int, error) {
+	return w.WriteLevel(zerolog.NoLevel, p)
+}
+
+func (w *FilterWriter) WriteLevel(level Level, p []byte) (int, error) {
+	w.mu.RLock()
+	predicates := w.predicates
+	w.mu.RUnlock()
+
+	if len(predicates) > 0 {
+		evt, err := decodeEvent(p)
+		if err != nil {
+			return 0, fmt.Errorf("cannot decode event: %w", err)
+		}
+
+		for _, predicate := range predicates {
+			if predicate(level, evt) {
+				return len(p), nil
+			}
+		}
+	}
+
+	if lw, ok := w.Out.(zerolog.LevelWriter); ok {
+		return lw.WriteLevel(level, p)
+	}
+
+	return w.Out.Write(p)
+}
+
+// ContextBelow drops events for the given context (eg: "network") with a level lower than level.
+func ContextBelow(ctx string, level Level) Predicate {
+	return func(lvl Level, evt map[string]interface{}) bool {
+		return evt[ContextFieldName] == ctx && Severity(lvl) < level
+	}
+}
+
+// FieldMatches drops events where the given field string representation matches re.
+func FieldMatches(field string, re *regexp.Regexp) Predicate {
+	return func(_ Level, evt map[string]interface{}) bool {
+		value, ok := evt[field]
+		if !ok {
+			return false
+		}
+
+		return re.MatchString(fmt.Sprintf("%v", value))
+	}
+}
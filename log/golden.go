@@ -0,0 +1,29 @@
+package log
+
+import (
+	"regexp"
+
+	"github.com/rs/zerolog"
+)
+
+// TimePlaceholder replaces timestamps in canonical output (see Canonical).
+const TimePlaceholder = "<time>"
+
+var (
+	ansiPattern     = regexp.MustCompile(`\x1b\[[0-9;?]*[A-Za-z]`)                                               //nolint:gochecknoglobals
+	rfc3339Pattern  = regexp.MustCompile(`\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:\d{2})`)       //nolint:gochecknoglobals
+	clockPattern    = regexp.MustCompile(`(?m)\b\d{1,2}:\d{2}(:\d{2}(\.\d+)?|AM|PM)|^\d{1,2}:\d{2}\b`)           //nolint:gochecknoglobals
+	unixTimePattern = regexp.MustCompile(`"` + regexp.QuoteMeta(zerolog.TimestampFieldName) + `":-?\d+(\.\d+)?`) //nolint:gochecknoglobals
+)
+
+// Canonical strips color and terminal control codes from console or JSON log output, and replaces timestamps with
+// TimePlaceholder, so that it can be compared with golden files whatever the time, timezone, or terminal.
+// Timestamps are recognized as RFC 3339, kitchen or millis console times, and unix time fields in JSON.
+// Tests that need the actual times should inject a Clock instead (see SetClock).
+func Canonical(out []byte) []byte {
+	out = ansiPattern.ReplaceAll(out, nil)
+	out = rfc3339Pattern.ReplaceAll(out, []byte(TimePlaceholder))
+	out = unixTimePattern.ReplaceAll(out, []byte(`"`+zerolog.TimestampFieldName+`":"`+TimePlaceholder+`"`))
+
+	return clockPattern.ReplaceAll(out, []byte(TimePlaceholder))
+}
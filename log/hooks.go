@@ -0,0 +1,13 @@
+package log
+
+import (
+	"github.com/rs/zerolog"
+	"go.codecomet.dev/core/id"
+)
+
+// eventIDHook assigns each event a short ID, so that users can reference a specific line when filing bugs.
+type eventIDHook struct{}
+
+func (eventIDHook) Run(e *Event, _ zerolog.Level, _ string) {
+	e.Str(EventIDFieldName, id.Short())
+}
@@ -0,0 +1,106 @@
+package log
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+)
+
+var ErrLevelReserved = errors.New("level value is reserved")
+
+// CustomLevel describes an additional log level, beyond the built-in ones.
+type CustomLevel struct {
+	// Name is the level value in json output (eg: "notice")
+	Name string
+	// Tag is the three letters tag used in console output (eg: "NOT")
+	Tag string
+	// Color is the ANSI color code used in console output (eg: 35 for magenta)
+	Color int
+	// Severity is the built-in level used for filtering and routing
+	Severity Level
+}
+
+var (
+	customLevels = map[Level]*CustomLevel{ //nolint:gochecknoglobals
+		NoticeLevel:   {Name: "notice", Tag: "NOT", Color: colorCyan, Severity: InfoLevel},
+		CriticalLevel: {Name: "critical", Tag: "CRT", Color: colorMagenta, Severity: ErrorLevel},
+	}
+	customLevelsMu sync.RWMutex //nolint:gochecknoglobals
+)
+
+// RegisterLevel registers (or replaces) a custom level. Values used by built-in levels cannot be registered.
+func RegisterLevel(level Level, custom CustomLevel) error {
+	if level >= TraceLevel && level <= Disabled {
+		return fmt.Errorf("%w: %d", ErrLevelReserved, level)
+	}
+
+	customLevelsMu.Lock()
+	defer customLevelsMu.Unlock()
+
+	customLevels[level] = &custom
+
+	return nil
+}
+
+// Severity returns the built-in level equivalent for level.
+func Severity(level Level) Level {
+	if custom := lookupLevel(level); custom != nil {
+		return custom.Severity
+	}
+
+	return level
+}
+
+// WithLevel starts a new message with the provided level, built-in or custom.
+// Custom levels are filtered according to their severity.
+func WithLevel(level Level) *Event {
+	custom := lookupLevel(level)
+	if custom == nil {
+		return log.WithLevel(level)
+	}
+
+	if GetLevel() == Disabled || custom.Severity < GetLevel() {
+		return nil
+	}
+
+	return log.WithLevel(level)
+}
+
+func Notice() *Event {
+	return WithLevel(NoticeLevel)
+}
+
+func Critical() *Event {
+	return WithLevel(CriticalLevel)
+}
+
+func lookupLevel(level Level) *CustomLevel {
+	customLevelsMu.RLock()
+	defer customLevelsMu.RUnlock()
+
+	return customLevels[level]
+}
+
+func lookupLevelName(name string) (Level, *CustomLevel) {
+	customLevelsMu.RLock()
+	defer customLevelsMu.RUnlock()
+
+	for level, custom := range customLevels {
+		if custom.Name == name {
+			return level, custom
+		}
+	}
+
+	return NoLevel, nil
+}
+
+// marshalLevel renders custom level names.
+func marshalLevel(level Level) string {
+	if custom := lookupLevel(level); custom != nil {
+		return custom.Name
+	}
+
+	return level.String()
+}
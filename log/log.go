@@ -1,18 +1,156 @@
 package log
 
 import (
+	"io"
 	"os"
+	"sync"
 
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
+	"github.com/rs/zerolog/pkgerrors"
+	"go.codecomet.dev/core/filesystem"
+)
+
+var (
+	errorStack bool            //nolint:gochecknoglobals
+	sink       *BufferedWriter //nolint:gochecknoglobals
+	eventLog   *EventLogWriter //nolint:gochecknoglobals
+	sinkMu     sync.Mutex      //nolint:gochecknoglobals
 )
 
 // Init should be called when the app starts, from a config object.
 func Init(conf *Config) {
 	// This mostly should be the responsibility of the app itself but hey
 	zerolog.SetGlobalLevel(conf.Level)
-	output := CodecometWriter{Out: os.Stderr, TimeFormat: zerolog.TimeFormatUnix}
-	log.Logger = zerolog.New(output).With().Timestamp().Logger()
+
+	zerolog.ErrorMarshalFunc = marshalErrorChain
+	zerolog.LevelFieldMarshalFunc = marshalLevel
+
+	errorStack = conf.ErrorStack
+	if errorStack {
+		zerolog.ErrorStackMarshaler = pkgerrors.MarshalStack
+	}
+
+	SetDefaultBudget(conf.WarningBudget)
+
+	// Console output goes through the status writer, so that log lines are kept above any status line
+	zerolog.TimeFieldFormat = wireTimeFormat(conf.TimeFieldFormat)
+	setClockUTC(conf.TimeUTC)
+	zerolog.TimestampFunc = timestamp
+
+	// Previous non-blocking writers need to be drained before we swap sinks
+	drainDiodes()
+
+	ci := setCI(conf.CI)
+
+	var console io.Writer = CodecometWriter{
+		Out:        statusOut,
+		NoColor:    ci != CINone || DetectNoColor(),
+		TimeFormat: displayTimeFormat(conf.TimeFormat),
+		UTC:        conf.TimeUTC,
+		Quoting:    conf.Quoting,
+		CI:         ci,
+		Accessible: conf.Accessible || DetectAccessible(),
+	}
+
+	if conf.NonBlocking {
+		console = nonBlocking(console, conf)
+	}
+
+	writers := []io.Writer{console}
+
+	file, fileErr := openSink(conf)
+	if file != nil {
+		if conf.NonBlocking {
+			writers = append(writers, nonBlocking(file, conf))
+		} else {
+			writers = append(writers, file)
+		}
+	}
+
+	elog, elogErr := openEventLog(conf)
+	if elog != nil {
+		writers = append(writers, elog)
+	}
+
+	var output io.Writer = writers[0]
+	if len(writers) > 1 {
+		output = zerolog.MultiLevelWriter(writers...)
+	}
+
+	filter.Out = output
+
+	log.Logger = zerolog.New(exitWriter{filter}).With().Timestamp().Logger().Hook(eventIDHook{}).Hook(sequenceHook{})
+
+	if fileErr != nil {
+		log.Error().Err(fileErr).Str("file", conf.File).Msg("Failed opening log file. Not logging to file.")
+	}
+
+	if elogErr != nil {
+		log.Error().Err(elogErr).Str("source", conf.EventLogSource).Msg("Failed opening event log. Not logging to it.")
+	}
+}
+
+// openEventLog closes the current event log writer if any, and opens a new one if the config asks for it.
+func openEventLog(conf *Config) (*EventLogWriter, error) {
+	sinkMu.Lock()
+	defer sinkMu.Unlock()
+
+	if eventLog != nil {
+		_ = eventLog.Close()
+		eventLog = nil
+	}
+
+	if conf.EventLogSource == "" {
+		return nil, nil //nolint:nilnil
+	}
+
+	var err error
+
+	eventLog, err = NewEventLogWriter(conf.EventLogSource)
+
+	return eventLog, err
+}
+
+// openSink closes the current file sink if any, and opens a new one if the config asks for it.
+func openSink(conf *Config) (*BufferedWriter, error) {
+	sinkMu.Lock()
+	defer sinkMu.Unlock()
+
+	if sink != nil {
+		_ = sink.Close()
+		sink = nil
+	}
+
+	if conf.File == "" {
+		return nil, nil //nolint:nilnil
+	}
+
+	file, err := os.OpenFile(conf.File, os.O_APPEND|os.O_CREATE|os.O_WRONLY, filesystem.FilePermissionsDefault)
+	if err != nil {
+		return nil, err
+	}
+
+	interval := conf.FlushInterval
+	if interval == 0 {
+		interval = defaultFlushInterval
+	}
+
+	sink = NewBufferedWriter(file, defaultBufferSize, interval)
+
+	return sink, nil
+}
+
+// Flush writes out any buffered log output. It should be called on shutdown.
+func Flush() error {
+	sinkMu.Lock()
+	defer sinkMu.Unlock()
+
+	if sink == nil {
+		return nil
+	}
+
+	return sink.Flush()
 }
 
 func SetLevel(lv Level) {
@@ -25,8 +163,8 @@ func GetLevel() Level {
 
 func LoggerForLevel(level string) *Event {
 	switch level {
-  case "trace":
-  return log.Trace()
+	case "trace":
+		return log.Trace()
 	case "debug":
 		return log.Debug()
 	case "info":
@@ -40,15 +178,27 @@ func LoggerForLevel(level string) *Event {
 	case "fatal":
 		return log.Fatal()
 	default:
+		if lvl, custom := lookupLevelName(level); custom != nil {
+			return WithLevel(lvl)
+		}
+
 		return log.Info()
 	}
 }
 
 func Fatal() *Event {
+	if errorStack {
+		return log.Fatal().Stack()
+	}
+
 	return log.Fatal()
 }
 
 func Error() *Event {
+	if errorStack {
+		return log.Error().Stack()
+	}
+
 	return log.Error()
 }
 
@@ -0,0 +1,37 @@
+package log
+
+import "sync"
+
+var (
+	middlewares   []Middleware //nolint:gochecknoglobals
+	middlewaresMu sync.RWMutex //nolint:gochecknoglobals
+)
+
+// Middleware receives the decoded event before it is formatted by CodecometWriter.
+// It may mutate or enrich the event in place, and returns false to veto (drop) it.
+type Middleware func(evt map[string]interface{}) bool
+
+// Use registers a middleware applied by all CodecometWriter instances, before their own.
+func Use(middleware Middleware) {
+	middlewaresMu.Lock()
+	defer middlewaresMu.Unlock()
+
+	middlewares = append(middlewares, middleware)
+}
+
+// runMiddlewares applies global, then local middlewares, stopping at the first veto.
+func runMiddlewares(local []Middleware, evt map[string]interface{}) bool {
+	middlewaresMu.RLock()
+	global := middlewares
+	middlewaresMu.RUnlock()
+
+	for _, chain := range [][]Middleware{global, local} {
+		for _, middleware := range chain {
+			if !middleware(evt) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
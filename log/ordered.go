@@ -0,0 +1,200 @@
+package log
+
+import (
+	"bytes"
+	"container/heap"
+	"encoding/json"
+	"io"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+const defaultOrderWindow = 100 * time.Millisecond
+
+var sequence atomic.Uint64 //nolint:gochecknoglobals
+
+// sequenceHook numbers events in the order they are logged, across goroutines. Events may still be written out of
+// that order by concurrent goroutines: consumers can sort on it, or use an OrderedWriter.
+type sequenceHook struct{}
+
+func (sequenceHook) Run(e *Event, _ zerolog.Level, _ string) {
+	e.Uint64(SequenceFieldName, sequence.Add(1))
+}
+
+// OrderedWriter reorders JSON or binary events by sequence number (see SequenceFieldName) before writing them to out.
+// Events that follow the last written one are written right away. Others are held until the missing ones arrive,
+// or for window at most, since events filtered out before reaching the writer leave gaps for good.
+// Events without a sequence number are written as is.
+type OrderedWriter struct {
+	mu      sync.Mutex
+	out     io.Writer
+	window  time.Duration
+	pending orderedEvents
+	last    uint64
+	timer   *time.Timer
+}
+
+// NewOrderedWriter returns an OrderedWriter on top of out. A zero or negative window defaults to 100ms.
+func NewOrderedWriter(out io.Writer, window time.Duration) *OrderedWriter {
+	if window <= 0 {
+		window = defaultOrderWindow
+	}
+
+	return &OrderedWriter{out: out, window: window}
+}
+
+func (w *OrderedWriter) Write(p []byte) (int, error) {
+	seq, ok := eventSequence(p)
+	if !ok {
+		return w.write(p)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	// Too late: it cannot be put back in order anymore
+	if seq <= w.last {
+		return w.out.Write(p)
+	}
+
+	// Callers may reuse p
+	heap.Push(&w.pending, &orderedEvent{seq: seq, data: append([]byte{}, p...), arrived: time.Now()})
+
+	if err := w.release(false); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+// Flush writes all held events, in order.
+func (w *OrderedWriter) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.release(true)
+}
+
+// Close flushes, and closes the underlying writer if it is a Closer.
+func (w *OrderedWriter) Close() error {
+	err := w.Flush()
+
+	w.mu.Lock()
+	if w.timer != nil {
+		w.timer.Stop()
+		w.timer = nil
+	}
+	w.mu.Unlock()
+
+	if closer, ok := w.out.(io.Closer); ok {
+		if cerr := closer.Close(); err == nil {
+			err = cerr
+		}
+	}
+
+	return err
+}
+
+func (w *OrderedWriter) write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.out.Write(p)
+}
+
+// release writes the events that are next in sequence, or that waited long enough - all of them if all is true.
+// Held events get written by a timer otherwise.
+func (w *OrderedWriter) release(all bool) error {
+	now := time.Now()
+
+	for w.pending.Len() > 0 {
+		next := w.pending[0]
+		if !all && next.seq != w.last+1 && now.Sub(next.arrived) < w.window {
+			break
+		}
+
+		heap.Pop(&w.pending)
+
+		w.last = next.seq
+
+		if _, err := w.out.Write(next.data); err != nil {
+			return err
+		}
+	}
+
+	if w.pending.Len() > 0 && w.timer == nil {
+		w.timer = time.AfterFunc(w.window-now.Sub(w.pending[0].arrived), func() {
+			w.mu.Lock()
+			defer w.mu.Unlock()
+
+			w.timer = nil
+			_ = w.release(false)
+		})
+	}
+
+	return nil
+}
+
+// eventSequence extracts the sequence number of a JSON or binary event.
+func eventSequence(p []byte) (uint64, bool) {
+	// Binary events are decoded as a whole: the last occurrence of the field wins there as well
+	if isBinaryEvent(p) {
+		evt, err := decodeBinaryEvent(p)
+		if err != nil {
+			return 0, false
+		}
+
+		number, _ := evt[SequenceFieldName].(json.Number)
+		seq, err := strconv.ParseUint(number.String(), 10, 64)
+
+		return seq, err == nil
+	}
+
+	// The hook runs after fields are added: the last occurrence is ours
+	key := []byte(`"` + SequenceFieldName + `":`)
+
+	start := bytes.LastIndex(p, key)
+	if start < 0 {
+		return 0, false
+	}
+
+	start += len(key)
+	end := start
+
+	for end < len(p) && p[end] >= '0' && p[end] <= '9' {
+		end++
+	}
+
+	seq, err := strconv.ParseUint(string(p[start:end]), 10, 64)
+
+	return seq, err == nil
+}
+
+type orderedEvent struct {
+	seq     uint64
+	data    []byte
+	arrived time.Time
+}
+
+// orderedEvents is a min-heap of events by sequence number.
+type orderedEvents []*orderedEvent
+
+func (events orderedEvents) Len() int           { return len(events) }
+func (events orderedEvents) Less(i, j int) bool { return events[i].seq < events[j].seq }
+func (events orderedEvents) Swap(i, j int)      { events[i], events[j] = events[j], events[i] }
+
+func (events *orderedEvents) Push(x interface{}) {
+	*events = append(*events, x.(*orderedEvent)) //nolint:forcetypeassert
+}
+
+func (events *orderedEvents) Pop() interface{} {
+	old := *events
+	last := old[len(old)-1]
+	*events = old[:len(old)-1]
+
+	return last
+}
@@ -0,0 +1,187 @@
+package log
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/mattn/go-colorable"
+	"github.com/mattn/go-isatty"
+)
+
+var (
+	statusOut     = &statusWriter{out: colorable.NewColorable(os.Stderr)}      //nolint:gochecknoglobals
+	statusFrames  = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"} //nolint:gochecknoglobals
+	statusCurrent *Status                                                      //nolint:gochecknoglobals
+	statusMu      sync.Mutex                                                   //nolint:gochecknoglobals
+)
+
+// statusWriter sits in front of the console, and makes sure log lines are written above the status line.
+type statusWriter struct {
+	mu   sync.Mutex
+	out  io.Writer
+	line string
+}
+
+func (w *statusWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.line == "" {
+		return w.out.Write(p)
+	}
+
+	_, _ = io.WriteString(w.out, "\r\x1b[K")
+	n, err := w.out.Write(p)
+	_, _ = io.WriteString(w.out, w.line)
+
+	return n, err
+}
+
+func (w *statusWriter) set(line string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	_, _ = io.WriteString(w.out, "\r\x1b[K"+line)
+	w.line = line
+}
+
+// Status is a transient, overwritable line rendered below log output (spinner, progress).
+// When stderr is not a terminal, it degrades to plain info messages, emitted at most every few seconds.
+type Status struct {
+	mu       sync.Mutex
+	msg      string
+	frame    int
+	tty      bool
+	lastEmit time.Time
+	done     chan struct{}
+}
+
+// NewStatus starts displaying a status line, replacing the current one if any.
+func NewStatus(msg string) *Status {
+	status := &Status{
+		msg:  msg,
+		tty:  isatty.IsTerminal(os.Stderr.Fd()) || isatty.IsCygwinTerminal(os.Stderr.Fd()),
+		done: make(chan struct{}),
+	}
+
+	statusMu.Lock()
+	previous := statusCurrent
+	statusCurrent = status
+	statusMu.Unlock()
+
+	if previous != nil {
+		previous.stop()
+	}
+
+	if status.tty {
+		status.render()
+
+		go status.loop()
+	} else {
+		status.emit(true)
+	}
+
+	return status
+}
+
+// Update changes the status message.
+func (s *Status) Update(msg string) {
+	s.mu.Lock()
+	s.msg = msg
+	s.mu.Unlock()
+
+	if s.tty {
+		s.render()
+	} else {
+		s.emit(false)
+	}
+}
+
+// Progress updates the status message with a completion percentage.
+func (s *Status) Progress(msg string, current int64, total int64) {
+	if total <= 0 {
+		s.Update(msg)
+
+		return
+	}
+
+	//nolint:gomnd
+	s.Update(fmt.Sprintf("%s %3d%%", msg, current*100/total))
+}
+
+// Done removes the status line, and logs the final message if not empty.
+func (s *Status) Done(msg string) {
+	statusMu.Lock()
+	if statusCurrent == s {
+		statusCurrent = nil
+	}
+	statusMu.Unlock()
+
+	s.stop()
+
+	if msg != "" {
+		Info().Msg(msg)
+	}
+}
+
+func (s *Status) stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	select {
+	case <-s.done:
+		return
+	default:
+		close(s.done)
+	}
+
+	if s.tty {
+		statusOut.set("")
+	}
+}
+
+func (s *Status) loop() {
+	ticker := time.NewTicker(statusRefresh)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.render()
+		case <-s.done:
+			return
+		}
+	}
+}
+
+func (s *Status) render() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	select {
+	case <-s.done:
+		return
+	default:
+	}
+
+	s.frame = (s.frame + 1) % len(statusFrames)
+	statusOut.set(colorize(statusFrames[s.frame], colorCyan, false) + " " + s.msg)
+}
+
+func (s *Status) emit(force bool) {
+	s.mu.Lock()
+	msg := s.msg
+	ready := force || time.Since(s.lastEmit) >= statusPlainInterval
+
+	if ready {
+		s.lastEmit = time.Now()
+	}
+	s.mu.Unlock()
+
+	if ready {
+		Info().Msg(msg)
+	}
+}
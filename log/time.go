@@ -0,0 +1,65 @@
+package log
+
+import (
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// Time format names accepted in Config, in addition to arbitrary Go layouts.
+const (
+	TimeUnix        = "unix"
+	TimeUnixMs      = "unixms"
+	TimeUnixMicro   = "unixmicro"
+	TimeUnixNano    = "unixnano"
+	TimeRFC3339     = "rfc3339"
+	TimeRFC3339Nano = "rfc3339nano"
+	TimeKitchen     = "kitchen"
+	TimeMillis      = "millis"
+	// TimeClock24 is a 24-hour clock (eg: 15:04)
+	TimeClock24 = "24h"
+	// TimeLocale is a 12 or 24-hour clock, according to the locale of the environment
+	TimeLocale = "locale"
+
+	millisLayout = "15:04:05.000"
+)
+
+// wireTimeFormat maps a config time field format to zerolog's.
+func wireTimeFormat(format string) string {
+	switch format {
+	case "", TimeRFC3339:
+		return time.RFC3339
+	case TimeUnix:
+		return zerolog.TimeFormatUnix
+	case TimeUnixMs:
+		return zerolog.TimeFormatUnixMs
+	case TimeUnixMicro:
+		return zerolog.TimeFormatUnixMicro
+	case TimeUnixNano:
+		return zerolog.TimeFormatUnixNano
+	case TimeRFC3339Nano:
+		return time.RFC3339Nano
+	default:
+		return format
+	}
+}
+
+// displayTimeFormat maps a config console time format to a Go layout.
+func displayTimeFormat(format string) string {
+	switch format {
+	case "", TimeKitchen:
+		return consoleDefaultTimeFormat
+	case TimeMillis:
+		return millisLayout
+	case TimeClock24:
+		return clock24Layout
+	case TimeLocale:
+		return localeTimeFormat()
+	case TimeRFC3339:
+		return time.RFC3339
+	case TimeRFC3339Nano:
+		return time.RFC3339Nano
+	default:
+		return format
+	}
+}
@@ -0,0 +1,97 @@
+// Package core provides the entrypoint of applications built on the core packages.
+package core
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"go.codecomet.dev/core/log"
+	"go.codecomet.dev/core/reporter"
+)
+
+// Exit codes used by Main, besides those carried by errors (see ExitCoder).
+const (
+	ExitOK          = 0
+	ExitFailure     = 1
+	ExitInterrupted = 130
+)
+
+// ExitCoder is implemented by errors carrying the exit code of the process (eg: *exec.ExitError).
+type ExitCoder interface {
+	ExitCode() int
+}
+
+type expectedError struct {
+	error
+}
+
+func (e *expectedError) Unwrap() error {
+	return e.error
+}
+
+// Expected marks err as an expected failure (invalid usage, missing input, etc): Main logs it, but does not report
+// it as a crash.
+func Expected(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return &expectedError{err}
+}
+
+// IsExpected tells whether err, or any error it wraps, was marked with Expected.
+func IsExpected(err error) bool {
+	var expected *expectedError
+
+	return errors.As(err, &expected)
+}
+
+// Main runs the application, and exits the process once run returns. The context is canceled on SIGINT or SIGTERM.
+// A non-nil error is logged, and reported (unless it is Expected, or caused by an interruption). Logs, reports and
+// spans are then flushed through the log exit hooks, and the process exits with:
+//   - ExitOK if run succeeded
+//   - the code of the error, if it is (or wraps) an ExitCoder with a positive code
+//   - ExitInterrupted if it failed after an interruption
+//   - ExitFailure otherwise
+func Main(run func(ctx context.Context) error) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+
+	err := run(ctx)
+	interrupted := ctx.Err() != nil
+
+	stop()
+
+	switch {
+	case err == nil:
+	case interrupted && errors.Is(err, context.Canceled):
+		log.Warn().Err(err).Msg("Interrupted")
+	case IsExpected(err):
+		log.Error().Err(err).Msg("Failed")
+	default:
+		log.Error().Err(err).Msg("Failed with an unexpected error")
+		reporter.CaptureException(err)
+	}
+
+	log.Exit(exitCode(err, interrupted))
+}
+
+// exitCode maps the outcome of Main to an exit code.
+func exitCode(err error, interrupted bool) int {
+	if err == nil {
+		return ExitOK
+	}
+
+	var coder ExitCoder
+	if errors.As(err, &coder) && coder.ExitCode() > 0 {
+		return coder.ExitCode()
+	}
+
+	if interrupted {
+		return ExitInterrupted
+	}
+
+	return ExitFailure
+}
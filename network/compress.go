@@ -0,0 +1,206 @@
+package network
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"go.codecomet.dev/core/filesystem"
+	"go.codecomet.dev/core/log"
+)
+
+const (
+	EncodingGzip = "gzip"
+	// EncodingZstd is recognized, but not supported yet (no zstd implementation is vendored): CompressingTransport
+	// falls back to gzip
+	EncodingZstd = "zstd"
+
+	defaultCompressThreshold = 1024
+)
+
+// CompressingTransport compresses request bodies larger than a threshold before handing them to the base
+// transport, for large payloads over slow links.
+// Bodies that are already compressed (see filesystem.Detect) are sent as is. Compressed bodies are buffered, so
+// that requests can be retried or redirected (GetBody).
+// Servers that do not support the encoding answer 415 (RFC 7694): the request is then resent uncompressed, and the
+// host is not sent compressed bodies anymore, unless it advertised another supported encoding in Accept-Encoding.
+// To sign compressed requests, use a SigningTransport as the Base.
+type CompressingTransport struct {
+	// Base is the underlying transport - defaults to GetTransport()
+	Base http.RoundTripper
+	// Encoding defaults to gzip, which is also used in place of unsupported encodings
+	Encoding string
+	// Threshold is the body size from which requests are compressed - defaults to 1KiB
+	Threshold int
+
+	hosts       sync.Map
+	unsupported sync.Once
+}
+
+// RoundTrip compresses the body of a copy of the request if it is large enough, and sends it.
+func (ct *CompressingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := ct.Base
+	if base == nil {
+		base = GetTransport()
+	}
+
+	encoding := ct.encoding(req.URL.Host)
+
+	// Already encoded, or nothing to compress
+	if encoding == "" || req.Body == nil || req.Body == http.NoBody || req.Header.Get("Content-Encoding") != "" {
+		return base.RoundTrip(req)
+	}
+
+	threshold := ct.Threshold
+	if threshold <= 0 {
+		threshold = defaultCompressThreshold
+	}
+
+	// Known small bodies are sent as is, without buffering
+	if req.ContentLength > 0 && req.ContentLength < int64(threshold) {
+		return base.RoundTrip(req)
+	}
+
+	plain := req.Clone(req.Context())
+
+	payload, err := readBody(plain, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed reading request body for compression: %w", err)
+	}
+
+	// Small, or already compressed (archives, images...): not worth it
+	if len(payload) < threshold || filesystem.Detect("", payload).Compressed {
+		return base.RoundTrip(plain)
+	}
+
+	compressed, err := compress(encoding, payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed compressing request body: %w", err)
+	}
+
+	encoded := plain.Clone(plain.Context())
+	encoded.Header.Set("Content-Encoding", encoding)
+	encoded.ContentLength = int64(len(compressed))
+	encoded.Body = io.NopCloser(bytes.NewReader(compressed))
+	encoded.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(compressed)), nil
+	}
+
+	resp, err := base.RoundTrip(encoded)
+	if err != nil || resp.StatusCode != http.StatusUnsupportedMediaType {
+		return resp, err
+	}
+
+	// Only retry if the server rejected the encoding, rather than the media type
+	accepted, advertised := resp.Header["Accept-Encoding"]
+	if !advertised || acceptsEncoding(accepted, encoding) {
+		return resp, nil
+	}
+
+	_, _ = io.Copy(io.Discard, resp.Body)
+	_ = resp.Body.Close()
+
+	ct.hosts.Store(req.URL.Host, supportedEncoding(accepted))
+
+	log.Debug().Str("host", req.URL.Host).Str("encoding", encoding).Msg("Server rejected compressed request, resending")
+
+	return base.RoundTrip(plain)
+}
+
+// encoding returns the encoding to use with host, or "" if it does not support compressed requests.
+func (ct *CompressingTransport) encoding(host string) string {
+	if negotiated, ok := ct.hosts.Load(host); ok {
+		return negotiated.(string) //nolint:forcetypeassert
+	}
+
+	if ct.Encoding == "" || strings.EqualFold(ct.Encoding, EncodingGzip) {
+		return EncodingGzip
+	}
+
+	ct.unsupported.Do(func() {
+		log.Warn().Str("encoding", ct.Encoding).Msg("Unsupported request encoding, compressing with gzip instead")
+	})
+
+	return EncodingGzip
+}
+
+func compress(encoding string, payload []byte) ([]byte, error) {
+	if encoding != EncodingGzip {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedEncoding, encoding)
+	}
+
+	var buf bytes.Buffer
+
+	writer := gzip.NewWriter(&buf)
+
+	if _, err := writer.Write(payload); err != nil {
+		return nil, err
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// acceptsEncoding tells whether Accept-Encoding values list encoding.
+func acceptsEncoding(values []string, encoding string) bool {
+	for _, value := range values {
+		for _, candidate := range strings.Split(value, ",") {
+			name, _, _ := strings.Cut(strings.TrimSpace(candidate), ";")
+			if strings.EqualFold(name, encoding) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// supportedEncoding returns the first encoding we support among Accept-Encoding values, or "".
+func supportedEncoding(values []string) string {
+	if acceptsEncoding(values, EncodingGzip) {
+		return EncodingGzip
+	}
+
+	return ""
+}
+
+// DecompressRequests is a middleware decoding gzip request bodies. Requests with other encodings are rejected with
+// a 415 advertising gzip (RFC 7694), so that CompressingTransport clients fall back.
+// Combine it with Limit to bound decompressed sizes.
+func DecompressRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
+		encoding := strings.TrimSpace(req.Header.Get("Content-Encoding"))
+
+		switch {
+		case encoding == "" || strings.EqualFold(encoding, "identity"):
+		case strings.EqualFold(encoding, EncodingGzip):
+			reader, err := gzip.NewReader(req.Body)
+			if err != nil {
+				http.Error(writer, "invalid gzip body", http.StatusBadRequest)
+
+				return
+			}
+
+			defer reader.Close()
+
+			req.Body = reader
+			req.ContentLength = -1
+			req.Header.Del("Content-Encoding")
+			req.Header.Del("Content-Length")
+		default:
+			writer.Header().Set("Accept-Encoding", EncodingGzip)
+			http.Error(writer, http.StatusText(http.StatusUnsupportedMediaType), http.StatusUnsupportedMediaType)
+
+			return
+		}
+
+		next.ServeHTTP(writer, req)
+	})
+}
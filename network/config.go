@@ -6,20 +6,37 @@ import "time"
 // and minimum TLS version, timeouts, and other network properties.
 // This should typically be marshalled from a local config file, and fed to network.Init.
 type Config struct {
-	// Common
+	// Common - the key pair is loaded when first needed, and reloaded on changes when watched (see WatchTLS)
 	CertPath            string        `json:"certPath,omitempty"`
 	KeyPath             string        `json:"keyPath,omitempty"`
-	TLSMin              uint16        `json:"tlsMin,omitempty"`
+	TLSMin              uint16        `json:"tlsMin,omitempty" enum:"769,770,771,772"`
 	TLSHandshakeTimeout time.Duration `json:"tlsHandshakeTimeout,omitempty"`
+	// H2C enables HTTP/2 over cleartext, for internal services where TLS is terminated by the mesh
+	H2C bool `json:"h2c,omitempty"`
+	// UnsafeTLSDebug logs TLS handshake details, and enables TLSKeyLogFile - never enable it in production
+	UnsafeTLSDebug bool `json:"unsafeTlsDebug,omitempty"`
+	// TLSKeyLogFile receives TLS session keys in the NSS key log format, allowing to decrypt captured traffic
+	// (eg: with Wireshark) - defaults to SSLKEYLOGFILE, and requires UnsafeTLSDebug
+	TLSKeyLogFile string `json:"tlsKeyLogFile,omitempty"`
 	// Client only
-	DialerTimeout      time.Duration `json:"dialerTimeout,omitempty"`
-	DialerKeepAlive    time.Duration `json:"dialerKeepAlive,omitempty"`
-	RootCAs            []string      `json:"rootCa,omitempty"`
-	DisallowSystemRoot bool          `json:"disallowSystemRoot,omitempty"`
+	DialerTimeout   time.Duration `json:"dialerTimeout,omitempty"`
+	DialerKeepAlive time.Duration `json:"dialerKeepAlive,omitempty"`
+	// RootCAs are PEM contents, or paths of PEM files
+	RootCAs            []string `json:"rootCa,omitempty"`
+	DisallowSystemRoot bool     `json:"disallowSystemRoot,omitempty"`
+	// DNSOverHTTPS is a DoH endpoint url (eg: https://1.1.1.1/dns-query) to use instead of the system resolver
+	DNSOverHTTPS string `json:"dnsOverHttps,omitempty"`
+	// DNSOverTLS is a DoT server (eg: 1.1.1.1 or dns.example.com:853) to use instead of the system resolver
+	DNSOverTLS string `json:"dnsOverTls,omitempty"`
+	// DNSFallback allows falling back to the system resolver if encrypted resolution fails
+	DNSFallback bool `json:"dnsFallback,omitempty"`
 	// Server only
+	// ClientCA is PEM content, or the path of a PEM file
 	ClientCA          string `json:"clientCa,omitempty"`
 	ClientCertRequire bool   `json:"clientCertRequire,omitempty"`
 	Port              uint16 `json:"port,omitempty"`
+	// Routes limits requests per path prefix (see Limit)
+	Routes map[string]*RouteLimits `json:"routes,omitempty"`
 
 	Resolve func(pth ...string) string `json:"-"`
 }
@@ -0,0 +1,116 @@
+package network
+
+import (
+	"crypto/tls"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"go.codecomet.dev/core/filesystem"
+	"go.codecomet.dev/core/log"
+)
+
+// keyLogEnv is the conventional variable pointing at a TLS key log file, honored by browsers, curl, and Wireshark.
+const keyLogEnv = "SSLKEYLOGFILE"
+
+var (
+	keyLogs   = map[string]io.Writer{} //nolint:gochecknoglobals
+	keyLogsMu sync.Mutex               //nolint:gochecknoglobals
+)
+
+// debugTLS sets up TLS debugging on tlsConfig if conf enables it: session keys are written to the key log file, and
+// handshake details are logged at debug level.
+// Key logs allow decrypting all traffic of the connections: this requires the explicit UnsafeTLSDebug flag.
+func debugTLS(conf *Config, tlsConfig *tls.Config, side string) {
+	if conf == nil {
+		return
+	}
+
+	keyLogFile := conf.TLSKeyLogFile
+
+	if !conf.UnsafeTLSDebug {
+		if keyLogFile != "" {
+			log.Warn().Str("file", keyLogFile).Msg("TLS key logging requires the unsafeTlsDebug flag. Ignoring it.")
+		}
+
+		return
+	}
+
+	if keyLogFile == "" {
+		keyLogFile = os.Getenv(keyLogEnv)
+	}
+
+	if keyLogFile != "" {
+		if writer := keyLog(resolvePath(conf, keyLogFile)); writer != nil {
+			log.Warn().Str("file", keyLogFile).Str("side", side).
+				Msg("TLS session keys are being logged. Anyone reading this file can decrypt the traffic.")
+
+			tlsConfig.KeyLogWriter = writer
+		}
+	}
+
+	verify := tlsConfig.VerifyConnection
+	tlsConfig.VerifyConnection = func(state tls.ConnectionState) error {
+		logHandshake(side, state)
+
+		if verify != nil {
+			return verify(state)
+		}
+
+		return nil
+	}
+}
+
+// keyLog returns the shared writer of a key log file, opened once for all configurations.
+func keyLog(name string) io.Writer {
+	keyLogsMu.Lock()
+	defer keyLogsMu.Unlock()
+
+	if writer, ok := keyLogs[name]; ok {
+		return writer
+	}
+
+	file, err := os.OpenFile(name, os.O_CREATE|os.O_APPEND|os.O_WRONLY, filesystem.FilePermissionsPrivate)
+	if err != nil {
+		log.Error().Err(err).Str("file", name).Msg("Cannot open TLS key log file")
+
+		return nil
+	}
+
+	keyLogs[name] = file
+
+	return file
+}
+
+func logHandshake(side string, state tls.ConnectionState) {
+	peers := make([]string, len(state.PeerCertificates))
+	for i, cert := range state.PeerCertificates {
+		peers[i] = cert.Subject.String() + " (issuer: " + cert.Issuer.String() + ")"
+	}
+
+	log.Debug().Str(log.ContextFieldName, "network/tls").
+		Str("side", side).
+		Str("serverName", state.ServerName).
+		Str("version", tlsVersionName(state.Version)).
+		Str("cipherSuite", tls.CipherSuiteName(state.CipherSuite)).
+		Str("protocol", state.NegotiatedProtocol).
+		Bool("resumed", state.DidResume).
+		Str("peerCertificates", strings.Join(peers, ", ")).
+		Msg("TLS handshake")
+}
+
+func tlsVersionName(version uint16) string {
+	switch version {
+	case tls.VersionTLS10:
+		return "TLS 1.0"
+	case tls.VersionTLS11:
+		return "TLS 1.1"
+	case tls.VersionTLS12:
+		return "TLS 1.2"
+	case tls.VersionTLS13:
+		return "TLS 1.3"
+	default:
+		return "unknown"
+	}
+}
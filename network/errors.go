@@ -0,0 +1,14 @@
+package network
+
+import "errors"
+
+var (
+	ErrSignatureInvalid    = errors.New("invalid request signature")
+	ErrSignatureExpired    = errors.New("expired request signature")
+	ErrSignatureReplayed   = errors.New("replayed request signature")
+	ErrRateLimited         = errors.New("rate limited")
+	ErrBodyTooLarge        = errors.New("request body too large")
+	ErrReadTimeout         = errors.New("request body read timeout")
+	ErrUnsupportedEncoding = errors.New("unsupported content encoding")
+	ErrNotInitialized      = errors.New("network is not initialized (see Init)")
+)
@@ -1,24 +1,80 @@
 package network
 
 import (
+	"context"
 	"crypto/tls"
 	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"go.codecomet.dev/core/log"
 )
 
-var network *Network //nolint:gochecknoglobals
+var (
+	network    *Network             //nolint:gochecknoglobals
+	globalHook sync.Once            //nolint:gochecknoglobals
+	global     = &globalTransport{} //nolint:gochecknoglobals
+)
+
+// globalTransport is installed once as http.DefaultTransport, and delegates to the transport of the network set by
+// Init, which can then be replaced without writing http.DefaultTransport while it is in use.
+type globalTransport struct {
+	current atomic.Value
+}
+
+// delegate is what globalTransport delegates to.
+type delegate struct {
+	owner     *Network
+	transport *Transport
+}
+
+func (t *globalTransport) set(owner *Network, transport *Transport) {
+	t.current.Store(&delegate{owner: owner, transport: transport})
+}
+
+func (t *globalTransport) get() *delegate {
+	current, _ := t.current.Load().(*delegate)
+
+	return current
+}
+
+func (t *globalTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return t.get().transport.RoundTrip(req)
+}
+
+func (t *globalTransport) CloseIdleConnections() {
+	t.get().transport.CloseIdleConnections()
+}
 
 // Init should be called when the app starts, from config objects.
 func Init(clientConf *Config, serverConf *Config) {
 	log.Debug().Msg("Initializing network core with config")
 
-	network = &Network{
-		clientConfig: clientConf,
-		serverConfig: serverConf,
+	network = New(clientConf, serverConf)
+
+	global.set(network, network.Transport())
+
+	// Transports do not pick up reloaded root CAs by themselves
+	globalHook.Do(func() {
+		http.DefaultTransport = global
+
+		OnChange(func(changed *Network) {
+			if current := global.get(); current != nil && current.owner == changed {
+				global.set(changed, changed.Transport())
+			}
+		})
+	})
+}
+
+// WatchTLS watches the TLS material of the network set by Init (see Network.WatchTLS). When it changes,
+// http.DefaultTransport switches to a new transport. It fails with ErrNotInitialized before Init.
+func WatchTLS(ctx context.Context, interval time.Duration) error {
+	if network == nil {
+		return ErrNotInitialized
 	}
 
-	http.DefaultTransport = network.Transport()
+	return network.WatchTLS(ctx, interval)
 }
 
 func GetTLSConfig() *tls.Config {
@@ -28,3 +84,12 @@ func GetTLSConfig() *tls.Config {
 func GetTransport() *Transport {
 	return network.Transport()
 }
+
+func GetHandler(handler http.Handler) http.Handler {
+	return network.Handler(handler)
+}
+
+// GetLimitStats returns the route limits counters of the handlers obtained from GetHandler.
+func GetLimitStats() map[string]LimitStats {
+	return network.LimitStats()
+}
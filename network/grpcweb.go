@@ -0,0 +1,192 @@
+package network
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+const (
+	grpcContentType        = "application/grpc"
+	grpcWebContentType     = "application/grpc-web"
+	grpcWebTextContentType = "application/grpc-web-text"
+	grpcWebTrailerFlag     = 0x80
+	grpcWebFrameHeaderLen  = 5
+)
+
+// GRPCWebHandler bridges gRPC-web requests (binary and text variants) to the provided gRPC handler (typically a
+// *grpc.Server), so that browser clients can talk to it without a separate proxy.
+// Any request that is not gRPC-web is passed through untouched.
+func GRPCWebHandler(grpcHandler http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
+		contentType := req.Header.Get("Content-Type")
+		if req.Method != http.MethodPost || !strings.HasPrefix(contentType, grpcWebContentType) {
+			grpcHandler.ServeHTTP(writer, req)
+
+			return
+		}
+
+		isText := strings.HasPrefix(contentType, grpcWebTextContentType)
+
+		grpcReq := req.Clone(req.Context())
+		grpcReq.ProtoMajor = 2
+		grpcReq.ProtoMinor = 0
+		grpcReq.Proto = "HTTP/2"
+		grpcReq.ContentLength = -1
+		grpcReq.Header.Del("Content-Length")
+		grpcReq.Header.Set("Te", "trailers")
+
+		if isText {
+			grpcReq.Header.Set("Content-Type", grpcContentType+strings.TrimPrefix(contentType, grpcWebTextContentType))
+			grpcReq.Body = io.NopCloser(base64.NewDecoder(base64.StdEncoding, req.Body))
+		} else {
+			grpcReq.Header.Set("Content-Type", grpcContentType+strings.TrimPrefix(contentType, grpcWebContentType))
+		}
+
+		resp := newGRPCWebResponse(writer, isText)
+		grpcHandler.ServeHTTP(resp, grpcReq)
+		resp.finish()
+	})
+}
+
+// grpcWebResponse captures the gRPC response, and translates headers, body and trailers into gRPC-web.
+type grpcWebResponse struct {
+	writer      http.ResponseWriter
+	header      http.Header
+	out         io.Writer
+	encoder     io.WriteCloser
+	isText      bool
+	wroteHeader bool
+}
+
+func newGRPCWebResponse(writer http.ResponseWriter, isText bool) *grpcWebResponse {
+	resp := &grpcWebResponse{
+		writer: writer,
+		header: http.Header{},
+		out:    writer,
+		isText: isText,
+	}
+
+	if isText {
+		resp.encoder = base64.NewEncoder(base64.StdEncoding, writer)
+		resp.out = resp.encoder
+	}
+
+	return resp
+}
+
+func (resp *grpcWebResponse) Header() http.Header {
+	return resp.header
+}
+
+func (resp *grpcWebResponse) WriteHeader(code int) {
+	if resp.wroteHeader {
+		return
+	}
+
+	resp.wroteHeader = true
+
+	declared := resp.declaredTrailers()
+
+	for key, values := range resp.header {
+		if key == "Trailer" || declared[key] || strings.HasPrefix(key, http.TrailerPrefix) {
+			continue
+		}
+
+		resp.writer.Header()[key] = values
+	}
+
+	contentType := resp.header.Get("Content-Type")
+	if resp.isText {
+		resp.writer.Header().Set("Content-Type", grpcWebTextContentType+strings.TrimPrefix(contentType, grpcContentType))
+	} else {
+		resp.writer.Header().Set("Content-Type", grpcWebContentType+strings.TrimPrefix(contentType, grpcContentType))
+	}
+
+	resp.writer.Header().Del("Content-Length")
+	resp.writer.WriteHeader(code)
+}
+
+func (resp *grpcWebResponse) Write(data []byte) (int, error) {
+	if !resp.wroteHeader {
+		resp.WriteHeader(http.StatusOK)
+	}
+
+	return resp.out.Write(data)
+}
+
+func (resp *grpcWebResponse) Flush() {
+	if !resp.wroteHeader {
+		resp.WriteHeader(http.StatusOK)
+	}
+
+	if flusher, ok := resp.writer.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// declaredTrailers returns the set of trailer names announced through the Trailer header.
+func (resp *grpcWebResponse) declaredTrailers() map[string]bool {
+	declared := map[string]bool{}
+
+	for _, value := range resp.header.Values("Trailer") {
+		for _, key := range strings.Split(value, ",") {
+			declared[http.CanonicalHeaderKey(strings.TrimSpace(key))] = true
+		}
+	}
+
+	return declared
+}
+
+// finish writes the trailers as a gRPC-web trailer frame at the end of the body.
+func (resp *grpcWebResponse) finish() {
+	if !resp.wroteHeader {
+		resp.WriteHeader(http.StatusOK)
+	}
+
+	trailers := http.Header{}
+
+	for key := range resp.declaredTrailers() {
+		if values := resp.header.Values(key); len(values) > 0 {
+			trailers[key] = values
+		}
+	}
+
+	for key, values := range resp.header {
+		if strings.HasPrefix(key, http.TrailerPrefix) {
+			trailers[http.CanonicalHeaderKey(strings.TrimPrefix(key, http.TrailerPrefix))] = values
+		}
+	}
+
+	keys := make([]string, 0, len(trailers))
+	for key := range trailers {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	payload := &bytes.Buffer{}
+
+	for _, key := range keys {
+		for _, value := range trailers[key] {
+			payload.WriteString(strings.ToLower(key) + ": " + value + "\r\n")
+		}
+	}
+
+	frame := make([]byte, grpcWebFrameHeaderLen, grpcWebFrameHeaderLen+payload.Len())
+	frame[0] = grpcWebTrailerFlag
+	binary.BigEndian.PutUint32(frame[1:], uint32(payload.Len()))
+	frame = append(frame, payload.Bytes()...)
+
+	_, _ = resp.out.Write(frame)
+
+	if resp.encoder != nil {
+		_ = resp.encoder.Close()
+	}
+
+	resp.Flush()
+}
@@ -0,0 +1,47 @@
+package network
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+// h2cTransport returns an HTTP/2 transport speaking cleartext (prior knowledge) to plain http:// endpoints.
+// This is meant for internal communication where TLS is terminated by the mesh.
+func h2cTransport(dialer *net.Dialer) *http2.Transport {
+	return &http2.Transport{
+		AllowHTTP: true,
+		DialTLSContext: func(ctx context.Context, network string, addr string, _ *tls.Config) (net.Conn, error) {
+			return dialer.DialContext(ctx, network, addr)
+		},
+	}
+}
+
+// Handler wraps the provided handler according to the server configuration.
+// Routes limits are applied (see LimitStats for their counters), and instrumentation, if any (see Instrument).
+// If H2C is enabled, cleartext HTTP/2 requests (prior knowledge or upgrade) are accepted in addition to HTTP/1.
+func (network *Network) Handler(handler http.Handler) http.Handler {
+	if len(network.serverConfig.Routes) > 0 {
+		limiter := Limit(network.serverConfig.Routes, handler)
+		handler = limiter
+
+		network.mu.Lock()
+		network.limiters = append(network.limiters, limiter)
+		network.mu.Unlock()
+	}
+
+	// Outermost, so that spans cover limits
+	if inst := instrumented(); inst != nil && inst.Server != nil {
+		handler = inst.Server(handler)
+	}
+
+	if !network.serverConfig.H2C {
+		return handler
+	}
+
+	return h2c.NewHandler(handler, &http2.Server{})
+}
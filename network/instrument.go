@@ -0,0 +1,62 @@
+package network
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+)
+
+// Instrumentation wraps the transports and handlers of the package, typically to trace requests. It is registered
+// by the telemetry package (see telemetry.Config.HTTP), which this package cannot depend on.
+type Instrumentation struct {
+	// Client wraps the transport sending each request
+	Client func(http.RoundTripper) http.RoundTripper
+	// Server wraps handlers obtained from Handler
+	Server func(http.Handler) http.Handler
+}
+
+var instrumentation atomic.Value //nolint:gochecknoglobals
+
+// Instrument registers inst for transports, starting with the next request, and for handlers obtained afterwards.
+// A nil inst removes instrumentation.
+func Instrument(inst *Instrumentation) {
+	if inst == nil {
+		inst = &Instrumentation{}
+	}
+
+	instrumentation.Store(inst)
+}
+
+func instrumented() *Instrumentation {
+	inst, _ := instrumentation.Load().(*Instrumentation)
+
+	return inst
+}
+
+// uninstrumentedKey marks the context of requests that skip instrumentation.
+type uninstrumentedKey struct{}
+
+// WithoutInstrumentation marks ctx, so that requests made with it are not instrumented: requests exporting
+// telemetry or reporting crashes would otherwise generate telemetry of their own, forever.
+func WithoutInstrumentation(ctx context.Context) context.Context {
+	return context.WithValue(ctx, uninstrumentedKey{}, true)
+}
+
+// IsUninstrumented tells whether ctx was marked with WithoutInstrumentation.
+func IsUninstrumented(ctx context.Context) bool {
+	return ctx.Value(uninstrumentedKey{}) != nil
+}
+
+// UninstrumentedTransport wraps base, so that the requests it sends are not instrumented (see
+// WithoutInstrumentation).
+func UninstrumentedTransport(base http.RoundTripper) http.RoundTripper {
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		return base.RoundTrip(req.WithContext(WithoutInstrumentation(req.Context())))
+	})
+}
+
+type roundTripperFunc func(req *http.Request) (*http.Response, error)
+
+func (fn roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return fn(req)
+}
@@ -0,0 +1,314 @@
+package network
+
+import (
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.codecomet.dev/core/log"
+)
+
+// RouteLimits protect a route of an HTTP server from oversized, slow, or too many concurrent requests.
+type RouteLimits struct {
+	// MaxBodySize, if set, caps request bodies, in bytes: larger requests are answered with a 413
+	MaxBodySize int64 `json:"maxBodySize,omitempty"`
+	// ReadTimeout, if set, caps the time spent reading the request body: slower requests are answered with a 408.
+	// It is checked on every read - clients sending nothing at all are only stopped by the server ReadTimeout
+	ReadTimeout time.Duration `json:"readTimeout,omitempty"`
+	// MaxConcurrent, if set, caps the requests served at once: excess requests are answered with a 429
+	MaxConcurrent int `json:"maxConcurrent,omitempty"`
+}
+
+// LimitStats are cumulative counters of a route protected by limits.
+type LimitStats struct {
+	// InFlight are requests being served
+	InFlight int64 `json:"inFlight"`
+	// Served are requests handed over to the handler
+	Served int64 `json:"served"`
+	// TooLarge are requests answered with a 413
+	TooLarge int64 `json:"tooLarge"`
+	// TimedOut are requests answered with a 408
+	TimedOut int64 `json:"timedOut"`
+	// Throttled are requests answered with a 429
+	Throttled int64 `json:"throttled"`
+}
+
+// Limiter is a middleware applying RouteLimits to requests, according to their path (see Limit).
+type Limiter struct {
+	routes []*routeLimiter
+	next   http.Handler
+}
+
+// routeLimiter applies the limits of a route, and counts what happens.
+type routeLimiter struct {
+	// Accessed atomically: first, so that they are 64-bit aligned on 32-bit platforms
+	inFlight  int64
+	served    int64
+	tooLarge  int64
+	timedOut  int64
+	throttled int64
+	prefix    string
+	limits    *RouteLimits
+	slots     chan struct{}
+}
+
+// Limit returns a middleware applying limits to requests before handing them over to next. Routes are path
+// prefixes (eg: "/upload/", or "/" for all requests): the longest one matching the request path applies. Requests
+// matching no route are not limited.
+// Bodies announcing a size over MaxBodySize are rejected upfront. Otherwise, handlers reading past the limit or the
+// deadline get an ErrBodyTooLarge or ErrReadTimeout error, the response is sent for them, and what they write next
+// is discarded.
+func Limit(routes map[string]*RouteLimits, next http.Handler) *Limiter {
+	limiter := &Limiter{next: next}
+
+	for prefix, limits := range routes {
+		if limits == nil {
+			continue
+		}
+
+		route := &routeLimiter{prefix: prefix, limits: limits}
+		if limits.MaxConcurrent > 0 {
+			route.slots = make(chan struct{}, limits.MaxConcurrent)
+		}
+
+		limiter.routes = append(limiter.routes, route)
+	}
+
+	sort.Slice(limiter.routes, func(i, j int) bool {
+		return len(limiter.routes[i].prefix) > len(limiter.routes[j].prefix)
+	})
+
+	return limiter
+}
+
+// ServeHTTP applies the limits of the route matching the request.
+func (limiter *Limiter) ServeHTTP(writer http.ResponseWriter, req *http.Request) {
+	for _, route := range limiter.routes {
+		if strings.HasPrefix(req.URL.Path, route.prefix) {
+			route.serve(writer, req, limiter.next)
+
+			return
+		}
+	}
+
+	limiter.next.ServeHTTP(writer, req)
+}
+
+// Stats returns the counters of each route, since the limiter was created.
+func (limiter *Limiter) Stats() map[string]LimitStats {
+	stats := make(map[string]LimitStats, len(limiter.routes))
+
+	for _, route := range limiter.routes {
+		stats[route.prefix] = LimitStats{
+			InFlight:  atomic.LoadInt64(&route.inFlight),
+			Served:    atomic.LoadInt64(&route.served),
+			TooLarge:  atomic.LoadInt64(&route.tooLarge),
+			TimedOut:  atomic.LoadInt64(&route.timedOut),
+			Throttled: atomic.LoadInt64(&route.throttled),
+		}
+	}
+
+	return stats
+}
+
+// LimitStats returns the counters of each route of Config.Routes, summed over the handlers obtained from Handler.
+// Limits themselves apply to each handler on its own.
+func (network *Network) LimitStats() map[string]LimitStats {
+	network.mu.Lock()
+	limiters := append([]*Limiter{}, network.limiters...)
+	network.mu.Unlock()
+
+	stats := map[string]LimitStats{}
+
+	for _, limiter := range limiters {
+		for prefix, route := range limiter.Stats() {
+			total := stats[prefix]
+			total.InFlight += route.InFlight
+			total.Served += route.Served
+			total.TooLarge += route.TooLarge
+			total.TimedOut += route.TimedOut
+			total.Throttled += route.Throttled
+			stats[prefix] = total
+		}
+	}
+
+	return stats
+}
+
+func (route *routeLimiter) serve(writer http.ResponseWriter, req *http.Request, next http.Handler) {
+	if route.slots != nil {
+		select {
+		case route.slots <- struct{}{}:
+			defer func() { <-route.slots }()
+		default:
+			atomic.AddInt64(&route.throttled, 1)
+			log.Debug().Str("path", req.URL.Path).Msg("Rejected request over the concurrency limit")
+			writer.Header().Set("Retry-After", "1")
+			http.Error(writer, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+
+			return
+		}
+	}
+
+	atomic.AddInt64(&route.inFlight, 1)
+	defer atomic.AddInt64(&route.inFlight, -1)
+
+	limits := route.limits
+
+	if limits.MaxBodySize > 0 && req.ContentLength > limits.MaxBodySize {
+		atomic.AddInt64(&route.tooLarge, 1)
+		log.Debug().Str("path", req.URL.Path).Int64("size", req.ContentLength).Msg("Rejected request body over the size limit")
+		http.Error(writer, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+
+		return
+	}
+
+	guarded := &limitWriter{ResponseWriter: writer}
+
+	if req.Body != nil && req.Body != http.NoBody && (limits.MaxBodySize > 0 || limits.ReadTimeout > 0) {
+		body := &limitBody{ReadCloser: req.Body, route: route, writer: guarded, path: req.URL.Path, remaining: -1}
+
+		if limits.MaxBodySize > 0 {
+			body.remaining = limits.MaxBodySize
+		}
+
+		if limits.ReadTimeout > 0 {
+			body.deadline = time.Now().Add(limits.ReadTimeout)
+		}
+
+		req.Body = body
+	}
+
+	atomic.AddInt64(&route.served, 1)
+
+	next.ServeHTTP(guarded, req)
+}
+
+// limitBody enforces the size limit and read deadline of a request body.
+type limitBody struct {
+	io.ReadCloser
+	route    *routeLimiter
+	writer   *limitWriter
+	path     string
+	deadline time.Time
+	// remaining is -1 without size limit
+	remaining int64
+	err       error
+}
+
+func (body *limitBody) Read(buf []byte) (int, error) {
+	if body.err != nil {
+		return 0, body.err
+	}
+
+	if body.expired() {
+		return 0, body.fail(ErrReadTimeout, http.StatusRequestTimeout, &body.route.timedOut)
+	}
+
+	// Read one byte past the limit, to tell bodies of exactly the maximum size from larger ones
+	if body.remaining >= 0 && int64(len(buf)) > body.remaining+1 {
+		buf = buf[:body.remaining+1]
+	}
+
+	read, err := body.ReadCloser.Read(buf)
+
+	if body.remaining >= 0 {
+		if int64(read) > body.remaining {
+			read = int(body.remaining)
+			body.remaining = 0
+
+			return read, body.fail(ErrBodyTooLarge, http.StatusRequestEntityTooLarge, &body.route.tooLarge)
+		}
+
+		body.remaining -= int64(read)
+	}
+
+	if err == nil && body.expired() {
+		return read, body.fail(ErrReadTimeout, http.StatusRequestTimeout, &body.route.timedOut)
+	}
+
+	return read, err
+}
+
+func (body *limitBody) expired() bool {
+	return !body.deadline.IsZero() && time.Now().After(body.deadline)
+}
+
+// fail answers the request with status, unless the handler already did, and fails subsequent reads with err.
+func (body *limitBody) fail(err error, status int, counter *int64) error {
+	body.err = err
+
+	atomic.AddInt64(counter, 1)
+	log.Debug().Err(err).Str("path", body.path).Msg("Rejected request body")
+	body.writer.reject(err, status)
+
+	return err
+}
+
+// limitWriter lets limitBody answer the request on behalf of the handler, and discards what the handler writes next.
+type limitWriter struct {
+	http.ResponseWriter
+	mu          sync.Mutex
+	wroteHeader bool
+	rejected    error
+}
+
+func (writer *limitWriter) reject(err error, status int) {
+	writer.mu.Lock()
+	defer writer.mu.Unlock()
+
+	if writer.wroteHeader {
+		return
+	}
+
+	writer.wroteHeader = true
+	writer.rejected = err
+
+	writer.ResponseWriter.Header().Set("Connection", "close")
+	http.Error(writer.ResponseWriter, http.StatusText(status), status)
+}
+
+func (writer *limitWriter) WriteHeader(status int) {
+	writer.mu.Lock()
+	defer writer.mu.Unlock()
+
+	if writer.wroteHeader {
+		return
+	}
+
+	writer.wroteHeader = true
+	writer.ResponseWriter.WriteHeader(status)
+}
+
+func (writer *limitWriter) Write(data []byte) (int, error) {
+	writer.mu.Lock()
+	defer writer.mu.Unlock()
+
+	if writer.rejected != nil {
+		return 0, writer.rejected
+	}
+
+	writer.wroteHeader = true
+
+	return writer.ResponseWriter.Write(data)
+}
+
+// Flush flushes the underlying writer, if it can, so that streaming handlers keep working.
+func (writer *limitWriter) Flush() {
+	writer.mu.Lock()
+	defer writer.mu.Unlock()
+
+	if flusher, ok := writer.ResponseWriter.(http.Flusher); ok && writer.rejected == nil {
+		writer.wroteHeader = true
+		flusher.Flush()
+	}
+}
+
+// Unwrap returns the underlying writer.
+func (writer *limitWriter) Unwrap() http.ResponseWriter {
+	return writer.ResponseWriter
+}
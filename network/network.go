@@ -5,6 +5,7 @@ import (
 	"crypto/x509"
 	"net"
 	"net/http"
+	"sync"
 
 	"go.codecomet.dev/core/log"
 )
@@ -14,17 +15,24 @@ import (
 type Network struct {
 	clientConfig *Config
 	serverConfig *Config
+	mu           sync.Mutex
+	pairs        map[*Config]*keyPair
+	limiters     []*Limiter
+}
+
+// New returns a Network for the given configurations, independent from the global one (see Init) - eg: for a client
+// with its own CAs and client certificate.
+func New(clientConf *Config, serverConf *Config) *Network {
+	return &Network{
+		clientConfig: clientConf,
+		serverConfig: serverConf,
+	}
 }
 
 // TLSConfig returns a new tls.Config object populated against the configuration.
 func (network *Network) TLSConfig() *tls.Config {
 	cCA := x509.NewCertPool()
-	if network.serverConfig.ClientCA != "" {
-		ok := cCA.AppendCertsFromPEM([]byte(network.serverConfig.ClientCA))
-		if !ok {
-			log.Error().Msg("Invalid client CA in your config... Not loaded.")
-		}
-	}
+	appendCAs(cCA, network.serverConfig, network.serverConfig.ClientCA)
 	/*
 		if serverConfig.ClientCA != nil {
 			for _, v := range serverConfig.ClientCA {
@@ -52,6 +60,15 @@ func (network *Network) TLSConfig() *tls.Config {
 		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
 	}
 
+	// Loaded on handshakes, so that reloaded key pairs are picked up
+	if pair := network.keyPair(network.serverConfig); pair != nil {
+		tlsConfig.GetCertificate = func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return pair.certificate()
+		}
+	}
+
+	debugTLS(network.serverConfig, tlsConfig, "server")
+
 	return tlsConfig
 }
 
@@ -60,9 +77,10 @@ func (network *Network) Transport() *Transport {
 	dialer := &net.Dialer{
 		Timeout:   network.clientConfig.DialerTimeout,
 		KeepAlive: network.clientConfig.DialerKeepAlive,
+		Resolver:  network.resolver(),
 	}
 
-	return &Transport{
+	transport := &Transport{
 		Transport: http.Transport{
 			Proxy:               http.ProxyFromEnvironment,
 			DialContext:         dialer.DialContext,
@@ -70,6 +88,12 @@ func (network *Network) Transport() *Transport {
 			TLSClientConfig:     network.getClientTLSConfig(),
 		},
 	}
+
+	if network.clientConfig.H2C {
+		transport.h2c = h2cTransport(dialer)
+	}
+
+	return transport
 }
 
 func (network *Network) getClientTLSConfig() *tls.Config {
@@ -80,14 +104,7 @@ func (network *Network) getClientTLSConfig() *tls.Config {
 		rootCAs, _ = x509.SystemCertPool()
 	}
 
-	if network.clientConfig.RootCAs != nil {
-		for _, v := range network.clientConfig.RootCAs {
-			ok := rootCAs.AppendCertsFromPEM([]byte(v))
-			if !ok {
-				log.Error().Msg("Invalid root CA in your config... Not loaded.")
-			}
-		}
-	}
+	appendCAs(rootCAs, network.clientConfig, network.clientConfig.RootCAs...)
 
 	tlsMin := network.clientConfig.TLSMin
 	if tlsMin < tls.VersionTLS12 {
@@ -101,5 +118,21 @@ func (network *Network) getClientTLSConfig() *tls.Config {
 		// VerifyPeerCertificate:
 	}
 
+	// Client certificates are optional: servers not requesting one do not need it to exist
+	if pair := network.keyPair(network.clientConfig); pair != nil {
+		tlsConfig.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			cert, err := pair.certificate()
+			if err != nil {
+				log.Warn().Err(err).Msg("No client certificate available")
+
+				return &tls.Certificate{}, nil
+			}
+
+			return cert, nil
+		}
+	}
+
+	debugTLS(network.clientConfig, tlsConfig, "client")
+
 	return tlsConfig
 }
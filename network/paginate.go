@@ -0,0 +1,260 @@
+package network
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	paginateMaxWait     = time.Minute
+	paginateRetryAfter  = time.Second
+	paginateMaxAttempts = 3
+)
+
+// Page is a page of results, along with where the next one is.
+type Page[T any] struct {
+	Items []T
+	// Next is the cursor of the next page (a token, an offset, a URL...) - empty on the last page
+	Next string
+	// Response, if set, is the response the page was read from. If Next is empty, it is taken from the Link header
+	// (rel="next", as an absolute URL), and rate limit headers are honored before fetching the next page. Its body
+	// is left to the fetcher to close.
+	Response *http.Response
+}
+
+// PageFetcher fetches the page at cursor - the empty cursor being the first page.
+type PageFetcher[T any] func(ctx context.Context, cursor string) (*Page[T], error)
+
+// Paginator iterates over the items of all pages, fetching them as needed:
+//
+//	pages := network.Paginate(ctx, fetch)
+//	for pages.Next() {
+//		item := pages.Item()
+//	}
+//	if err := pages.Err(); err != nil {
+type Paginator[T any] struct {
+	// MaxWait caps how long to wait for a rate limit to reset, beyond which iteration fails with ErrRateLimited
+	MaxWait time.Duration
+
+	ctx    context.Context //nolint:containedctx
+	fetch  PageFetcher[T]
+	items  []T
+	index  int
+	item   T
+	cursor string
+	wait   time.Duration
+	done   bool
+	err    error
+}
+
+// Paginate returns a paginator over the pages returned by fetch, which stops when ctx is done.
+func Paginate[T any](ctx context.Context, fetch PageFetcher[T]) *Paginator[T] {
+	return &Paginator[T]{
+		MaxWait: paginateMaxWait,
+		ctx:     ctx,
+		fetch:   fetch,
+	}
+}
+
+// Next advances to the next item, fetching the next page if needed. It returns false when there are no more items,
+// or on error (see Err).
+func (pages *Paginator[T]) Next() bool {
+	for pages.index >= len(pages.items) {
+		if pages.done || pages.err != nil {
+			return false
+		}
+
+		pages.err = pages.fetchPage()
+	}
+
+	pages.item = pages.items[pages.index]
+	pages.index++
+
+	return true
+}
+
+// Item returns the current item.
+func (pages *Paginator[T]) Item() T {
+	return pages.item
+}
+
+// Err returns the error that stopped iteration, if any.
+func (pages *Paginator[T]) Err() error {
+	return pages.err
+}
+
+// All returns the remaining items of all pages.
+func (pages *Paginator[T]) All() ([]T, error) {
+	items := []T{}
+
+	for pages.Next() {
+		items = append(items, pages.Item())
+	}
+
+	return items, pages.Err()
+}
+
+// fetchPage fetches the page at the current cursor, retrying pages answered with a 429.
+func (pages *Paginator[T]) fetchPage() error {
+	for attempt := 1; ; attempt++ {
+		if err := pages.sleep(); err != nil {
+			return err
+		}
+
+		page, err := pages.fetch(pages.ctx, pages.cursor)
+
+		pages.wait = 0
+
+		if page != nil && page.Response != nil {
+			pages.wait = rateLimitWait(page.Response, time.Now())
+
+			if page.Response.StatusCode == http.StatusTooManyRequests && attempt < paginateMaxAttempts {
+				if pages.wait == 0 {
+					pages.wait = paginateRetryAfter
+				}
+
+				continue
+			}
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if page == nil {
+			pages.done = true
+
+			return nil
+		}
+
+		next := page.Next
+		if next == "" && page.Response != nil {
+			next = linkNext(page.Response)
+		}
+
+		pages.items = page.Items
+		pages.index = 0
+		// A server repeating the same cursor would have us loop forever
+		pages.done = next == "" || next == pages.cursor
+		pages.cursor = next
+
+		return nil
+	}
+}
+
+// sleep waits for the rate limit of the previous page to reset.
+func (pages *Paginator[T]) sleep() error {
+	if pages.wait <= 0 {
+		return pages.ctx.Err()
+	}
+
+	if pages.wait > pages.MaxWait {
+		return fmt.Errorf("%w: retry in %s", ErrRateLimited, pages.wait.Round(time.Second))
+	}
+
+	timer := time.NewTimer(pages.wait)
+	defer timer.Stop()
+
+	select {
+	case <-pages.ctx.Done():
+		return pages.ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// OffsetCursor returns the offset an offset cursor stands for - zero for the first page.
+func OffsetCursor(cursor string) int {
+	offset, _ := strconv.Atoi(cursor)
+
+	return offset
+}
+
+// NextOffset returns the cursor of the page after the one at cursor, for APIs paginating by offset and limit: empty
+// if the page had less than limit items, hence was the last one.
+func NextOffset(cursor string, items int, limit int) string {
+	if items < limit || items == 0 {
+		return ""
+	}
+
+	return strconv.Itoa(OffsetCursor(cursor) + items)
+}
+
+// linkNext returns the URL of the rel="next" link of resp, resolved against the request URL.
+func linkNext(resp *http.Response) string {
+	for _, header := range resp.Header.Values("Link") {
+		for _, link := range strings.Split(header, ",") {
+			parts := strings.Split(link, ";")
+
+			target := strings.TrimSpace(parts[0])
+			if !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+				continue
+			}
+
+			for _, param := range parts[1:] {
+				key, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+				if strings.EqualFold(key, "rel") && hasToken(strings.Trim(value, `"`), "next") {
+					return resolveLink(resp, target[1:len(target)-1])
+				}
+			}
+		}
+	}
+
+	return ""
+}
+
+func hasToken(list string, token string) bool {
+	for _, item := range strings.Fields(list) {
+		if strings.EqualFold(item, token) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func resolveLink(resp *http.Response, target string) string {
+	if resp.Request == nil || resp.Request.URL == nil {
+		return target
+	}
+
+	ref, err := url.Parse(target)
+	if err != nil {
+		return target
+	}
+
+	return resp.Request.URL.ResolveReference(ref).String()
+}
+
+// rateLimitWait returns how long to wait before the next request, from Retry-After, or rate limit headers
+// announcing an exhausted quota (X-RateLimit-* with an epoch reset, as GitHub, or RateLimit-* with a delay).
+func rateLimitWait(resp *http.Response, now time.Time) time.Duration {
+	if retry := resp.Header.Get("Retry-After"); retry != "" {
+		if seconds, err := strconv.Atoi(retry); err == nil {
+			return time.Duration(seconds) * time.Second
+		}
+
+		if date, err := http.ParseTime(retry); err == nil {
+			return date.Sub(now)
+		}
+	}
+
+	if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+		if reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+			return time.Unix(reset, 0).Sub(now)
+		}
+	}
+
+	if resp.Header.Get("RateLimit-Remaining") == "0" {
+		if reset, err := strconv.Atoi(resp.Header.Get("RateLimit-Reset")); err == nil {
+			return time.Duration(reset) * time.Second
+		}
+	}
+
+	return 0
+}
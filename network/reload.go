@@ -0,0 +1,204 @@
+package network
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"go.codecomet.dev/core/filesystem"
+	"go.codecomet.dev/core/log"
+)
+
+var (
+	changeHooks   []func(*Network) //nolint:gochecknoglobals
+	changeHooksMu sync.RWMutex     //nolint:gochecknoglobals
+)
+
+// OnChange registers a hook called after a network reloaded its TLS material (see WatchTLS). Key pairs are picked
+// up by existing TLS configurations, but CAs are not: hooks should replace the TLS configurations, transports and
+// clients built from the network.
+func OnChange(hook func(*Network)) {
+	changeHooksMu.Lock()
+	defer changeHooksMu.Unlock()
+
+	changeHooks = append(changeHooks, hook)
+}
+
+func notifyChange(network *Network) {
+	changeHooksMu.RLock()
+	hooks := changeHooks
+	changeHooksMu.RUnlock()
+
+	for _, hook := range hooks {
+		hook(network)
+	}
+}
+
+// keyPair is a certificate and its key, loaded from files on first use, and reloaded when they change.
+type keyPair struct {
+	certPath string
+	keyPath  string
+	mu       sync.RWMutex
+	cert     *tls.Certificate
+}
+
+func (pair *keyPair) certificate() (*tls.Certificate, error) {
+	pair.mu.RLock()
+	cert := pair.cert
+	pair.mu.RUnlock()
+
+	if cert != nil {
+		return cert, nil
+	}
+
+	return pair.load()
+}
+
+// load reads the key pair. On failure (eg: during a rotation, the certificate being written before its key), the
+// previous key pair is kept.
+func (pair *keyPair) load() (*tls.Certificate, error) {
+	cert, err := tls.LoadX509KeyPair(pair.certPath, pair.keyPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed loading key pair %s: %w", pair.certPath, err)
+	}
+
+	pair.mu.Lock()
+	pair.cert = &cert
+	pair.mu.Unlock()
+
+	return &cert, nil
+}
+
+// keyPair returns the key pair of conf, or nil if it has none.
+func (network *Network) keyPair(conf *Config) *keyPair {
+	if conf == nil || conf.CertPath == "" || conf.KeyPath == "" {
+		return nil
+	}
+
+	network.mu.Lock()
+	defer network.mu.Unlock()
+
+	if network.pairs == nil {
+		network.pairs = map[*Config]*keyPair{}
+	}
+
+	pair := network.pairs[conf]
+	if pair == nil {
+		pair = &keyPair{certPath: resolvePath(conf, conf.CertPath), keyPath: resolvePath(conf, conf.KeyPath)}
+		network.pairs[conf] = pair
+	}
+
+	return pair
+}
+
+// WatchTLS polls the certificates, keys and CA files referenced by the configuration every interval, until ctx is
+// done. When they change, key pairs are reloaded, and OnChange hooks are called.
+func (network *Network) WatchTLS(ctx context.Context, interval time.Duration) error {
+	files := network.tlsFiles()
+	if len(files) == 0 {
+		return nil
+	}
+
+	watcher, err := filesystem.NewWatcher(interval, files...)
+	if err != nil {
+		return fmt.Errorf("failed watching TLS material: %w", err)
+	}
+
+	changes := watcher.Watch(ctx)
+
+	go func() {
+		for changed := range changes {
+			log.Info().Strs("files", changed).Msg("TLS material changed, reloading")
+			network.reloadTLS()
+			notifyChange(network)
+		}
+	}()
+
+	return nil
+}
+
+func (network *Network) reloadTLS() {
+	for _, conf := range []*Config{network.serverConfig, network.clientConfig} {
+		if pair := network.keyPair(conf); pair != nil {
+			if _, err := pair.load(); err != nil {
+				log.Warn().Err(err).Msg("Failed reloading key pair. Keeping the previous one.")
+			}
+		}
+	}
+}
+
+// tlsFiles returns the files referenced by the configuration.
+func (network *Network) tlsFiles() []string {
+	seen := map[string]bool{}
+	files := []string{}
+
+	add := func(conf *Config, name string) {
+		name = resolvePath(conf, name)
+		if !seen[name] {
+			seen[name] = true
+			files = append(files, name)
+		}
+	}
+
+	for _, conf := range []*Config{network.serverConfig, network.clientConfig} {
+		if conf == nil {
+			continue
+		}
+
+		if network.keyPair(conf) != nil {
+			add(conf, conf.CertPath)
+			add(conf, conf.KeyPath)
+		}
+
+		for _, ca := range append([]string{conf.ClientCA}, conf.RootCAs...) {
+			if ca != "" && !isPEM(ca) {
+				add(conf, ca)
+			}
+		}
+	}
+
+	return files
+}
+
+// appendCAs adds CAs to pool. Each one is either PEM content, or the path of a PEM file.
+func appendCAs(pool *x509.CertPool, conf *Config, cas ...string) {
+	for _, ca := range cas {
+		if ca == "" {
+			continue
+		}
+
+		data := []byte(ca)
+
+		if !isPEM(ca) {
+			var err error
+
+			data, err = os.ReadFile(resolvePath(conf, ca))
+			if err != nil {
+				log.Error().Err(err).Msg("Cannot read CA file in your config... Not loaded.")
+
+				continue
+			}
+		}
+
+		if !pool.AppendCertsFromPEM(data) {
+			log.Error().Msg("Invalid CA in your config... Not loaded.")
+		}
+	}
+}
+
+func isPEM(ca string) bool {
+	return strings.Contains(ca, "-----BEGIN")
+}
+
+func resolvePath(conf *Config, name string) string {
+	if conf.Resolve == nil {
+		return name
+	}
+
+	return conf.Resolve(name)
+}
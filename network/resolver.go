@@ -0,0 +1,252 @@
+package network
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.codecomet.dev/core/log"
+)
+
+const (
+	dnsMessageContentType = "application/dns-message"
+	dnsOverTLSPort        = "853"
+	dnsFrameHeaderLen     = 2
+	dnsMaxResponseSize    = 65535
+)
+
+var errDNSConnClosed = errors.New("dns connection closed")
+
+// resolver returns an encrypted DNS resolver if the client config asks for one, or nil to use the system resolver.
+// DNS over HTTPS takes precedence over DNS over TLS if both are configured.
+func (network *Network) resolver() *net.Resolver {
+	conf := network.clientConfig
+
+	switch {
+	case conf.DNSOverHTTPS != "":
+		client := &http.Client{
+			Timeout: conf.DialerTimeout,
+			Transport: &http.Transport{
+				Proxy:               http.ProxyFromEnvironment,
+				TLSHandshakeTimeout: conf.TLSHandshakeTimeout,
+				TLSClientConfig:     network.getClientTLSConfig(),
+				// The endpoint itself is resolved by the system resolver - prefer ip literals in config
+				DialContext: (&net.Dialer{Timeout: conf.DialerTimeout}).DialContext,
+			},
+		}
+
+		return &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, _ string, address string) (net.Conn, error) {
+				return &dohConn{
+					ctx:      ctx,
+					client:   client,
+					endpoint: conf.DNSOverHTTPS,
+					fallback: fallbackAddress(conf, address),
+				}, nil
+			},
+		}
+	case conf.DNSOverTLS != "":
+		endpoint := conf.DNSOverTLS
+		if _, _, err := net.SplitHostPort(endpoint); err != nil {
+			endpoint = net.JoinHostPort(endpoint, dnsOverTLSPort)
+		}
+
+		host, _, _ := net.SplitHostPort(endpoint)
+		tlsConfig := network.getClientTLSConfig()
+		tlsConfig.ServerName = host
+
+		return &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network string, address string) (net.Conn, error) {
+				dialer := &tls.Dialer{
+					NetDialer: &net.Dialer{Timeout: conf.DialerTimeout},
+					Config:    tlsConfig,
+				}
+
+				conn, err := dialer.DialContext(ctx, "tcp", endpoint)
+				if err != nil && conf.DNSFallback {
+					log.Warn().Err(err).Str("endpoint", endpoint).Msg("DNS over TLS failed. Falling back to system resolver.")
+
+					return (&net.Dialer{}).DialContext(ctx, network, address)
+				}
+
+				return conn, err
+			},
+		}
+	}
+
+	return nil
+}
+
+func fallbackAddress(conf *Config, address string) string {
+	if !conf.DNSFallback {
+		return ""
+	}
+
+	return address
+}
+
+// dohConn speaks DNS over TCP framing to the go resolver, and forwards each query as a DNS over HTTPS request.
+type dohConn struct {
+	ctx      context.Context //nolint:containedctx
+	client   *http.Client
+	endpoint string
+	fallback string
+
+	mu       sync.Mutex
+	pending  bytes.Buffer
+	response bytes.Buffer
+	closed   bool
+}
+
+func (conn *dohConn) Write(data []byte) (int, error) {
+	conn.mu.Lock()
+	defer conn.mu.Unlock()
+
+	if conn.closed {
+		return 0, errDNSConnClosed
+	}
+
+	conn.pending.Write(data)
+
+	// Process every complete framed message
+	for conn.pending.Len() >= dnsFrameHeaderLen {
+		size := int(binary.BigEndian.Uint16(conn.pending.Bytes()[:dnsFrameHeaderLen]))
+		if conn.pending.Len() < dnsFrameHeaderLen+size {
+			break
+		}
+
+		conn.pending.Next(dnsFrameHeaderLen)
+		query := append([]byte{}, conn.pending.Next(size)...)
+
+		answer, err := conn.exchange(query)
+		if err != nil {
+			return 0, err
+		}
+
+		header := make([]byte, dnsFrameHeaderLen)
+		binary.BigEndian.PutUint16(header, uint16(len(answer)))
+		conn.response.Write(header)
+		conn.response.Write(answer)
+	}
+
+	return len(data), nil
+}
+
+func (conn *dohConn) Read(data []byte) (int, error) {
+	conn.mu.Lock()
+	defer conn.mu.Unlock()
+
+	if conn.response.Len() == 0 {
+		return 0, io.EOF
+	}
+
+	return conn.response.Read(data)
+}
+
+func (conn *dohConn) exchange(query []byte) ([]byte, error) {
+	answer, err := conn.post(query)
+	if err == nil || conn.fallback == "" {
+		return answer, err
+	}
+
+	log.Warn().Err(err).Str("endpoint", conn.endpoint).Msg("DNS over HTTPS failed. Falling back to system resolver.")
+
+	return conn.system(query)
+}
+
+func (conn *dohConn) post(query []byte) ([]byte, error) {
+	req, err := http.NewRequestWithContext(conn.ctx, http.MethodPost, conn.endpoint, bytes.NewReader(query))
+	if err != nil {
+		return nil, fmt.Errorf("failed creating DNS over HTTPS request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", dnsMessageContentType)
+	req.Header.Set("Accept", dnsMessageContentType)
+
+	resp, err := conn.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("DNS over HTTPS request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("DNS over HTTPS request failed with status %d", resp.StatusCode) //nolint:goerr113
+	}
+
+	return io.ReadAll(io.LimitReader(resp.Body, dnsMaxResponseSize))
+}
+
+// system forwards a query to the system nameserver over UDP.
+func (conn *dohConn) system(query []byte) ([]byte, error) {
+	udp, err := (&net.Dialer{}).DialContext(conn.ctx, "udp", conn.fallback)
+	if err != nil {
+		return nil, fmt.Errorf("fallback DNS dial failed: %w", err)
+	}
+	defer udp.Close()
+
+	if deadline, ok := conn.ctx.Deadline(); ok {
+		_ = udp.SetDeadline(deadline)
+	}
+
+	if _, err = udp.Write(query); err != nil {
+		return nil, fmt.Errorf("fallback DNS write failed: %w", err)
+	}
+
+	answer := make([]byte, dnsMaxResponseSize)
+
+	size, err := udp.Read(answer)
+	if err != nil {
+		return nil, fmt.Errorf("fallback DNS read failed: %w", err)
+	}
+
+	return answer[:size], nil
+}
+
+func (conn *dohConn) Close() error {
+	conn.mu.Lock()
+	defer conn.mu.Unlock()
+
+	conn.closed = true
+
+	return nil
+}
+
+func (conn *dohConn) LocalAddr() net.Addr {
+	return dohAddr(conn.endpoint)
+}
+
+func (conn *dohConn) RemoteAddr() net.Addr {
+	return dohAddr(conn.endpoint)
+}
+
+func (conn *dohConn) SetDeadline(_ time.Time) error {
+	return nil
+}
+
+func (conn *dohConn) SetReadDeadline(_ time.Time) error {
+	return nil
+}
+
+func (conn *dohConn) SetWriteDeadline(_ time.Time) error {
+	return nil
+}
+
+type dohAddr string
+
+func (addr dohAddr) Network() string {
+	return "https"
+}
+
+func (addr dohAddr) String() string {
+	return string(addr)
+}
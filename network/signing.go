@@ -0,0 +1,327 @@
+package network
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.codecomet.dev/core/id"
+	"go.codecomet.dev/core/log"
+)
+
+// Requests are signed in the spirit of AWS SigV4: a canonical form of the request (method, path, query, signed
+// headers, payload hash) is combined with a timestamp and a nonce, and HMAC-SHA256'd with a shared key.
+const (
+	SignatureAlgorithm = "CODECOMET-HMAC-SHA256"
+	HeaderSignDate     = "X-Codecomet-Date"
+	HeaderSignNonce    = "X-Codecomet-Nonce"
+	HeaderSignContent  = "X-Codecomet-Content-Sha256"
+
+	signTimeFormat        = "20060102T150405Z"
+	defaultSigningSkew    = 5 * time.Minute
+	defaultSigningMaxBody = 10 << 20
+	signingNonceCleanup   = time.Minute
+)
+
+// KeyProvider returns the shared key for a key ID.
+type KeyProvider func(keyID string) ([]byte, error)
+
+// SigningTransport signs outgoing requests with a shared key, before handing them to the base transport.
+type SigningTransport struct {
+	// Base is the underlying transport - defaults to GetTransport()
+	Base http.RoundTripper
+	// KeyID identifies the key to the verifier
+	KeyID string
+	// Key is the shared secret
+	Key []byte
+}
+
+// RoundTrip signs a copy of the request, and sends it.
+func (st *SigningTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	signed := req.Clone(req.Context())
+
+	payload, err := readBody(signed, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed reading request body for signing: %w", err)
+	}
+
+	sign(signed, st.KeyID, st.Key, payload, time.Now().UTC(), id.New())
+
+	base := st.Base
+	if base == nil {
+		base = GetTransport()
+	}
+
+	return base.RoundTrip(signed)
+}
+
+// VerifySignatures is a middleware rejecting requests without a valid signature with a 401.
+// Requests must be no older (or newer) than skew (defaults to 5 minutes), and nonces cannot be replayed within
+// that window.
+// Bodies are read in memory to be hashed: those larger than maxBody bytes (defaults to 10MiB) are rejected with a 413.
+func VerifySignatures(keys KeyProvider, skew time.Duration, maxBody int64, next http.Handler) http.Handler {
+	if skew <= 0 {
+		skew = defaultSigningSkew
+	}
+
+	if maxBody <= 0 {
+		maxBody = defaultSigningMaxBody
+	}
+
+	nonces := &nonceCache{seen: map[string]time.Time{}}
+
+	return http.HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
+		if err := verify(req, keys, skew, maxBody, nonces); err != nil {
+			log.Debug().Err(err).Str("path", req.URL.Path).Msg("Rejected request signature")
+
+			status := http.StatusUnauthorized
+			if errors.Is(err, ErrBodyTooLarge) {
+				status = http.StatusRequestEntityTooLarge
+			}
+
+			http.Error(writer, http.StatusText(status), status)
+
+			return
+		}
+
+		next.ServeHTTP(writer, req)
+	})
+}
+
+func sign(req *http.Request, keyID string, key []byte, payload []byte, now time.Time, nonce string) {
+	sum := sha256.Sum256(payload)
+
+	req.Header.Set(HeaderSignDate, now.Format(signTimeFormat))
+	req.Header.Set(HeaderSignNonce, nonce)
+	req.Header.Set(HeaderSignContent, hex.EncodeToString(sum[:]))
+
+	signedHeaders := []string{"host", strings.ToLower(HeaderSignContent), strings.ToLower(HeaderSignDate),
+		strings.ToLower(HeaderSignNonce)}
+	if req.Header.Get("Content-Type") != "" {
+		signedHeaders = append(signedHeaders, "content-type")
+	}
+
+	sort.Strings(signedHeaders)
+
+	signature := signature(req, key, signedHeaders)
+
+	req.Header.Set("Authorization", fmt.Sprintf("%s KeyId=%s, SignedHeaders=%s, Signature=%s",
+		SignatureAlgorithm, keyID, strings.Join(signedHeaders, ";"), signature))
+}
+
+func verify(req *http.Request, keys KeyProvider, skew time.Duration, maxBody int64, nonces *nonceCache) error {
+	auth := req.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, SignatureAlgorithm+" ") {
+		return fmt.Errorf("%w: missing or unsupported authorization", ErrSignatureInvalid)
+	}
+
+	params := map[string]string{}
+
+	for _, part := range strings.Split(strings.TrimPrefix(auth, SignatureAlgorithm+" "), ",") {
+		k, v, _ := strings.Cut(strings.TrimSpace(part), "=")
+		params[k] = v
+	}
+
+	signedHeaders := strings.Split(params["SignedHeaders"], ";")
+	for _, required := range []string{HeaderSignDate, HeaderSignNonce, HeaderSignContent, "host"} {
+		if !contains(signedHeaders, strings.ToLower(required)) {
+			return fmt.Errorf("%w: %s is not signed", ErrSignatureInvalid, required)
+		}
+	}
+
+	date, err := time.Parse(signTimeFormat, req.Header.Get(HeaderSignDate))
+	if err != nil {
+		return fmt.Errorf("%w: invalid date: %s", ErrSignatureInvalid, err.Error())
+	}
+
+	if delta := time.Since(date); delta > skew || delta < -skew {
+		return fmt.Errorf("%w: request date is out of the allowed window", ErrSignatureExpired)
+	}
+
+	key, err := keys(params["KeyId"])
+	if err != nil {
+		return fmt.Errorf("%w: unknown key %s: %s", ErrSignatureInvalid, params["KeyId"], err.Error())
+	}
+
+	if req.ContentLength > maxBody {
+		return fmt.Errorf("%w: %d bytes", ErrBodyTooLarge, req.ContentLength)
+	}
+
+	payload, err := readBody(req, maxBody)
+	if err != nil {
+		return fmt.Errorf("failed reading request body: %w", err)
+	}
+
+	sum := sha256.Sum256(payload)
+	if !hmac.Equal([]byte(hex.EncodeToString(sum[:])), []byte(req.Header.Get(HeaderSignContent))) {
+		return fmt.Errorf("%w: payload hash mismatch", ErrSignatureInvalid)
+	}
+
+	expected := signature(req, key, signedHeaders)
+	if !hmac.Equal([]byte(expected), []byte(params["Signature"])) {
+		return fmt.Errorf("%w: signature mismatch", ErrSignatureInvalid)
+	}
+
+	// Only record nonces for valid signatures, so that garbage does not fill the cache
+	if !nonces.use(params["KeyId"]+":"+req.Header.Get(HeaderSignNonce), date, skew) {
+		return fmt.Errorf("%w: nonce reuse", ErrSignatureReplayed)
+	}
+
+	return nil
+}
+
+// signature computes the hex HMAC of the string to sign.
+func signature(req *http.Request, key []byte, signedHeaders []string) string {
+	var canonical strings.Builder
+
+	canonical.WriteString(req.Method + "\n")
+	canonical.WriteString(canonicalPath(req.URL) + "\n")
+	canonical.WriteString(canonicalQuery(req.URL) + "\n")
+
+	for _, name := range signedHeaders {
+		value := req.Header.Get(name)
+		if name == "host" {
+			value = req.Host
+			if value == "" {
+				value = req.URL.Host
+			}
+		}
+
+		canonical.WriteString(name + ":" + strings.TrimSpace(value) + "\n")
+	}
+
+	canonical.WriteString(strings.Join(signedHeaders, ";") + "\n")
+	canonical.WriteString(req.Header.Get(HeaderSignContent))
+
+	canonicalSum := sha256.Sum256([]byte(canonical.String()))
+
+	toSign := strings.Join([]string{
+		SignatureAlgorithm,
+		req.Header.Get(HeaderSignDate),
+		req.Header.Get(HeaderSignNonce),
+		hex.EncodeToString(canonicalSum[:]),
+	}, "\n")
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(toSign))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func canonicalPath(u *url.URL) string {
+	pth := u.EscapedPath()
+	if pth == "" {
+		return "/"
+	}
+
+	return pth
+}
+
+func canonicalQuery(u *url.URL) string {
+	query := u.Query()
+	keys := make([]string, 0, len(query))
+
+	for k := range query {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	parts := []string{}
+
+	for _, k := range keys {
+		values := query[k]
+		sort.Strings(values)
+
+		for _, v := range values {
+			parts = append(parts, url.QueryEscape(k)+"="+url.QueryEscape(v))
+		}
+	}
+
+	return strings.Join(parts, "&")
+}
+
+// readBody reads the request body entirely, and replaces it so that it can be read again. Bodies larger than limit
+// bytes, if positive, fail with ErrBodyTooLarge.
+func readBody(req *http.Request, limit int64) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return []byte{}, nil
+	}
+
+	var reader io.Reader = req.Body
+	if limit > 0 {
+		reader = io.LimitReader(req.Body, limit+1)
+	}
+
+	payload, err := io.ReadAll(reader)
+	_ = req.Body.Close()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if limit > 0 && int64(len(payload)) > limit {
+		return nil, fmt.Errorf("%w: more than %d bytes", ErrBodyTooLarge, limit)
+	}
+
+	req.Body = io.NopCloser(bytes.NewReader(payload))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(payload)), nil
+	}
+
+	return payload, nil
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+// nonceCache remembers nonces for the duration of the allowed window.
+type nonceCache struct {
+	mu      sync.Mutex
+	seen    map[string]time.Time
+	cleaned time.Time
+}
+
+// use records nonce, and returns false if it has already been seen.
+func (cache *nonceCache) use(nonce string, date time.Time, skew time.Duration) bool {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	now := time.Now()
+
+	if now.Sub(cache.cleaned) > signingNonceCleanup {
+		for k, expires := range cache.seen {
+			if now.After(expires) {
+				delete(cache.seen, k)
+			}
+		}
+
+		cache.cleaned = now
+	}
+
+	if _, ok := cache.seen[nonce]; ok {
+		return false
+	}
+
+	// A nonce can only be replayed while the request date is within the window
+	cache.seen[nonce] = date.Add(skew)
+
+	return true
+}
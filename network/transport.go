@@ -12,6 +12,9 @@ type Transport struct {
 	http.Transport
 	TokenValue string
 	TokenType  string
+
+	// h2c, if set, is used for plain http:// requests
+	h2c http.RoundTripper
 }
 
 func (adt *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
@@ -24,7 +27,24 @@ func (adt *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
 		req.Header.Set("Accept", "application/json")
 	}
 
-	resp, err := adt.Transport.RoundTrip(req)
+	if inst := instrumented(); inst != nil && inst.Client != nil && !IsUninstrumented(req.Context()) {
+		return inst.Client(roundTripperFunc(adt.roundTrip)).RoundTrip(req)
+	}
+
+	return adt.roundTrip(req)
+}
+
+func (adt *Transport) roundTrip(req *http.Request) (*http.Response, error) {
+	var resp *http.Response
+
+	var err error
+
+	if adt.h2c != nil && req.URL.Scheme == "http" {
+		resp, err = adt.h2c.RoundTrip(req)
+	} else {
+		resp, err = adt.Transport.RoundTrip(req)
+	}
+
 	if err != nil {
 		err = fmt.Errorf("RoundTrip error: %w", err)
 	}
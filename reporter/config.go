@@ -10,4 +10,24 @@ type Config struct {
 	Disabled    bool   `json:"disabled"`
 	Environment string `json:"-"`
 	Release     string `json:"-"`
+
+	// IssueURLTemplate is used to build a user-facing issue URL for captured events, with {eventID} replaced
+	IssueURLTemplate string `json:"issueUrlTemplate,omitempty"`
+
+	// CaptureEscalations sends a message whenever a log warning budget is exceeded (see log.WarnBudget)
+	CaptureEscalations bool `json:"captureEscalations,omitempty"`
+
+	// Sessions enables release health tracking: a session starts with Init and ends with Shutdown, as crashed
+	// if the process is going down on a fatal or panic log event - requires Release to be set
+	Sessions bool `json:"sessions,omitempty"`
+
+	// Routes send matching events to other DSNs
+	Routes []*Route `json:"routes,omitempty"`
+
+	// InAppModules are the import path prefixes of in-app stack frames (eg: go.codecomet.dev) - if empty, frames
+	// outside of the module cache and GOROOT are in-app
+	InAppModules []string `json:"inAppModules,omitempty"`
+
+	// SourceContextLines attaches as many lines of source code around in-app frames, when sources are available
+	SourceContextLines int `json:"sourceContextLines,omitempty"`
 }
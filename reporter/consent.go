@@ -0,0 +1,140 @@
+package reporter
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	"go.codecomet.dev/core/filesystem"
+	"go.codecomet.dev/core/log"
+)
+
+const consentQuestion = "Send anonymous crash reports to help us fix bugs? They may include error messages, " +
+	"stack traces and system information."
+
+var (
+	consentDenied bool       //nolint:gochecknoglobals
+	consentMu     sync.Mutex //nolint:gochecknoglobals
+)
+
+// Interactor asks the user a yes / no question - interactive prompts satisfy it.
+type Interactor interface {
+	Confirm(question string, defaultAnswer bool) (bool, error)
+}
+
+// consentRecord is the persisted answer.
+type consentRecord struct {
+	CrashReporting bool      `json:"crashReporting"`
+	Answered       time.Time `json:"answered"`
+}
+
+// EnsureConsent returns whether the user agreed to crash reporting. The first time, the question is asked through
+// interactor, and the answer persisted to location (a json file) so that it is never asked again.
+// Without a recorded answer, or an interactor (eg: non interactive runs), consent is not assumed.
+// Programs that never call EnsureConsent (eg: services, with no one to ask) report crashes. Others should call it
+// before Init, which leaves crash reporting disabled on refusal. A refusal after Init stops reporting right away.
+func EnsureConsent(interactor Interactor, location string) (bool, error) {
+	granted, err := consent(interactor, location)
+
+	consentMu.Lock()
+	consentDenied = !granted
+	consentMu.Unlock()
+
+	return granted, err
+}
+
+// RevokeConsent forgets the recorded answer, so that the question is asked again on the next EnsureConsent.
+// Nothing is reported anymore until consent is given again, including by an already initialized reporter.
+func RevokeConsent(location string) error {
+	consentMu.Lock()
+	consentDenied = true
+	consentMu.Unlock()
+
+	if err := os.Remove(location); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}
+
+func consent(interactor Interactor, location string) (bool, error) {
+	data, err := os.ReadFile(location)
+	if err == nil {
+		record := &consentRecord{}
+		if err = json.Unmarshal(data, record); err == nil {
+			return record.CrashReporting, nil
+		}
+
+		log.Warn().Err(err).Str("file", location).Msg("Crash reporting consent record is invalid. Asking again.")
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return false, fmt.Errorf("failed reading consent record: %w", err)
+	}
+
+	if interactor == nil {
+		log.Debug().Msg("No recorded crash reporting consent, and no way to ask for it.")
+
+		return false, nil
+	}
+
+	granted, err := interactor.Confirm(consentQuestion, false)
+	if err != nil {
+		return false, fmt.Errorf("failed asking for consent: %w", err)
+	}
+
+	data, err = json.Marshal(&consentRecord{CrashReporting: granted, Answered: time.Now().UTC()})
+	if err != nil {
+		return granted, err
+	}
+
+	if err = os.MkdirAll(filepath.Dir(location), filesystem.DirPermissionsDefault); err == nil {
+		err = filesystem.WriteFile(location, data, filesystem.FilePermissionsDefault)
+	}
+
+	if err != nil {
+		return granted, fmt.Errorf("failed persisting consent record: %w", err)
+	}
+
+	return granted, nil
+}
+
+func consentWithheld() bool {
+	consentMu.Lock()
+	defer consentMu.Unlock()
+
+	return consentDenied
+}
+
+// honorConsent drops events and transactions while consent is withheld, so that withdrawing it after Init works.
+func honorConsent(options *sentry.ClientOptions) {
+	beforeSend := options.BeforeSend
+	options.BeforeSend = func(event *sentry.Event, hint *sentry.EventHint) *sentry.Event {
+		if consentWithheld() {
+			return nil
+		}
+
+		if beforeSend != nil {
+			return beforeSend(event, hint)
+		}
+
+		return event
+	}
+
+	beforeSendTransaction := options.BeforeSendTransaction
+	options.BeforeSendTransaction = func(event *sentry.Event, hint *sentry.EventHint) *sentry.Event {
+		if consentWithheld() {
+			return nil
+		}
+
+		if beforeSendTransaction != nil {
+			return beforeSendTransaction(event, hint)
+		}
+
+		return event
+	}
+}
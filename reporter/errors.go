@@ -0,0 +1,5 @@
+package reporter
+
+import "errors"
+
+var ErrSessionRejected = errors.New("session update rejected")
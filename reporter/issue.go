@@ -0,0 +1,68 @@
+package reporter
+
+import (
+	"strings"
+	"sync"
+)
+
+// IssueURLPlaceholder is replaced by the event ID in Config.IssueURLTemplate.
+const IssueURLPlaceholder = "{eventID}"
+
+var (
+	issueURLTemplate string       //nolint:gochecknoglobals
+	issueCallback    CaptureHook  //nolint:gochecknoglobals
+	issueMu          sync.RWMutex //nolint:gochecknoglobals
+)
+
+// CaptureResult carries the outcome of a capture.
+type CaptureResult struct {
+	// EventID is nil if the event was not sent (reporter disabled, event dropped, etc)
+	EventID *EventID
+	// IssueURL is the suggested URL for the user to file an issue, if an IssueURLTemplate is configured
+	IssueURL string
+}
+
+// CaptureHook is invoked after an event has been captured.
+type CaptureHook func(result *CaptureResult)
+
+// OnCapture registers a callback invoked after each successful capture, typically used by CLIs to print
+// something like "Report this at https://.../new?eventID=...". Pass nil to unregister.
+func OnCapture(hook CaptureHook) {
+	issueMu.Lock()
+	defer issueMu.Unlock()
+
+	issueCallback = hook
+}
+
+func setIssueURLTemplate(template string) {
+	issueMu.Lock()
+	defer issueMu.Unlock()
+
+	issueURLTemplate = template
+}
+
+// notify builds the result for a captured event and invokes the registered hook, if any.
+func notify(eventID *EventID) *CaptureResult {
+	result := &CaptureResult{
+		EventID: eventID,
+	}
+
+	if eventID == nil {
+		return result
+	}
+
+	issueMu.RLock()
+	template := issueURLTemplate
+	hook := issueCallback
+	issueMu.RUnlock()
+
+	if template != "" {
+		result.IssueURL = strings.ReplaceAll(template, IssueURLPlaceholder, string(*eventID))
+	}
+
+	if hook != nil {
+		hook(result)
+	}
+
+	return result
+}
@@ -0,0 +1,27 @@
+package reporter
+
+import (
+	"github.com/getsentry/sentry-go"
+)
+
+// CapturePanic reports a value recovered from a panic, with contexts attached (eg: "command" with the binary and
+// arguments that were running). Call it from the deferred function recovering the panic, so that the stack trace
+// is the one of the panicking goroutine.
+func CapturePanic(recovered interface{}, contexts map[string]Context) *CaptureResult {
+	sessionError()
+
+	err, _ := recovered.(error)
+	hub := hubFor(err, nil)
+
+	var eventID *EventID
+
+	hub.WithScope(func(scope *sentry.Scope) {
+		// Recovered: the process goes on
+		scope.SetLevel(sentry.LevelError)
+		scope.SetContexts(contexts)
+
+		eventID = hub.Recover(recovered)
+	})
+
+	return notify(eventID)
+}
@@ -1,13 +1,22 @@
 package reporter
 
 import (
+	"fmt"
 	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/getsentry/sentry-go"
 	"go.codecomet.dev/core/log"
 	"go.codecomet.dev/core/network"
 )
 
+var (
+	captureEscalations atomic.Bool //nolint:gochecknoglobals
+	hooks              sync.Once   //nolint:gochecknoglobals
+)
+
 // Init should be called when the app starts, from a config object.
 func Init(conf *Config) {
 	if conf.Disabled {
@@ -16,44 +25,109 @@ func Init(conf *Config) {
 		return
 	}
 
+	if consentWithheld() {
+		log.Info().Msg("Crash reporting is disabled, as the user did not consent to it.")
+
+		return
+	}
+
 	log.Debug().Msg("Initializing crash reporter with config")
 
+	setIssueURLTemplate(conf.IssueURLTemplate)
+
 	httpClient := &http.Client{}
 	if conf.httpClient != nil {
 		httpClient = conf.httpClient
 	}
 
 	// XXX tricky: this means network MUST be initialized before reporter
-	httpClient.Transport = network.GetTransport()
+	// Our own requests are not traced: Sentry would report their spans as transactions, sent by more requests
+	httpClient.Transport = network.UninstrumentedTransport(network.GetTransport())
 
-	err := sentry.Init(sentry.ClientOptions{
-		HTTPClient:       httpClient,
+	// Sessions are not events: count the latter on their own client
+	eventClient := &http.Client{
+		Transport: &statsTransport{base: httpClient.Transport},
+		Timeout:   httpClient.Timeout,
+	}
+
+	options := sentry.ClientOptions{
+		HTTPClient:       eventClient,
 		Dsn:              conf.DSN,
 		Environment:      conf.Environment,
 		EnableTracing:    true,
 		Release:          conf.Release,
 		Debug:            conf.Debug,
 		TracesSampleRate: 1.0,
-	})
+	}
+
+	// Stack traces are enhanced for every event, including those going to routes
+	options.BeforeSend = newStackEnhancer(conf).beforeSend(options.BeforeSend)
+
+	// Dropped events are not counted
+	honorConsent(&options)
+	countEvents(&options)
+
+	err := sentry.Init(options)
 	if err != nil {
 		log.Fatal().Err(err).Msg("sentry.Init failed")
 	}
+
+	initRoutes(conf.Routes, options)
+
+	if conf.Sessions {
+		startSession(httpClient, conf)
+	}
+
+	captureEscalations.Store(conf.CaptureEscalations)
+
+	// Hooks are registered once, whatever the number of Init calls
+	hooks.Do(func() {
+		log.OnEscalation(func(key string, count int, window time.Duration) {
+			if captureEscalations.Load() {
+				CaptureMessage(fmt.Sprintf("warning budget exceeded for %s: %d occurrences within %s", key, count,
+					window))
+			}
+		})
+
+		// Make sure pending events are flushed if we die on a fatal log
+		log.AddExitHook(log.ExitHookFunc(func() error {
+			Shutdown()
+
+			return nil
+		}))
+	})
 }
 
 func CaptureException(err error) *EventID {
-	return sentry.CaptureException(err)
+	return Capture(err).EventID
 }
 
 func CaptureMessage(msg string) *EventID {
-	return sentry.CaptureMessage(msg)
+	return notify(sentry.CaptureMessage(msg)).EventID
 }
 
 func CaptureEvent(e *Event) *EventID {
-	return sentry.CaptureEvent(e)
+	return notify(hubFor(nil, e.Tags).CaptureEvent(e)).EventID
+}
+
+// Capture reports err, and returns the capture result along with a suggested issue URL.
+func Capture(err error) *CaptureResult {
+	sessionError()
+
+	return notify(hubFor(err, nil).CaptureException(err))
 }
 
 func Shutdown() {
+	// Ends the release health session, if any
+	endSession()
+
 	// Flush buffered events before the program terminates.
 	// Set the timeout to the maximum duration the program can afford to wait.
 	sentry.Flush(flushTimeout)
+	flushRoutes(flushTimeout)
+
+	if stats := Stats(); stats.Queued > 0 || stats.Failed > 0 || stats.RateLimited > 0 {
+		log.Warn().Uint64("queued", stats.Queued).Uint64("failed", stats.Failed).
+			Uint64("rateLimited", stats.RateLimited).Msg("Some crash reports could not be sent.")
+	}
 }
@@ -0,0 +1,135 @@
+package reporter
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	"go.codecomet.dev/core/log"
+)
+
+var (
+	routes   []*route     //nolint:gochecknoglobals
+	routesMu sync.RWMutex //nolint:gochecknoglobals
+)
+
+// Route sends matching events to a dedicated DSN (eg: a different Sentry project), so that teams only triage
+// their own components. Routes are evaluated in order, and the first match wins. Unmatched events go to the
+// default DSN.
+type Route struct {
+	DSN string `json:"dsn"`
+	// Tags matches events carrying all of these tags
+	Tags map[string]string `json:"tags,omitempty"`
+	// Packages matches errors (or any error they wrap) whose type is declared in a package with one of these
+	// import path prefixes
+	Packages []string `json:"packages,omitempty"`
+}
+
+type route struct {
+	*Route
+	client *sentry.Client
+}
+
+func initRoutes(conf []*Route, options sentry.ClientOptions) {
+	initialized := []*route{}
+
+	for _, r := range conf {
+		options.Dsn = r.DSN
+
+		client, err := sentry.NewClient(options)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed initializing reporter route. Ignoring it.")
+
+			continue
+		}
+
+		initialized = append(initialized, &route{
+			Route:  r,
+			client: client,
+		})
+	}
+
+	routesMu.Lock()
+	defer routesMu.Unlock()
+
+	routes = initialized
+}
+
+func (r *route) matchTags(tags map[string]string) bool {
+	if len(r.Tags) == 0 {
+		return false
+	}
+
+	for k, v := range r.Tags {
+		if tags[k] != v {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (r *route) matchError(err error) bool {
+	for ; err != nil; err = errors.Unwrap(err) {
+		typ := reflect.TypeOf(err)
+		for typ.Kind() == reflect.Pointer {
+			typ = typ.Elem()
+		}
+
+		for _, pkg := range r.Packages {
+			if typ.PkgPath() == pkg || strings.HasPrefix(typ.PkgPath(), strings.TrimSuffix(pkg, "/")+"/") {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// hubFor returns the hub to use for an error and/or a set of tags, completing the tags of the current scope.
+// Routed events get a copy of the current scope, so that they carry the same user, tags and contexts as the others.
+func hubFor(err error, tags map[string]string) *sentry.Hub {
+	current := sentry.CurrentHub()
+
+	routesMu.RLock()
+	defer routesMu.RUnlock()
+
+	if len(routes) == 0 {
+		return current
+	}
+
+	merged := scopeTags(current.Scope())
+	for k, v := range tags {
+		merged[k] = v
+	}
+
+	for _, r := range routes {
+		if r.matchTags(merged) || (err != nil && r.matchError(err)) {
+			return sentry.NewHub(r.client, current.Scope().Clone())
+		}
+	}
+
+	return current
+}
+
+// scopeTags returns the tags set on scope, which it only exposes by applying them to an event.
+func scopeTags(scope *sentry.Scope) map[string]string {
+	event := &sentry.Event{Tags: map[string]string{}}
+
+	// Tags are copied before event processors run, whatever they return
+	scope.ApplyToEvent(event, nil)
+
+	return event.Tags
+}
+
+func flushRoutes(timeout time.Duration) {
+	routesMu.RLock()
+	defer routesMu.RUnlock()
+
+	for _, r := range routes {
+		r.client.Flush(timeout)
+	}
+}
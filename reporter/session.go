@@ -0,0 +1,223 @@
+package reporter
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	"go.codecomet.dev/core/id"
+	"go.codecomet.dev/core/log"
+)
+
+// Session statuses, as understood by Sentry release health.
+const (
+	SessionOK      = "ok"
+	SessionExited  = "exited"
+	SessionCrashed = "crashed"
+)
+
+type sessionAttrs struct {
+	Release     string `json:"release"`
+	Environment string `json:"environment,omitempty"`
+}
+
+type sessionUpdate struct {
+	SID       string       `json:"sid"`
+	Init      bool         `json:"init"`
+	Started   time.Time    `json:"started"`
+	Timestamp time.Time    `json:"timestamp"`
+	Status    string       `json:"status"`
+	Errors    int          `json:"errors"`
+	Duration  float64      `json:"duration,omitempty"`
+	Attrs     sessionAttrs `json:"attrs"`
+}
+
+// session tracks the lifetime of the process for release health.
+type session struct {
+	mu      sync.Mutex
+	client  *http.Client
+	dsn     *sentry.Dsn
+	update  *sessionUpdate
+	pending sync.WaitGroup
+	ended   bool
+}
+
+var (
+	current   *session   //nolint:gochecknoglobals
+	sessionMu sync.Mutex //nolint:gochecknoglobals
+)
+
+// startSession starts tracking a session. Sentry requires a release for sessions to be accounted for.
+func startSession(client *http.Client, conf *Config) {
+	if conf.Release == "" {
+		log.Warn().Msg("Session tracking requires a release. Not tracking sessions.")
+
+		return
+	}
+
+	dsn, err := sentry.NewDsn(conf.DSN)
+	if err != nil {
+		log.Warn().Err(err).Msg("Invalid DSN. Not tracking sessions.")
+
+		return
+	}
+
+	sid := id.New()
+	now := time.Now().UTC()
+
+	sess := &session{
+		client: client,
+		dsn:    dsn,
+		update: &sessionUpdate{
+			// UUID formatting
+			SID:       fmt.Sprintf("%s-%s-%s-%s-%s", sid[:8], sid[8:12], sid[12:16], sid[16:20], sid[20:]),
+			Init:      true,
+			Started:   now,
+			Timestamp: now,
+			Status:    SessionOK,
+			Attrs: sessionAttrs{
+				Release:     conf.Release,
+				Environment: conf.Environment,
+			},
+		},
+	}
+
+	sessionMu.Lock()
+	current = sess
+	sessionMu.Unlock()
+
+	sess.send(sess.snapshot(), false)
+}
+
+// sessionError marks the current session as errored, if any.
+func sessionError() {
+	sessionMu.Lock()
+	sess := current
+	sessionMu.Unlock()
+
+	if sess == nil {
+		return
+	}
+
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+
+	sess.update.Errors++
+}
+
+// endSession closes the current session, as crashed if a fatal or panic event was logged, or exited otherwise.
+func endSession() {
+	sessionMu.Lock()
+	sess := current
+	current = nil
+	sessionMu.Unlock()
+
+	if sess == nil {
+		return
+	}
+
+	status := SessionExited
+	if log.Crashed() {
+		status = SessionCrashed
+	}
+
+	sess.mu.Lock()
+	if sess.ended {
+		sess.mu.Unlock()
+
+		return
+	}
+
+	sess.ended = true
+	now := time.Now().UTC()
+	sess.update.Status = status
+	sess.update.Timestamp = now
+	sess.update.Duration = now.Sub(sess.update.Started).Seconds()
+	sess.mu.Unlock()
+
+	sess.send(sess.snapshot(), true)
+}
+
+func (sess *session) snapshot() sessionUpdate {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+
+	update := *sess.update
+	// Only the first update initiates the session
+	sess.update.Init = false
+
+	return update
+}
+
+// send posts the update as an envelope. If wait is true, pending updates are waited for first, and the update
+// is sent synchronously. Otherwise, it is sent in the background.
+func (sess *session) send(update sessionUpdate, wait bool) {
+	if wait {
+		sess.pending.Wait()
+		sess.deliver(update)
+
+		return
+	}
+
+	sess.pending.Add(1)
+
+	go func() {
+		defer sess.pending.Done()
+
+		sess.deliver(update)
+	}()
+}
+
+func (sess *session) deliver(update sessionUpdate) {
+	if consentWithheld() {
+		return
+	}
+
+	if err := sess.post(update); err != nil {
+		log.Debug().Err(err).Str("status", update.Status).Msg("Failed sending session update")
+	}
+}
+
+func (sess *session) post(update sessionUpdate) error {
+	body, err := json.Marshal(update)
+	if err != nil {
+		return err
+	}
+
+	var envelope bytes.Buffer
+
+	enc := json.NewEncoder(&envelope)
+	_ = enc.Encode(map[string]interface{}{"sent_at": time.Now().UTC(), "dsn": sess.dsn.String()})
+	_ = enc.Encode(map[string]interface{}{"type": "session", "length": len(body)})
+	envelope.Write(body)
+
+	req, err := http.NewRequest(http.MethodPost, sess.dsn.GetAPIURL().String(), &envelope) //nolint:noctx
+	if err != nil {
+		return err
+	}
+
+	for k, v := range sess.dsn.RequestHeaders() {
+		req.Header.Set(k, v)
+	}
+
+	req.Header.Set("Content-Type", "application/x-sentry-envelope")
+
+	client := *sess.client
+	client.Timeout = flushTimeout
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%w: %d", ErrSessionRejected, resp.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,192 @@
+package reporter
+
+import (
+	"bufio"
+	"go/build"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// stackEnhancer post-processes the stack traces of events before they are sent.
+type stackEnhancer struct {
+	inApp    []string
+	context  int
+	prefixes []*pathPrefix
+}
+
+// pathPrefix is a directory trimmed from frame paths.
+type pathPrefix struct {
+	dir        string
+	dependency bool
+}
+
+func newStackEnhancer(conf *Config) *stackEnhancer {
+	gopath := build.Default.GOPATH
+
+	modCache := os.Getenv("GOMODCACHE")
+	if modCache == "" && gopath != "" {
+		modCache = filepath.Join(gopath, "pkg", "mod")
+	}
+
+	enhancer := &stackEnhancer{inApp: conf.InAppModules, context: conf.SourceContextLines}
+
+	// Most specific first: the module cache usually lives in GOPATH
+	for _, prefix := range []*pathPrefix{
+		{dir: modCache, dependency: true},
+		{dir: filepath.Join(build.Default.GOROOT, "src"), dependency: true},
+		{dir: filepath.Join(gopath, "src")},
+	} {
+		if prefix.dir != "" && prefix.dir != "src" {
+			prefix.dir = filepath.ToSlash(prefix.dir) + "/"
+			enhancer.prefixes = append(enhancer.prefixes, prefix)
+		}
+	}
+
+	return enhancer
+}
+
+// beforeSend enhances the stack traces of the event, then calls next, if any.
+func (enhancer *stackEnhancer) beforeSend(next func(*sentry.Event, *sentry.EventHint) *sentry.Event,
+) func(*sentry.Event, *sentry.EventHint) *sentry.Event {
+	return func(event *sentry.Event, hint *sentry.EventHint) *sentry.Event {
+		sources := map[string][]string{}
+
+		for i := range event.Exception {
+			enhancer.enhance(event.Exception[i].Stacktrace, sources)
+		}
+
+		for i := range event.Threads {
+			enhancer.enhance(event.Threads[i].Stacktrace, sources)
+		}
+
+		if next != nil {
+			return next(event, hint)
+		}
+
+		return event
+	}
+}
+
+func (enhancer *stackEnhancer) enhance(stack *sentry.Stacktrace, sources map[string][]string) {
+	if stack == nil {
+		return
+	}
+
+	for i := range stack.Frames {
+		frame := &stack.Frames[i]
+		trimmed, dependency := enhancer.trim(frame)
+
+		if frame.Filename == "" {
+			frame.Filename = trimmed
+		}
+
+		switch {
+		case len(enhancer.inApp) > 0:
+			frame.InApp = enhancer.isInApp(framePackage(frame))
+		case dependency:
+			frame.InApp = false
+		}
+
+		if enhancer.context > 0 && frame.InApp && frame.AbsPath != "" && frame.Lineno > 0 {
+			enhancer.addContext(frame, sources)
+		}
+	}
+}
+
+// trim returns the path of the frame file, relative to the module cache, GOROOT or GOPATH, or else as the package
+// import path followed by the file name. Dependency is true for files in the module cache or GOROOT.
+func (enhancer *stackEnhancer) trim(frame *sentry.Frame) (string, bool) {
+	abs := filepath.ToSlash(frame.AbsPath)
+	if abs == "" {
+		return "", false
+	}
+
+	for _, prefix := range enhancer.prefixes {
+		if strings.HasPrefix(abs, prefix.dir) {
+			return strings.TrimPrefix(abs, prefix.dir), prefix.dependency
+		}
+	}
+
+	// Local sources: the directory ends with the last element of the import path of the package
+	dir, base := filepath.ToSlash(filepath.Dir(abs)), filepath.Base(abs)
+	if pkg := framePackage(frame); pkg != "" && strings.HasSuffix("/"+dir, "/"+pkg[strings.LastIndex(pkg, "/")+1:]) {
+		return pkg + "/" + base, false
+	}
+
+	return abs, false
+}
+
+// framePackage returns the import path of the package of the frame, from its function if the module is not set.
+func framePackage(frame *sentry.Frame) string {
+	if frame.Module != "" {
+		return frame.Module
+	}
+
+	// eg: go.codecomet.dev/core/exec.(*Commander).Run
+	slash := strings.LastIndex(frame.Function, "/")
+	if dot := strings.Index(frame.Function[slash+1:], "."); dot > 0 {
+		return frame.Function[:slash+1+dot]
+	}
+
+	return ""
+}
+
+func (enhancer *stackEnhancer) isInApp(module string) bool {
+	for _, prefix := range enhancer.inApp {
+		if module == prefix || strings.HasPrefix(module, strings.TrimSuffix(prefix, "/")+"/") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// addContext attaches the lines surrounding the frame, if its source is available.
+func (enhancer *stackEnhancer) addContext(frame *sentry.Frame, sources map[string][]string) {
+	lines, ok := sources[frame.AbsPath]
+	if !ok {
+		lines = readLines(frame.AbsPath)
+		sources[frame.AbsPath] = lines
+	}
+
+	line := frame.Lineno - 1
+	if line >= len(lines) {
+		return
+	}
+
+	start := line - enhancer.context
+	if start < 0 {
+		start = 0
+	}
+
+	end := line + 1 + enhancer.context
+	if end > len(lines) {
+		end = len(lines)
+	}
+
+	frame.PreContext = lines[start:line]
+	frame.ContextLine = lines[line]
+	frame.PostContext = lines[line+1 : end]
+}
+
+// readLines returns the lines of a source file, or nil if it cannot be read.
+func readLines(name string) []string {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil
+	}
+
+	defer file.Close()
+
+	lines := []string{}
+	scanner := bufio.NewScanner(file)
+
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+
+	return lines
+}
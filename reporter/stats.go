@@ -0,0 +1,95 @@
+package reporter
+
+import (
+	"net/http"
+	"sync/atomic"
+
+	"github.com/getsentry/sentry-go"
+)
+
+var (
+	statsAccepted    uint64 //nolint:gochecknoglobals
+	statsSent        uint64 //nolint:gochecknoglobals
+	statsFailed      uint64 //nolint:gochecknoglobals
+	statsRateLimited uint64 //nolint:gochecknoglobals
+)
+
+// TransportStats are cumulative counters of events (errors, messages and transactions) sent to Sentry, so that dropped crash
+// reports do not go unnoticed.
+type TransportStats struct {
+	// Queued are events handed over to the transport that did not reach Sentry yet - or never will, if the
+	// transport dropped them (queue full, rate limit in effect). A count that keeps growing means events are lost
+	Queued uint64 `json:"queued"`
+	// Sent are events accepted by Sentry
+	Sent uint64 `json:"sent"`
+	// Failed are events that could not be delivered (network failure, or non 2xx response besides 429)
+	Failed uint64 `json:"failed"`
+	// RateLimited are events rejected by Sentry with a 429
+	RateLimited uint64 `json:"rateLimited"`
+}
+
+// Stats returns the counters since the process started. The telemetry package also collects them as metrics.
+func Stats() TransportStats {
+	stats := TransportStats{
+		Sent:        atomic.LoadUint64(&statsSent),
+		Failed:      atomic.LoadUint64(&statsFailed),
+		RateLimited: atomic.LoadUint64(&statsRateLimited),
+	}
+
+	if accepted, done := atomic.LoadUint64(&statsAccepted), stats.Sent+stats.Failed+stats.RateLimited; accepted > done {
+		stats.Queued = accepted - done
+	}
+
+	return stats
+}
+
+// countEvents counts events as they are handed over to the transport.
+func countEvents(options *sentry.ClientOptions) {
+	beforeSend := options.BeforeSend
+	options.BeforeSend = func(event *sentry.Event, hint *sentry.EventHint) *sentry.Event {
+		if beforeSend != nil {
+			if event = beforeSend(event, hint); event == nil {
+				return nil
+			}
+		}
+
+		atomic.AddUint64(&statsAccepted, 1)
+
+		return event
+	}
+
+	beforeSendTransaction := options.BeforeSendTransaction
+	options.BeforeSendTransaction = func(event *sentry.Event, hint *sentry.EventHint) *sentry.Event {
+		if beforeSendTransaction != nil {
+			if event = beforeSendTransaction(event, hint); event == nil {
+				return nil
+			}
+		}
+
+		atomic.AddUint64(&statsAccepted, 1)
+
+		return event
+	}
+}
+
+// statsTransport counts the outcome of requests sending events.
+type statsTransport struct {
+	base http.RoundTripper
+}
+
+func (t *statsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+
+	switch {
+	case err != nil:
+		atomic.AddUint64(&statsFailed, 1)
+	case resp.StatusCode == http.StatusTooManyRequests:
+		atomic.AddUint64(&statsRateLimited, 1)
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		atomic.AddUint64(&statsFailed, 1)
+	default:
+		atomic.AddUint64(&statsSent, 1)
+	}
+
+	return resp, err
+}
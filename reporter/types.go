@@ -5,4 +5,5 @@ import "github.com/getsentry/sentry-go"
 type (
 	EventID = sentry.EventID
 	Event   = sentry.Event
+	Context = sentry.Context
 )
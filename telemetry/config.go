@@ -1,21 +1,98 @@
 package telemetry
 
+import (
+	"time"
+
+	"go.codecomet.dev/core/network"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
 // traceEndpoint := os.Getenv("OTEL_EXPORTER_JAEGER_ENDPOINT")
-// PROMETHEUS ExporterType = "prometheus"
-// OTLP       ExporterType = "otlp"
 
 type ExporterType string
 
 const (
 	JAEGGER ExporterType = "jaegger"
 	SENTRY  ExporterType = "sentry"
+	// PROMETHEUS exports no spans: metrics are scraped from MetricsHandler (experimental)
+	PROMETHEUS ExporterType = "prometheus"
+	// OTLP sends spans to an OTLP/HTTP collector, with the JSON encoding - Endpoint is its base URL
+	// (eg: http://localhost:4318)
+	OTLP ExporterType = "otlp"
 )
 
 type Config struct {
-	ServiceName string       `json:"serviceName"`
-	Disabled    bool         `json:"disabled"`
-	Type        ExporterType `json:"type"`
+	ServiceName string `json:"serviceName"`
+	// ServiceVersion defaults to the version the binary was built with (see the version package), if any
+	ServiceVersion string `json:"serviceVersion,omitempty"`
+	// Environment is the deployment environment (eg: staging, production)
+	Environment string `json:"environment,omitempty"`
+	// Attributes are added to the resource, describing the service on every span
+	Attributes map[string]string `json:"attributes,omitempty"`
+	// NoDetectors skips detecting host, OS, runtime, container and Kubernetes resource attributes
+	NoDetectors bool `json:"noDetectors,omitempty"`
+
+	Disabled bool         `json:"disabled"`
+	Type     ExporterType `json:"type" enum:"jaegger,sentry,prometheus,otlp"`
 
 	// Only for jaegger it seems
 	Endpoint string `json:"endpoint"`
+
+	// Exporters are registered in addition to Type, all receiving the same spans (eg: to migrate between backends).
+	// Their own TLS and auth settings go there
+	Exporters []*ExporterConfig `json:"exporters,omitempty"`
+
+	// Summary prints a timing summary of collected spans when telemetry is closed.
+	// It works without any exporter type.
+	Summary bool `json:"summary,omitempty"`
+
+	// Limits, if set, caps span attributes, events and links before they are exported
+	Limits *Limits `json:"limits,omitempty"`
+
+	// Enrichment, if set, adds attributes to every span
+	Enrichment *Enrichment `json:"enrichment,omitempty"`
+
+	// ReportErrors reports errors ending spans (see Span.End)
+	ReportErrors bool `json:"reportErrors,omitempty"`
+
+	// HTTP traces requests sent through network transports, and received by network handlers, propagating the
+	// trace context (see HTTPTransport and HTTPHandler)
+	HTTP bool `json:"http,omitempty"`
+
+	// DisabledModules lists instrumentation scopes that do not create spans (see SetModuleEnabled) - modules disabled
+	// by a previous Init are enabled again
+	DisabledModules []string `json:"disabledModules,omitempty"`
+
+	// MetricExporter, if set, receives the collected metrics (see Meter) every MetricsInterval, and when
+	// telemetry is closed. Experimental, like Meter
+	MetricExporter MetricExporter `json:"-"`
+	// MetricsInterval defaults to a minute
+	MetricsInterval time.Duration `json:"metricsInterval,omitempty"`
+
+	// IDGenerator, if set, generates trace and span IDs instead of the default random generator
+	// (see telemetrytest for a deterministic one)
+	IDGenerator IDGenerator `json:"-"`
 }
+
+// ExporterConfig is an exporter of Config.Exporters.
+type ExporterConfig struct {
+	Type     ExporterType `json:"type" enum:"jaegger,sentry,prometheus,otlp"`
+	Endpoint string       `json:"endpoint,omitempty"`
+
+	// The following apply to exporters sending to Endpoint (jaegger, otlp)
+
+	// Network, if set, is the client configuration used to reach the endpoint (CAs, client certificate, TLS
+	// version, timeouts) - defaults to the network package configuration (see network.Init)
+	Network *network.Config `json:"network,omitempty"`
+	// Headers are added to export requests
+	Headers map[string]string `json:"headers,omitempty"`
+	// TokenEnv is the environment variable holding an auth token, sent as an Authorization header
+	TokenEnv string `json:"tokenEnv,omitempty"`
+	// TokenType prefixes the token in the Authorization header - defaults to Bearer
+	TokenType string `json:"tokenType,omitempty"`
+	// Gzip compresses export requests (otlp)
+	Gzip bool `json:"gzip,omitempty"`
+}
+
+// IDGenerator generates trace and span IDs.
+type IDGenerator = sdktrace.IDGenerator
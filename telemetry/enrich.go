@@ -0,0 +1,77 @@
+package telemetry
+
+import (
+	"context"
+	"sort"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/baggage"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// Enrichment tags every span as it starts, so that platform-wide attributes (team, region, etc) come from
+// configuration rather than from code in every service. Attributes set by the code creating the span win.
+type Enrichment struct {
+	// Attributes are set on every span (eg: team: foo)
+	Attributes map[string]string `json:"attributes,omitempty"`
+	// Baggage copies baggage members of the parent context into span attributes, as member key: attribute key.
+	// Spans without the member are left untouched
+	Baggage map[string]string `json:"baggage,omitempty"`
+}
+
+// enrichProcessor applies an enrichment to starting spans.
+type enrichProcessor struct {
+	enrichment *Enrichment
+}
+
+func (proc *enrichProcessor) OnStart(parent context.Context, span sdktrace.ReadWriteSpan) {
+	existing := map[attribute.Key]bool{}
+	for _, attr := range span.Attributes() {
+		existing[attr.Key] = true
+	}
+
+	attrs := []attribute.KeyValue{}
+
+	for _, k := range sortedKeys(proc.enrichment.Attributes) {
+		if !existing[attribute.Key(k)] {
+			attrs = append(attrs, attribute.String(k, proc.enrichment.Attributes[k]))
+		}
+	}
+
+	bag := baggage.FromContext(parent)
+
+	for _, member := range sortedKeys(proc.enrichment.Baggage) {
+		k := proc.enrichment.Baggage[member]
+		if existing[attribute.Key(k)] {
+			continue
+		}
+
+		if value := bag.Member(member).Value(); value != "" {
+			attrs = append(attrs, attribute.String(k, value))
+		}
+	}
+
+	span.SetAttributes(attrs...)
+}
+
+func (proc *enrichProcessor) OnEnd(sdktrace.ReadOnlySpan) {}
+
+func (proc *enrichProcessor) Shutdown(context.Context) error {
+	return nil
+}
+
+func (proc *enrichProcessor) ForceFlush(context.Context) error {
+	return nil
+}
+
+// sortedKeys makes enrichment deterministic.
+func sortedKeys(values map[string]string) []string {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
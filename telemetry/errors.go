@@ -2,4 +2,8 @@ package telemetry
 
 import "errors"
 
-var ErrUnsupportedProviderType = errors.New("unsupported provider type")
+var (
+	ErrUnsupportedProviderType = errors.New("unsupported provider type")
+	ErrMissingToken            = errors.New("exporter token variable is not set")
+	ErrExportFailed            = errors.New("export request failed")
+)
@@ -0,0 +1,59 @@
+package telemetry
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+
+	"go.codecomet.dev/core/network"
+)
+
+const defaultTokenType = "Bearer"
+
+// httpClient returns the client sending export requests: through the exporter network configuration, or the
+// default transport (set by network.Init), adding the configured headers.
+func (exporter *ExporterConfig) httpClient() (*http.Client, error) {
+	var base http.RoundTripper = http.DefaultTransport
+
+	if exporter.Network != nil {
+		base = network.New(exporter.Network, nil).Transport()
+	}
+
+	headers := http.Header{}
+	for name, value := range exporter.Headers {
+		headers.Set(name, value)
+	}
+
+	if exporter.TokenEnv != "" {
+		token := os.Getenv(exporter.TokenEnv)
+		if token == "" {
+			return nil, fmt.Errorf("%w: %s", ErrMissingToken, exporter.TokenEnv)
+		}
+
+		tokenType := exporter.TokenType
+		if tokenType == "" {
+			tokenType = defaultTokenType
+		}
+
+		headers.Set("Authorization", tokenType+" "+token)
+	}
+
+	return &http.Client{Transport: &headerTransport{base: base, headers: headers}}, nil
+}
+
+// headerTransport adds headers to export requests, and keeps them from being traced (see Config.HTTP).
+type headerTransport struct {
+	base    http.RoundTripper
+	headers http.Header
+}
+
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// RoundTrippers must not modify the request
+	req = req.Clone(network.WithoutInstrumentation(req.Context()))
+
+	for name, values := range t.headers {
+		req.Header[name] = values
+	}
+
+	return t.base.RoundTrip(req)
+}
@@ -0,0 +1,185 @@
+package telemetry
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+
+	"go.codecomet.dev/core/network"
+	"go.codecomet.dev/core/version"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/semconv/v1.17.0/httpconv"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const httpModule = "network"
+
+// HTTPTransport wraps base so that requests get a client span, child of the span of their context, and carry the
+// trace context to the server. Config.HTTP does it for network transports.
+// Requests marked with network.WithoutInstrumentation are not traced.
+func HTTPTransport(base http.RoundTripper) http.RoundTripper {
+	return &tracingTransport{base: base}
+}
+
+type tracingTransport struct {
+	base http.RoundTripper
+}
+
+func (t *tracingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if network.IsUninstrumented(req.Context()) {
+		return t.base.RoundTrip(req)
+	}
+
+	ctx, span := Tracer(httpModule, version.Module(coreModule)).Start(req.Context(), "HTTP "+req.Method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(httpconv.ClientRequest(req)...),
+	)
+	defer span.End()
+
+	// RoundTrippers must not modify the request
+	req = req.Clone(ctx)
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		return resp, err
+	}
+
+	span.SetAttributes(httpconv.ClientResponse(resp)...)
+	span.SetStatus(httpconv.ClientStatus(resp.StatusCode))
+
+	return resp, nil
+}
+
+// HTTPHandler wraps next so that requests get a server span, continuing the trace of the client, if any.
+// Config.HTTP does it for network handlers. Spans record the size of the request body read by next, and of the
+// response it wrote. Routes are unknown at this point: use HTTPRoute to record them.
+// The response writer passed to next can still be hijacked (eg: for WebSocket upgrades), or push, if the server
+// supports it.
+func HTTPHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(req.Context(), propagation.HeaderCarrier(req.Header))
+
+		ctx, span := Tracer(httpModule, version.Module(coreModule)).Start(ctx, "HTTP "+req.Method,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(httpconv.ServerRequest("", req)...),
+		)
+		defer span.End()
+
+		recorder := &statusRecorder{ResponseWriter: writer, status: http.StatusOK}
+
+		// Bodies of requests without any are left alone: the server expects http.NoBody
+		body := &countingBody{ReadCloser: req.Body}
+		if req.Body != nil && req.Body != http.NoBody {
+			req.Body = body
+		}
+
+		next.ServeHTTP(recorder, req.WithContext(ctx))
+
+		span.SetAttributes(semconv.HTTPStatusCode(recorder.status),
+			semconv.HTTPRequestContentLength(int(body.read)),
+			semconv.HTTPResponseContentLength(int(recorder.written)))
+		span.SetStatus(httpconv.ServerStatus(recorder.status))
+	})
+}
+
+// HTTPRoute wraps next so that the server span of requests (see HTTPHandler) records route, the pattern that
+// matched the request (eg: /users/{id}), and is named after it.
+func HTTPRoute(route string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
+		span := trace.SpanFromContext(req.Context())
+		span.SetAttributes(semconv.HTTPRoute(route))
+		span.SetName("HTTP " + req.Method + " " + route)
+
+		next.ServeHTTP(writer, req)
+	})
+}
+
+// countingBody counts the bytes read from a request body.
+type countingBody struct {
+	io.ReadCloser
+	read int64
+}
+
+func (body *countingBody) Read(p []byte) (int, error) {
+	n, err := body.ReadCloser.Read(p)
+	body.read += int64(n)
+
+	return n, err
+}
+
+// statusRecorder records the response status, and the size of the response body.
+type statusRecorder struct {
+	http.ResponseWriter
+	status  int
+	written int64
+}
+
+func (rec *statusRecorder) WriteHeader(status int) {
+	rec.status = status
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *statusRecorder) Write(p []byte) (int, error) {
+	n, err := rec.ResponseWriter.Write(p)
+	rec.written += int64(n)
+
+	return n, err
+}
+
+// Hijack hands over the connection, which is then assumed to switch protocols.
+func (rec *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := rec.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+
+	conn, buf, err := hijacker.Hijack()
+	if err == nil {
+		rec.status = http.StatusSwitchingProtocols
+	}
+
+	return conn, buf, err
+}
+
+func (rec *statusRecorder) Push(target string, opts *http.PushOptions) error {
+	if pusher, ok := rec.ResponseWriter.(http.Pusher); ok {
+		return pusher.Push(target, opts)
+	}
+
+	return http.ErrNotSupported
+}
+
+func (rec *statusRecorder) Flush() {
+	if flusher, ok := rec.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (rec *statusRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
+
+// instrumentHTTP traces the requests of network transports and handlers, and makes sure the trace context is
+// propagated (Sentry brings its own propagator).
+func instrumentHTTP(enabled bool) {
+	if !enabled {
+		network.Instrument(nil)
+
+		return
+	}
+
+	if len(otel.GetTextMapPropagator().Fields()) == 0 {
+		otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{},
+			propagation.Baggage{}))
+	}
+
+	network.Instrument(&network.Instrumentation{Client: HTTPTransport, Server: HTTPHandler})
+}
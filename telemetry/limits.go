@@ -0,0 +1,175 @@
+package telemetry
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+const (
+	// TruncationMarker is appended to attribute values cut to the value length limit
+	TruncationMarker = "…[truncated]"
+	// TruncatedAttribute is set on spans that had attributes, events or links removed or cut by limits
+	TruncatedAttribute = attribute.Key("telemetry.truncated")
+)
+
+// Limits caps what a span may carry by the time it reaches processors and exporters, so that a buggy caller
+// (eg: a 10MB payload recorded as an attribute) cannot blow them up. Zero values mean no limit.
+type Limits struct {
+	// Attributes is the maximum number of attributes per span - extra attributes are dropped
+	Attributes int `json:"attributes,omitempty"`
+	// ValueLength is the maximum length of string attribute values (span and event attributes), beyond which they
+	// are cut and marked with TruncationMarker
+	ValueLength int `json:"valueLength,omitempty"`
+	// Events is the maximum number of events per span - the most recent events are dropped
+	Events int `json:"events,omitempty"`
+	// Links is the maximum number of links per span
+	Links int `json:"links,omitempty"`
+}
+
+// limitProcessor enforces limits on ended spans, before handing them to the next processor.
+type limitProcessor struct {
+	next   sdktrace.SpanProcessor
+	limits *Limits
+}
+
+func newLimitProcessor(next sdktrace.SpanProcessor, limits *Limits) sdktrace.SpanProcessor {
+	if limits == nil {
+		return next
+	}
+
+	return &limitProcessor{next: next, limits: limits}
+}
+
+func (proc *limitProcessor) OnStart(parent context.Context, span sdktrace.ReadWriteSpan) {
+	proc.next.OnStart(parent, span)
+}
+
+func (proc *limitProcessor) OnEnd(span sdktrace.ReadOnlySpan) {
+	proc.next.OnEnd(proc.limits.apply(span))
+}
+
+func (proc *limitProcessor) Shutdown(ctx context.Context) error {
+	return proc.next.Shutdown(ctx)
+}
+
+func (proc *limitProcessor) ForceFlush(ctx context.Context) error {
+	return proc.next.ForceFlush(ctx)
+}
+
+// apply returns span itself if it is within limits, or a limited copy of it.
+func (limits *Limits) apply(span sdktrace.ReadOnlySpan) sdktrace.ReadOnlySpan {
+	if limits.within(span) {
+		return span
+	}
+
+	stub := tracetest.SpanStubFromReadOnlySpan(span)
+
+	if limits.Attributes > 0 && len(stub.Attributes) > limits.Attributes {
+		stub.DroppedAttributes += len(stub.Attributes) - limits.Attributes
+		stub.Attributes = stub.Attributes[:limits.Attributes]
+	}
+
+	stub.Attributes = limits.truncate(stub.Attributes)
+
+	if limits.Events > 0 && len(stub.Events) > limits.Events {
+		stub.DroppedEvents += len(stub.Events) - limits.Events
+		stub.Events = stub.Events[:limits.Events]
+	}
+
+	for i := range stub.Events {
+		stub.Events[i].Attributes = limits.truncate(stub.Events[i].Attributes)
+	}
+
+	if limits.Links > 0 && len(stub.Links) > limits.Links {
+		stub.DroppedLinks += len(stub.Links) - limits.Links
+		stub.Links = stub.Links[:limits.Links]
+	}
+
+	stub.Attributes = append(stub.Attributes, TruncatedAttribute.Bool(true))
+
+	return stub.Snapshot()
+}
+
+func (limits *Limits) within(span sdktrace.ReadOnlySpan) bool {
+	if (limits.Attributes > 0 && len(span.Attributes()) > limits.Attributes) ||
+		(limits.Events > 0 && len(span.Events()) > limits.Events) ||
+		(limits.Links > 0 && len(span.Links()) > limits.Links) ||
+		limits.tooLong(span.Attributes()) {
+		return false
+	}
+
+	for _, event := range span.Events() {
+		if limits.tooLong(event.Attributes) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (limits *Limits) tooLong(attrs []attribute.KeyValue) bool {
+	if limits.ValueLength <= 0 {
+		return false
+	}
+
+	for _, attr := range attrs {
+		switch attr.Value.Type() { //nolint:exhaustive
+		case attribute.STRING:
+			if len(attr.Value.AsString()) > limits.ValueLength {
+				return true
+			}
+		case attribute.STRINGSLICE:
+			for _, value := range attr.Value.AsStringSlice() {
+				if len(value) > limits.ValueLength {
+					return true
+				}
+			}
+		}
+	}
+
+	return false
+}
+
+// truncate returns a copy of attrs, with string values cut to the value length limit.
+func (limits *Limits) truncate(attrs []attribute.KeyValue) []attribute.KeyValue {
+	if !limits.tooLong(attrs) {
+		return attrs
+	}
+
+	result := make([]attribute.KeyValue, len(attrs))
+
+	for i, attr := range attrs {
+		switch attr.Value.Type() { //nolint:exhaustive
+		case attribute.STRING:
+			attr = attr.Key.String(limits.cut(attr.Value.AsString()))
+		case attribute.STRINGSLICE:
+			values := attr.Value.AsStringSlice()
+			for j := range values {
+				values[j] = limits.cut(values[j])
+			}
+
+			attr = attr.Key.StringSlice(values)
+		}
+
+		result[i] = attr
+	}
+
+	return result
+}
+
+func (limits *Limits) cut(value string) string {
+	if len(value) <= limits.ValueLength {
+		return value
+	}
+
+	// Do not split a multi-byte character
+	end := limits.ValueLength
+	for end > 0 && value[end]&0xC0 == 0x80 {
+		end--
+	}
+
+	return value[:end] + TruncationMarker
+}
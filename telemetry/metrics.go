@@ -0,0 +1,348 @@
+package telemetry
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"go.codecomet.dev/core/log"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const (
+	defaultMetricsInterval = time.Minute
+	// maxMetricPoints caps the attribute sets of an instrument, so that a high cardinality attribute (eg: a request
+	// ID) cannot exhaust memory - extra sets are aggregated into a single point marked with OverflowAttribute
+	maxMetricPoints = 2000
+	// OverflowAttribute marks the point aggregating measurements past the attribute sets limit
+	OverflowAttribute = attribute.Key("codecomet.metric.overflow")
+)
+
+// MetricKind is the kind of an instrument.
+type MetricKind string
+
+const (
+	// MetricCounter sums increments
+	MetricCounter MetricKind = "counter"
+	// MetricHistogram counts measurements into buckets
+	MetricHistogram MetricKind = "histogram"
+	// MetricGauge keeps the last value set
+	MetricGauge MetricKind = "gauge"
+)
+
+// DefaultBuckets are the histogram bucket boundaries used when none are provided, suited to milliseconds.
+var DefaultBuckets = []float64{0, 5, 10, 25, 50, 75, 100, 250, 500, 750, 1000, 2500, 5000, 7500, 10000} //nolint:gochecknoglobals
+
+var meters sync.Map //nolint:gochecknoglobals
+
+// MetricData is the state of an instrument, at collection time.
+//
+// Experimental: see Instruments.
+type MetricData struct {
+	Scope       string         `json:"scope"`
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	Unit        string         `json:"unit,omitempty"`
+	Kind        MetricKind     `json:"kind"`
+	Points      []*MetricPoint `json:"points"`
+}
+
+// MetricPoint is the aggregated value of an instrument for a set of attributes.
+type MetricPoint struct {
+	Attributes []attribute.KeyValue `json:"attributes,omitempty"`
+	// Value is the sum of a counter, or the last value of a gauge
+	Value float64 `json:"value,omitempty"`
+	// Count, Sum, Bounds and Counts describe histograms: Counts has one more entry than Bounds, for values above
+	// the last boundary
+	Count  uint64    `json:"count,omitempty"`
+	Sum    float64   `json:"sum,omitempty"`
+	Bounds []float64 `json:"bounds,omitempty"`
+	Counts []uint64  `json:"counts,omitempty"`
+}
+
+// MetricExporter receives collected metrics (see Config.MetricExporter).
+//
+// Experimental: see Instruments.
+type MetricExporter interface {
+	Export(ctx context.Context, metrics []*MetricData) error
+}
+
+// Instruments are the instruments of an instrumentation scope (see Meter). Measurements are aggregated in memory,
+// whether telemetry is initialized or not, and collected by CollectMetrics.
+//
+// Experimental: this is a stopgap, until the OpenTelemetry metrics SDK (go.opentelemetry.io/otel/sdk/metric)
+// becomes a dependency of this module. Meter will then return a metric.Meter, and instruments, MetricExporter,
+// MetricData and CollectMetrics will go away in favor of OpenTelemetry instruments, readers and exporters - callers
+// will have to be updated. Until then, instruments only offer the aggregations above, without views or exemplars,
+// and OpenTelemetry instrumentation libraries and exporters cannot be plugged in.
+type Instruments struct {
+	scope       string
+	mu          sync.Mutex
+	instruments map[string]*instrument
+}
+
+// Meter returns the (cached) instruments of an instrumentation scope. As with Tracer, short names (eg: "exec")
+// designate go-core packages.
+//
+// Experimental: see Instruments.
+func Meter(instrumentationName string) *Instruments {
+	instrumentationName = scopeName(instrumentationName)
+
+	if meter, ok := meters.Load(instrumentationName); ok {
+		return meter.(*Instruments) //nolint:forcetypeassert
+	}
+
+	meter, _ := meters.LoadOrStore(instrumentationName, &Instruments{
+		scope:       instrumentationName,
+		instruments: map[string]*instrument{},
+	})
+
+	return meter.(*Instruments) //nolint:forcetypeassert
+}
+
+// Counter returns the counter called name, creating it if needed.
+func (meter *Instruments) Counter(name string, description string, unit string) *Counter {
+	return &Counter{meter.instrument(MetricCounter, name, description, unit, nil)}
+}
+
+// Histogram returns the histogram called name, creating it with bounds (defaults to DefaultBuckets) if needed.
+func (meter *Instruments) Histogram(name string, description string, unit string, bounds ...float64) *Histogram {
+	if len(bounds) == 0 {
+		bounds = DefaultBuckets
+	}
+
+	bounds = append([]float64{}, bounds...)
+	sort.Float64s(bounds)
+
+	return &Histogram{meter.instrument(MetricHistogram, name, description, unit, bounds)}
+}
+
+// Gauge returns the gauge called name, creating it if needed.
+func (meter *Instruments) Gauge(name string, description string, unit string) *Gauge {
+	return &Gauge{meter.instrument(MetricGauge, name, description, unit, nil)}
+}
+
+func (meter *Instruments) instrument(kind MetricKind, name string, description string, unit string,
+	bounds []float64,
+) *instrument {
+	meter.mu.Lock()
+	defer meter.mu.Unlock()
+
+	key := string(kind) + ":" + name
+
+	if inst, ok := meter.instruments[key]; ok {
+		return inst
+	}
+
+	inst := &instrument{
+		data: MetricData{
+			Scope:       meter.scope,
+			Name:        name,
+			Description: description,
+			Unit:        unit,
+			Kind:        kind,
+		},
+		bounds: bounds,
+		points: map[attribute.Distinct]*MetricPoint{},
+	}
+	meter.instruments[key] = inst
+
+	return inst
+}
+
+// Counter is a monotonic sum.
+type Counter struct {
+	inst *instrument
+}
+
+// Add increments the counter for attrs. Negative values are ignored.
+func (counter *Counter) Add(_ context.Context, value float64, attrs ...attribute.KeyValue) {
+	if value < 0 {
+		return
+	}
+
+	counter.inst.update(attrs, func(point *MetricPoint) {
+		point.Value += value
+	})
+}
+
+// Histogram is a distribution of measurements.
+type Histogram struct {
+	inst *instrument
+}
+
+// Record adds value to the distribution for attrs.
+func (histogram *Histogram) Record(_ context.Context, value float64, attrs ...attribute.KeyValue) {
+	bucket := sort.SearchFloat64s(histogram.inst.bounds, value)
+
+	histogram.inst.update(attrs, func(point *MetricPoint) {
+		point.Count++
+		point.Sum += value
+		point.Counts[bucket]++
+	})
+}
+
+// Gauge is a value that goes up and down.
+type Gauge struct {
+	inst *instrument
+}
+
+// Set records the current value for attrs.
+func (gauge *Gauge) Set(_ context.Context, value float64, attrs ...attribute.KeyValue) {
+	gauge.inst.update(attrs, func(point *MetricPoint) {
+		point.Value = value
+	})
+}
+
+type instrument struct {
+	mu     sync.Mutex
+	data   MetricData
+	bounds []float64
+	points map[attribute.Distinct]*MetricPoint
+}
+
+func (inst *instrument) update(attrs []attribute.KeyValue, apply func(*MetricPoint)) {
+	set := attribute.NewSet(attrs...)
+
+	inst.mu.Lock()
+	defer inst.mu.Unlock()
+
+	point, ok := inst.points[set.Equivalent()]
+	if !ok {
+		if len(inst.points) >= maxMetricPoints {
+			set = attribute.NewSet(OverflowAttribute.Bool(true))
+			point = inst.points[set.Equivalent()]
+		}
+
+		if point == nil {
+			point = &MetricPoint{Attributes: set.ToSlice()}
+			if inst.data.Kind == MetricHistogram {
+				point.Bounds = inst.bounds
+				point.Counts = make([]uint64, len(inst.bounds)+1)
+			}
+
+			inst.points[set.Equivalent()] = point
+		}
+	}
+
+	apply(point)
+}
+
+// collect returns a copy of the instrument state, with points sorted by attributes.
+func (inst *instrument) collect() *MetricData {
+	inst.mu.Lock()
+	defer inst.mu.Unlock()
+
+	data := inst.data
+	data.Points = make([]*MetricPoint, 0, len(inst.points))
+
+	for _, point := range inst.points {
+		copied := *point
+		copied.Counts = append([]uint64(nil), point.Counts...)
+		data.Points = append(data.Points, &copied)
+	}
+
+	sort.Slice(data.Points, func(i, j int) bool {
+		return encodeAttributes(data.Points[i].Attributes) < encodeAttributes(data.Points[j].Attributes)
+	})
+
+	return &data
+}
+
+func encodeAttributes(attrs []attribute.KeyValue) string {
+	set := attribute.NewSet(attrs...)
+
+	return set.Encoded(attribute.DefaultEncoder())
+}
+
+// CollectMetrics returns the state of all instruments, sorted by scope and name, along with the counters of the
+// crash reporter (see reporter.Stats).
+//
+// Experimental: see Instruments.
+func CollectMetrics() []*MetricData {
+	collected := reporterMetrics()
+
+	meters.Range(func(_, value any) bool {
+		meter := value.(*Instruments) //nolint:forcetypeassert
+
+		meter.mu.Lock()
+		instruments := make([]*instrument, 0, len(meter.instruments))
+
+		for _, inst := range meter.instruments {
+			instruments = append(instruments, inst)
+		}
+		meter.mu.Unlock()
+
+		for _, inst := range instruments {
+			collected = append(collected, inst.collect())
+		}
+
+		return true
+	})
+
+	sort.Slice(collected, func(i, j int) bool {
+		if collected[i].Scope != collected[j].Scope {
+			return collected[i].Scope < collected[j].Scope
+		}
+
+		if collected[i].Name != collected[j].Name {
+			return collected[i].Name < collected[j].Name
+		}
+
+		return collected[i].Kind < collected[j].Kind
+	})
+
+	return collected
+}
+
+// metricReader hands collected metrics to an exporter periodically, and a last time when closed.
+type metricReader struct {
+	exporter MetricExporter
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+func newMetricReader(exporter MetricExporter, interval time.Duration) *metricReader {
+	if interval <= 0 {
+		interval = defaultMetricsInterval
+	}
+
+	reader := &metricReader{
+		exporter: exporter,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+
+	go func() {
+		defer close(reader.done)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				reader.export(context.Background())
+			case <-reader.stop:
+				return
+			}
+		}
+	}()
+
+	return reader
+}
+
+func (reader *metricReader) export(ctx context.Context) {
+	if err := reader.exporter.Export(ctx, CollectMetrics()); err != nil {
+		log.Warn().Err(err).Msg("Failed exporting metrics")
+	}
+}
+
+// shutdown stops periodic exports, and exports a last time.
+func (reader *metricReader) shutdown(ctx context.Context) {
+	close(reader.stop)
+	<-reader.done
+
+	reader.export(ctx)
+}
@@ -0,0 +1,75 @@
+package telemetry
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+var disabledModules sync.Map //nolint:gochecknoglobals
+
+// SetModuleEnabled turns span creation on or off for an instrumentation scope, effective immediately, including for
+// tracers already obtained. As with Tracer, short names (eg: "exec") designate go-core packages.
+// Spans of a disabled module are not recorded, but the trace context flows through, so that spans started by
+// enabled modules below still belong to the right trace.
+func SetModuleEnabled(module string, enabled bool) {
+	if enabled {
+		disabledModules.Delete(scopeName(module))
+	} else {
+		disabledModules.Store(scopeName(module), true)
+	}
+}
+
+// setDisabledModules disables exactly modules, re-enabling the others.
+func setDisabledModules(modules []string) {
+	disabled := map[string]bool{}
+
+	for _, module := range modules {
+		disabled[scopeName(module)] = true
+		disabledModules.Store(scopeName(module), true)
+	}
+
+	disabledModules.Range(func(key, _ interface{}) bool {
+		if name, _ := key.(string); !disabled[name] {
+			disabledModules.Delete(key)
+		}
+
+		return true
+	})
+}
+
+// ModuleEnabled is true unless span creation has been turned off for the module (see SetModuleEnabled).
+func ModuleEnabled(module string) bool {
+	_, disabled := disabledModules.Load(scopeName(module))
+
+	return !disabled
+}
+
+func scopeName(name string) string {
+	if !strings.Contains(name, "/") {
+		return instrumentationPrefix + name
+	}
+
+	return name
+}
+
+// toggledTracer defers to its tracer, unless its module is disabled.
+type toggledTracer struct {
+	trace.Tracer
+	module string
+}
+
+func (t *toggledTracer) Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context,
+	trace.Span,
+) {
+	if _, disabled := disabledModules.Load(t.module); !disabled {
+		return t.Tracer.Start(ctx, name, opts...)
+	}
+
+	// A non-recording span carrying the parent span context: ending it is a no-op, and children keep the parent
+	ctx = trace.ContextWithSpanContext(ctx, trace.SpanContextFromContext(ctx))
+
+	return ctx, trace.SpanFromContext(ctx)
+}
@@ -0,0 +1,296 @@
+package telemetry
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+const otlpTracesPath = "/v1/traces"
+
+// otlpExporter sends spans to an OTLP/HTTP endpoint, with the JSON encoding of OTLP: no OTLP exporter is vendored,
+// and the JSON encoding needs no generated code.
+type otlpExporter struct {
+	client *http.Client
+	url    string
+	gzip   bool
+}
+
+// newOTLPExporter returns an exporter posting to endpoint, the base URL of the collector (eg:
+// http://localhost:4318), or its traces URL.
+func newOTLPExporter(endpoint string, client *http.Client, compress bool) *otlpExporter {
+	url := strings.TrimSuffix(endpoint, "/")
+	if !strings.HasSuffix(url, otlpTracesPath) {
+		url += otlpTracesPath
+	}
+
+	return &otlpExporter{client: client, url: url, gzip: compress}
+}
+
+func (exp *otlpExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	if len(spans) == 0 {
+		return nil
+	}
+
+	payload, err := json.Marshal(otlpTraces(spans))
+	if err != nil {
+		return err
+	}
+
+	if exp.gzip {
+		var buf bytes.Buffer
+
+		writer := gzip.NewWriter(&buf)
+		if _, err = writer.Write(payload); err != nil {
+			return err
+		}
+
+		if err = writer.Close(); err != nil {
+			return err
+		}
+
+		payload = buf.Bytes()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, exp.url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	if exp.gzip {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+
+	resp, err := exp.client.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("%w: %s", ErrExportFailed, resp.Status)
+	}
+
+	return nil
+}
+
+func (exp *otlpExporter) Shutdown(_ context.Context) error {
+	exp.client.CloseIdleConnections()
+
+	return nil
+}
+
+// OTLP JSON payloads (see https://opentelemetry.io/docs/specs/otlp/#json-protobuf-encoding): IDs are hex encoded,
+// 64 bits integers are strings, and enums are numbers.
+type (
+	otlpKeyValue struct {
+		Key   string    `json:"key"`
+		Value otlpValue `json:"value"`
+	}
+
+	otlpValue struct {
+		String *string    `json:"stringValue,omitempty"`
+		Bool   *bool      `json:"boolValue,omitempty"`
+		Int    string     `json:"intValue,omitempty"`
+		Double *float64   `json:"doubleValue,omitempty"`
+		Array  *otlpArray `json:"arrayValue,omitempty"`
+	}
+
+	otlpArray struct {
+		Values []otlpValue `json:"values"`
+	}
+
+	otlpEvent struct {
+		Time       string         `json:"timeUnixNano"`
+		Name       string         `json:"name"`
+		Attributes []otlpKeyValue `json:"attributes,omitempty"`
+	}
+
+	otlpStatus struct {
+		Code    int    `json:"code,omitempty"`
+		Message string `json:"message,omitempty"`
+	}
+
+	otlpSpan struct {
+		TraceID      string         `json:"traceId"`
+		SpanID       string         `json:"spanId"`
+		TraceState   string         `json:"traceState,omitempty"`
+		ParentSpanID string         `json:"parentSpanId,omitempty"`
+		Name         string         `json:"name"`
+		Kind         int            `json:"kind"`
+		Start        string         `json:"startTimeUnixNano"`
+		End          string         `json:"endTimeUnixNano"`
+		Attributes   []otlpKeyValue `json:"attributes,omitempty"`
+		Events       []otlpEvent    `json:"events,omitempty"`
+		Status       otlpStatus     `json:"status"`
+	}
+
+	otlpScope struct {
+		Name    string `json:"name"`
+		Version string `json:"version,omitempty"`
+	}
+
+	otlpScopeSpans struct {
+		Scope otlpScope   `json:"scope"`
+		Spans []*otlpSpan `json:"spans"`
+	}
+
+	otlpResource struct {
+		Attributes []otlpKeyValue `json:"attributes"`
+	}
+
+	otlpResourceSpans struct {
+		Resource   otlpResource      `json:"resource"`
+		ScopeSpans []*otlpScopeSpans `json:"scopeSpans"`
+	}
+
+	otlpTracesPayload struct {
+		ResourceSpans []*otlpResourceSpans `json:"resourceSpans"`
+	}
+)
+
+// otlpTraces groups spans by resource, then by instrumentation scope.
+func otlpTraces(spans []sdktrace.ReadOnlySpan) *otlpTracesPayload {
+	payload := &otlpTracesPayload{}
+	resources := map[attribute.Distinct]*otlpResourceSpans{}
+	scopes := map[*otlpResourceSpans]map[otlpScope]*otlpScopeSpans{}
+
+	for _, span := range spans {
+		key := span.Resource().Equivalent()
+
+		res, ok := resources[key]
+		if !ok {
+			res = &otlpResourceSpans{Resource: otlpResource{Attributes: otlpAttributes(span.Resource().Attributes())}}
+			resources[key] = res
+			scopes[res] = map[otlpScope]*otlpScopeSpans{}
+			payload.ResourceSpans = append(payload.ResourceSpans, res)
+		}
+
+		lib := span.InstrumentationScope()
+		scopeKey := otlpScope{Name: lib.Name, Version: lib.Version}
+
+		scope, ok := scopes[res][scopeKey]
+		if !ok {
+			scope = &otlpScopeSpans{Scope: scopeKey}
+			scopes[res][scopeKey] = scope
+			res.ScopeSpans = append(res.ScopeSpans, scope)
+		}
+
+		scope.Spans = append(scope.Spans, otlpSpanOf(span))
+	}
+
+	return payload
+}
+
+func otlpSpanOf(span sdktrace.ReadOnlySpan) *otlpSpan {
+	ctx := span.SpanContext()
+
+	converted := &otlpSpan{
+		TraceID:    ctx.TraceID().String(),
+		SpanID:     ctx.SpanID().String(),
+		TraceState: ctx.TraceState().String(),
+		Name:       span.Name(),
+		// SpanKind values match the OTLP enum
+		Kind:       int(span.SpanKind()),
+		Start:      strconv.FormatInt(span.StartTime().UnixNano(), 10),
+		End:        strconv.FormatInt(span.EndTime().UnixNano(), 10),
+		Attributes: otlpAttributes(span.Attributes()),
+		Status:     otlpStatus{Message: span.Status().Description},
+	}
+
+	if parent := span.Parent(); parent.SpanID().IsValid() {
+		converted.ParentSpanID = parent.SpanID().String()
+	}
+
+	// Status codes do not: OTLP has Unset, Ok, Error
+	switch span.Status().Code {
+	case codes.Ok:
+		converted.Status.Code = 1
+	case codes.Error:
+		converted.Status.Code = 2
+	case codes.Unset:
+	}
+
+	for _, event := range span.Events() {
+		converted.Events = append(converted.Events, otlpEvent{
+			Time:       strconv.FormatInt(event.Time.UnixNano(), 10),
+			Name:       event.Name,
+			Attributes: otlpAttributes(event.Attributes),
+		})
+	}
+
+	return converted
+}
+
+func otlpAttributes(attrs []attribute.KeyValue) []otlpKeyValue {
+	converted := make([]otlpKeyValue, 0, len(attrs))
+
+	for _, attr := range attrs {
+		converted = append(converted, otlpKeyValue{Key: string(attr.Key), Value: otlpValueOf(attr.Value)})
+	}
+
+	return converted
+}
+
+func otlpValueOf(value attribute.Value) otlpValue {
+	switch value.Type() {
+	case attribute.BOOL:
+		b := value.AsBool()
+
+		return otlpValue{Bool: &b}
+	case attribute.INT64:
+		return otlpValue{Int: strconv.FormatInt(value.AsInt64(), 10)}
+	case attribute.FLOAT64:
+		f := value.AsFloat64()
+
+		return otlpValue{Double: &f}
+	case attribute.BOOLSLICE:
+		values := []otlpValue{}
+		for _, b := range value.AsBoolSlice() {
+			values = append(values, otlpValueOf(attribute.BoolValue(b)))
+		}
+
+		return otlpValue{Array: &otlpArray{Values: values}}
+	case attribute.INT64SLICE:
+		values := []otlpValue{}
+		for _, i := range value.AsInt64Slice() {
+			values = append(values, otlpValueOf(attribute.Int64Value(i)))
+		}
+
+		return otlpValue{Array: &otlpArray{Values: values}}
+	case attribute.FLOAT64SLICE:
+		values := []otlpValue{}
+		for _, f := range value.AsFloat64Slice() {
+			values = append(values, otlpValueOf(attribute.Float64Value(f)))
+		}
+
+		return otlpValue{Array: &otlpArray{Values: values}}
+	case attribute.STRINGSLICE:
+		values := []otlpValue{}
+		for _, s := range value.AsStringSlice() {
+			values = append(values, otlpValueOf(attribute.StringValue(s)))
+		}
+
+		return otlpValue{Array: &otlpArray{Values: values}}
+	case attribute.STRING, attribute.INVALID:
+	}
+
+	s := value.Emit()
+
+	return otlpValue{String: &s}
+}
@@ -0,0 +1,194 @@
+package telemetry
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.codecomet.dev/core/log"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const (
+	prometheusContentType = "text/plain; version=0.0.4; charset=utf-8"
+	// prometheusScopeLabel is the label of the OpenTelemetry Prometheus exporter, so that queries keep working if
+	// metrics move to it
+	prometheusScopeLabel  = "otel_scope_name"
+	prometheusBucketLabel = "le"
+	// prometheusExportedPrefix renames attributes colliding with our own labels, as Prometheus does for target labels
+	prometheusExportedPrefix = "exported_"
+)
+
+var (
+	prometheusInvalidName  = regexp.MustCompile(`[^a-zA-Z0-9_:]`)                  //nolint:gochecknoglobals
+	prometheusInvalidLabel = regexp.MustCompile(`[^a-zA-Z0-9_]`)                   //nolint:gochecknoglobals
+	prometheusEscaper      = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`) //nolint:gochecknoglobals
+	prometheusHelpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)            //nolint:gochecknoglobals
+)
+
+// MetricsHandler serves the collected metrics (see Meter) in the Prometheus text exposition format, typically on
+// /metrics, so that Prometheus can scrape them. It works whatever the Config Type - PROMETHEUS is for services that
+// export metrics only.
+// Counters get a _total suffix, and every sample carries the instrumentation scope as an otel_scope_name label.
+// Attributes named like that label, or like le on histograms, get an exported_ prefix. Attributes whose names only
+// differ by characters invalid in labels (eg: a.b and a_b) share a label, their values joined with ";".
+//
+// Experimental: metrics are rendered by hand, from the experimental instruments (see Instruments). Once Meter is
+// backed by the OpenTelemetry metrics SDK, the handler will be served by the OpenTelemetry Prometheus exporter
+// (go.opentelemetry.io/otel/exporters/prometheus) instead, which the labels above already match.
+func MetricsHandler() http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+		writer.Header().Set("Content-Type", prometheusContentType)
+		_, _ = writer.Write(prometheusText(CollectMetrics()))
+	})
+}
+
+// prometheusFamily is the metrics sharing a Prometheus name, across scopes.
+type prometheusFamily struct {
+	name    string
+	kind    MetricKind
+	help    string
+	metrics []*MetricData
+}
+
+func prometheusText(metrics []*MetricData) []byte {
+	families := map[string]*prometheusFamily{}
+	names := []string{}
+
+	for _, metric := range metrics {
+		name := prometheusName(metric)
+
+		family, ok := families[name]
+		if !ok {
+			family = &prometheusFamily{name: name, kind: metric.Kind, help: metric.Description}
+			families[name] = family
+			names = append(names, name)
+		}
+
+		// A family has a single type: Prometheus would reject the whole scrape
+		if family.kind != metric.Kind {
+			log.Debug().Str("metric", name).Str("scope", metric.Scope).Msg("Skipping metric conflicting with another kind")
+
+			continue
+		}
+
+		family.metrics = append(family.metrics, metric)
+	}
+
+	sort.Strings(names)
+
+	var out bytes.Buffer
+
+	for _, name := range names {
+		family := families[name]
+
+		if family.help != "" {
+			fmt.Fprintf(&out, "# HELP %s %s\n", name, prometheusHelpEscaper.Replace(family.help))
+		}
+
+		fmt.Fprintf(&out, "# TYPE %s %s\n", name, prometheusType(family.kind))
+
+		for _, metric := range family.metrics {
+			for _, point := range metric.Points {
+				writePrometheusPoint(&out, name, metric, point)
+			}
+		}
+	}
+
+	return out.Bytes()
+}
+
+func writePrometheusPoint(out *bytes.Buffer, name string, metric *MetricData, point *MetricPoint) {
+	labels := prometheusLabels(metric.Scope, metric.Kind, point.Attributes)
+
+	if metric.Kind != MetricHistogram {
+		fmt.Fprintf(out, "%s{%s} %s\n", name, labels, prometheusValue(point.Value))
+
+		return
+	}
+
+	// Buckets are cumulative in Prometheus
+	var cumulative uint64
+
+	for i, count := range point.Counts {
+		cumulative += count
+
+		bound := "+Inf"
+		if i < len(point.Bounds) {
+			bound = prometheusValue(point.Bounds[i])
+		}
+
+		fmt.Fprintf(out, "%s_bucket{%s,%s=\"%s\"} %d\n", name, labels, prometheusBucketLabel, bound, cumulative)
+	}
+
+	fmt.Fprintf(out, "%s_sum{%s} %s\n", name, labels, prometheusValue(point.Sum))
+	fmt.Fprintf(out, "%s_count{%s} %d\n", name, labels, point.Count)
+}
+
+func prometheusName(metric *MetricData) string {
+	name := prometheusInvalidName.ReplaceAllString(metric.Name, "_")
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		name = "_" + name
+	}
+
+	if metric.Kind == MetricCounter && !strings.HasSuffix(name, "_total") {
+		name += "_total"
+	}
+
+	return name
+}
+
+func prometheusType(kind MetricKind) string {
+	switch kind {
+	case MetricCounter:
+		return "counter"
+	case MetricHistogram:
+		return "histogram"
+	default:
+		return "gauge"
+	}
+}
+
+func prometheusLabels(scope string, kind MetricKind, attrs []attribute.KeyValue) string {
+	reserved := map[string]bool{prometheusScopeLabel: true}
+	if kind == MetricHistogram {
+		reserved[prometheusBucketLabel] = true
+	}
+
+	values := map[string][]string{}
+	names := []string{}
+
+	// Attributes are sorted by key, which orders joined values
+	for _, attr := range attrs {
+		name := prometheusInvalidLabel.ReplaceAllString(string(attr.Key), "_")
+		if name == "" || (name[0] >= '0' && name[0] <= '9') {
+			name = "_" + name
+		}
+
+		if reserved[name] {
+			name = prometheusExportedPrefix + name
+		}
+
+		if _, ok := values[name]; !ok {
+			names = append(names, name)
+		}
+
+		values[name] = append(values[name], attr.Value.Emit())
+	}
+
+	labels := []string{prometheusScopeLabel + `="` + prometheusEscaper.Replace(scope) + `"`}
+
+	for _, name := range names {
+		labels = append(labels, name+`="`+prometheusEscaper.Replace(strings.Join(values[name], ";"))+`"`)
+	}
+
+	return strings.Join(labels, ",")
+}
+
+func prometheusValue(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
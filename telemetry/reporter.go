@@ -0,0 +1,44 @@
+package telemetry
+
+import (
+	"go.codecomet.dev/core/reporter"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const (
+	reporterModule = "reporter"
+	// reporterOutcome tells what became of crash reports
+	reporterOutcome = attribute.Key("outcome")
+)
+
+// reporterMetrics reads the counters of the crash reporter (see reporter.Stats), which cannot import this package.
+func reporterMetrics() []*MetricData {
+	stats := reporter.Stats()
+	scope := scopeName(reporterModule)
+
+	return []*MetricData{
+		{
+			Scope:       scope,
+			Name:        "codecomet.reporter.events",
+			Description: "Crash reports (errors, messages and transactions) sent to Sentry, by outcome",
+			Unit:        "{event}",
+			Kind:        MetricCounter,
+			Points: []*MetricPoint{
+				{Attributes: []attribute.KeyValue{reporterOutcome.String("failed")}, Value: float64(stats.Failed)},
+				{
+					Attributes: []attribute.KeyValue{reporterOutcome.String("rate_limited")},
+					Value:      float64(stats.RateLimited),
+				},
+				{Attributes: []attribute.KeyValue{reporterOutcome.String("sent")}, Value: float64(stats.Sent)},
+			},
+		},
+		{
+			Scope:       scope,
+			Name:        "codecomet.reporter.queued",
+			Description: "Crash reports handed over to the transport that did not reach Sentry yet",
+			Unit:        "{event}",
+			Kind:        MetricGauge,
+			Points:      []*MetricPoint{{Value: float64(stats.Queued)}},
+		},
+	}
+}
@@ -0,0 +1,99 @@
+package telemetry
+
+import (
+	"context"
+	"os"
+	"strings"
+
+	"go.codecomet.dev/core/log"
+	"go.codecomet.dev/core/version"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+)
+
+const k8sNamespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+
+// newResource describes the service emitting telemetry. From lowest to highest precedence: detected host, OS,
+// process runtime, container and Kubernetes attributes, then OTEL_RESOURCE_ATTRIBUTES and OTEL_SERVICE_NAME, then
+// the configuration.
+// Detection failures are not fatal: whatever was detected is kept.
+func newResource(conf *Config) *resource.Resource {
+	opts := []resource.Option{resource.WithSchemaURL(semconv.SchemaURL)}
+
+	if !conf.NoDetectors {
+		opts = append(opts,
+			resource.WithHost(),
+			resource.WithOSType(),
+			resource.WithProcessPID(),
+			resource.WithProcessRuntimeName(),
+			resource.WithProcessRuntimeVersion(),
+			resource.WithContainer(),
+			resource.WithDetectors(k8sDetector{}),
+		)
+	}
+
+	opts = append(opts, resource.WithFromEnv(), resource.WithAttributes(conf.resourceAttributes()...))
+
+	res, err := resource.New(context.Background(), opts...)
+	if err != nil {
+		log.Debug().Err(err).Msg("Failed detecting some resource attributes")
+	}
+
+	return res
+}
+
+// resourceAttributes are the attributes set by the configuration. Attributes cannot override the dedicated fields.
+func (conf *Config) resourceAttributes() []attribute.KeyValue {
+	attrs := []attribute.KeyValue{}
+
+	for key, value := range conf.Attributes {
+		attrs = append(attrs, attribute.String(key, value))
+	}
+
+	if conf.ServiceName != "" {
+		attrs = append(attrs, semconv.ServiceName(conf.ServiceName))
+	}
+
+	serviceVersion := conf.ServiceVersion
+	if serviceVersion == "" && version.Version != "unknown" {
+		serviceVersion = version.Version
+	}
+
+	if serviceVersion != "" {
+		attrs = append(attrs, semconv.ServiceVersion(serviceVersion))
+	}
+
+	if conf.Environment != "" {
+		attrs = append(attrs, semconv.DeploymentEnvironment(conf.Environment))
+	}
+
+	return attrs
+}
+
+// k8sDetector detects pods from the environment Kubernetes sets up for them: the pod name is the hostname, and the
+// namespace comes with the service account.
+type k8sDetector struct{}
+
+func (k8sDetector) Detect(context.Context) (*resource.Resource, error) {
+	if os.Getenv("KUBERNETES_SERVICE_HOST") == "" {
+		return resource.Empty(), nil
+	}
+
+	attrs := []attribute.KeyValue{}
+
+	if pod, err := os.Hostname(); err == nil {
+		attrs = append(attrs, semconv.K8SPodName(pod))
+	}
+
+	if namespace, err := os.ReadFile(k8sNamespaceFile); err == nil {
+		attrs = append(attrs, semconv.K8SNamespaceName(strings.TrimSpace(string(namespace))))
+	}
+
+	// Not set by Kubernetes itself, but commonly exposed through the downward API
+	if node := os.Getenv("K8S_NODE_NAME"); node != "" {
+		attrs = append(attrs, semconv.K8SNodeName(node))
+	}
+
+	return resource.NewWithAttributes(semconv.SchemaURL, attrs...), nil
+}
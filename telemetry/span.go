@@ -0,0 +1,85 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"strings"
+	"sync/atomic"
+
+	"go.codecomet.dev/core/reporter"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const eventIDAttribute = attribute.Key("sentry.event_id")
+
+var reportSpanErrors int32 //nolint:gochecknoglobals
+
+// Span is a span started with Start. End it with End.
+type Span struct {
+	trace.Span
+}
+
+// Start starts a span named name, as a child of the span of ctx, and returns the context carrying it.
+// The tracer is the one of the calling package (see Tracer), so that module toggles apply. Its import path is used
+// as is: the main package of a program gets a "main" scope, not a go-core one.
+// The usual pattern is:
+//
+//	ctx, span := telemetry.Start(ctx, "fetch", attribute.String("url", url))
+//	defer func() { span.End(err) }()
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, *Span) {
+	ctx, span := scopedTracer(callerPackage(), "").Start(ctx, name, trace.WithAttributes(attrs...))
+
+	return ctx, &Span{Span: span}
+}
+
+// End ends the span with the outcome of the operation, and returns err. A non-nil err is recorded on the span, and
+// sets its status. If Config.ReportErrors is set, it is also reported (except for cancellations), and the span
+// carries the event ID.
+func (span *Span) End(err error) error {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		if atomic.LoadInt32(&reportSpanErrors) == 1 && span.IsRecording() &&
+			!errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded) {
+			if eventID := reporter.CaptureException(err); eventID != nil {
+				span.SetAttributes(eventIDAttribute.String(string(*eventID)))
+			}
+		}
+	}
+
+	span.Span.End()
+
+	return err
+}
+
+// setReportSpanErrors toggles reporting errors ending spans.
+func setReportSpanErrors(enabled bool) {
+	var value int32
+	if enabled {
+		value = 1
+	}
+
+	atomic.StoreInt32(&reportSpanErrors, value)
+}
+
+// callerPackage returns the import path of the package calling the caller of callerPackage.
+func callerPackage() string {
+	pc, _, _, ok := runtime.Caller(2) //nolint:gomnd
+	if !ok {
+		return coreModule
+	}
+
+	// Function names are the import path, followed by a dot and the (qualified) function name
+	name := runtime.FuncForPC(pc).Name()
+	slash := strings.LastIndex(name, "/")
+
+	if dot := strings.Index(name[slash+1:], "."); dot >= 0 {
+		return name[:slash+1+dot]
+	}
+
+	return name
+}
@@ -0,0 +1,121 @@
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	summaryTopOperations = 10
+	categoryNetwork      = "network"
+	categoryExec         = "exec"
+	categoryOther        = "other"
+)
+
+type operationStats struct {
+	name     string
+	count    int
+	duration time.Duration
+}
+
+// summaryProcessor aggregates ended spans, and prints a timing summary on shutdown.
+type summaryProcessor struct {
+	mu         sync.Mutex
+	out        io.Writer
+	start      time.Time
+	operations map[string]*operationStats
+	categories map[string]time.Duration
+}
+
+func newSummaryProcessor() *summaryProcessor {
+	return &summaryProcessor{
+		out:        os.Stderr,
+		start:      time.Now(),
+		operations: map[string]*operationStats{},
+		categories: map[string]time.Duration{},
+	}
+}
+
+func (proc *summaryProcessor) OnStart(context.Context, sdktrace.ReadWriteSpan) {}
+
+func (proc *summaryProcessor) OnEnd(span sdktrace.ReadOnlySpan) {
+	duration := span.EndTime().Sub(span.StartTime())
+
+	proc.mu.Lock()
+	defer proc.mu.Unlock()
+
+	stats, ok := proc.operations[span.Name()]
+	if !ok {
+		stats = &operationStats{name: span.Name()}
+		proc.operations[span.Name()] = stats
+	}
+
+	stats.count++
+	stats.duration += duration
+	proc.categories[category(span)] += duration
+}
+
+// category classifies a span as network, exec, or other, from its kind and instrumentation scope.
+func category(span sdktrace.ReadOnlySpan) string {
+	scope := span.InstrumentationScope().Name
+
+	switch {
+	case strings.HasSuffix(scope, "/exec"):
+		return categoryExec
+	case span.SpanKind() == trace.SpanKindClient || strings.Contains(scope, "http") || strings.HasSuffix(scope, "/network"):
+		return categoryNetwork
+	default:
+		return categoryOther
+	}
+}
+
+func (proc *summaryProcessor) Shutdown(context.Context) error {
+	proc.mu.Lock()
+	defer proc.mu.Unlock()
+
+	if len(proc.operations) == 0 {
+		return nil
+	}
+
+	operations := make([]*operationStats, 0, len(proc.operations))
+	for _, stats := range proc.operations {
+		operations = append(operations, stats)
+	}
+
+	sort.Slice(operations, func(i, j int) bool {
+		return operations[i].duration > operations[j].duration
+	})
+
+	if len(operations) > summaryTopOperations {
+		operations = operations[:summaryTopOperations]
+	}
+
+	fmt.Fprintf(proc.out, "\nTiming summary (total %s)\n", time.Since(proc.start).Round(time.Millisecond))
+	fmt.Fprintf(proc.out, "  network: %s, exec: %s, other: %s\n",
+		proc.categories[categoryNetwork].Round(time.Millisecond),
+		proc.categories[categoryExec].Round(time.Millisecond),
+		proc.categories[categoryOther].Round(time.Millisecond))
+
+	writer := tabwriter.NewWriter(proc.out, 0, 0, 2, ' ', 0) //nolint:gomnd
+	fmt.Fprintf(writer, "  OPERATION\tCOUNT\tDURATION\n")
+
+	for _, stats := range operations {
+		fmt.Fprintf(writer, "  %s\t%d\t%s\n", stats.name, stats.count, stats.duration.Round(time.Millisecond))
+	}
+
+	return writer.Flush()
+}
+
+func (proc *summaryProcessor) ForceFlush(context.Context) error {
+	return nil
+}
@@ -7,13 +7,12 @@ import (
 	"io"
 	"time"
 
+	"github.com/getsentry/sentry-go"
 	sentryotel "github.com/getsentry/sentry-go/otel"
 	"go.codecomet.dev/core/log"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/exporters/jaeger"
-	"go.opentelemetry.io/otel/sdk/resource"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
-	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
 	"go.opentelemetry.io/otel/trace"
 )
 
@@ -26,23 +25,40 @@ func GetTracerProvider() TracerProvider {
 }
 
 func Init(conf *Config) io.Closer {
+	// Modules disabled by a previous configuration are enabled again
+	setDisabledModules(conf.DisabledModules)
+
+	setReportSpanErrors(conf.ReportErrors)
+
 	if conf.Disabled {
 		log.Warn().Msg("Telemetry is disabled.")
 
 		return &noopCloser{}
 	}
 
-	prov, err := provider(conf.Type, conf.Endpoint, conf.ServiceName)
+	prov, err := provider(conf)
 	if err != nil {
 		log.Fatal().Err(err).Str("type", string(conf.Type)).Msg("Failed creating telemetry provider")
 	}
 
 	// Register with OTEL
 	otel.SetTracerProvider(prov)
+	resetTracers()
+
+	instrumentHTTP(conf.HTTP)
 
-	return providerCloser{
+	closer := providerCloser{
 		TracerProvider: prov,
 	}
+
+	if conf.MetricExporter != nil {
+		closer.metrics = newMetricReader(conf.MetricExporter, conf.MetricsInterval)
+	}
+
+	// Make sure spans are exported if we die on a fatal log
+	log.AddExitHook(closer)
+
+	return closer
 }
 
 type noopCloser struct{}
@@ -53,45 +69,57 @@ func (*noopCloser) Close() error {
 
 type providerCloser struct {
 	*sdktrace.TracerProvider
+	metrics *metricReader
 }
 
 func (t providerCloser) Close() error {
 	ctx, cancel := context.WithTimeout(context.Background(), closeTimeout)
 	defer cancel()
 
+	// The Sentry span processor flushes the hub of the context on shutdown, and crashes without one
+	ctx = sentry.SetHubOnContext(ctx, sentry.CurrentHub())
+
+	if t.metrics != nil {
+		t.metrics.shutdown(ctx)
+	}
+
 	return t.Shutdown(ctx)
 }
 
-func provider(expType ExporterType, url string, serviceName string) (*sdktrace.TracerProvider, error) {
-	var err error
+func provider(conf *Config) (*sdktrace.TracerProvider, error) {
+	exporters := conf.exporters()
 
-	var exp sdktrace.SpanExporter
+	// No trace exporter is fine if we at least summarize, or export metrics
+	if len(exporters) == 0 && !conf.Summary && conf.MetricExporter == nil {
+		return nil, fmt.Errorf("failed to create provider: %w", ErrUnsupportedProviderType)
+	}
 
 	opts := []sdktrace.TracerProviderOption{
-		sdktrace.WithResource(resource.NewWithAttributes(
-			semconv.SchemaURL,
-			semconv.ServiceNameKey.String(serviceName),
-		)),
+		sdktrace.WithResource(newResource(conf)),
 	}
 
-	switch expType {
-	case JAEGGER:
-		exp, err = jaeger.New(jaeger.WithCollectorEndpoint(jaeger.WithEndpoint(url)))
-		opts = append(opts, sdktrace.WithBatcher(exp, sdktrace.WithMaxExportBatchSize(1)))
-	case SENTRY:
-		opts = append(opts, sdktrace.WithSpanProcessor(sentryotel.NewSentrySpanProcessor()))
-		otel.SetTextMapPropagator(sentryotel.NewSentryPropagator())
-	/*
-		case PROMETHEUS:
-		case OTLP:
+	if conf.IDGenerator != nil {
+		opts = append(opts, sdktrace.WithIDGenerator(conf.IDGenerator))
+	}
 
-	*/
-	default:
-		err = ErrUnsupportedProviderType
+	// Enrichment goes first, so that other processors see the attributes
+	if conf.Enrichment != nil {
+		opts = append(opts, sdktrace.WithSpanProcessor(&enrichProcessor{enrichment: conf.Enrichment}))
 	}
 
-	if err != nil {
-		return nil, fmt.Errorf("failed to create provider: %w", err)
+	if conf.Summary {
+		opts = append(opts, sdktrace.WithSpanProcessor(newSummaryProcessor()))
+	}
+
+	for _, exporter := range exporters {
+		proc, err := exporter.processor()
+		if err != nil {
+			return nil, fmt.Errorf("failed to create provider: %w", err)
+		}
+
+		if proc != nil {
+			opts = append(opts, sdktrace.WithSpanProcessor(newLimitProcessor(proc, conf.Limits)))
+		}
 	}
 
 	tracerProvider := sdktrace.NewTracerProvider(
@@ -100,3 +128,55 @@ func provider(expType ExporterType, url string, serviceName string) (*sdktrace.T
 
 	return tracerProvider, nil
 }
+
+// exporters returns the configured exporters: Exporters, preceded by Type if set.
+func (conf *Config) exporters() []*ExporterConfig {
+	exporters := []*ExporterConfig{}
+
+	if conf.Type != "" {
+		exporters = append(exporters, &ExporterConfig{Type: conf.Type, Endpoint: conf.Endpoint})
+	}
+
+	for _, exporter := range conf.Exporters {
+		if exporter != nil {
+			exporters = append(exporters, exporter)
+		}
+	}
+
+	return exporters
+}
+
+// processor returns the span processor of the exporter - nil for exporters that do not export spans.
+func (exporter *ExporterConfig) processor() (sdktrace.SpanProcessor, error) {
+	switch exporter.Type {
+	case JAEGGER:
+		client, err := exporter.httpClient()
+		if err != nil {
+			return nil, err
+		}
+
+		exp, err := jaeger.New(jaeger.WithCollectorEndpoint(jaeger.WithEndpoint(exporter.Endpoint),
+			jaeger.WithHTTPClient(client)))
+		if err != nil {
+			return nil, err
+		}
+
+		return sdktrace.NewBatchSpanProcessor(exp, sdktrace.WithMaxExportBatchSize(1)), nil
+	case SENTRY:
+		otel.SetTextMapPropagator(sentryotel.NewSentryPropagator())
+
+		return sentryotel.NewSentrySpanProcessor(), nil
+	case PROMETHEUS:
+		// Pull based: nothing to register, MetricsHandler serves what instruments collect
+		return nil, nil //nolint:nilnil
+	case OTLP:
+		client, err := exporter.httpClient()
+		if err != nil {
+			return nil, err
+		}
+
+		return sdktrace.NewBatchSpanProcessor(newOTLPExporter(exporter.Endpoint, client, exporter.Gzip)), nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedProviderType, exporter.Type)
+	}
+}
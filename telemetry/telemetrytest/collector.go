@@ -0,0 +1,400 @@
+package telemetrytest
+
+import (
+	"compress/gzip"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+// OTLP/HTTP paths served by the Collector.
+const (
+	TracesPath  = "/v1/traces"
+	MetricsPath = "/v1/metrics"
+)
+
+// OTLP/gRPC methods served by the Collector.
+const (
+	TraceServiceMethod   = "/opentelemetry.proto.collector.trace.v1.TraceService/Export"
+	MetricsServiceMethod = "/opentelemetry.proto.collector.metrics.v1.MetricsService/Export"
+)
+
+// Kinds of metrics.
+const (
+	Gauge                = "gauge"
+	Sum                  = "sum"
+	Histogram            = "histogram"
+	ExponentialHistogram = "exponentialHistogram"
+	Summary              = "summary"
+)
+
+const (
+	collectorPoll = 10 * time.Millisecond
+
+	jsonContentType     = "application/json"
+	protobufContentType = "application/x-protobuf"
+)
+
+// Collector is an in-process OTLP receiver, recording what exporters send to it so that tests can assert on
+// exported spans and metrics, and on the requests themselves (headers, compression, TLS).
+// It serves OTLP/HTTP, with the JSON or protobuf encoding, and OTLP/gRPC on the same port. gRPC requires HTTP/2,
+// which is spoken in cleartext (h2c) by plain collectors.
+type Collector struct {
+	// URL is the base URL of the collector (eg: http://127.0.0.1:4318) - OTLP/HTTP exporters post to URL +
+	// TracesPath
+	URL string
+	// Endpoint is the host:port of the collector, for OTLP/gRPC exporters
+	Endpoint string
+
+	server   *httptest.Server
+	mu       sync.Mutex
+	spans    []*Span
+	metrics  []*Metric
+	requests []*Request
+}
+
+// Request is an export request received by the Collector.
+type Request struct {
+	// Path is TracesPath or MetricsPath, or TraceServiceMethod or MetricsServiceMethod for gRPC
+	Path string
+	// Header holds the request headers, or the metadata of gRPC requests
+	Header http.Header
+	// GRPC is true if the request was received through OTLP/gRPC
+	GRPC bool
+	// Protobuf is true if the payload used the protobuf encoding (always, with gRPC), false for JSON
+	Protobuf bool
+	// Gzip is true if the payload was gzip compressed
+	Gzip bool
+	// TLS is true if the request was received over TLS
+	TLS bool
+}
+
+// Span is an exported span.
+type Span struct {
+	TraceID       string
+	SpanID        string
+	ParentSpanID  string
+	Name          string
+	Kind          int
+	Start         time.Time
+	End           time.Time
+	Attributes    map[string]interface{}
+	StatusCode    int
+	StatusMessage string
+	// Scope is the name of the instrumentation scope
+	Scope string
+	// Resource holds the attributes of the resource that emitted the span
+	Resource map[string]interface{}
+}
+
+// Metric is an exported metric.
+type Metric struct {
+	Name        string
+	Description string
+	Unit        string
+	// Kind is Gauge, Sum, Histogram, ExponentialHistogram or Summary
+	Kind string
+	// Temporality is the aggregation temporality of sums and histograms: 1 for delta, 2 for cumulative
+	Temporality int
+	// Monotonic is true for monotonic sums
+	Monotonic bool
+	Points    []*DataPoint
+	// Scope is the name of the instrumentation scope
+	Scope string
+	// Resource holds the attributes of the resource that emitted the metric
+	Resource map[string]interface{}
+}
+
+// DataPoint is a data point of a Metric.
+type DataPoint struct {
+	Attributes map[string]interface{}
+	Start      time.Time
+	Time       time.Time
+	// Value is the value of gauge and sum points - integer values are converted
+	Value float64
+	// Count and Sum describe histograms and summaries
+	Count uint64
+	Sum   float64
+	// Bounds and BucketCounts describe explicit bucket histograms: BucketCounts has one more entry than Bounds
+	Bounds       []float64
+	BucketCounts []uint64
+}
+
+// NewCollector starts a plain HTTP collector, closed when the test completes.
+func NewCollector(tb testing.TB) *Collector {
+	tb.Helper()
+
+	col := &Collector{}
+	col.server = httptest.NewServer(h2c.NewHandler(col, &http2.Server{}))
+	col.URL = col.server.URL
+	col.Endpoint = col.server.Listener.Addr().String()
+
+	tb.Cleanup(col.server.Close)
+
+	return col
+}
+
+// NewTLSCollector starts a collector over TLS, closed when the test completes. Exporters must trust Client (or
+// the certificate of its transport).
+func NewTLSCollector(tb testing.TB) *Collector {
+	tb.Helper()
+
+	col := &Collector{}
+	col.server = httptest.NewUnstartedServer(col)
+	col.server.EnableHTTP2 = true
+	col.server.StartTLS()
+	col.URL = col.server.URL
+	col.Endpoint = col.server.Listener.Addr().String()
+
+	tb.Cleanup(col.server.Close)
+
+	return col
+}
+
+// Client returns an HTTP client trusting the collector certificate, if it serves TLS.
+func (col *Collector) Client() *http.Client {
+	return col.server.Client()
+}
+
+// Certificate returns the certificate of the collector, if it serves TLS, so that exporters can be configured to
+// trust it.
+func (col *Collector) Certificate() *x509.Certificate {
+	return col.server.Certificate()
+}
+
+// ServeHTTP implements the OTLP/HTTP traces and metrics endpoints, and the OTLP/gRPC services.
+func (col *Collector) ServeHTTP(writer http.ResponseWriter, req *http.Request) {
+	if isGRPC(req) {
+		col.serveGRPC(writer, req)
+
+		return
+	}
+
+	if req.Method != http.MethodPost {
+		http.Error(writer, "method not allowed", http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	if req.URL.Path != TracesPath && req.URL.Path != MetricsPath {
+		http.NotFound(writer, req)
+
+		return
+	}
+
+	record := &Request{
+		Path:   req.URL.Path,
+		Header: req.Header.Clone(),
+		Gzip:   req.Header.Get("Content-Encoding") == "gzip",
+		TLS:    req.TLS != nil,
+	}
+
+	contentType, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+
+	switch contentType {
+	case jsonContentType:
+	case protobufContentType:
+		record.Protobuf = true
+	default:
+		http.Error(writer, "unsupported content type", http.StatusUnsupportedMediaType)
+
+		return
+	}
+
+	payload, err := readPayload(req.Body, record.Gzip)
+	if err == nil {
+		err = col.receive(record, payload)
+	}
+
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	// Export responses are empty, which both encodings agree on
+	if record.Protobuf {
+		writer.Header().Set("Content-Type", protobufContentType)
+
+		return
+	}
+
+	writer.Header().Set("Content-Type", jsonContentType)
+	_, _ = writer.Write([]byte("{}"))
+}
+
+// receive decodes and records a payload, along with the request that carried it.
+func (col *Collector) receive(record *Request, payload []byte) error {
+	traces := record.Path == TracesPath || record.Path == TraceServiceMethod
+
+	var (
+		spans   []*Span
+		metrics []*Metric
+		err     error
+	)
+
+	switch {
+	case traces && record.Protobuf:
+		spans, err = protoTraces(payload)
+	case traces:
+		spans, err = jsonTraces(payload)
+	case record.Protobuf:
+		metrics, err = protoMetrics(payload)
+	default:
+		metrics, err = jsonMetrics(payload)
+	}
+
+	if err != nil {
+		return fmt.Errorf("invalid OTLP payload: %w", err)
+	}
+
+	col.mu.Lock()
+	defer col.mu.Unlock()
+
+	col.spans = append(col.spans, spans...)
+	col.metrics = append(col.metrics, metrics...)
+	col.requests = append(col.requests, record)
+
+	return nil
+}
+
+func readPayload(body io.Reader, compressed bool) ([]byte, error) {
+	if compressed {
+		reader, err := gzip.NewReader(body)
+		if err != nil {
+			return nil, err
+		}
+
+		defer reader.Close()
+
+		body = reader
+	}
+
+	return io.ReadAll(body)
+}
+
+// Spans returns the spans received so far.
+func (col *Collector) Spans() []*Span {
+	col.mu.Lock()
+	defer col.mu.Unlock()
+
+	return append([]*Span{}, col.spans...)
+}
+
+// Metrics returns the metrics received so far.
+func (col *Collector) Metrics() []*Metric {
+	col.mu.Lock()
+	defer col.mu.Unlock()
+
+	return append([]*Metric{}, col.metrics...)
+}
+
+// Requests returns the export requests received so far.
+func (col *Collector) Requests() []*Request {
+	col.mu.Lock()
+	defer col.mu.Unlock()
+
+	return append([]*Request{}, col.requests...)
+}
+
+// WaitForSpans waits until at least count spans have been received, failing the test after timeout.
+func (col *Collector) WaitForSpans(tb testing.TB, count int, timeout time.Duration) []*Span {
+	tb.Helper()
+
+	deadline := time.Now().Add(timeout)
+
+	for {
+		spans := col.Spans()
+		if len(spans) >= count {
+			return spans
+		}
+
+		if time.Now().After(deadline) {
+			tb.Fatalf("collector received %d spans, expected %d within %s", len(spans), count, timeout)
+		}
+
+		time.Sleep(collectorPoll)
+	}
+}
+
+// WaitForMetrics waits until at least count metrics have been received, failing the test after timeout.
+func (col *Collector) WaitForMetrics(tb testing.TB, count int, timeout time.Duration) []*Metric {
+	tb.Helper()
+
+	deadline := time.Now().Add(timeout)
+
+	for {
+		metrics := col.Metrics()
+		if len(metrics) >= count {
+			return metrics
+		}
+
+		if time.Now().After(deadline) {
+			tb.Fatalf("collector received %d metrics, expected %d within %s", len(metrics), count, timeout)
+		}
+
+		time.Sleep(collectorPoll)
+	}
+}
+
+// RequireSpan returns the first span named name, failing the test if there is none.
+func (col *Collector) RequireSpan(tb testing.TB, name string) *Span {
+	tb.Helper()
+
+	for _, span := range col.Spans() {
+		if span.Name == name {
+			return span
+		}
+	}
+
+	tb.Fatalf("collector did not receive any span named %q", name)
+
+	return nil
+}
+
+// RequireMetric returns the last metric named name, failing the test if there is none. With cumulative
+// temporality, it holds the latest values.
+func (col *Collector) RequireMetric(tb testing.TB, name string) *Metric {
+	tb.Helper()
+
+	metrics := col.Metrics()
+	for i := len(metrics) - 1; i >= 0; i-- {
+		if metrics[i].Name == name {
+			return metrics[i]
+		}
+	}
+
+	tb.Fatalf("collector did not receive any metric named %q", name)
+
+	return nil
+}
+
+// RequireHeader fails the test unless every request received so far carries header with value.
+func (col *Collector) RequireHeader(tb testing.TB, header string, value string) {
+	tb.Helper()
+
+	requests := col.Requests()
+	if len(requests) == 0 {
+		tb.Fatalf("collector did not receive any request")
+	}
+
+	for _, req := range requests {
+		if got := req.Header.Get(header); got != value {
+			tb.Fatalf("request to %s has %s %q, expected %q", req.Path, header, got, value)
+		}
+	}
+}
+
+func isGRPC(req *http.Request) bool {
+	return req.ProtoMajor == 2 && strings.HasPrefix(req.Header.Get("Content-Type"), "application/grpc")
+}
@@ -0,0 +1,96 @@
+package telemetrytest
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// gRPC status codes.
+const (
+	grpcOK              = 0
+	grpcInvalidArgument = 3
+	grpcUnimplemented   = 12
+)
+
+// grpcPrefixSize is the size of the prefix of gRPC messages: a compression flag, and the message length.
+const grpcPrefixSize = 5
+
+// serveGRPC implements the Export method of the OTLP/gRPC trace and metrics services, without depending on gRPC:
+// requests carry a single length-prefixed message, which is gzip compressed if flagged so.
+func (col *Collector) serveGRPC(writer http.ResponseWriter, req *http.Request) {
+	writer.Header().Set("Content-Type", "application/grpc")
+
+	if req.URL.Path != TraceServiceMethod && req.URL.Path != MetricsServiceMethod {
+		grpcStatus(writer, grpcUnimplemented, "unknown method "+req.URL.Path)
+
+		return
+	}
+
+	record := &Request{
+		Path:     req.URL.Path,
+		Header:   req.Header.Clone(),
+		GRPC:     true,
+		Protobuf: true,
+		TLS:      req.TLS != nil,
+	}
+
+	payload, compressed, err := readGRPCMessage(req.Body)
+	if err == nil && compressed {
+		if encoding := req.Header.Get("Grpc-Encoding"); encoding != "gzip" {
+			grpcStatus(writer, grpcUnimplemented, "unsupported compression "+encoding)
+
+			return
+		}
+
+		record.Gzip = true
+		payload, err = readPayload(bytes.NewReader(payload), true)
+	}
+
+	if err == nil {
+		err = col.receive(record, payload)
+	}
+
+	if err != nil {
+		grpcStatus(writer, grpcInvalidArgument, err.Error())
+
+		return
+	}
+
+	// The response message is empty, and the status follows in trailers
+	writer.WriteHeader(http.StatusOK)
+	_, _ = writer.Write(make([]byte, grpcPrefixSize))
+	writer.Header().Set(http.TrailerPrefix+"Grpc-Status", strconv.Itoa(grpcOK))
+}
+
+// grpcStatus answers with a status alone, which gRPC allows in headers.
+func grpcStatus(writer http.ResponseWriter, code int, message string) {
+	writer.Header().Set("Grpc-Status", strconv.Itoa(code))
+	writer.Header().Set("Grpc-Message", url.PathEscape(message))
+	writer.WriteHeader(http.StatusOK)
+}
+
+// readGRPCMessage reads a length-prefixed message, and tells whether it is compressed.
+func readGRPCMessage(body io.Reader) ([]byte, bool, error) {
+	prefix := make([]byte, grpcPrefixSize)
+	if _, err := io.ReadFull(body, prefix); err != nil {
+		return nil, false, fmt.Errorf("invalid gRPC message: %w", err)
+	}
+
+	size := int64(binary.BigEndian.Uint32(prefix[1:]))
+
+	payload, err := io.ReadAll(io.LimitReader(body, size))
+	if err == nil && int64(len(payload)) < size {
+		err = io.ErrUnexpectedEOF
+	}
+
+	if err != nil {
+		return nil, false, fmt.Errorf("invalid gRPC message: %w", err)
+	}
+
+	return payload, prefix[0] == 1, nil
+}
@@ -0,0 +1,65 @@
+// Package telemetrytest provides helpers to test instrumented code.
+package telemetrytest
+
+import (
+	"context"
+	"encoding/binary"
+	"sync"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// IDGenerator generates sequential, deterministic trace and span IDs, so that tests can assert on them.
+// The first trace ID is 00000000000000000000000000000001, and the first span ID 0000000000000001.
+type IDGenerator struct {
+	mu    sync.Mutex
+	trace uint64
+	span  uint64
+}
+
+// NewIDGenerator returns a sequential IDGenerator.
+func NewIDGenerator() *IDGenerator {
+	return &IDGenerator{}
+}
+
+// NewIDs returns the next trace ID and span ID.
+func (gen *IDGenerator) NewIDs(_ context.Context) (trace.TraceID, trace.SpanID) {
+	gen.mu.Lock()
+	defer gen.mu.Unlock()
+
+	gen.trace++
+	gen.span++
+
+	var traceID trace.TraceID
+
+	binary.BigEndian.PutUint64(traceID[8:], gen.trace)
+
+	return traceID, gen.spanID()
+}
+
+// NewSpanID returns the next span ID.
+func (gen *IDGenerator) NewSpanID(_ context.Context, _ trace.TraceID) trace.SpanID {
+	gen.mu.Lock()
+	defer gen.mu.Unlock()
+
+	gen.span++
+
+	return gen.spanID()
+}
+
+// Reset restarts both sequences.
+func (gen *IDGenerator) Reset() {
+	gen.mu.Lock()
+	defer gen.mu.Unlock()
+
+	gen.trace = 0
+	gen.span = 0
+}
+
+func (gen *IDGenerator) spanID() trace.SpanID {
+	var spanID trace.SpanID
+
+	binary.BigEndian.PutUint64(spanID[:], gen.span)
+
+	return spanID
+}
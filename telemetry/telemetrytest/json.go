@@ -0,0 +1,292 @@
+package telemetrytest
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"time"
+)
+
+// OTLP JSON payloads, limited to what the collector records.
+type (
+	otlpKeyValue struct {
+		Key   string          `json:"key"`
+		Value json.RawMessage `json:"value"`
+	}
+
+	otlpResource struct {
+		Attributes []otlpKeyValue `json:"attributes"`
+	}
+
+	otlpScope struct {
+		Name string `json:"name"`
+	}
+
+	otlpSpan struct {
+		TraceID      string         `json:"traceId"`
+		SpanID       string         `json:"spanId"`
+		ParentSpanID string         `json:"parentSpanId"`
+		Name         string         `json:"name"`
+		Kind         int            `json:"kind"`
+		Start        json.Number    `json:"startTimeUnixNano"`
+		End          json.Number    `json:"endTimeUnixNano"`
+		Attributes   []otlpKeyValue `json:"attributes"`
+		Status       struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		} `json:"status"`
+	}
+
+	otlpTraces struct {
+		ResourceSpans []struct {
+			Resource   otlpResource `json:"resource"`
+			ScopeSpans []struct {
+				Scope otlpScope   `json:"scope"`
+				Spans []*otlpSpan `json:"spans"`
+			} `json:"scopeSpans"`
+		} `json:"resourceSpans"`
+	}
+
+	// otlpDataPoint covers number, histogram and summary data points - 64 bits integers are strings
+	otlpDataPoint struct {
+		Attributes     []otlpKeyValue `json:"attributes"`
+		Start          json.Number    `json:"startTimeUnixNano"`
+		Time           json.Number    `json:"timeUnixNano"`
+		AsDouble       *json.Number   `json:"asDouble"`
+		AsInt          *json.Number   `json:"asInt"`
+		Count          json.Number    `json:"count"`
+		Sum            json.Number    `json:"sum"`
+		BucketCounts   []json.Number  `json:"bucketCounts"`
+		ExplicitBounds []json.Number  `json:"explicitBounds"`
+	}
+
+	otlpData struct {
+		DataPoints  []*otlpDataPoint `json:"dataPoints"`
+		Temporality int              `json:"aggregationTemporality"`
+		Monotonic   bool             `json:"isMonotonic"`
+	}
+
+	otlpMetric struct {
+		Name                 string    `json:"name"`
+		Description          string    `json:"description"`
+		Unit                 string    `json:"unit"`
+		Gauge                *otlpData `json:"gauge"`
+		Sum                  *otlpData `json:"sum"`
+		Histogram            *otlpData `json:"histogram"`
+		ExponentialHistogram *otlpData `json:"exponentialHistogram"`
+		Summary              *otlpData `json:"summary"`
+	}
+
+	otlpMetrics struct {
+		ResourceMetrics []struct {
+			Resource     otlpResource `json:"resource"`
+			ScopeMetrics []struct {
+				Scope   otlpScope     `json:"scope"`
+				Metrics []*otlpMetric `json:"metrics"`
+			} `json:"scopeMetrics"`
+		} `json:"resourceMetrics"`
+	}
+)
+
+func jsonTraces(payload []byte) ([]*Span, error) {
+	var traces otlpTraces
+
+	if err := decodeJSON(payload, &traces); err != nil {
+		return nil, err
+	}
+
+	var spans []*Span
+
+	for _, res := range traces.ResourceSpans {
+		resource := attributes(res.Resource.Attributes)
+
+		for _, scope := range res.ScopeSpans {
+			for _, span := range scope.Spans {
+				spans = append(spans, &Span{
+					TraceID:       span.TraceID,
+					SpanID:        span.SpanID,
+					ParentSpanID:  span.ParentSpanID,
+					Name:          span.Name,
+					Kind:          span.Kind,
+					Start:         unixNano(span.Start),
+					End:           unixNano(span.End),
+					Attributes:    attributes(span.Attributes),
+					StatusCode:    span.Status.Code,
+					StatusMessage: span.Status.Message,
+					Scope:         scope.Scope.Name,
+					Resource:      resource,
+				})
+			}
+		}
+	}
+
+	return spans, nil
+}
+
+func jsonMetrics(payload []byte) ([]*Metric, error) {
+	var exported otlpMetrics
+
+	if err := decodeJSON(payload, &exported); err != nil {
+		return nil, err
+	}
+
+	var metrics []*Metric
+
+	for _, res := range exported.ResourceMetrics {
+		resource := attributes(res.Resource.Attributes)
+
+		for _, scope := range res.ScopeMetrics {
+			for _, metric := range scope.Metrics {
+				metrics = append(metrics, jsonMetric(metric, scope.Scope.Name, resource))
+			}
+		}
+	}
+
+	return metrics, nil
+}
+
+func jsonMetric(exported *otlpMetric, scope string, resource map[string]interface{}) *Metric {
+	metric := &Metric{
+		Name:        exported.Name,
+		Description: exported.Description,
+		Unit:        exported.Unit,
+		Scope:       scope,
+		Resource:    resource,
+	}
+
+	var data *otlpData
+
+	for kind, candidate := range map[string]*otlpData{
+		Gauge:                exported.Gauge,
+		Sum:                  exported.Sum,
+		Histogram:            exported.Histogram,
+		ExponentialHistogram: exported.ExponentialHistogram,
+		Summary:              exported.Summary,
+	} {
+		if candidate != nil {
+			metric.Kind, data = kind, candidate
+		}
+	}
+
+	if data == nil {
+		return metric
+	}
+
+	metric.Temporality = data.Temporality
+	metric.Monotonic = data.Monotonic
+
+	for _, point := range data.DataPoints {
+		converted := &DataPoint{
+			Attributes: attributes(point.Attributes),
+			Start:      unixNano(point.Start),
+			Time:       unixNano(point.Time),
+			Count:      jsonUint(point.Count),
+			Sum:        jsonFloat(point.Sum),
+		}
+
+		switch {
+		case point.AsDouble != nil:
+			converted.Value = jsonFloat(*point.AsDouble)
+		case point.AsInt != nil:
+			converted.Value = jsonFloat(*point.AsInt)
+		}
+
+		for _, count := range point.BucketCounts {
+			converted.BucketCounts = append(converted.BucketCounts, jsonUint(count))
+		}
+
+		for _, bound := range point.ExplicitBounds {
+			converted.Bounds = append(converted.Bounds, jsonFloat(bound))
+		}
+
+		metric.Points = append(metric.Points, converted)
+	}
+
+	return metric
+}
+
+func decodeJSON(payload []byte, value interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(payload))
+	decoder.UseNumber()
+
+	return decoder.Decode(value)
+}
+
+// attributes decodes OTLP key values: strings, booleans, numbers (int64 values are int64, doubles float64), and
+// arrays and maps of those. Bytes are left base64 encoded.
+func attributes(kvs []otlpKeyValue) map[string]interface{} {
+	result := make(map[string]interface{}, len(kvs))
+
+	for _, kv := range kvs {
+		result[kv.Key] = anyValue(kv.Value)
+	}
+
+	return result
+}
+
+func anyValue(raw json.RawMessage) interface{} {
+	var value struct {
+		String *string      `json:"stringValue"`
+		Bool   *bool        `json:"boolValue"`
+		Int    *json.Number `json:"intValue"`
+		Double *json.Number `json:"doubleValue"`
+		Bytes  *string      `json:"bytesValue"`
+		Array  *struct {
+			Values []json.RawMessage `json:"values"`
+		} `json:"arrayValue"`
+		Map *struct {
+			Values []otlpKeyValue `json:"values"`
+		} `json:"kvlistValue"`
+	}
+
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return nil
+	}
+
+	switch {
+	case value.String != nil:
+		return *value.String
+	case value.Bool != nil:
+		return *value.Bool
+	case value.Int != nil:
+		i, _ := strconv.ParseInt(value.Int.String(), 10, 64)
+
+		return i
+	case value.Double != nil:
+		return jsonFloat(*value.Double)
+	case value.Bytes != nil:
+		return *value.Bytes
+	case value.Array != nil:
+		values := make([]interface{}, len(value.Array.Values))
+		for i, item := range value.Array.Values {
+			values[i] = anyValue(item)
+		}
+
+		return values
+	case value.Map != nil:
+		return attributes(value.Map.Values)
+	}
+
+	return nil
+}
+
+func jsonFloat(value json.Number) float64 {
+	f, _ := value.Float64()
+
+	return f
+}
+
+func jsonUint(value json.Number) uint64 {
+	u, _ := strconv.ParseUint(value.String(), 10, 64)
+
+	return u
+}
+
+func unixNano(value json.Number) time.Time {
+	nanos, err := strconv.ParseInt(value.String(), 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+
+	return time.Unix(0, nanos)
+}
@@ -0,0 +1,412 @@
+package telemetrytest
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Protobuf wire types.
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+var errTruncated = errors.New("truncated protobuf message")
+
+// protoMetricKinds maps the fields of the Metric message holding data to the kind of metric.
+var protoMetricKinds = map[int]string{ //nolint:gochecknoglobals
+	5:  Gauge,
+	7:  Sum,
+	9:  Histogram,
+	10: ExponentialHistogram,
+	11: Summary,
+}
+
+// protoField is a field of a protobuf message: varint and fixed values are in num, length-delimited ones in raw.
+type protoField struct {
+	number int
+	wire   int
+	num    uint64
+	raw    []byte
+}
+
+func (field protoField) string() string {
+	return string(field.raw)
+}
+
+func (field protoField) double() float64 {
+	return math.Float64frombits(field.num)
+}
+
+func (field protoField) time() time.Time {
+	return time.Unix(0, int64(field.num))
+}
+
+// fixed64s returns the values of a repeated fixed64 or double field, packed or not.
+func (field protoField) fixed64s() []uint64 {
+	if field.wire != wireBytes {
+		return []uint64{field.num}
+	}
+
+	values := make([]uint64, 0, len(field.raw)/8)
+	for raw := field.raw; len(raw) >= 8; raw = raw[8:] {
+		values = append(values, binary.LittleEndian.Uint64(raw))
+	}
+
+	return values
+}
+
+// protoFields calls fn for each field of the protobuf message data, in order. The OTLP messages are decoded by hand,
+// as the generated code is not a dependency of this module.
+func protoFields(data []byte, fn func(protoField) error) error {
+	for len(data) > 0 {
+		key, read := binary.Uvarint(data)
+		if read <= 0 {
+			return errTruncated
+		}
+
+		data = data[read:]
+		field := protoField{number: int(key >> 3), wire: int(key & 7)}
+
+		switch field.wire {
+		case wireVarint:
+			field.num, read = binary.Uvarint(data)
+			if read <= 0 {
+				return errTruncated
+			}
+
+			data = data[read:]
+		case wireFixed64:
+			if len(data) < 8 {
+				return errTruncated
+			}
+
+			field.num = binary.LittleEndian.Uint64(data)
+			data = data[8:]
+		case wireFixed32:
+			if len(data) < 4 {
+				return errTruncated
+			}
+
+			field.num = uint64(binary.LittleEndian.Uint32(data))
+			data = data[4:]
+		case wireBytes:
+			size, read := binary.Uvarint(data)
+			if read <= 0 || uint64(len(data)-read) < size {
+				return errTruncated
+			}
+
+			field.raw = data[read : read+int(size)]
+			data = data[read+int(size):]
+		default:
+			return fmt.Errorf("unsupported protobuf wire type %d", field.wire)
+		}
+
+		if err := fn(field); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// protoResources walks the ResourceSpans or ResourceMetrics of an export request, calling fn with the resource
+// attributes, and the scope name and content (spans or metrics, field 2) of each scope.
+func protoResources(data []byte, fn func(resource map[string]interface{}, scope string, content protoField) error,
+) error {
+	return protoFields(data, func(resources protoField) error {
+		if resources.number != 1 {
+			return nil
+		}
+
+		resource := map[string]interface{}{}
+		scopes := [][]byte{}
+
+		err := protoFields(resources.raw, func(field protoField) error {
+			var err error
+
+			switch field.number {
+			case 1:
+				resource, err = protoResource(field.raw)
+			case 2:
+				scopes = append(scopes, field.raw)
+			}
+
+			return err
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, raw := range scopes {
+			var (
+				name     string
+				contents []protoField
+			)
+
+			err = protoFields(raw, func(field protoField) error {
+				switch field.number {
+				case 1:
+					return protoFields(field.raw, func(scope protoField) error {
+						if scope.number == 1 {
+							name = scope.string()
+						}
+
+						return nil
+					})
+				case 2:
+					contents = append(contents, field)
+				}
+
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+
+			for _, content := range contents {
+				if err = fn(resource, name, content); err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	})
+}
+
+func protoResource(data []byte) (map[string]interface{}, error) {
+	resource := map[string]interface{}{}
+
+	err := protoFields(data, func(field protoField) error {
+		if field.number != 1 {
+			return nil
+		}
+
+		return protoKeyValue(field.raw, resource)
+	})
+
+	return resource, err
+}
+
+func protoTraces(data []byte) ([]*Span, error) {
+	var spans []*Span
+
+	err := protoResources(data, func(resource map[string]interface{}, scope string, content protoField) error {
+		span := &Span{Scope: scope, Resource: resource, Attributes: map[string]interface{}{}}
+
+		err := protoFields(content.raw, func(field protoField) error {
+			switch field.number {
+			case 1:
+				span.TraceID = hex.EncodeToString(field.raw)
+			case 2:
+				span.SpanID = hex.EncodeToString(field.raw)
+			case 4:
+				span.ParentSpanID = hex.EncodeToString(field.raw)
+			case 5:
+				span.Name = field.string()
+			case 6:
+				span.Kind = int(field.num)
+			case 7:
+				span.Start = field.time()
+			case 8:
+				span.End = field.time()
+			case 9:
+				return protoKeyValue(field.raw, span.Attributes)
+			case 15:
+				return protoFields(field.raw, func(status protoField) error {
+					switch status.number {
+					case 2:
+						span.StatusMessage = status.string()
+					case 3:
+						span.StatusCode = int(status.num)
+					}
+
+					return nil
+				})
+			}
+
+			return nil
+		})
+
+		spans = append(spans, span)
+
+		return err
+	})
+
+	return spans, err
+}
+
+func protoMetrics(data []byte) ([]*Metric, error) {
+	var metrics []*Metric
+
+	err := protoResources(data, func(resource map[string]interface{}, scope string, content protoField) error {
+		metric := &Metric{Scope: scope, Resource: resource}
+
+		err := protoFields(content.raw, func(field protoField) error {
+			switch field.number {
+			case 1:
+				metric.Name = field.string()
+			case 2:
+				metric.Description = field.string()
+			case 3:
+				metric.Unit = field.string()
+			case 5, 7, 9, 10, 11:
+				metric.Kind = protoMetricKinds[field.number]
+
+				return protoMetricData(field.raw, metric)
+			}
+
+			return nil
+		})
+
+		metrics = append(metrics, metric)
+
+		return err
+	})
+
+	return metrics, err
+}
+
+// protoMetricData decodes a Gauge, Sum, Histogram, ExponentialHistogram or Summary message, once Kind is known.
+func protoMetricData(data []byte, metric *Metric) error {
+	return protoFields(data, func(field protoField) error {
+		switch field.number {
+		case 1:
+			point, err := protoDataPoint(field.raw, metric.Kind)
+			if err != nil {
+				return err
+			}
+
+			metric.Points = append(metric.Points, point)
+		case 2:
+			metric.Temporality = int(field.num)
+		case 3:
+			metric.Monotonic = field.num != 0
+		}
+
+		return nil
+	})
+}
+
+// protoDataPoint decodes a NumberDataPoint, HistogramDataPoint, ExponentialHistogramDataPoint or
+// SummaryDataPoint. They agree on times, and on count and sum, but not on where attributes go.
+func protoDataPoint(data []byte, kind string) (*DataPoint, error) {
+	point := &DataPoint{Attributes: map[string]interface{}{}}
+	number := kind == Gauge || kind == Sum
+
+	attributesField := 7
+
+	switch kind {
+	case Histogram:
+		attributesField = 9
+	case ExponentialHistogram:
+		attributesField = 1
+	}
+
+	err := protoFields(data, func(field protoField) error {
+		switch {
+		case field.number == attributesField:
+			return protoKeyValue(field.raw, point.Attributes)
+		case field.number == 2:
+			point.Start = field.time()
+		case field.number == 3:
+			point.Time = field.time()
+		case field.number == 4 && number:
+			point.Value = field.double()
+		case field.number == 6 && number:
+			point.Value = float64(int64(field.num))
+		case field.number == 4:
+			point.Count = field.num
+		case field.number == 5:
+			point.Sum = field.double()
+		case field.number == 6 && kind == Histogram:
+			point.BucketCounts = append(point.BucketCounts, field.fixed64s()...)
+		case field.number == 7 && kind == Histogram:
+			for _, bound := range field.fixed64s() {
+				point.Bounds = append(point.Bounds, math.Float64frombits(bound))
+			}
+		}
+
+		return nil
+	})
+
+	return point, err
+}
+
+// protoKeyValue decodes a KeyValue into attrs.
+func protoKeyValue(data []byte, attrs map[string]interface{}) error {
+	var (
+		key   string
+		value interface{}
+	)
+
+	err := protoFields(data, func(field protoField) error {
+		var err error
+
+		switch field.number {
+		case 1:
+			key = field.string()
+		case 2:
+			value, err = protoAnyValue(field.raw)
+		}
+
+		return err
+	})
+
+	attrs[key] = value
+
+	return err
+}
+
+// protoAnyValue decodes an AnyValue like anyValue does its JSON form.
+func protoAnyValue(data []byte) (interface{}, error) {
+	var value interface{}
+
+	err := protoFields(data, func(field protoField) error {
+		switch field.number {
+		case 1:
+			value = field.string()
+		case 2:
+			value = field.num != 0
+		case 3:
+			value = int64(field.num)
+		case 4:
+			value = field.double()
+		case 5:
+			values := []interface{}{}
+
+			err := protoFields(field.raw, func(item protoField) error {
+				decoded, err := protoAnyValue(item.raw)
+				values = append(values, decoded)
+
+				return err
+			})
+			value = values
+
+			return err
+		case 6:
+			values := map[string]interface{}{}
+
+			err := protoFields(field.raw, func(item protoField) error {
+				return protoKeyValue(item.raw, values)
+			})
+			value = values
+
+			return err
+		case 7:
+			value = base64.StdEncoding.EncodeToString(field.raw)
+		}
+
+		return nil
+	})
+
+	return value, err
+}
@@ -0,0 +1,62 @@
+package telemetry
+
+import (
+	"sync"
+
+	"go.codecomet.dev/core/version"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	coreModule            = "go.codecomet.dev/core"
+	coreVersionAttribute  = "codecomet.core.version"
+	instrumentationPrefix = coreModule + "/"
+)
+
+var tracers sync.Map //nolint:gochecknoglobals
+
+type tracerScope struct {
+	name    string
+	version string
+}
+
+// Tracer returns a (cached) tracer for the given instrumentation scope.
+// Names are expected to be the import path of the instrumented package - short names (eg: "exec") are considered
+// to be go-core packages and are prefixed accordingly.
+// The go-core version is recorded as an instrumentation scope attribute.
+// Tracers stop creating spans while their module is disabled (see SetModuleEnabled).
+func Tracer(instrumentationName string, instrumentationVersion string) trace.Tracer {
+	return scopedTracer(scopeName(instrumentationName), instrumentationVersion)
+}
+
+// scopedTracer returns a (cached) tracer for the instrumentation scope named instrumentationName, unprefixed.
+func scopedTracer(instrumentationName string, instrumentationVersion string) trace.Tracer {
+	scope := tracerScope{name: instrumentationName, version: instrumentationVersion}
+
+	if tracer, ok := tracers.Load(scope); ok {
+		return tracer.(trace.Tracer) //nolint:forcetypeassert
+	}
+
+	// Tracers obtained from the global provider before Init are delegating, and later ones are dropped by Init
+	tracer, _ := tracers.LoadOrStore(scope, &toggledTracer{
+		Tracer: otel.GetTracerProvider().Tracer(
+			instrumentationName,
+			trace.WithInstrumentationVersion(instrumentationVersion),
+			trace.WithInstrumentationAttributes(attribute.String(coreVersionAttribute, version.Module(coreModule))),
+		),
+		module: instrumentationName,
+	})
+
+	return tracer.(trace.Tracer) //nolint:forcetypeassert
+}
+
+// resetTracers drops cached tracers, bound to the provider Init replaces.
+func resetTracers() {
+	tracers.Range(func(key, _ interface{}) bool {
+		tracers.Delete(key)
+
+		return true
+	})
+}
@@ -0,0 +1,93 @@
+package tests_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.codecomet.dev/core/log/audit"
+)
+
+func TestAuditChain(t *testing.T) {
+	location := filepath.Join(t.TempDir(), "audit.log")
+
+	logger, err := audit.Open(location)
+	if err != nil {
+		t.Fatalf("unexpected failure! %s", err)
+	}
+
+	for _, action := range []string{"login", "read", "logout"} {
+		if err = logger.Record("alice", action, "vault", "success"); err != nil {
+			t.Fatalf("unexpected failure! %s", err)
+		}
+	}
+
+	err = logger.Record("alice", "read", "", "success")
+	if err == nil || !errors.Is(err, audit.ErrInvalidRecord) {
+		t.Fatalf("should have returned audit.ErrInvalidRecord: %s", err)
+	}
+
+	if err = logger.Close(); err != nil {
+		t.Fatalf("unexpected failure! %s", err)
+	}
+
+	head, err := audit.Verify(location)
+	if err != nil || head == nil || head.Seq != 2 {
+		t.Fatalf("should have verified a chain of 3 records: %v %s", head, err)
+	}
+
+	// Reopening continues the chain
+	logger, err = audit.Open(location)
+	if err != nil {
+		t.Fatalf("unexpected failure! %s", err)
+	}
+
+	if err = logger.Record("bob", "login", "vault", "denied"); err != nil {
+		t.Fatalf("unexpected failure! %s", err)
+	}
+
+	_ = logger.Close()
+
+	if head, err = audit.Verify(location); err != nil || head.Seq != 3 {
+		t.Fatalf("should have verified a chain of 4 records: %v %s", head, err)
+	}
+}
+
+func TestAuditTampered(t *testing.T) {
+	location := filepath.Join(t.TempDir(), "audit.log")
+
+	logger, err := audit.Open(location)
+	if err != nil {
+		t.Fatalf("unexpected failure! %s", err)
+	}
+
+	_ = logger.Record("alice", "login", "vault", "success")
+	_ = logger.Record("alice", "read", "vault", "success")
+	_ = logger.Close()
+
+	data, _ := os.ReadFile(location)
+
+	// Edit
+	_ = os.WriteFile(location, bytes.Replace(data, []byte("alice"), []byte("mallory"), 1), 0o600)
+
+	if _, err = audit.Verify(location); err == nil || !errors.Is(err, audit.ErrTampered) {
+		t.Fatalf("should have returned audit.ErrTampered for an edit: %s", err)
+	}
+
+	// Removal of the first record
+	lines := bytes.SplitAfter(data, []byte("\n"))
+	_ = os.WriteFile(location, lines[1], 0o600)
+
+	if _, err = audit.Verify(location); err == nil || !errors.Is(err, audit.ErrTampered) {
+		t.Fatalf("should have returned audit.ErrTampered for a removal: %s", err)
+	}
+
+	// Truncation in the middle of a record
+	_ = os.WriteFile(location, data[:len(data)-10], 0o600)
+
+	if _, err = audit.Verify(location); err == nil || !errors.Is(err, audit.ErrTampered) {
+		t.Fatalf("should have returned audit.ErrTampered for a truncation: %s", err)
+	}
+}
@@ -0,0 +1,61 @@
+package tests_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.codecomet.dev/core/config"
+)
+
+const generateSeeds = 20
+
+func TestConfigGeneratedLoad(t *testing.T) {
+	dir := t.TempDir()
+
+	for seed := int64(0); seed < generateSeeds; seed++ {
+		conf := config.New(dir, "generated.json")
+		config.NewGenerator(seed).Fill(conf)
+		// Do not mess with the process umask
+		conf.Umask = 0o022
+
+		data, err := json.Marshal(conf)
+		if err != nil {
+			t.Fatalf("seed %d: failed marshalling generated config: %s", seed, err)
+		}
+
+		if err = os.WriteFile(filepath.Join(dir, "generated.json"), data, 0o600); err != nil {
+			t.Fatalf("unexpected failure! %s", err)
+		}
+
+		if err = config.Load(config.New(dir, "generated.json")); err != nil {
+			t.Fatalf("seed %d: generated config should load: %s", seed, err)
+		}
+	}
+}
+
+func TestConfigMutationsDoNotPanic(t *testing.T) {
+	dir := t.TempDir()
+
+	for seed := int64(0); seed < generateSeeds; seed++ {
+		gen := config.NewGenerator(seed)
+		conf := config.New(dir, "mutated.json")
+		gen.Fill(conf)
+		conf.Umask = 0o022
+
+		mutations, err := gen.Mutations(conf)
+		if err != nil {
+			t.Fatalf("seed %d: failed generating mutations: %s", seed, err)
+		}
+
+		for _, mutation := range mutations {
+			if err = os.WriteFile(filepath.Join(dir, "mutated.json"), mutation.Data, 0o600); err != nil {
+				t.Fatalf("unexpected failure! %s", err)
+			}
+
+			// Invalid configs may or may not be rejected, but must not crash
+			_ = config.Load(config.New(dir, "mutated.json"))
+		}
+	}
+}
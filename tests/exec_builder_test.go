@@ -0,0 +1,65 @@
+package tests_test
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.codecomet.dev/core/exec"
+)
+
+func TestCommandBuilder(t *testing.T) {
+	dir := t.TempDir()
+
+	var out bytes.Buffer
+
+	cmd := exec.New("sh", "").Command(context.Background(), "-c", `echo "$BUILDER_VALUE"; pwd; echo oops >&2`).
+		Dir(dir).
+		Env("BUILDER_VALUE", "built").
+		Stdout(&out)
+
+	stdout, stderr, err := cmd.Start()
+	if err != nil {
+		t.Fatalf("unexpected failure! %s", err)
+	}
+
+	if stdout != nil {
+		t.Fatal("a redirected stdout should not have a pipe")
+	}
+
+	errOut := new(bytes.Buffer)
+	if _, err = errOut.ReadFrom(stderr); err != nil {
+		t.Fatalf("unexpected failure! %s", err)
+	}
+
+	if err = cmd.Wait(); err != nil {
+		t.Fatalf("unexpected failure! %s", err)
+	}
+
+	// The temporary directory may sit behind a symlink (eg: macOS)
+	resolved, _ := filepath.EvalSymlinks(dir)
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 || lines[0] != "built" || (lines[1] != dir && lines[1] != resolved) {
+		t.Fatalf("unexpected output: %q", out.String())
+	}
+
+	if errOut.String() != "oops\n" {
+		t.Fatalf("unexpected error output: %q", errOut.String())
+	}
+}
+
+func TestCommandBuilderOutput(t *testing.T) {
+	var out bytes.Buffer
+
+	stdout, _, err := exec.New("sh", "").Command(context.Background(), "-c", "echo both").Stdout(&out).Output()
+	if err != nil {
+		t.Fatalf("unexpected failure! %s", err)
+	}
+
+	if stdout.String() != "both\n" || out.String() != "both\n" {
+		t.Fatalf("output should be both captured and redirected, got %q and %q", stdout.String(), out.String())
+	}
+}
@@ -0,0 +1,100 @@
+package tests_test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go.codecomet.dev/core/exec"
+)
+
+// cachedCounter returns a commander counting its executions in a file, and a function running it.
+func cachedCounter(t *testing.T, ttl time.Duration) (*exec.Commander, func() (string, bool)) {
+	t.Helper()
+
+	counter := filepath.Join(t.TempDir(), "counter")
+
+	com := exec.New("/bin/sh", "")
+	com.NoReport = true
+	com.Cache = &exec.ResultCache{Dir: t.TempDir(), TTL: ttl, Env: []string{"CACHE_TEST_VALUE"}}
+
+	return com, func() (string, bool) {
+		t.Helper()
+
+		res, err := com.Run("-c", `echo run >> "$0"; wc -l < "$0"`, counter)
+		if err != nil {
+			t.Fatalf("unexpected failure! %s", err)
+		}
+
+		return strings.TrimSpace(string(res.Stdout)), res.Cached
+	}
+}
+
+func TestResultCacheHit(t *testing.T) {
+	_, run := cachedCounter(t, time.Minute)
+
+	if runs, cached := run(); runs != "1" || cached {
+		t.Fatalf("the first execution should run, got %s (cached: %t)", runs, cached)
+	}
+
+	if runs, cached := run(); runs != "1" || !cached {
+		t.Fatalf("the second execution should be served from cache, got %s (cached: %t)", runs, cached)
+	}
+}
+
+func TestResultCacheExpiry(t *testing.T) {
+	_, run := cachedCounter(t, 50*time.Millisecond)
+
+	run()
+	time.Sleep(100 * time.Millisecond)
+
+	if runs, cached := run(); runs != "2" || cached {
+		t.Fatalf("expired results should not be served, got %s (cached: %t)", runs, cached)
+	}
+}
+
+func TestResultCacheKey(t *testing.T) {
+	t.Setenv("CACHE_TEST_VALUE", "a")
+
+	com, run := cachedCounter(t, time.Minute)
+	run()
+
+	changes := []struct {
+		name   string
+		change func()
+	}{
+		{"env", func() { com.Env = map[string]string{"EXTRA": "1"} }},
+		{"inherited value", func() { t.Setenv("CACHE_TEST_VALUE", "b") }},
+		{"unset env", func() { com.UnsetEnv = []string{"CACHE_TEST_*"} }},
+		{"clean env", func() { com.CleanEnv = true }},
+		{"inherit env", func() { com.InheritEnv = []string{"PATH"} }},
+		{"dir", func() { com.Dir = t.TempDir() }},
+	}
+
+	for _, tc := range changes {
+		tc.change()
+
+		if _, cached := run(); cached {
+			t.Fatalf("%s: the key should have changed", tc.name)
+		}
+	}
+
+	// Not an input of the execution
+	com.Transcribe = true
+
+	if _, cached := run(); !cached {
+		t.Fatal("the result should be served from cache")
+	}
+
+	// Standard input is not part of the key: never cached
+	com.Stdin = strings.NewReader("input")
+
+	if _, cached := run(); cached {
+		t.Fatal("executions with stdin should not be served from cache")
+	}
+
+	if _, cached := run(); cached {
+		t.Fatal("executions with stdin should not be cached")
+	}
+}
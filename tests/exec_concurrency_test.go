@@ -0,0 +1,73 @@
+package tests_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"go.codecomet.dev/core/exec"
+)
+
+// Run with -race: a Commander is shared by goroutines, each preparing its own execution.
+func TestCommanderConcurrentUse(t *testing.T) {
+	const workers = 8
+
+	com := exec.New("/bin/sh", "")
+	com.Env = map[string]string{"SHARED": "shared"}
+	com.NoReport = true
+
+	var lines atomic.Int64
+
+	com.OnStdoutLine(func(string) {
+		lines.Add(1)
+	})
+
+	var wg sync.WaitGroup
+
+	for worker := 0; worker < workers; worker++ {
+		wg.Add(1)
+
+		go func(worker int) {
+			defer wg.Done()
+
+			script := fmt.Sprintf(`echo "$SHARED %d"`, worker)
+			expected := fmt.Sprintf("shared %d\n", worker)
+
+			stdout, _, err := com.Command(context.Background(), "-c", script).Env("WORKER", "1").Output()
+			if err != nil || stdout.String() != expected {
+				t.Errorf("Command: unexpected output %q (%v)", stdout.String(), err)
+			}
+
+			stdout, _, err = com.ExecAndComplete("-c", script)
+			if err != nil || stdout.String() != expected {
+				t.Errorf("ExecAndComplete: unexpected output %q (%v)", stdout.String(), err)
+			}
+
+			result, err := com.Run("-c", script)
+			if err != nil || string(result.Stdout) != expected {
+				t.Errorf("Run: unexpected result %+v (%v)", result, err)
+			}
+
+			// The shims operate on the last prepared command, which other goroutines replace: only their safety
+			// is checked
+			com.PreExec(strings.NewReader(""), "-c", script)
+
+			if sout, serr, err := com.ExecAndWait(); err == nil {
+				_, _ = io.Copy(io.Discard, sout)
+				_, _ = io.Copy(io.Discard, serr)
+			}
+
+			_ = com.Wait()
+		}(worker)
+	}
+
+	wg.Wait()
+
+	if lines.Load() < 3*workers {
+		t.Fatalf("line handlers should have seen every line, got %d", lines.Load())
+	}
+}
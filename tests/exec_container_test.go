@@ -0,0 +1,40 @@
+package tests_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.codecomet.dev/core/exec"
+)
+
+func TestContainerEnvStaysOffTheCommandLine(t *testing.T) {
+	// A fake runtime, printing its arguments, then the value it would forward
+	runtime := filepath.Join(t.TempDir(), "runtime")
+	if err := os.WriteFile(runtime, []byte("#!/bin/sh\necho \"$@\"\necho \"$CONTAINER_SECRET\"\n"), 0o700); err != nil {
+		t.Fatalf("unexpected failure! %s", err)
+	}
+
+	com := exec.NewInContainer("tool", &exec.Container{Runtime: runtime, Image: "image"})
+	com.Env = map[string]string{"CONTAINER_SECRET": "hunter2"}
+
+	stdout, _, err := com.Command(context.Background(), "arg").Output()
+	if err != nil {
+		t.Fatalf("unexpected failure! %s", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("unexpected output: %q", stdout.String())
+	}
+
+	if strings.Contains(lines[0], "hunter2") || !strings.Contains(lines[0], "--env CONTAINER_SECRET ") {
+		t.Fatalf("the variable should be forwarded by name only, got: %q", lines[0])
+	}
+
+	if lines[1] != "hunter2" {
+		t.Fatalf("the runtime should hold the value to forward, got: %q", lines[1])
+	}
+}
@@ -0,0 +1,130 @@
+package tests_test
+
+import (
+	"context"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+
+	"go.codecomet.dev/core/exec"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// envEntries returns the entries of env starting with prefix, checking that env is sorted by name and unique.
+func envEntries(t *testing.T, env []string, prefix string) []string {
+	t.Helper()
+
+	names := make([]string, 0, len(env))
+	entries := []string{}
+
+	for _, kv := range env {
+		name, _, _ := strings.Cut(kv, "=")
+		names = append(names, name)
+
+		if strings.HasPrefix(kv, prefix) {
+			entries = append(entries, kv)
+		}
+	}
+
+	if !sort.StringsAreSorted(names) {
+		t.Fatalf("the environment should be sorted by name: %v", names)
+	}
+
+	for i := 1; i < len(names); i++ {
+		if names[i] == names[i-1] {
+			t.Fatalf("the environment should not have duplicates: %s", names[i])
+		}
+	}
+
+	return entries
+}
+
+func envShell() *exec.Commander {
+	com := exec.New("/bin/sh", "")
+	com.NoReport = true
+
+	return com
+}
+
+func TestEffectiveEnvOverrides(t *testing.T) {
+	t.Setenv("ENV_TEST_B", "inherited")
+	t.Setenv("ENV_TEST_C", "a=b")
+
+	com := envShell()
+	com.Env = map[string]string{"ENV_TEST_B": "configured", "ENV_TEST_A": "configured"}
+
+	cmd := com.Command(context.Background(), "-c", "true").Env("ENV_TEST_A", "builder")
+
+	entries := envEntries(t, cmd.EffectiveEnv(), "ENV_TEST_")
+	if strings.Join(entries, " ") != "ENV_TEST_A=builder ENV_TEST_B=configured ENV_TEST_C=a=b" {
+		t.Fatalf("unexpected environment: %v", entries)
+	}
+}
+
+func TestEffectiveEnvUnset(t *testing.T) {
+	t.Setenv("ENV_TEST_DROP_1", "1")
+	t.Setenv("ENV_TEST_DROPPED", "1")
+	t.Setenv("ENV_TEST_EXACT", "1")
+	t.Setenv("ENV_TEST_EXACTLY", "1")
+
+	com := envShell()
+	com.UnsetEnv = []string{"ENV_TEST_DROP_*", "ENV_TEST_EXACT"}
+	// Configured variables are passed anyway
+	com.Env = map[string]string{"ENV_TEST_DROP_2": "configured"}
+
+	entries := envEntries(t, com.Command(context.Background(), "-c", "true").EffectiveEnv(), "ENV_TEST_")
+	if strings.Join(entries, " ") != "ENV_TEST_DROPPED=1 ENV_TEST_DROP_2=configured ENV_TEST_EXACTLY=1" {
+		t.Fatalf("unexpected environment: %v", entries)
+	}
+}
+
+func TestEffectiveEnvClean(t *testing.T) {
+	t.Setenv("ENV_TEST_KEEP_1", "1")
+	t.Setenv("ENV_TEST_OTHER", "1")
+
+	com := envShell()
+	com.CleanEnv = true
+	com.InheritEnv = []string{"ENV_TEST_KEEP_*", "PATH"}
+	com.Env = map[string]string{"ENV_TEST_CONFIGURED": "1"}
+
+	env := com.Command(context.Background(), "-c", "true").EffectiveEnv()
+
+	entries := envEntries(t, env, "")
+	if strings.Join(entries, " ") != "ENV_TEST_CONFIGURED=1 ENV_TEST_KEEP_1=1 PATH="+os.Getenv("PATH") {
+		t.Fatalf("unexpected environment: %v", entries)
+	}
+}
+
+func TestEffectiveEnvTraceContext(t *testing.T) {
+	// A stale context we inherited must be replaced, not duplicated
+	t.Setenv("TRACEPARENT", "00-00000000000000000000000000000001-0000000000000001-01")
+
+	traceID, _ := trace.TraceIDFromHex("0af7651916cd43dd8448eb211c80319c")
+	spanID, _ := trace.SpanIDFromHex("b7ad6b7169203331")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+	}))
+
+	cmd := envShell().Command(ctx, "-c", "true")
+
+	stdout, stderr, err := cmd.Start()
+	if err != nil {
+		t.Fatalf("unexpected failure! %s", err)
+	}
+
+	_, _ = io.Copy(io.Discard, stdout)
+	_, _ = io.Copy(io.Discard, stderr)
+
+	if err = cmd.Wait(); err != nil {
+		t.Fatalf("unexpected failure! %s", err)
+	}
+
+	entries := envEntries(t, cmd.EffectiveEnv(), "TRACEPARENT=")
+	if len(entries) != 1 || !strings.HasPrefix(entries[0], "TRACEPARENT=00-"+traceID.String()+"-") {
+		t.Fatalf("the trace context should be passed once, got %v", entries)
+	}
+}
@@ -0,0 +1,126 @@
+package tests_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"go.codecomet.dev/core/exec"
+)
+
+var errEnough = errors.New("enough")
+
+type jsonItem struct {
+	Name string `json:"name"`
+}
+
+func jsonShell() *exec.Commander {
+	com := exec.New("/bin/sh", "")
+	com.NoReport = true
+
+	return com
+}
+
+func TestRunJSONSlices(t *testing.T) {
+	for name, script := range map[string]string{
+		"array":  `echo '[{"name": "a"}, {"name": "b"}]'`,
+		"stream": `echo '{"name": "a"}'; echo '{"name": "b"}'`,
+	} {
+		var items []jsonItem
+
+		if err := jsonShell().RunJSON(context.Background(), &items, "-c", script); err != nil {
+			t.Fatalf("%s: unexpected failure! %s", name, err)
+		}
+
+		if len(items) != 2 || items[0].Name != "a" || items[1].Name != "b" {
+			t.Fatalf("%s: unexpected items: %+v", name, items)
+		}
+	}
+
+	var item jsonItem
+
+	if err := jsonShell().RunJSON(context.Background(), &item, "-c", `echo '{"name": "a"}'`); err != nil ||
+		item.Name != "a" {
+		t.Fatalf("unexpected result: %+v, %v", item, err)
+	}
+}
+
+func TestRunJSONTrailingData(t *testing.T) {
+	for name, script := range map[string]string{
+		"garbage": `echo '{"name": "a"} garbage'`,
+		"value":   `echo '{"name": "a"} {"name": "b"}'`,
+		"bracket": `echo '{"name": "a"}]'`,
+	} {
+		var item jsonItem
+
+		if err := jsonShell().RunJSON(context.Background(), &item, "-c", script); err == nil {
+			t.Fatalf("%s: trailing data should fail, got %+v", name, item)
+		}
+	}
+
+	// Streams only apply when the output does not start as an array
+	var items []jsonItem
+
+	if err := jsonShell().RunJSON(context.Background(), &items, "-c", `echo '[{"name": "a"}] {"name": "b"}'`); err == nil {
+		t.Fatalf("trailing data after an array should fail, got %+v", items)
+	}
+}
+
+func TestRunJSONStderr(t *testing.T) {
+	var item jsonItem
+
+	err := jsonShell().RunJSON(context.Background(), &item, "-c", `echo '{}'; echo "no such thing" >&2; exit 2`)
+
+	var stderrErr *exec.StderrError
+	if !errors.As(err, &stderrErr) || stderrErr.Stderr != "no such thing" {
+		t.Fatalf("the error should carry stderr, got %v", err)
+	}
+}
+
+func TestStreamJSON(t *testing.T) {
+	var names []string
+
+	err := jsonShell().StreamJSON(context.Background(), func(raw json.RawMessage) error {
+		var item jsonItem
+		if err := json.Unmarshal(raw, &item); err != nil {
+			return err
+		}
+
+		names = append(names, item.Name)
+
+		return nil
+	}, "-c", `echo '{"name": "a"}'; echo '{"name": "b"}'`)
+	if err != nil || len(names) != 2 || names[1] != "b" {
+		t.Fatalf("unexpected result: %v, %v", names, err)
+	}
+
+	err = jsonShell().StreamJSON(context.Background(), func(json.RawMessage) error {
+		return nil
+	}, "-c", `echo '{}'; echo "failed" >&2; exit 1`)
+
+	var stderrErr *exec.StderrError
+	if !errors.As(err, &stderrErr) || stderrErr.Stderr != "failed" {
+		t.Fatalf("the error should carry stderr, got %v", err)
+	}
+}
+
+func TestStreamJSONHandlerError(t *testing.T) {
+	start := time.Now()
+	calls := 0
+
+	// The command would run for a minute: the handler error must end it, children included
+	err := jsonShell().StreamJSON(context.Background(), func(json.RawMessage) error {
+		calls++
+
+		return errEnough
+	}, "-c", `echo '{}'; echo '{}'; sleep 60`)
+	if !errors.Is(err, errEnough) || calls != 1 {
+		t.Fatalf("the handler error should be returned after one call, got %v after %d", err, calls)
+	}
+
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Fatalf("the command should have been terminated, took %s", elapsed)
+	}
+}
@@ -0,0 +1,67 @@
+package tests_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.codecomet.dev/core/exec"
+)
+
+func TestPoolCollectsErrors(t *testing.T) {
+	sh := exec.New("/bin/sh", "")
+
+	results, err := exec.NewPool(2).
+		Add(sh, "-c", "exit 0").
+		Add(sh, "-c", "exit 3").
+		Add(sh, "-c", "exit 0").
+		Run(context.Background())
+
+	var poolErr *exec.PoolError
+	if !errors.As(err, &poolErr) {
+		t.Fatalf("should have returned a PoolError: %s", err)
+	}
+
+	if poolErr.Errors[0] != nil || poolErr.Errors[1] == nil || poolErr.Errors[2] != nil {
+		t.Fatalf("only the second execution should have failed: %v", poolErr.Errors)
+	}
+
+	if results[1].ExitCode != 3 || !results[2].Success() {
+		t.Fatalf("unexpected results: %d, %d", results[1].ExitCode, results[2].ExitCode)
+	}
+}
+
+func TestPoolFailFast(t *testing.T) {
+	sh := exec.New("/bin/sh", "")
+	pool := &exec.Pool{Limit: 2, FailFast: true}
+
+	started := time.Now()
+
+	results, err := pool.
+		Add(sh, "-c", "sleep 10").
+		Add(sh, "-c", "exit 1").
+		Add(sh, "-c", "exit 0").
+		Run(context.Background())
+
+	if err == nil || time.Since(started) > 5*time.Second {
+		t.Fatalf("should have failed fast: %s", err)
+	}
+
+	if results[2] != nil {
+		t.Fatalf("third execution should not have started")
+	}
+}
+
+func TestPoolCanceled(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	_, err := (&exec.Pool{Limit: 1}).
+		Add(exec.New("/bin/sh", ""), "-c", "sleep 10").
+		Run(ctx)
+
+	if !errors.Is(err, exec.ErrCanceled) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("should have been canceled: %s", err)
+	}
+}
@@ -0,0 +1,37 @@
+package tests_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.codecomet.dev/core/exec"
+)
+
+type panickingReader struct{}
+
+func (panickingReader) Read([]byte) (int, error) {
+	panic("reader exploded")
+}
+
+func TestStreamPanicLineHandler(t *testing.T) {
+	com := exec.New("sh", "")
+	com.OnStdoutLine(func(line string) {
+		panic("handler exploded on " + line)
+	})
+
+	_, err := com.RunContext(context.Background(), "-c", "echo boom")
+	if !errors.Is(err, exec.ErrStreamPanic) {
+		t.Fatalf("a panicking line handler should fail the execution with ErrStreamPanic, got %v", err)
+	}
+}
+
+func TestStreamPanicStdin(t *testing.T) {
+	com := exec.New("cat", "")
+	com.Stdin = panickingReader{}
+
+	_, err := com.RunContext(context.Background())
+	if !errors.Is(err, exec.ErrStreamPanic) {
+		t.Fatalf("a panicking stdin should fail the execution with ErrStreamPanic, got %v", err)
+	}
+}
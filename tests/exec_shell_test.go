@@ -0,0 +1,28 @@
+package tests_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"go.codecomet.dev/core/exec"
+)
+
+func TestShellQuote(t *testing.T) {
+	if _, err := exec.Shell("true"); !errors.Is(err, exec.ErrShellDisabled) {
+		t.Fatalf("should have returned exec.ErrShellDisabled: %s", err)
+	}
+
+	exec.EnableShell()
+
+	args := []string{"plain", "with space", "it's", "$HOME", "`id`", "a;b|c&d", "", "*"}
+
+	res, err := exec.Shell("printf '%s\\n' " + exec.Quote(args...))
+	if err != nil {
+		t.Fatalf("unexpected failure! %s", err)
+	}
+
+	if out := string(res.Stdout); out != strings.Join(args, "\n")+"\n" {
+		t.Fatalf("arguments should have reached the shell verbatim: %q", out)
+	}
+}
@@ -0,0 +1,87 @@
+package tests_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.codecomet.dev/core/exec"
+)
+
+var errNotYet = errors.New("not yet")
+
+func TestWaitFor(t *testing.T) {
+	attempts := 0
+
+	err := exec.WaitFor(context.Background(), func(context.Context) error {
+		attempts++
+		if attempts < 3 {
+			return errNotYet
+		}
+
+		return nil
+	}, time.Millisecond, time.Second)
+	if err != nil || attempts != 3 {
+		t.Fatalf("the probe should succeed on the third attempt, got %v after %d", err, attempts)
+	}
+}
+
+func TestWaitForTimeout(t *testing.T) {
+	attempts := 0
+
+	// A zero interval must not spin
+	err := exec.WaitFor(context.Background(), func(context.Context) error {
+		attempts++
+
+		return errNotYet
+	}, 0, 100*time.Millisecond)
+
+	var waitErr *exec.WaitError
+	if !errors.Is(err, exec.ErrNotReady) || !errors.Is(err, errNotYet) || !errors.As(err, &waitErr) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if attempts < 2 || attempts > 10 || waitErr.Attempts != attempts {
+		t.Fatalf("unexpected attempts: %d (%d reported)", attempts, waitErr.Attempts)
+	}
+}
+
+func TestWaitForCanceled(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	err := exec.WaitFor(ctx, func(context.Context) error {
+		return errNotYet
+	}, 10*time.Millisecond, time.Minute)
+	if !errors.Is(err, exec.ErrCanceled) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestCommandProbe(t *testing.T) {
+	ready := filepath.Join(t.TempDir(), "ready")
+
+	com := exec.New("/bin/sh", "")
+	com.NoReport = true
+
+	// Ready on the second attempt
+	probe := com.Probe("-c", `test -e "$0" || { touch "$0"; echo "starting" >&2; exit 1; }`, ready)
+
+	if err := probe(context.Background()); err == nil {
+		t.Fatal("the first attempt should fail")
+	}
+
+	if err := exec.WaitFor(context.Background(), probe, time.Millisecond, time.Second); err != nil {
+		t.Fatalf("unexpected failure! %s", err)
+	}
+
+	err := exec.WaitFor(context.Background(), com.Probe("-c", "echo broken >&2; exit 3"), time.Millisecond,
+		50*time.Millisecond)
+
+	var stderrErr *exec.StderrError
+	if !errors.Is(err, exec.ErrNotReady) || !errors.As(err, &stderrErr) || stderrErr.Stderr != "broken" {
+		t.Fatalf("the last probe error should carry stderr, got %v", err)
+	}
+}
@@ -0,0 +1,75 @@
+package tests_test
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.codecomet.dev/core/exec"
+)
+
+func watchedShell(dog *exec.Watchdog) *exec.Commander {
+	com := exec.New("/bin/sh", "")
+	com.NoReport = true
+	com.Watchdog = dog
+
+	return com
+}
+
+func TestWatchdogIdle(t *testing.T) {
+	start := time.Now()
+
+	_, err := watchedShell(&exec.Watchdog{Inactivity: 100 * time.Millisecond}).Run("-c", "echo started; sleep 10")
+
+	var inactivity *exec.InactivityError
+	if !errors.Is(err, exec.ErrInactive) || !errors.As(err, &inactivity) || inactivity.Idle < 100*time.Millisecond {
+		t.Fatalf("an idle command should fail with ErrInactive, got %v", err)
+	}
+
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("the idle command should have been terminated, took %s", elapsed)
+	}
+}
+
+func TestWatchdogOnIdle(t *testing.T) {
+	var calls int32
+
+	start := time.Now()
+
+	// Two more periods, then give up
+	_, err := watchedShell(&exec.Watchdog{
+		Inactivity: 50 * time.Millisecond,
+		OnIdle: func(idle time.Duration) bool {
+			return atomic.AddInt32(&calls, 1) < 3
+		},
+	}).Run("-c", "sleep 10")
+	if !errors.Is(err, exec.ErrInactive) || atomic.LoadInt32(&calls) != 3 {
+		t.Fatalf("the command should fail on the third idle period, got %v after %d calls", err, calls)
+	}
+
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Fatalf("OnIdle should have extended the deadline, terminated after %s", elapsed)
+	}
+
+	// Extended for as long as it takes
+	if _, err = watchedShell(&exec.Watchdog{
+		Inactivity: 50 * time.Millisecond,
+		OnIdle:     func(time.Duration) bool { return true },
+	}).Run("-c", "sleep 0.3"); err != nil {
+		t.Fatalf("unexpected failure! %s", err)
+	}
+}
+
+func TestWatchdogSteadyOutput(t *testing.T) {
+	// Runs for longer than Inactivity, but never stays silent that long
+	res, err := watchedShell(&exec.Watchdog{Inactivity: 300 * time.Millisecond}).Run("-c",
+		"for i in 1 2 3 4 5 6 7 8; do echo $i; sleep 0.1; done")
+	if err != nil {
+		t.Fatalf("unexpected failure! %s", err)
+	}
+
+	if res.Duration < 600*time.Millisecond {
+		t.Fatalf("the command should have outlived Inactivity, took %s", res.Duration)
+	}
+}
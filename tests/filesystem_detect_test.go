@@ -0,0 +1,74 @@
+package tests_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.codecomet.dev/core/filesystem"
+)
+
+func TestDetectType(t *testing.T) {
+	dir := t.TempDir()
+
+	var gzipped bytes.Buffer
+
+	writer := gzip.NewWriter(&gzipped)
+	_, _ = writer.Write([]byte("content"))
+	_ = writer.Close()
+
+	for name, test := range map[string]struct {
+		content    []byte
+		mime       string
+		text       bool
+		compressed bool
+	}{
+		"image.bin":   {[]byte("\x89PNG\r\n\x1a\n0000"), "image/png", false, true},
+		"archive":     {gzipped.Bytes(), "application/x-gzip", false, true},
+		"data.zst":    {[]byte{0x28, 0xB5, 0x2F, 0xFD, 0, 0}, "application/zstd", false, true},
+		"config.json": {[]byte(`{"key": "value"}`), "application/json", true, false},
+		"notes.md":    {[]byte("# Title"), "text/markdown; charset=utf-8", true, false},
+		"page":        {[]byte("<!DOCTYPE html><html></html>"), "text/html; charset=utf-8", true, false},
+		"blob":        {[]byte{0, 1, 2, 3}, "application/octet-stream", false, false},
+	} {
+		pth := filepath.Join(dir, name)
+		if err := os.WriteFile(pth, test.content, 0o600); err != nil {
+			t.Fatalf("unexpected failure! %s", err)
+		}
+
+		detected, err := filesystem.DetectType(pth)
+		if err != nil {
+			t.Fatalf("unexpected failure! %s", err)
+		}
+
+		if detected.MIME != test.mime || detected.Text != test.text || detected.Compressed != test.compressed {
+			t.Errorf("%s: unexpected type %+v", name, detected)
+		}
+	}
+
+	if _, err := filesystem.DetectType(dir); err == nil {
+		t.Error("detecting the type of a directory should fail")
+	}
+}
+
+func TestDetectReader(t *testing.T) {
+	content := "%PDF-1.7\n" + strings.Repeat("x", 2*filesystem.SniffLength)
+
+	detected, reader, err := filesystem.DetectReader("", strings.NewReader(content))
+	if err != nil {
+		t.Fatalf("unexpected failure! %s", err)
+	}
+
+	if detected.MIME != "application/pdf" {
+		t.Fatalf("unexpected type %+v", detected)
+	}
+
+	replayed, _ := io.ReadAll(reader)
+	if string(replayed) != content {
+		t.Fatal("the returned reader should yield the whole content")
+	}
+}
@@ -0,0 +1,80 @@
+package tests_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.codecomet.dev/core/filesystem"
+)
+
+func TestJournalReplay(t *testing.T) {
+	src := t.TempDir()
+	dst := t.TempDir()
+	journalPath := filepath.Join(t.TempDir(), "journal")
+
+	journal, err := filesystem.OpenJournal(src, journalPath)
+	if err != nil {
+		t.Fatalf("unexpected failure! %s", err)
+	}
+
+	if err = os.MkdirAll(filepath.Join(src, "dir"), 0o700); err != nil {
+		t.Fatalf("unexpected failure! %s", err)
+	}
+
+	for _, name := range []string{"dir/a", "b", "dir/a"} {
+		if err = journal.WriteFile(name, []byte("content of "+name), 0o600); err != nil {
+			t.Fatalf("unexpected failure! %s", err)
+		}
+	}
+
+	if err = os.WriteFile(filepath.Join(dst, "b"), []byte("stale"), 0o600); err != nil {
+		t.Fatalf("unexpected failure! %s", err)
+	}
+
+	if err = journal.Remove("b", nil); err != nil {
+		t.Fatalf("unexpected failure! %s", err)
+	}
+
+	if err = journal.WriteFile("../escape", nil, 0o600); !errors.Is(err, filesystem.ErrOutsideRoot) {
+		t.Fatalf("writing outside of the root should fail, got %v", err)
+	}
+
+	_ = journal.Close()
+
+	entries, err := filesystem.ReadJournal(journalPath)
+	if err != nil {
+		t.Fatalf("unexpected failure! %s", err)
+	}
+
+	ops := ""
+	for _, entry := range entries {
+		ops += string(entry.Op) + ":" + entry.Path + " "
+	}
+
+	if ops != "create:dir/a create:b modify:dir/a delete:b " {
+		t.Fatalf("unexpected journal: %s", ops)
+	}
+
+	if err = filesystem.ReplayJournal(entries, src, dst); err != nil {
+		t.Fatalf("unexpected failure! %s", err)
+	}
+
+	if data, _ := os.ReadFile(filepath.Join(dst, "dir", "a")); string(data) != "content of dir/a" {
+		t.Fatalf("unexpected replayed content: %q", data)
+	}
+
+	if _, err = os.Stat(filepath.Join(dst, "b")); !os.IsNotExist(err) {
+		t.Fatalf("deleted file should have been removed")
+	}
+
+	// Content changed behind the journal
+	if err = os.WriteFile(filepath.Join(src, "dir", "a"), []byte("changed"), 0o600); err != nil {
+		t.Fatalf("unexpected failure! %s", err)
+	}
+
+	if err = filesystem.ReplayJournal(entries, src, dst); !errors.Is(err, filesystem.ErrJournalMismatch) {
+		t.Fatalf("replaying stale entries should fail, got %v", err)
+	}
+}
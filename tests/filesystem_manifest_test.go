@@ -0,0 +1,55 @@
+package tests_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.codecomet.dev/core/filesystem"
+)
+
+func TestManifestDiff(t *testing.T) {
+	root := t.TempDir()
+	makeTree(t, root, 1, 2, 2)
+
+	before, err := filesystem.Manifest(root)
+	if err != nil {
+		t.Fatalf("unexpected failure! %s", err)
+	}
+
+	again, _ := filesystem.Manifest(root)
+	if before.Digest() != again.Digest() || !filesystem.DiffManifests(before, again).Empty() {
+		t.Fatalf("manifests of an unchanged tree should be identical")
+	}
+
+	if err = os.WriteFile(filepath.Join(root, "file0"), []byte("changed"), 0o600); err != nil {
+		t.Fatalf("unexpected failure! %s", err)
+	}
+
+	if err = os.Remove(filepath.Join(root, "dir1", "file1")); err != nil {
+		t.Fatalf("unexpected failure! %s", err)
+	}
+
+	if err = os.WriteFile(filepath.Join(root, "dir0", "new"), nil, 0o600); err != nil {
+		t.Fatalf("unexpected failure! %s", err)
+	}
+
+	after, _ := filesystem.Manifest(root)
+	diff := filesystem.DiffManifests(before, after)
+
+	if len(diff.Added) != 1 || diff.Added[0].Path != "dir0/new" {
+		t.Fatalf("should have found dir0/new added: %v", diff.Added)
+	}
+
+	if len(diff.Removed) != 1 || diff.Removed[0].Path != "dir1/file1" {
+		t.Fatalf("should have found dir1/file1 removed: %v", diff.Removed)
+	}
+
+	if len(diff.Changed) != 1 || diff.Changed[0].After.Path != "file0" {
+		t.Fatalf("should have found file0 changed: %v", diff.Changed)
+	}
+
+	if before.Digest() == after.Digest() {
+		t.Fatalf("manifest digest should have changed")
+	}
+}
@@ -0,0 +1,249 @@
+package tests_test
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"testing/fstest"
+
+	"go.codecomet.dev/core/filesystem"
+)
+
+func newTestOverlay(t *testing.T) (*filesystem.Overlay, string) {
+	t.Helper()
+
+	top := fstest.MapFS{
+		"shared.txt":   {Data: []byte("top"), Mode: 0o644},
+		"top.txt":      {Data: []byte("top only"), Mode: 0o644},
+		"dir/a.txt":    {Data: []byte("a"), Mode: 0o644},
+		"dir/sub/x.md": {Data: []byte("x"), Mode: 0o644},
+	}
+
+	bottom := fstest.MapFS{
+		"shared.txt": {Data: []byte("bottom"), Mode: 0o644},
+		"bottom.txt": {Data: []byte("bottom only"), Mode: 0o600},
+		"dir/b.txt":  {Data: []byte("b"), Mode: 0o644},
+	}
+
+	upper := t.TempDir()
+
+	ov, err := filesystem.NewOverlay(upper, top, bottom)
+	if err != nil {
+		t.Fatalf("unexpected failure! %s", err)
+	}
+
+	return ov, upper
+}
+
+func readOverlay(t *testing.T, ov *filesystem.Overlay, name string) string {
+	t.Helper()
+
+	data, err := fs.ReadFile(ov, name)
+	if err != nil {
+		t.Fatalf("unexpected failure reading %s! %s", name, err)
+	}
+
+	return string(data)
+}
+
+func entryNames(t *testing.T, ov *filesystem.Overlay, name string) []string {
+	t.Helper()
+
+	entries, err := ov.ReadDir(name)
+	if err != nil {
+		t.Fatalf("unexpected failure listing %s! %s", name, err)
+	}
+
+	names := []string{}
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+
+	return names
+}
+
+func TestOverlayMergedView(t *testing.T) {
+	ov, _ := newTestOverlay(t)
+
+	if err := ov.WriteFile("upper.txt", []byte("upper"), 0o644); err != nil {
+		t.Fatalf("unexpected failure! %s", err)
+	}
+
+	// Topmost layers win, and listings are merged and sorted whatever the layer
+	if got := readOverlay(t, ov, "shared.txt"); got != "top" {
+		t.Fatalf("the top layer should win, got %q", got)
+	}
+
+	expected := []string{"bottom.txt", "dir", "shared.txt", "top.txt", "upper.txt"}
+	if names := entryNames(t, ov, "."); !reflect.DeepEqual(names, expected) {
+		t.Fatalf("unexpected entries %v", names)
+	}
+
+	if names := entryNames(t, ov, "dir"); !reflect.DeepEqual(names, []string{"a.txt", "b.txt", "sub"}) {
+		t.Fatalf("directories should be merged across layers, got %v", names)
+	}
+
+	if err := fstest.TestFS(ov, "bottom.txt", "dir/a.txt", "dir/b.txt", "dir/sub/x.md", "shared.txt", "top.txt",
+		"upper.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ov.OpenFile("top.txt", os.O_RDONLY, 0); !errors.Is(err, filesystem.ErrReadOnlyOpen) {
+		t.Fatalf("read-only opens should go through Open, got %v", err)
+	}
+}
+
+func TestOverlayCopyUp(t *testing.T) {
+	ov, upper := newTestOverlay(t)
+
+	file, err := ov.OpenFile("dir/b.txt", os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatalf("unexpected failure! %s", err)
+	}
+
+	_, _ = file.WriteString("+")
+	file.Close()
+
+	if got := readOverlay(t, ov, "dir/b.txt"); got != "b+" {
+		t.Fatalf("the lower content should have been copied up before appending, got %q", got)
+	}
+
+	if data, _ := os.ReadFile(filepath.Join(upper, "dir", "b.txt")); string(data) != "b+" {
+		t.Fatalf("the copy should live in the upper directory, got %q", data)
+	}
+
+	// Truncating does not need the lower content, but keeps the mode
+	if err = ov.WriteFile("bottom.txt", []byte("new"), 0o644); err != nil {
+		t.Fatalf("unexpected failure! %s", err)
+	}
+
+	info, err := ov.Stat("bottom.txt")
+	if err != nil || info.Mode().Perm() != 0o600 || readOverlay(t, ov, "bottom.txt") != "new" {
+		t.Fatalf("unexpected copy up of a truncated file: %v %v", info, err)
+	}
+
+	if _, err = ov.OpenFile("dir/sub", os.O_WRONLY, 0); !errors.Is(err, filesystem.ErrIsDirectory) {
+		t.Fatalf("lower directories cannot be opened for writing, got %v", err)
+	}
+}
+
+func TestOverlayWhiteouts(t *testing.T) {
+	ov, _ := newTestOverlay(t)
+
+	if err := ov.Remove("shared.txt"); err != nil {
+		t.Fatalf("unexpected failure! %s", err)
+	}
+
+	// Hidden in every layer, not only the topmost one
+	if _, err := ov.Stat("shared.txt"); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("a removed file should not be visible, got %v", err)
+	}
+
+	if names := entryNames(t, ov, "."); !reflect.DeepEqual(names, []string{"bottom.txt", "dir", "top.txt"}) {
+		t.Fatalf("a removed file should not be listed, got %v", names)
+	}
+
+	if err := ov.Remove("shared.txt"); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("removing twice should fail, got %v", err)
+	}
+
+	if err := ov.WriteFile("shared.txt", []byte("again"), 0o644); err != nil {
+		t.Fatalf("unexpected failure! %s", err)
+	}
+
+	if got := readOverlay(t, ov, "shared.txt"); got != "again" {
+		t.Fatalf("a recreated file should be visible, got %q", got)
+	}
+}
+
+func TestOverlayOpaqueDirectories(t *testing.T) {
+	ov, _ := newTestOverlay(t)
+
+	if err := ov.Remove("dir"); err != nil {
+		t.Fatalf("unexpected failure! %s", err)
+	}
+
+	if _, err := ov.Stat("dir/a.txt"); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("the content of a removed directory should not be visible, got %v", err)
+	}
+
+	// Recreated: the lower content must not come back
+	if err := ov.MkdirAll("dir/sub", 0o755); err != nil {
+		t.Fatalf("unexpected failure! %s", err)
+	}
+
+	if err := ov.WriteFile("dir/c.txt", []byte("c"), 0o644); err != nil {
+		t.Fatalf("unexpected failure! %s", err)
+	}
+
+	if names := entryNames(t, ov, "dir"); !reflect.DeepEqual(names, []string{"c.txt", "sub"}) {
+		t.Fatalf("a recreated directory should be opaque, got %v", names)
+	}
+
+	if names := entryNames(t, ov, "dir/sub"); len(names) != 0 {
+		t.Fatalf("directories below an opaque one should be opaque too, got %v", names)
+	}
+
+	for _, name := range []string{"dir/a.txt", "dir/b.txt", "dir/sub/x.md"} {
+		if _, err := ov.Stat(name); !errors.Is(err, fs.ErrNotExist) {
+			t.Fatalf("%s should not be visible, got %v", name, err)
+		}
+	}
+
+	// Other directories are unaffected
+	if got := readOverlay(t, ov, "top.txt"); got != "top only" {
+		t.Fatalf("unexpected content %q", got)
+	}
+}
+
+func TestOverlayMaterialize(t *testing.T) {
+	ov, _ := newTestOverlay(t)
+
+	if err := ov.Remove("dir/sub"); err != nil {
+		t.Fatalf("unexpected failure! %s", err)
+	}
+
+	if err := ov.WriteFile("dir/a.txt", []byte("changed"), 0o644); err != nil {
+		t.Fatalf("unexpected failure! %s", err)
+	}
+
+	dst := filepath.Join(t.TempDir(), "out")
+	if err := ov.Materialize(dst); err != nil {
+		t.Fatalf("unexpected failure! %s", err)
+	}
+
+	materialized := map[string]string{}
+
+	err := filepath.WalkDir(dst, func(name string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+
+		data, err := os.ReadFile(name)
+		rel, _ := filepath.Rel(dst, name)
+		materialized[filepath.ToSlash(rel)] = string(data)
+
+		return err
+	})
+	if err != nil {
+		t.Fatalf("unexpected failure! %s", err)
+	}
+
+	expected := map[string]string{
+		"bottom.txt": "bottom only",
+		"dir/a.txt":  "changed",
+		"dir/b.txt":  "b",
+		"shared.txt": "top",
+		"top.txt":    "top only",
+	}
+	if !reflect.DeepEqual(materialized, expected) {
+		t.Fatalf("unexpected materialized tree %v", materialized)
+	}
+
+	if info, err := os.Stat(filepath.Join(dst, "bottom.txt")); err != nil || info.Mode().Perm() != 0o600 {
+		t.Fatalf("modes should be preserved: %v %v", info, err)
+	}
+}
@@ -0,0 +1,141 @@
+package tests_test
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"go.codecomet.dev/core/filesystem"
+)
+
+// makeTree creates width directories per level, depth levels deep, each holding files files.
+func makeTree(tb testing.TB, root string, depth int, width int, files int) {
+	tb.Helper()
+
+	for i := 0; i < files; i++ {
+		if err := os.WriteFile(filepath.Join(root, "file"+strconv.Itoa(i)), nil, 0o600); err != nil {
+			tb.Fatalf("unexpected failure! %s", err)
+		}
+	}
+
+	if depth == 0 {
+		return
+	}
+
+	for i := 0; i < width; i++ {
+		dir := filepath.Join(root, "dir"+strconv.Itoa(i))
+		if err := os.Mkdir(dir, 0o700); err != nil {
+			tb.Fatalf("unexpected failure! %s", err)
+		}
+
+		makeTree(tb, dir, depth-1, width, files)
+	}
+}
+
+func TestWalkParallel(t *testing.T) {
+	root := t.TempDir()
+	makeTree(t, root, 3, 4, 5)
+
+	expected := map[string]bool{}
+
+	_ = filepath.WalkDir(root, func(name string, _ fs.DirEntry, _ error) error {
+		if name != root {
+			expected[name] = true
+		}
+
+		return nil
+	})
+
+	entries, wait := filesystem.WalkParallel(context.Background(), root, 3)
+
+	found := map[string]bool{}
+	for entry := range entries {
+		if found[entry.Path] {
+			t.Fatalf("entry sent twice: %s", entry.Path)
+		}
+
+		found[entry.Path] = true
+	}
+
+	if err := wait(); err != nil {
+		t.Fatalf("unexpected failure! %s", err)
+	}
+
+	if len(found) != len(expected) {
+		t.Fatalf("should have found %d entries, found %d", len(expected), len(found))
+	}
+
+	for name := range expected {
+		if !found[name] {
+			t.Fatalf("should have found %s", name)
+		}
+	}
+}
+
+func TestWalkParallelCanceled(t *testing.T) {
+	root := t.TempDir()
+	makeTree(t, root, 3, 4, 5)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	entries, wait := filesystem.WalkParallel(ctx, root, 0)
+
+	<-entries
+	cancel()
+
+	for range entries { //nolint:revive
+	}
+
+	if err := wait(); !errors.Is(err, context.Canceled) {
+		t.Fatalf("should have returned context.Canceled: %s", err)
+	}
+}
+
+func TestWalkParallelMissingRoot(t *testing.T) {
+	entries, wait := filesystem.WalkParallel(context.Background(), filepath.Join(t.TempDir(), "missing"), 0)
+
+	for range entries { //nolint:revive
+	}
+
+	var walkErr *filesystem.WalkError
+	if err := wait(); !errors.As(err, &walkErr) || !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("should have returned a WalkError matching fs.ErrNotExist: %s", err)
+	}
+}
+
+func benchmarkTree(b *testing.B) string {
+	b.Helper()
+
+	root := b.TempDir()
+	makeTree(b, root, 4, 6, 10)
+	b.ResetTimer()
+
+	return root
+}
+
+func BenchmarkWalkParallel(b *testing.B) {
+	root := benchmarkTree(b)
+
+	for i := 0; i < b.N; i++ {
+		entries, wait := filesystem.WalkParallel(context.Background(), root, 0)
+		for range entries { //nolint:revive
+		}
+
+		if err := wait(); err != nil {
+			b.Fatalf("unexpected failure! %s", err)
+		}
+	}
+}
+
+func BenchmarkWalkDir(b *testing.B) {
+	root := benchmarkTree(b)
+
+	for i := 0; i < b.N; i++ {
+		if err := filepath.WalkDir(root, func(string, fs.DirEntry, error) error { return nil }); err != nil {
+			b.Fatalf("unexpected failure! %s", err)
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package tests_test
+
+import (
+	"testing"
+
+	"go.codecomet.dev/core/log"
+)
+
+func TestDetectAccessible(t *testing.T) {
+	cases := []struct {
+		term     string
+		env      string
+		expected bool
+	}{
+		{"xterm", "", false},
+		{"dumb", "", true},
+		{"xterm", "true", true},
+		{"dumb", "0", false},
+		{"xterm", "garbage", false},
+	}
+
+	for _, c := range cases {
+		t.Setenv("TERM", c.term)
+		t.Setenv("CODECOMET_LOG_ACCESSIBLE", c.env)
+
+		if log.DetectAccessible() != c.expected {
+			t.Fatalf("TERM=%q CODECOMET_LOG_ACCESSIBLE=%q should detect %t", c.term, c.env, c.expected)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package tests_test
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"go.codecomet.dev/core/log"
+)
+
+// binaryField returns a binary (CBOR) event holding a single field, v, encoded as value.
+func binaryField(value ...byte) []byte {
+	return append([]byte{0xA1, 0x61, 'v'}, value...)
+}
+
+// decodeBinaryField writes event to a CodecometWriter, and returns the decoded value of v, rendered with fmt.
+func decodeBinaryField(event []byte) (string, error) {
+	var decoded interface{}
+
+	writer := log.CodecometWriter{Out: &bytes.Buffer{}, NoColor: true, FormatExtra: func(evt map[string]interface{},
+		_ *bytes.Buffer,
+	) error {
+		decoded = evt["v"]
+
+		return nil
+	}}
+
+	if _, err := writer.Write(event); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprint(decoded), nil
+}
+
+func TestBinaryEvents(t *testing.T) {
+	previous := zerolog.TimeFieldFormat
+	defer func() { zerolog.TimeFieldFormat = previous }()
+
+	cases := []struct {
+		name       string
+		timeFormat string
+		event      []byte
+		expected   string
+	}{
+		{"uint", time.RFC3339, binaryField(0x19, 0x01, 0x00), "256"},
+		{"negative", time.RFC3339, binaryField(0x38, 0x63), "-100"},
+		{"largest negative int64", time.RFC3339, binaryField(0x3B, 0x7F, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF),
+			"-9223372036854775808"},
+		{"negative bigint", time.RFC3339, binaryField(0x3B, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF),
+			"-18446744073709551616"},
+		{"half float", time.RFC3339, binaryField(0xF9, 0x3E, 0x00), "1.5"},
+		{"half float subnormal", time.RFC3339, binaryField(0xF9, 0x80, 0x01), "-0.000000059604645"},
+		{"float32", time.RFC3339, binaryField(0xFA, 0x3F, 0xC0, 0x00, 0x00), "1.5"},
+		{"float64", time.RFC3339, binaryField(0xFB, 0x3F, 0xB9, 0x99, 0x99, 0x99, 0x99, 0x99, 0x9A), "0.1"},
+		{"NaN", time.RFC3339, binaryField(0xF9, 0x7E, 0x00), "NaN"},
+		{"negative infinity", time.RFC3339, binaryField(0xFA, 0xFF, 0x80, 0x00, 0x00), "-Inf"},
+		{"timestamp", time.RFC3339, binaryField(0xC1, 0x1A, 0x64, 0x27, 0xF9, 0x98), "2023-04-01T09:30:00Z"},
+		{"float timestamp", zerolog.TimeFormatUnixMs,
+			binaryField(0xC1, 0xFB, 0x41, 0xD9, 0x09, 0xFE, 0x66, 0x20, 0x00, 0x00), "1680341400500"},
+		{"unix timestamp", zerolog.TimeFormatUnix, binaryField(0xC1, 0x1A, 0x64, 0x27, 0xF9, 0x98), "1680341400"},
+		{"indefinite array", time.RFC3339, binaryField(0x9F, 0x01, 0x02, 0xFF), "[1 2]"},
+		{"indefinite string", time.RFC3339, binaryField(0x7F, 0x62, 'a', 'b', 0x61, 'c', 0xFF), "abc"},
+		{"indefinite map", time.RFC3339, binaryField(0xBF, 0x61, 'k', 0xF5, 0xFF), "map[k:true]"},
+		{"embedded JSON", time.RFC3339, binaryField(0xD9, 0x01, 0x06, 0x67, '{', '"', 'a', '"', ':', '1', '}'),
+			"map[a:1]"},
+		{"IPv4 address", time.RFC3339, binaryField(0xD9, 0x01, 0x04, 0x44, 10, 0, 0, 1), "10.0.0.1"},
+	}
+
+	for _, c := range cases {
+		zerolog.TimeFieldFormat = c.timeFormat
+
+		decoded, err := decodeBinaryField(c.event)
+		if err != nil {
+			t.Fatalf("%s: unexpected failure! %s", c.name, err)
+		}
+
+		if decoded != c.expected {
+			t.Fatalf("%s: expected %s, got %s", c.name, c.expected, decoded)
+		}
+	}
+}
+
+func TestTruncatedBinaryEvents(t *testing.T) {
+	for name, event := range map[string][]byte{
+		"missing value":              {0xA1, 0x61, 'v'},
+		"missing argument bytes":     binaryField(0x1A, 0x00, 0x01),
+		"short string":               binaryField(0x65, 'a', 'b'),
+		"unterminated array":         binaryField(0x9F, 0x01, 0x02),
+		"unterminated string chunks": binaryField(0x7F, 0x61, 'a'),
+		"missing map entries":        {0xA2, 0x61, 'v', 0x01},
+		"nested too deeply":          binaryField(bytes.Repeat([]byte{0x81}, 100)...),
+	} {
+		if _, err := decodeBinaryField(event); err == nil {
+			t.Fatalf("%s: decoding should have failed", name)
+		}
+	}
+}
+
+func TestFilterBinaryEvents(t *testing.T) {
+	out := &bytes.Buffer{}
+	writer := log.NewFilterWriter(out, log.FieldMatches("v", regexp.MustCompile("^drop")))
+
+	dropped := binaryField(0x64, 'd', 'r', 'o', 'p')
+	if n, err := writer.Write(dropped); err != nil || n != len(dropped) || out.Len() != 0 {
+		t.Fatalf("the event should have been dropped: %d %v %q", n, err, out.Bytes())
+	}
+
+	kept := binaryField(0x64, 'k', 'e', 'e', 'p')
+	if _, err := writer.Write(kept); err != nil || !bytes.Equal(out.Bytes(), kept) {
+		t.Fatalf("the event should have been written as is: %v %q", err, out.Bytes())
+	}
+}
@@ -0,0 +1,52 @@
+package tests_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	pkgerrors "github.com/pkg/errors"
+	"go.codecomet.dev/core/log"
+)
+
+var errChainRoot = errors.New("no such file")
+
+func TestErrorChain(t *testing.T) {
+	cases := []struct {
+		err      error
+		expected interface{}
+	}{
+		{errChainRoot, "no such file"},
+		{fmt.Errorf("open x: %w", errChainRoot), []interface{}{"open x", "no such file"}},
+		{pkgerrors.Wrap(errChainRoot, "open x"), []interface{}{"open x", "no such file"}},
+		{pkgerrors.WithStack(errChainRoot), "no such file"},
+		{fmt.Errorf("loading: %w", pkgerrors.Wrap(errChainRoot, "open x")),
+			[]interface{}{"loading", "open x", "no such file"}},
+	}
+
+	for _, c := range cases {
+		file := filepath.Join(t.TempDir(), "out.log")
+
+		log.Init(&log.Config{Level: log.InfoLevel, File: file})
+		log.Info().Err(c.err).Msg("failed")
+		log.Init(&log.Config{Level: log.InfoLevel})
+
+		content, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatalf("unexpected failure! %s", err)
+		}
+
+		event := map[string]interface{}{}
+		if err = json.Unmarshal(content, &event); err != nil {
+			t.Fatalf("unexpected failure! %s", err)
+		}
+
+		if !reflect.DeepEqual(event["error"], c.expected) {
+			t.Errorf("%q should be marshalled as %v, got %v", c.err, c.expected, event["error"])
+		}
+	}
+}
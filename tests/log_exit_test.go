@@ -0,0 +1,41 @@
+package tests_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.codecomet.dev/core/log"
+)
+
+func TestExitHooksKeepNonBlockingOutput(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "out.log")
+
+	log.Init(&log.Config{Level: log.InfoLevel, File: file, NonBlocking: true})
+	defer log.Init(&log.Config{Level: log.InfoLevel})
+
+	log.AddExitHook(log.ExitHookFunc(func() error {
+		log.Info().Msg("from the hook")
+
+		return nil
+	}))
+
+	log.Info().Msg("before")
+	// Panic events run exit hooks, and the process may go on if the panic is recovered
+	log.WithLevel(log.PanicLevel).Msg("recovered")
+	log.Info().Msg("after")
+
+	log.RunExitHooks()
+
+	content, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatalf("unexpected failure! %s", err)
+	}
+
+	for _, message := range []string{"before", "recovered", "from the hook", "after"} {
+		if !strings.Contains(string(content), `"message":"`+message+`"`) {
+			t.Errorf("missing %q in:\n%s", message, content)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package tests_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"go.codecomet.dev/core/log"
+)
+
+func TestLogGolden(t *testing.T) {
+	log.SetClock(log.FixedClock(time.Date(2023, 4, 1, 9, 30, 0, 0, time.UTC)))
+	defer log.SetClock(nil)
+
+	var out bytes.Buffer
+
+	logger := zerolog.New(log.CodecometWriter{Out: &out, UTC: true, TimeFormat: time.RFC3339}).With().Timestamp().Logger()
+	logger.Info().Str("key", "value").Msg("hello")
+
+	if !bytes.HasPrefix(out.Bytes(), []byte("\x1b[65m2023-04-01T09:30:00Z\x1b[0m")) {
+		t.Fatalf("should have used the injected clock: %q", out.String())
+	}
+
+	canonical := string(log.Canonical(out.Bytes()))
+	if canonical != log.TimePlaceholder+" INF core            appint: hello  \n\t\t\tkey=value\n" {
+		t.Fatalf("unexpected canonical output: %q", canonical)
+	}
+
+	json := string(log.Canonical([]byte(`{"level":"info","time":1680341400,"message":"at 9:30AM"}`)))
+	if json != `{"level":"info","time":"<time>","message":"at <time>"}` {
+		t.Fatalf("unexpected canonical output: %q", json)
+	}
+}
@@ -0,0 +1,103 @@
+package tests_test
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"go.codecomet.dev/core/log"
+)
+
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.String()
+}
+
+// orderedEvent carries a "seq" field of the application as well, which must not be mistaken for the sequence number.
+func orderedEvent(seq int) []byte {
+	return []byte(fmt.Sprintf(`{"level":"info","seq":%d,%q:%d,"message":"event %d"}`+"\n",
+		100-seq, log.SequenceFieldName, seq, seq))
+}
+
+// binaryOrderedEvent is a binary (CBOR) event holding the sequence number seq (at most 23).
+func binaryOrderedEvent(seq int) []byte {
+	event := []byte{0xBF, 0x60 + byte(len(log.SequenceFieldName))}
+	event = append(event, log.SequenceFieldName...)
+
+	return append(event, byte(seq), 0xFF)
+}
+
+func TestOrderedWriter(t *testing.T) {
+	out := &lockedBuffer{}
+	writer := log.NewOrderedWriter(out, 50*time.Millisecond)
+
+	for _, seq := range []int{1, 3, 2, 4} {
+		if _, err := writer.Write(orderedEvent(seq)); err != nil {
+			t.Fatalf("unexpected failure! %s", err)
+		}
+	}
+
+	expected := string(orderedEvent(1)) + string(orderedEvent(2)) + string(orderedEvent(3)) + string(orderedEvent(4))
+	if out.String() != expected {
+		t.Fatalf("events should have been written in order, got:\n%s", out.String())
+	}
+
+	// 5 never comes (eg: filtered out): 6 is held for the window only
+	_, _ = writer.Write(orderedEvent(6))
+
+	if out.String() != expected {
+		t.Fatal("an event following a gap should be held")
+	}
+
+	time.Sleep(150 * time.Millisecond)
+
+	expected += string(orderedEvent(6))
+	if out.String() != expected {
+		t.Fatalf("a held event should be written once the window elapsed, got:\n%s", out.String())
+	}
+
+	// Too late to be put back in order, and events without sequence numbers: written as is
+	_, _ = writer.Write(orderedEvent(5))
+	_, _ = writer.Write([]byte("plain\n"))
+
+	if err := writer.Close(); err != nil {
+		t.Fatalf("unexpected failure! %s", err)
+	}
+
+	expected += string(orderedEvent(5)) + "plain\n"
+	if out.String() != expected {
+		t.Fatalf("unexpected output:\n%s", out.String())
+	}
+}
+
+func TestOrderedWriterBinaryEvents(t *testing.T) {
+	out := &lockedBuffer{}
+	writer := log.NewOrderedWriter(out, time.Minute)
+
+	for _, seq := range []int{2, 3, 1} {
+		if _, err := writer.Write(binaryOrderedEvent(seq)); err != nil {
+			t.Fatalf("unexpected failure! %s", err)
+		}
+	}
+
+	expected := string(binaryOrderedEvent(1)) + string(binaryOrderedEvent(2)) + string(binaryOrderedEvent(3))
+	if out.String() != expected {
+		t.Fatalf("binary events should have been written in order, got: %x", out.String())
+	}
+}
@@ -0,0 +1,138 @@
+package tests_test
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.codecomet.dev/core/network"
+)
+
+func TestCompressingTransport(t *testing.T) {
+	payload := strings.Repeat(`{"key":"value"}`, 200)
+	encodings := []string{}
+
+	server := httptest.NewServer(network.DecompressRequests(http.HandlerFunc(
+		func(writer http.ResponseWriter, req *http.Request) {
+			body, _ := io.ReadAll(req.Body)
+			if string(body) != payload && string(body) != "small" {
+				t.Errorf("unexpected body: %q", body)
+			}
+
+			writer.WriteHeader(http.StatusNoContent)
+		})))
+	defer server.Close()
+
+	client := &http.Client{Transport: &network.CompressingTransport{
+		Base: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			encodings = append(encodings, req.Header.Get("Content-Encoding"))
+
+			return http.DefaultTransport.RoundTrip(req)
+		}),
+	}}
+
+	for _, body := range []string{payload, "small"} {
+		resp, err := client.Post(server.URL, "application/json", bytes.NewBufferString(body))
+		if err != nil {
+			t.Fatalf("unexpected failure! %s", err)
+		}
+
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusNoContent {
+			t.Fatalf("unexpected status %d", resp.StatusCode)
+		}
+	}
+
+	if len(encodings) != 2 || encodings[0] != "gzip" || encodings[1] != "" {
+		t.Fatalf("only the large body should have been compressed, got %q", encodings)
+	}
+}
+
+func TestCompressingTransportFallback(t *testing.T) {
+	payload := strings.Repeat("a", 4096)
+	requests := 0
+
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
+		requests++
+
+		if req.Header.Get("Content-Encoding") != "" {
+			writer.Header().Set("Accept-Encoding", "identity")
+			writer.WriteHeader(http.StatusUnsupportedMediaType)
+
+			return
+		}
+
+		if body, _ := io.ReadAll(req.Body); string(body) != payload {
+			t.Errorf("unexpected body of %d bytes", len(body))
+		}
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: &network.CompressingTransport{Base: http.DefaultTransport}}
+
+	for i := 0; i < 2; i++ {
+		resp, err := client.Post(server.URL, "text/plain", strings.NewReader(payload))
+		if err != nil {
+			t.Fatalf("unexpected failure! %s", err)
+		}
+
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("unexpected status %d", resp.StatusCode)
+		}
+	}
+
+	// Rejected once, then resent - the second request goes uncompressed right away
+	if requests != 3 {
+		t.Fatalf("expected 3 requests, got %d", requests)
+	}
+}
+
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (fn roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return fn(req)
+}
+
+func TestCompressingTransportUnsupportedEncoding(t *testing.T) {
+	payload := strings.Repeat(`{"key":"value"}`, 200)
+
+	server := httptest.NewServer(network.DecompressRequests(http.HandlerFunc(
+		func(writer http.ResponseWriter, req *http.Request) {
+			body, _ := io.ReadAll(req.Body)
+			if string(body) != payload {
+				t.Errorf("unexpected body: %q", body)
+			}
+
+			writer.WriteHeader(http.StatusNoContent)
+		})))
+	defer server.Close()
+
+	encoding := ""
+
+	client := &http.Client{Transport: &network.CompressingTransport{
+		Encoding: network.EncodingZstd,
+		Base: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			encoding = req.Header.Get("Content-Encoding")
+
+			return http.DefaultTransport.RoundTrip(req)
+		}),
+	}}
+
+	resp, err := client.Post(server.URL, "application/json", bytes.NewBufferString(payload))
+	if err != nil {
+		t.Fatalf("unexpected failure! %s", err)
+	}
+
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent || encoding != "gzip" {
+		t.Fatalf("the request should have been compressed with gzip instead, got %d with %q", resp.StatusCode,
+			encoding)
+	}
+}
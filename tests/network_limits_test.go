@@ -0,0 +1,123 @@
+package tests_test
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.codecomet.dev/core/network"
+)
+
+func TestLimitBodySize(t *testing.T) {
+	limiter := network.Limit(map[string]*network.RouteLimits{
+		"/upload/": {MaxBodySize: 8},
+	}, http.HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
+		if _, err := io.ReadAll(req.Body); err != nil {
+			if !errors.Is(err, network.ErrBodyTooLarge) {
+				t.Errorf("unexpected read error! %s", err)
+			}
+
+			// Discarded: the limiter answered already
+			http.Error(writer, "handler", http.StatusBadRequest)
+
+			return
+		}
+
+		writer.WriteHeader(http.StatusNoContent)
+	}))
+
+	cases := []struct {
+		path   string
+		body   string
+		length int64
+		status int
+	}{
+		{"/upload/a", "12345678", 8, http.StatusNoContent},
+		{"/upload/a", "123456789", 9, http.StatusRequestEntityTooLarge},
+		// Chunked: the size is only known while reading
+		{"/upload/a", "123456789", -1, http.StatusRequestEntityTooLarge},
+		{"/other", "123456789", -1, http.StatusNoContent},
+	}
+
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodPost, tc.path, io.NopCloser(strings.NewReader(tc.body)))
+		req.ContentLength = tc.length
+
+		recorder := httptest.NewRecorder()
+		limiter.ServeHTTP(recorder, req)
+
+		if recorder.Code != tc.status {
+			t.Fatalf("%s (%d bytes): got status %d, expected %d", tc.path, len(tc.body), recorder.Code, tc.status)
+		}
+	}
+
+	stats := limiter.Stats()["/upload/"]
+	if stats.TooLarge != 2 || stats.Served != 2 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestLimitConcurrency(t *testing.T) {
+	entered := make(chan struct{})
+	release := make(chan struct{})
+
+	limiter := network.Limit(map[string]*network.RouteLimits{
+		"/": {MaxConcurrent: 1},
+	}, http.HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
+		entered <- struct{}{}
+		<-release
+	}))
+
+	done := make(chan int)
+
+	go func() {
+		recorder := httptest.NewRecorder()
+		limiter.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/slow", nil))
+		done <- recorder.Code
+	}()
+
+	<-entered
+
+	recorder := httptest.NewRecorder()
+	limiter.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/fast", nil))
+
+	if recorder.Code != http.StatusTooManyRequests || recorder.Header().Get("Retry-After") == "" {
+		t.Fatalf("expected a 429 with Retry-After, got %d", recorder.Code)
+	}
+
+	close(release)
+
+	if code := <-done; code != http.StatusOK {
+		t.Fatalf("first request should have succeeded, got %d", code)
+	}
+
+	if stats := limiter.Stats()["/"]; stats.Throttled != 1 || stats.InFlight != 0 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestNetworkLimitStats(t *testing.T) {
+	net := network.New(&network.Config{}, &network.Config{Routes: map[string]*network.RouteLimits{
+		"/upload/": {MaxBodySize: 4},
+	}})
+
+	ok := http.HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
+		_, _ = io.ReadAll(req.Body)
+	})
+
+	// Both handlers count towards the same route
+	for _, handler := range []http.Handler{net.Handler(ok), net.Handler(ok)} {
+		for _, body := range []string{"1234", "12345"} {
+			req := httptest.NewRequest(http.MethodPost, "/upload/a", strings.NewReader(body))
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+		}
+	}
+
+	stats, found := net.LimitStats()["/upload/"]
+	if !found || stats.Served != 2 || stats.TooLarge != 2 || stats.InFlight != 0 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+}
@@ -0,0 +1,73 @@
+package tests_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"go.codecomet.dev/core/network"
+)
+
+func TestPaginateLinks(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
+		page, _ := strconv.Atoi(req.URL.Query().Get("page"))
+		if page < 2 {
+			writer.Header().Set("Link", fmt.Sprintf(`</items?page=%d>; rel="next", </items?page=2>; rel="last"`, page+1))
+		}
+
+		_ = json.NewEncoder(writer).Encode([]int{page * 2, page*2 + 1})
+	}))
+	defer server.Close()
+
+	fetch := func(ctx context.Context, cursor string) (*network.Page[int], error) {
+		if cursor == "" {
+			cursor = server.URL + "/items"
+		}
+
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, cursor, nil)
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+
+		defer resp.Body.Close()
+
+		page := &network.Page[int]{Response: resp}
+
+		return page, json.NewDecoder(resp.Body).Decode(&page.Items)
+	}
+
+	items, err := network.Paginate(context.Background(), fetch).All()
+	if err != nil {
+		t.Fatalf("unexpected failure! %s", err)
+	}
+
+	if fmt.Sprint(items) != "[0 1 2 3 4 5]" {
+		t.Fatalf("should have followed next links over 3 pages: %v", items)
+	}
+}
+
+func TestPaginateOffsets(t *testing.T) {
+	data := []string{"a", "b", "c", "d", "e"}
+
+	fetch := func(_ context.Context, cursor string) (*network.Page[string], error) {
+		offset := network.OffsetCursor(cursor)
+		end := offset + 2
+
+		if end > len(data) {
+			end = len(data)
+		}
+
+		return &network.Page[string]{Items: data[offset:end], Next: network.NextOffset(cursor, end-offset, 2)}, nil
+	}
+
+	items, err := network.Paginate(context.Background(), fetch).All()
+	if err != nil || fmt.Sprint(items) != "[a b c d e]" {
+		t.Fatalf("should have iterated over all offsets: %v %s", items, err)
+	}
+}
@@ -0,0 +1,145 @@
+package tests_test
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go.codecomet.dev/core/network"
+)
+
+var errUnknownKey = errors.New("unknown key")
+
+func signingServer(t *testing.T, skew time.Duration, maxBody int64) *httptest.Server {
+	t.Helper()
+
+	keys := func(keyID string) ([]byte, error) {
+		if keyID != "test" {
+			return nil, errUnknownKey
+		}
+
+		return []byte("secret"), nil
+	}
+
+	server := httptest.NewServer(network.VerifySignatures(keys, skew, maxBody, http.HandlerFunc(
+		func(writer http.ResponseWriter, req *http.Request) {
+			body, _ := io.ReadAll(req.Body)
+			_, _ = writer.Write(body)
+		})))
+
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+// signedStatus sends body signed through tamper, which may alter the signed request, and returns the status.
+func signedStatus(t *testing.T, url string, key string, body string, tamper func(*http.Request)) int {
+	t.Helper()
+
+	client := &http.Client{Transport: &network.SigningTransport{
+		KeyID: "test",
+		Key:   []byte(key),
+		Base: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			if tamper != nil {
+				tamper(req)
+			}
+
+			return http.DefaultTransport.RoundTrip(req)
+		}),
+	}}
+
+	resp, err := client.Post(url+"/path?b=2&a=1", "text/plain", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("unexpected failure! %s", err)
+	}
+
+	defer resp.Body.Close()
+
+	echoed, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode == http.StatusOK && string(echoed) != body {
+		t.Fatalf("the handler should read the verified body, got %q", echoed)
+	}
+
+	return resp.StatusCode
+}
+
+func TestSignatures(t *testing.T) {
+	server := signingServer(t, 0, 16)
+
+	cases := []struct {
+		name     string
+		key      string
+		body     string
+		tamper   func(*http.Request)
+		expected int
+	}{
+		{"round trip", "secret", "payload", nil, http.StatusOK},
+		{"empty body", "secret", "", nil, http.StatusOK},
+		{"wrong key", "other", "payload", nil, http.StatusUnauthorized},
+		{"tampered body", "secret", "payload", func(req *http.Request) {
+			req.Body = io.NopCloser(strings.NewReader("PAYLOAD"))
+		}, http.StatusUnauthorized},
+		{"tampered query", "secret", "payload", func(req *http.Request) {
+			req.URL.RawQuery = "a=1&b=3"
+		}, http.StatusUnauthorized},
+		{"tampered method", "secret", "payload", func(req *http.Request) {
+			req.Method = http.MethodPut
+		}, http.StatusUnauthorized},
+		{"unsigned", "secret", "payload", func(req *http.Request) {
+			req.Header.Del("Authorization")
+		}, http.StatusUnauthorized},
+		{"too large", "secret", strings.Repeat("x", 17), nil, http.StatusRequestEntityTooLarge},
+		{"too large without length", "secret", strings.Repeat("x", 17), func(req *http.Request) {
+			req.ContentLength = -1
+		}, http.StatusRequestEntityTooLarge},
+	}
+
+	for _, c := range cases {
+		if status := signedStatus(t, server.URL, c.key, c.body, c.tamper); status != c.expected {
+			t.Fatalf("%s: expected status %d, got %d", c.name, c.expected, status)
+		}
+	}
+}
+
+func TestSignatureSkew(t *testing.T) {
+	// Dates have a one second resolution: they are always off by more than this
+	server := signingServer(t, time.Nanosecond, 0)
+
+	if status := signedStatus(t, server.URL, "secret", "payload", nil); status != http.StatusUnauthorized {
+		t.Fatalf("a request outside of the window should be rejected, got %d", status)
+	}
+}
+
+func TestSignatureReplay(t *testing.T) {
+	server := signingServer(t, 0, 0)
+
+	var replayed *http.Request
+
+	status := signedStatus(t, server.URL, "secret", "payload", func(req *http.Request) {
+		replayed = req.Clone(req.Context())
+		replayed.Body, _ = req.GetBody()
+	})
+	if status != http.StatusOK {
+		t.Fatalf("unexpected status %d", status)
+	}
+
+	resp, err := http.DefaultTransport.RoundTrip(replayed)
+	if err != nil {
+		t.Fatalf("unexpected failure! %s", err)
+	}
+
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("a replayed request should be rejected, got %d", resp.StatusCode)
+	}
+
+	// The same content, signed anew, goes through
+	if status = signedStatus(t, server.URL, "secret", "payload", nil); status != http.StatusOK {
+		t.Fatalf("unexpected status %d", status)
+	}
+}
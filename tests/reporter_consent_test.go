@@ -0,0 +1,84 @@
+package tests_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"go.codecomet.dev/core/network"
+	"go.codecomet.dev/core/reporter"
+)
+
+type answer struct {
+	granted bool
+	asked   int
+}
+
+func (a *answer) Confirm(_ string, _ bool) (bool, error) {
+	a.asked++
+
+	return a.granted, nil
+}
+
+func TestConsentWithdrawnAfterInit(t *testing.T) {
+	server := newSentryServer(t)
+	location := filepath.Join(t.TempDir(), "consent.json")
+
+	network.Init(&network.Config{}, &network.Config{})
+	reporter.Init(&reporter.Config{DSN: server.dsn()})
+
+	// Never asked: reported
+	reporter.CaptureMessage("before asking")
+	reporter.Shutdown()
+
+	if len(server.received()) != 1 {
+		t.Fatalf("crashes should be reported until consent is refused, got %d envelopes", len(server.received()))
+	}
+
+	refusal := &answer{}
+	if granted, err := reporter.EnsureConsent(refusal, location); err != nil || granted {
+		t.Fatalf("unexpected consent: %t %v", granted, err)
+	}
+
+	reporter.CaptureMessage("after refusing")
+	reporter.Shutdown()
+
+	if len(server.received()) != 1 {
+		t.Fatalf("nothing should be reported after a refusal, got %d envelopes", len(server.received()))
+	}
+
+	// The answer is persisted
+	if granted, err := reporter.EnsureConsent(refusal, location); err != nil || granted || refusal.asked != 1 {
+		t.Fatalf("the recorded answer should be used: %t %v, asked %d times", granted, err, refusal.asked)
+	}
+
+	if err := reporter.RevokeConsent(location); err != nil {
+		t.Fatalf("unexpected failure! %s", err)
+	}
+
+	if granted, err := reporter.EnsureConsent(&answer{granted: true}, location); err != nil || !granted {
+		t.Fatalf("unexpected consent: %t %v", granted, err)
+	}
+
+	reporter.CaptureMessage("after consenting")
+	reporter.Shutdown()
+
+	if len(server.received()) != 2 {
+		t.Fatalf("crashes should be reported again once consent is given, got %d envelopes", len(server.received()))
+	}
+
+	if err := reporter.RevokeConsent(location); err != nil {
+		t.Fatalf("unexpected failure! %s", err)
+	}
+
+	reporter.CaptureMessage("after revoking")
+	reporter.Shutdown()
+
+	if len(server.received()) != 2 {
+		t.Fatalf("nothing should be reported once consent is revoked, got %d envelopes", len(server.received()))
+	}
+
+	// Leave reporting enabled for other tests
+	if _, err := reporter.EnsureConsent(&answer{granted: true}, location); err != nil {
+		t.Fatalf("unexpected failure! %s", err)
+	}
+}
@@ -0,0 +1,42 @@
+package tests_test
+
+import (
+	"testing"
+	"time"
+
+	"go.codecomet.dev/core/log"
+	"go.codecomet.dev/core/network"
+	"go.codecomet.dev/core/reporter"
+)
+
+func TestEscalationsCapturedOnceAcrossInits(t *testing.T) {
+	server := newSentryServer(t)
+
+	network.Init(&network.Config{}, &network.Config{})
+
+	conf := &reporter.Config{DSN: server.dsn(), CaptureEscalations: true}
+	reporter.Init(conf)
+	reporter.Init(conf)
+
+	log.SetBudget("escalation-test", &log.Budget{Count: 1, Window: time.Minute})
+	defer log.SetBudget("escalation-test", nil)
+
+	log.WarnBudget("escalation-test").Msg("within budget")
+	log.WarnBudget("escalation-test").Msg("over budget")
+	reporter.Shutdown()
+
+	if len(server.received()) != 1 {
+		t.Fatalf("the escalation should be captured once, got %d envelopes", len(server.received()))
+	}
+
+	conf.CaptureEscalations = false
+	reporter.Init(conf)
+
+	log.WarnBudget("escalation-test").Msg("within budget")
+	log.WarnBudget("escalation-test").Msg("over budget")
+	reporter.Shutdown()
+
+	if len(server.received()) != 1 {
+		t.Fatalf("escalations should not be captured anymore, got %d envelopes", len(server.received()))
+	}
+}
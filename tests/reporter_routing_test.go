@@ -0,0 +1,58 @@
+package tests_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/getsentry/sentry-go"
+	"go.codecomet.dev/core/network"
+	"go.codecomet.dev/core/reporter"
+)
+
+var errRouted = errors.New("routed failure")
+
+func TestRoutesMatchScopeTags(t *testing.T) {
+	main := newSentryServer(t)
+	routed := newSentryServer(t)
+
+	network.Init(&network.Config{}, &network.Config{})
+	reporter.Init(&reporter.Config{
+		DSN:    main.dsn(),
+		Routes: []*reporter.Route{{DSN: routed.dsn(), Tags: map[string]string{"team": "tests"}}},
+	})
+
+	sentry.ConfigureScope(func(scope *sentry.Scope) {
+		scope.SetUser(sentry.User{ID: "someone"})
+	})
+
+	reporter.Capture(errRouted)
+	reporter.Shutdown()
+
+	if len(main.received()) != 1 || len(routed.received()) != 0 {
+		t.Fatalf("an untagged error should go to the default DSN, got %d and %d envelopes", len(main.received()),
+			len(routed.received()))
+	}
+
+	sentry.ConfigureScope(func(scope *sentry.Scope) {
+		scope.SetTag("team", "tests")
+	})
+	defer sentry.ConfigureScope(func(scope *sentry.Scope) {
+		scope.Clear()
+	})
+
+	reporter.Capture(errRouted)
+	reporter.CapturePanic(errRouted, nil)
+	reporter.Shutdown()
+
+	if len(main.received()) != 1 || len(routed.received()) != 2 {
+		t.Fatalf("errors captured in a tagged scope should be routed, got %d and %d envelopes",
+			len(main.received()), len(routed.received()))
+	}
+
+	for _, envelope := range routed.received() {
+		if !strings.Contains(envelope, `"id":"someone"`) || !strings.Contains(envelope, `"team":"tests"`) {
+			t.Fatalf("routed events should carry the current scope, got:\n%s", envelope)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package tests_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"go.codecomet.dev/core/network"
+	"go.codecomet.dev/core/reporter"
+	"go.codecomet.dev/core/telemetry"
+)
+
+func TestReporterStatsMetrics(t *testing.T) {
+	server := newSentryServer(t)
+
+	network.Init(&network.Config{}, &network.Config{})
+	reporter.Init(&reporter.Config{DSN: server.dsn()})
+
+	reporter.CaptureMessage("counted")
+	reporter.Shutdown()
+
+	stats := reporter.Stats()
+	if stats.Sent == 0 {
+		t.Fatalf("the event should have been counted, got %+v", stats)
+	}
+
+	var sent, queued *telemetry.MetricPoint
+
+	for _, metric := range telemetry.CollectMetrics() {
+		if metric.Scope != "go.codecomet.dev/core/reporter" {
+			continue
+		}
+
+		for _, point := range metric.Points {
+			switch {
+			case metric.Name == "codecomet.reporter.events" && point.Attributes[0].Value.AsString() == "sent":
+				sent = point
+			case metric.Name == "codecomet.reporter.queued":
+				queued = point
+			}
+		}
+	}
+
+	if sent == nil || sent.Value != float64(stats.Sent) || queued == nil || queued.Value != float64(stats.Queued) {
+		t.Fatalf("the reporter counters should be collected, got %+v and %+v", sent, queued)
+	}
+
+	recorder := httptest.NewRecorder()
+	telemetry.MetricsHandler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	expected := `codecomet_reporter_events_total{otel_scope_name="go.codecomet.dev/core/reporter",outcome="sent"} ` +
+		strconv.FormatUint(stats.Sent, 10) + "\n"
+	if !strings.Contains(recorder.Body.String(), expected) {
+		t.Fatalf("missing %q in:\n%s", expected, recorder.Body.String())
+	}
+}
@@ -0,0 +1,309 @@
+package tests_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"go.codecomet.dev/core/telemetry/telemetrytest"
+	"golang.org/x/net/http2"
+)
+
+// message builds protobuf messages.
+type message []byte
+
+func (msg message) varint(field int, value uint64) message {
+	msg = binary.AppendUvarint(msg, uint64(field<<3))
+
+	return binary.AppendUvarint(msg, value)
+}
+
+func (msg message) fixed64(field int, value uint64) message {
+	msg = binary.AppendUvarint(msg, uint64(field<<3|1))
+
+	return binary.LittleEndian.AppendUint64(msg, value)
+}
+
+func (msg message) bytes(field int, value []byte) message {
+	msg = binary.AppendUvarint(msg, uint64(field<<3|2))
+	msg = binary.AppendUvarint(msg, uint64(len(value)))
+
+	return append(msg, value...)
+}
+
+func (msg message) str(field int, value string) message {
+	return msg.bytes(field, []byte(value))
+}
+
+func protoAttribute(key string, value message) message {
+	return message{}.str(1, key).bytes(2, value)
+}
+
+// protoExport wraps the spans or metrics of a scope into an export request.
+func protoExport(content ...message) message {
+	resource := message{}.bytes(1, protoAttribute("service.name", message{}.str(1, "collector-test")))
+	scope := message{}.bytes(1, message{}.str(1, "tests"))
+
+	for _, item := range content {
+		scope = scope.bytes(2, item)
+	}
+
+	return message{}.bytes(1, message{}.bytes(1, resource).bytes(2, scope))
+}
+
+func protoTestSpan(start time.Time) message {
+	return message{}.
+		bytes(1, bytes.Repeat([]byte{1}, 16)).
+		bytes(2, bytes.Repeat([]byte{2}, 8)).
+		bytes(4, bytes.Repeat([]byte{3}, 8)).
+		str(5, "work").
+		varint(6, 3).
+		fixed64(7, uint64(start.UnixNano())).
+		fixed64(8, uint64(start.Add(time.Second).UnixNano())).
+		bytes(9, protoAttribute("string", message{}.str(1, "value"))).
+		bytes(9, protoAttribute("int", message{}.varint(3, 42))).
+		bytes(9, protoAttribute("slice", message{}.bytes(5, message{}.
+			bytes(1, message{}.str(1, "a")).
+			bytes(1, message{}.varint(2, 1))))).
+		bytes(15, message{}.str(2, "failed").varint(3, 2))
+}
+
+func gzipped(t *testing.T, payload []byte) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+
+	writer := gzip.NewWriter(&buf)
+	if _, err := writer.Write(payload); err != nil {
+		t.Fatalf("unexpected failure! %s", err)
+	}
+
+	if err := writer.Close(); err != nil {
+		t.Fatalf("unexpected failure! %s", err)
+	}
+
+	return buf.Bytes()
+}
+
+func exportTo(t *testing.T, client *http.Client, url string, contentType string, payload []byte,
+	header http.Header,
+) *http.Response {
+	t.Helper()
+
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		t.Fatalf("unexpected failure! %s", err)
+	}
+
+	for key, values := range header {
+		req.Header[key] = values
+	}
+
+	req.Header.Set("Content-Type", contentType)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("unexpected failure! %s", err)
+	}
+
+	_, _ = io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	return resp
+}
+
+func TestCollectorHTTP(t *testing.T) {
+	collector := telemetrytest.NewTLSCollector(t)
+	start := time.Unix(1680341400, 0)
+
+	resp := exportTo(t, collector.Client(), collector.URL+telemetrytest.TracesPath, "application/x-protobuf",
+		gzipped(t, protoExport(protoTestSpan(start))), http.Header{"Content-Encoding": {"gzip"}, "X-Tenant": {"tests"}})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected status %s", resp.Status)
+	}
+
+	span := collector.RequireSpan(t, "work")
+
+	if span.TraceID != "01010101010101010101010101010101" || span.SpanID != "0202020202020202" ||
+		span.ParentSpanID != "0303030303030303" || span.Kind != 3 {
+		t.Fatalf("unexpected span: %+v", span)
+	}
+
+	slice, _ := span.Attributes["slice"].([]interface{})
+	if span.Attributes["string"] != "value" || span.Attributes["int"] != int64(42) || len(slice) != 2 ||
+		slice[0] != "a" || slice[1] != true {
+		t.Fatalf("unexpected attributes: %v", span.Attributes)
+	}
+
+	if span.StatusCode != 2 || span.StatusMessage != "failed" || !span.Start.Equal(start) ||
+		span.End.Sub(span.Start) != time.Second {
+		t.Fatalf("unexpected span: %+v", span)
+	}
+
+	if span.Scope != "tests" || span.Resource["service.name"] != "collector-test" {
+		t.Fatalf("unexpected scope or resource: %q %v", span.Scope, span.Resource)
+	}
+
+	// The same span, with the JSON encoding
+	resp = exportTo(t, collector.Client(), collector.URL+telemetrytest.TracesPath, "application/json", []byte(`{
+		"resourceSpans": [{"scopeSpans": [{"scope": {"name": "tests"}, "spans": [{
+			"traceId": "01010101010101010101010101010101", "spanId": "0202020202020202", "name": "json",
+			"startTimeUnixNano": "1680341400000000000", "endTimeUnixNano": "1680341401000000000",
+			"attributes": [{"key": "int", "value": {"intValue": "42"}}],
+			"status": {"code": 1}
+		}]}]}]
+	}`), http.Header{"X-Tenant": {"tests"}})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected status %s", resp.Status)
+	}
+
+	if span = collector.RequireSpan(t, "json"); span.Attributes["int"] != int64(42) || !span.Start.Equal(start) {
+		t.Fatalf("unexpected span: %+v", span)
+	}
+
+	collector.RequireHeader(t, "X-Tenant", "tests")
+
+	requests := collector.Requests()
+	if len(requests) != 2 || !requests[0].Protobuf || !requests[0].Gzip || requests[1].Protobuf ||
+		requests[1].Gzip || !requests[0].TLS || requests[0].GRPC {
+		t.Fatalf("unexpected requests: %+v %+v", requests[0], requests[1])
+	}
+
+	resp = exportTo(t, collector.Client(), collector.URL+telemetrytest.TracesPath, "text/plain", []byte("{}"), nil)
+	if resp.StatusCode != http.StatusUnsupportedMediaType {
+		t.Fatalf("unsupported encodings should be rejected, got %s", resp.Status)
+	}
+}
+
+func TestCollectorMetrics(t *testing.T) {
+	collector := telemetrytest.NewCollector(t)
+
+	resp := exportTo(t, http.DefaultClient, collector.URL+telemetrytest.MetricsPath, "application/json", []byte(`{
+		"resourceMetrics": [{"scopeMetrics": [{"scope": {"name": "tests"}, "metrics": [{
+			"name": "queue", "unit": "1",
+			"gauge": {"dataPoints": [{"asInt": "7", "attributes": [{"key": "q", "value": {"stringValue": "a"}}]}]}
+		}, {
+			"name": "latency", "unit": "ms",
+			"histogram": {"aggregationTemporality": 2, "dataPoints": [
+				{"count": "3", "sum": 12.5, "bucketCounts": ["1", "2", "0"], "explicitBounds": [1, 10]}
+			]}
+		}]}]}]
+	}`), nil)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected status %s", resp.Status)
+	}
+
+	gauge := collector.RequireMetric(t, "queue")
+	if gauge.Kind != telemetrytest.Gauge || len(gauge.Points) != 1 || gauge.Points[0].Value != 7 ||
+		gauge.Points[0].Attributes["q"] != "a" || gauge.Scope != "tests" {
+		t.Fatalf("unexpected gauge: %+v", gauge)
+	}
+
+	checkHistogram(t, collector.RequireMetric(t, "latency"))
+}
+
+func checkHistogram(t *testing.T, histogram *telemetrytest.Metric) {
+	t.Helper()
+
+	if histogram.Kind != telemetrytest.Histogram || histogram.Temporality != 2 || len(histogram.Points) != 1 {
+		t.Fatalf("unexpected histogram: %+v", histogram)
+	}
+
+	point := histogram.Points[0]
+	if point.Count != 3 || point.Sum != 12.5 || len(point.Bounds) != 2 || point.Bounds[1] != 10 ||
+		len(point.BucketCounts) != 3 || point.BucketCounts[1] != 2 {
+		t.Fatalf("unexpected histogram point: %+v", point)
+	}
+}
+
+// grpcExport calls an OTLP/gRPC Export method over h2c, and returns the gRPC status.
+func grpcExport(t *testing.T, collector *telemetrytest.Collector, method string, payload []byte) string {
+	t.Helper()
+
+	client := &http.Client{Transport: &http2.Transport{
+		AllowHTTP: true,
+		DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+			var dialer net.Dialer
+
+			return dialer.DialContext(ctx, network, addr)
+		},
+	}}
+
+	body := append([]byte{1, 0, 0, 0, 0}, gzipped(t, payload)...)
+	binary.BigEndian.PutUint32(body[1:], uint32(len(body)-5))
+
+	resp := exportTo(t, client, "http://"+collector.Endpoint+method, "application/grpc", body,
+		http.Header{"Grpc-Encoding": {"gzip"}, "X-Tenant": {"tests"}})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected status %s", resp.Status)
+	}
+
+	if status := resp.Header.Get("Grpc-Status"); status != "" {
+		return status
+	}
+
+	return resp.Trailer.Get("Grpc-Status")
+}
+
+func TestCollectorGRPC(t *testing.T) {
+	collector := telemetrytest.NewCollector(t)
+
+	if status := grpcExport(t, collector, telemetrytest.TraceServiceMethod,
+		protoExport(protoTestSpan(time.Now()))); status != "0" {
+		t.Fatalf("unexpected gRPC status %q", status)
+	}
+
+	if span := collector.RequireSpan(t, "work"); span.Attributes["string"] != "value" {
+		t.Fatalf("unexpected span: %+v", span)
+	}
+
+	sum := message{}.
+		bytes(1, message{}.bytes(7, protoAttribute("route", message{}.str(1, "/a"))).fixed64(6, 5)).
+		varint(2, 2).
+		varint(3, 1)
+
+	counts := binary.LittleEndian.AppendUint64(nil, 1)
+	counts = binary.LittleEndian.AppendUint64(counts, 2)
+	counts = binary.LittleEndian.AppendUint64(counts, 0)
+	bounds := binary.LittleEndian.AppendUint64(nil, math.Float64bits(1))
+	bounds = binary.LittleEndian.AppendUint64(bounds, math.Float64bits(10))
+
+	histogram := message{}.
+		bytes(1, message{}.fixed64(4, 3).fixed64(5, math.Float64bits(12.5)).bytes(6, counts).bytes(7, bounds)).
+		varint(2, 2)
+
+	if status := grpcExport(t, collector, telemetrytest.MetricsServiceMethod, protoExport(
+		message{}.str(1, "requests").str(3, "1").bytes(7, sum),
+		message{}.str(1, "latency").str(3, "ms").bytes(9, histogram),
+	)); status != "0" {
+		t.Fatalf("unexpected gRPC status %q", status)
+	}
+
+	counter := collector.RequireMetric(t, "requests")
+	if counter.Kind != telemetrytest.Sum || !counter.Monotonic || counter.Temporality != 2 ||
+		len(counter.Points) != 1 || counter.Points[0].Value != 5 || counter.Points[0].Attributes["route"] != "/a" ||
+		counter.Resource["service.name"] != "collector-test" {
+		t.Fatalf("unexpected counter: %+v", counter)
+	}
+
+	checkHistogram(t, collector.RequireMetric(t, "latency"))
+	collector.RequireHeader(t, "X-Tenant", "tests")
+
+	for _, req := range collector.Requests() {
+		if !req.GRPC || !req.Protobuf || !req.Gzip || req.TLS {
+			t.Fatalf("unexpected request: %+v", req)
+		}
+	}
+
+	if status := grpcExport(t, collector, "/unknown.Service/Export", nil); status != "12" {
+		t.Fatalf("unknown methods should be unimplemented, got %q", status)
+	}
+}
@@ -0,0 +1,130 @@
+package tests_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.codecomet.dev/core/network"
+	"go.codecomet.dev/core/telemetry"
+	"go.codecomet.dev/core/telemetry/telemetrytest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestHTTPInstrumentation(t *testing.T) {
+	closer := telemetry.Init(&telemetry.Config{Summary: true, HTTP: true})
+	defer closer.Close()
+
+	defer network.Instrument(nil)
+
+	net := network.New(&network.Config{}, &network.Config{})
+
+	var received trace.SpanContext
+
+	server := httptest.NewServer(net.Handler(http.HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
+		received = trace.SpanContextFromContext(req.Context())
+	})))
+	defer server.Close()
+
+	ctx, parent := telemetry.Start(context.Background(), "parent")
+	defer parent.End(nil)
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+
+	resp, err := (&http.Client{Transport: net.Transport()}).Do(req)
+	if err != nil {
+		t.Fatalf("unexpected failure! %s", err)
+	}
+
+	resp.Body.Close()
+
+	if !received.IsValid() || received.TraceID() != parent.SpanContext().TraceID() {
+		t.Fatalf("the server span should continue the trace of the client, got %v", received.TraceID())
+	}
+
+	// Server span, child of the client span, child of the parent
+	if received.SpanID() == parent.SpanContext().SpanID() {
+		t.Fatal("requests should get spans of their own")
+	}
+}
+
+func TestHTTPHandlerSpans(t *testing.T) {
+	collector := telemetrytest.NewCollector(t)
+
+	closer := telemetry.Init(&telemetry.Config{
+		NoDetectors: true,
+		HTTP:        true,
+		Exporters:   []*telemetry.ExporterConfig{{Type: telemetry.OTLP, Endpoint: collector.URL}},
+	})
+	defer network.Instrument(nil)
+
+	mux := http.NewServeMux()
+	mux.Handle("/users/", telemetry.HTTPRoute("/users/{id}", http.HandlerFunc(func(writer http.ResponseWriter,
+		req *http.Request,
+	) {
+		_, _ = io.Copy(io.Discard, req.Body)
+		_, _ = writer.Write([]byte("hello, world"))
+	})))
+	mux.HandleFunc("/upgrade", func(writer http.ResponseWriter, req *http.Request) {
+		hijacker, ok := writer.(http.Hijacker)
+		if !ok {
+			http.Error(writer, "cannot hijack", http.StatusInternalServerError)
+
+			return
+		}
+
+		conn, buf, err := hijacker.Hijack()
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusInternalServerError)
+
+			return
+		}
+
+		_, _ = buf.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: tests\r\nConnection: Upgrade\r\n\r\n")
+		_ = buf.Flush()
+		conn.Close()
+	})
+
+	server := httptest.NewServer(network.New(&network.Config{}, &network.Config{}).Handler(mux))
+	defer server.Close()
+
+	resp, err := http.Post(server.URL+"/users/42", "text/plain", strings.NewReader("hello"))
+	if err != nil {
+		t.Fatalf("unexpected failure! %s", err)
+	}
+
+	resp.Body.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/upgrade", nil)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "tests")
+
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("unexpected failure! %s", err)
+	}
+
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("the connection should have been hijacked, got %s", resp.Status)
+	}
+
+	if err = closer.Close(); err != nil {
+		t.Fatalf("unexpected failure! %s", err)
+	}
+
+	routed := collector.RequireSpan(t, "HTTP POST /users/{id}")
+	if routed.Attributes["http.route"] != "/users/{id}" ||
+		routed.Attributes["http.request_content_length"] != int64(5) ||
+		routed.Attributes["http.response_content_length"] != int64(12) {
+		t.Fatalf("unexpected attributes: %v", routed.Attributes)
+	}
+
+	if upgraded := collector.RequireSpan(t, "HTTP GET"); upgraded.Attributes["http.status_code"] != int64(101) {
+		t.Fatalf("unexpected attributes: %v", upgraded.Attributes)
+	}
+}
@@ -0,0 +1,113 @@
+package tests_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.codecomet.dev/core/telemetry"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+type metricsRecorder struct {
+	exported [][]*telemetry.MetricData
+}
+
+func (rec *metricsRecorder) Export(_ context.Context, metrics []*telemetry.MetricData) error {
+	rec.exported = append(rec.exported, metrics)
+
+	return nil
+}
+
+func findMetric(metrics []*telemetry.MetricData, name string) *telemetry.MetricData {
+	for _, metric := range metrics {
+		if metric.Scope == "go.codecomet.dev/core/tests" && metric.Name == name {
+			return metric
+		}
+	}
+
+	return nil
+}
+
+func TestMetrics(t *testing.T) {
+	ctx := context.Background()
+	meter := telemetry.Meter("tests")
+
+	requests := meter.Counter("requests", "Requests served", "1")
+	requests.Add(ctx, 1, attribute.String("route", "/a"))
+	requests.Add(ctx, 2, attribute.String("route", "/a"))
+	requests.Add(ctx, 1, attribute.String("route", "/b"))
+
+	latency := meter.Histogram("latency", "Request latency", "ms", 10, 100)
+	for _, value := range []float64{5, 10, 50, 500} {
+		latency.Record(ctx, value)
+	}
+
+	meter.Gauge("queue", "Queue depth", "1").Set(ctx, 7)
+	meter.Gauge("queue", "Queue depth", "1").Set(ctx, 3)
+
+	rec := &metricsRecorder{}
+	closer := telemetry.Init(&telemetry.Config{MetricExporter: rec})
+
+	if err := closer.Close(); err != nil {
+		t.Fatalf("unexpected failure! %s", err)
+	}
+
+	if len(rec.exported) != 1 {
+		t.Fatalf("metrics should have been exported once on close, got %d", len(rec.exported))
+	}
+
+	counter := findMetric(rec.exported[0], "requests")
+	if counter == nil || len(counter.Points) != 2 || counter.Points[0].Value != 3 || counter.Points[1].Value != 1 {
+		t.Fatalf("unexpected counter: %+v", counter)
+	}
+
+	histogram := findMetric(rec.exported[0], "latency")
+	if histogram == nil || histogram.Points[0].Count != 4 || histogram.Points[0].Sum != 565 {
+		t.Fatalf("unexpected histogram: %+v", histogram)
+	}
+
+	// Bounds are inclusive: 10 falls in the first bucket
+	if counts := histogram.Points[0].Counts; counts[0] != 2 || counts[1] != 1 || counts[2] != 1 {
+		t.Fatalf("unexpected histogram buckets: %v", counts)
+	}
+
+	if gauge := findMetric(rec.exported[0], "queue"); gauge == nil || gauge.Points[0].Value != 3 {
+		t.Fatalf("unexpected gauge: %+v", gauge)
+	}
+}
+
+func TestMetricsHandler(t *testing.T) {
+	ctx := context.Background()
+	meter := telemetry.Meter("tests/prometheus")
+
+	meter.Counter("jobs.done", "Jobs done\nso far", "1").Add(ctx, 2, attribute.String("queue", `say "hi"`))
+	meter.Histogram("job_duration", "", "ms", 1, 10).Record(ctx, 5)
+	// Colliding with our own labels, or with one another once sanitized
+	meter.Histogram("job_size", "", "By", 1).Record(ctx, 5, attribute.String("le", "x"),
+		attribute.String("otel_scope_name", "y"), attribute.String("a.b", "1"), attribute.String("a_b", "2"))
+
+	recorder := httptest.NewRecorder()
+	telemetry.MetricsHandler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	body := recorder.Body.String()
+
+	for _, expected := range []string{
+		"# HELP jobs_done_total Jobs done\\nso far\n# TYPE jobs_done_total counter\n",
+		`jobs_done_total{otel_scope_name="tests/prometheus",queue="say \"hi\""} 2` + "\n",
+		"# TYPE job_duration histogram\n",
+		`job_duration_bucket{otel_scope_name="tests/prometheus",le="1"} 0` + "\n",
+		`job_duration_bucket{otel_scope_name="tests/prometheus",le="10"} 1` + "\n",
+		`job_duration_bucket{otel_scope_name="tests/prometheus",le="+Inf"} 1` + "\n",
+		`job_duration_sum{otel_scope_name="tests/prometheus"} 5` + "\n",
+		`job_duration_count{otel_scope_name="tests/prometheus"} 1` + "\n",
+		`job_size_bucket{otel_scope_name="tests/prometheus",a_b="1;2",exported_le="x",exported_otel_scope_name="y",` +
+			`le="+Inf"} 1` + "\n",
+	} {
+		if !strings.Contains(body, expected) {
+			t.Fatalf("missing %q in:\n%s", expected, body)
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package tests_test
+
+import (
+	"testing"
+
+	"go.codecomet.dev/core/telemetry"
+)
+
+func TestInitReplacesDisabledModules(t *testing.T) {
+	defer telemetry.Init(&telemetry.Config{Disabled: true})
+
+	telemetry.Init(&telemetry.Config{Disabled: true, DisabledModules: []string{"exec", "network"}})
+
+	if telemetry.ModuleEnabled("exec") || telemetry.ModuleEnabled("network") {
+		t.Fatal("configured modules should be disabled")
+	}
+
+	telemetry.Init(&telemetry.Config{Disabled: true, DisabledModules: []string{"network"}})
+
+	if !telemetry.ModuleEnabled("exec") || telemetry.ModuleEnabled("network") {
+		t.Fatal("a reload should disable exactly the configured modules")
+	}
+}
@@ -0,0 +1,115 @@
+package tests_test
+
+import (
+	"context"
+	"encoding/pem"
+	"errors"
+	"testing"
+	"time"
+
+	"go.codecomet.dev/core/network"
+	"go.codecomet.dev/core/telemetry"
+	"go.codecomet.dev/core/telemetry/telemetrytest"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+var errOTLP = errors.New("failed")
+
+func TestOTLPExporter(t *testing.T) {
+	collector := telemetrytest.NewCollector(t)
+
+	closer := telemetry.Init(&telemetry.Config{
+		ServiceName: "otlp-test",
+		NoDetectors: true,
+		Exporters: []*telemetry.ExporterConfig{{
+			Type:     telemetry.OTLP,
+			Endpoint: collector.URL,
+			Headers:  map[string]string{"X-Tenant": "tests"},
+			Gzip:     true,
+		}},
+	})
+
+	ctx, parent := telemetry.Tracer("tests", "v1.0.0").Start(context.Background(), "parent")
+	_, child := telemetry.Tracer("tests", "v1.0.0").Start(ctx, "child")
+
+	child.SetAttributes(attribute.String("string", "value"), attribute.Int("int", 42),
+		attribute.Bool("bool", true), attribute.StringSlice("slice", []string{"a", "b"}))
+	child.SetStatus(codes.Error, errOTLP.Error())
+	child.End()
+	parent.End()
+
+	if err := closer.Close(); err != nil {
+		t.Fatalf("unexpected failure! %s", err)
+	}
+
+	collector.WaitForSpans(t, 2, time.Second)
+
+	exported := collector.RequireSpan(t, "child")
+	root := collector.RequireSpan(t, "parent")
+
+	if exported.TraceID != root.TraceID || exported.ParentSpanID != root.SpanID || root.ParentSpanID != "" {
+		t.Fatalf("the child should belong to the trace of its parent, got %+v and %+v", exported, root)
+	}
+
+	slice, _ := exported.Attributes["slice"].([]interface{})
+	if exported.Attributes["string"] != "value" || exported.Attributes["int"] != int64(42) ||
+		exported.Attributes["bool"] != true || len(slice) != 2 || slice[1] != "b" {
+		t.Fatalf("unexpected attributes: %v", exported.Attributes)
+	}
+
+	if exported.StatusCode != 2 || exported.StatusMessage != "failed" {
+		t.Fatalf("unexpected status: %d %q", exported.StatusCode, exported.StatusMessage)
+	}
+
+	if exported.Scope != "go.codecomet.dev/core/tests" || exported.Resource["service.name"] != "otlp-test" {
+		t.Fatalf("unexpected scope or resource: %q %v", exported.Scope, exported.Resource)
+	}
+
+	if exported.Start.IsZero() || exported.End.Before(exported.Start) {
+		t.Fatalf("unexpected timing: %s - %s", exported.Start, exported.End)
+	}
+
+	collector.RequireHeader(t, "X-Tenant", "tests")
+
+	for _, req := range collector.Requests() {
+		if !req.Gzip || req.Protobuf || req.Path != telemetrytest.TracesPath {
+			t.Fatalf("unexpected request: %+v", req)
+		}
+	}
+}
+
+func TestOTLPExporterTLS(t *testing.T) {
+	collector := telemetrytest.NewTLSCollector(t)
+	t.Setenv("OTLP_TEST_TOKEN", "secret")
+
+	closer := telemetry.Init(&telemetry.Config{
+		NoDetectors: true,
+		Exporters: []*telemetry.ExporterConfig{{
+			Type:     telemetry.OTLP,
+			Endpoint: collector.URL + telemetrytest.TracesPath,
+			Network: &network.Config{RootCAs: []string{string(pem.EncodeToMemory(&pem.Block{
+				Type:  "CERTIFICATE",
+				Bytes: collector.Certificate().Raw,
+			}))}},
+			TokenEnv: "OTLP_TEST_TOKEN",
+		}},
+	})
+
+	_, span := telemetry.Tracer("tests", "v1.0.0").Start(context.Background(), "secure")
+	span.End()
+
+	if err := closer.Close(); err != nil {
+		t.Fatalf("unexpected failure! %s", err)
+	}
+
+	collector.WaitForSpans(t, 1, time.Second)
+	collector.RequireSpan(t, "secure")
+	collector.RequireHeader(t, "Authorization", "Bearer secret")
+
+	for _, req := range collector.Requests() {
+		if !req.TLS || req.Gzip {
+			t.Fatalf("unexpected request: %+v", req)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package tests_test
+
+import (
+	"context"
+	"testing"
+
+	"go.codecomet.dev/core/telemetry"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+func TestTelemetryResource(t *testing.T) {
+	t.Setenv("OTEL_RESOURCE_ATTRIBUTES", "team=core,deployment.environment=overridden")
+
+	closer := telemetry.Init(&telemetry.Config{
+		ServiceName:    "resource-test",
+		ServiceVersion: "1.2.3",
+		Environment:    "staging",
+		Attributes:     map[string]string{"region": "eu"},
+		Summary:        true,
+	})
+	defer closer.Close()
+
+	_, span := telemetry.GetTracerProvider().Tracer("tests").Start(context.Background(), "resource")
+	defer span.End()
+
+	readable, ok := span.(sdktrace.ReadOnlySpan)
+	if !ok {
+		t.Fatal("expected an SDK span")
+	}
+
+	attrs := map[string]string{}
+	for _, attr := range readable.Resource().Attributes() {
+		attrs[string(attr.Key)] = attr.Value.Emit()
+	}
+
+	for key, expected := range map[string]string{
+		"service.name":           "resource-test",
+		"service.version":        "1.2.3",
+		"deployment.environment": "staging",
+		"region":                 "eu",
+		"team":                   "core",
+	} {
+		if attrs[key] != expected {
+			t.Errorf("expected resource attribute %s=%q, got %q", key, expected, attrs[key])
+		}
+	}
+
+	if attrs["host.name"] == "" {
+		t.Error("the host should have been detected")
+	}
+}
@@ -0,0 +1,90 @@
+package tests_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"go.codecomet.dev/core/network"
+	"go.codecomet.dev/core/reporter"
+	"go.codecomet.dev/core/telemetry"
+)
+
+// sentryServer records the envelopes it receives.
+type sentryServer struct {
+	*httptest.Server
+	mu        sync.Mutex
+	envelopes []string
+}
+
+func newSentryServer(t *testing.T) *sentryServer {
+	t.Helper()
+
+	server := &sentryServer{}
+	server.Server = httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+
+		server.mu.Lock()
+		server.envelopes = append(server.envelopes, string(body))
+		server.mu.Unlock()
+
+		writer.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func (server *sentryServer) dsn() string {
+	return "http://key@" + strings.TrimPrefix(server.URL, "http://") + "/1"
+}
+
+func (server *sentryServer) received() []string {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	return append([]string{}, server.envelopes...)
+}
+
+func TestHTTPInstrumentationWithSentry(t *testing.T) {
+	main := newSentryServer(t)
+	routed := newSentryServer(t)
+
+	network.Init(&network.Config{}, &network.Config{})
+	reporter.Init(&reporter.Config{
+		DSN:    main.dsn(),
+		Routes: []*reporter.Route{{DSN: routed.dsn(), Tags: map[string]string{"team": "tests"}}},
+	})
+
+	closer := telemetry.Init(&telemetry.Config{Type: telemetry.SENTRY, HTTP: true})
+	defer closer.Close()
+
+	defer network.Instrument(nil)
+
+	_, span := telemetry.Start(context.Background(), "operation")
+	span.End(nil)
+
+	reporter.CaptureEvent(&reporter.Event{Message: "routed", Tags: map[string]string{"team": "tests"}})
+	reporter.Shutdown()
+	// Spans of requests sent while flushing routes would only be sent now
+	reporter.Shutdown()
+
+	if len(routed.received()) != 1 {
+		t.Fatalf("the event should have been routed, got %d envelopes", len(routed.received()))
+	}
+
+	// Requests to Sentry are not traced: their spans would be sent as transactions, by more requests
+	for _, envelope := range main.received() {
+		if strings.Contains(envelope, `"transaction":"HTTP POST"`) {
+			t.Fatalf("requests to Sentry should not be traced, got:\n%s", envelope)
+		}
+	}
+
+	if len(main.received()) != 1 || !strings.Contains(main.received()[0], `"transaction":"operation"`) {
+		t.Fatalf("only the operation transaction should have been sent, got %q", main.received())
+	}
+}
@@ -0,0 +1,71 @@
+package tests_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go.codecomet.dev/core/telemetry"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+func TestSpanHelper(t *testing.T) {
+	closer := telemetry.Init(&telemetry.Config{Summary: true})
+	defer closer.Close()
+
+	failure := errors.New("failed")
+
+	_, span := telemetry.Start(context.Background(), "operation", attribute.String("key", "value"))
+
+	if err := span.End(failure); !errors.Is(err, failure) {
+		t.Fatalf("End should return the error, got %v", err)
+	}
+
+	readable, ok := span.Span.(sdktrace.ReadOnlySpan)
+	if !ok {
+		t.Fatal("expected an SDK span")
+	}
+
+	if scope := readable.InstrumentationScope().Name; scope != "go.codecomet.dev/core/tests_test" {
+		t.Errorf("the tracer should be the one of the calling package, got %s", scope)
+	}
+
+	if readable.Status().Code != codes.Error || readable.Status().Description != "failed" {
+		t.Errorf("unexpected status: %+v", readable.Status())
+	}
+
+	if events := readable.Events(); len(events) != 1 || events[0].Name != "exception" {
+		t.Errorf("the error should have been recorded, got %+v", events)
+	}
+
+	if readable.EndTime().IsZero() {
+		t.Error("the span should have ended")
+	}
+}
+
+func TestSpanHelperSlashlessCaller(t *testing.T) {
+	closer := telemetry.Init(&telemetry.Config{Summary: true})
+	defer closer.Close()
+
+	// Called through reflection, Start is called by the reflect package, whose import path has no slash - like main
+	results := reflect.ValueOf(telemetry.Start).Call([]reflect.Value{
+		reflect.ValueOf(context.Background()),
+		reflect.ValueOf("operation"),
+	})
+
+	span, _ := results[1].Interface().(*telemetry.Span)
+	defer span.End(nil)
+
+	readable, ok := span.Span.(sdktrace.ReadOnlySpan)
+	if !ok {
+		t.Fatal("expected an SDK span")
+	}
+
+	if scope := readable.InstrumentationScope().Name; strings.HasPrefix(scope, "go.codecomet.dev/core/") {
+		t.Errorf("packages outside of go-core should not get a go-core scope, got %s", scope)
+	}
+}
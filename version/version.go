@@ -49,3 +49,27 @@ func NewReport() *Report {
 
 	return rep
 }
+
+// Module returns the version of the given module as recorded in the build information, or "unknown".
+func Module(path string) string {
+	buildInfo, ok := debug.ReadBuildInfo()
+	if !ok {
+		return unknown
+	}
+
+	if buildInfo.Main.Path == path && buildInfo.Main.Version != "" {
+		return buildInfo.Main.Version
+	}
+
+	for _, dep := range buildInfo.Deps {
+		if dep.Path == path {
+			if dep.Replace != nil && dep.Replace.Version != "" {
+				return dep.Replace.Version
+			}
+
+			return dep.Version
+		}
+	}
+
+	return unknown
+}